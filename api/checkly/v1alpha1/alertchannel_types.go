@@ -33,16 +33,72 @@ type AlertChannelSpec struct {
 	// SendFailure determines if the Failure event should be sent to the alerting channel
 	SendFailure bool `json:"sendfailure,omitempty"`
 
+	// SendDegraded determines if the Degraded event should be sent to the alerting channel
+	SendDegraded bool `json:"senddegraded,omitempty"`
+
+	// SSLExpiry determines if an alert should be sent when the SSL certificate of a checked site is about to expire
+	SSLExpiry bool `json:"sslexpiry,omitempty"`
+
+	// SSLExpiryThreshold holds how many days before the SSL certificate expires the alert is sent, between 1 and 30,
+	// default 30
+	SSLExpiryThreshold int `json:"sslexpirythreshold,omitempty"`
+
 	// OpsGenie holds information about the Opsgenie alert configuration
 	OpsGenie AlertChannelOpsGenie `json:"opsgenie,omitempty"`
 
 	// Email holds information about the Email alert configuration
 	Email checkly.AlertChannelEmail `json:"email,omitempty"`
+
+	// Webhook holds information about the Webhook alert configuration
+	Webhook AlertChannelWebhook `json:"webhook,omitempty"`
+
+	// Slack holds information about the Slack alert configuration
+	Slack AlertChannelSlack `json:"slack,omitempty"`
+
+	// PagerDuty holds information about the PagerDuty alert configuration
+	PagerDuty AlertChannelPagerDuty `json:"pagerduty,omitempty"`
+
+	// SMS holds information about the SMS alert configuration
+	SMS AlertChannelSMS `json:"sms,omitempty"`
+
+	// Phone holds information about the phone call alert configuration
+	Phone AlertChannelPhone `json:"phone,omitempty"`
+
+	// ParentRef holds the name of the AlertChannel this AlertChannel inherits the unset fields from
+	ParentRef string `json:"parentref,omitempty"`
+
+	// PolicyRef holds the name of the AlertPolicy whose defaults the unset fields are taken from, after the parents
+	PolicyRef string `json:"policyref,omitempty"`
+
+	// Tier holds the escalation tier of the AlertChannel, groups alerting to it only alert after the delay of the tier
+	Tier string `json:"tier,omitempty"`
+
+	// Groups holds the names of the groups the AlertChannel is attached to in bulk, a group is only attached once it
+	// exists and is synced to checklyhq.com
+	Groups []string `json:"groups,omitempty"`
+
+	// RawConfig holds a JSON object merged onto the alert channel after the structured fields, it allows setting
+	// attributes the spec does not model yet. Its contents are not validated by the operator.
+	RawConfig string `json:"rawconfig,omitempty"`
+
+	// Credentials references a secret holding the "apikey" and "accountid" of the checklyhq.com account the
+	// AlertChannel is managed in, the operator account is used when unset
+	Credentials corev1.SecretReference `json:"credentials,omitempty"`
+}
+
+// AlertChannelSubscription subscribes a check or group to an AlertChannel
+type AlertChannelSubscription struct {
+	// Name holds the name of the AlertChannel resource
+	Name string `json:"name"`
+
+	// Activated determines if alerts are sent to the AlertChannel, a deactivated subscription is kept in
+	// checklyhq.com without alerting
+	Activated bool `json:"activated,omitempty"`
 }
 
 type AlertChannelOpsGenie struct {
 	// APISecret determines where the secret ref is to pull the OpsGenie API key from
-	APISecret corev1.ObjectReference `json:"apisecret"`
+	APISecret corev1.ObjectReference `json:"apisecret,omitempty"`
 
 	// Region holds information about the OpsGenie region (EU or US)
 	Region string `json:"region,omitempty"`
@@ -51,14 +107,131 @@ type AlertChannelOpsGenie struct {
 	Priority string `json:"priority,omitempty"`
 }
 
+type AlertChannelSlack struct {
+	// URL holds the Slack incoming webhook URL, ex. https://hooks.slack.com/services/..., use URLSecret to keep it out
+	// of the spec
+	URL string `json:"url,omitempty"`
+
+	// URLSecret determines where the secret ref is to pull the Slack incoming webhook URL from, it takes precedence over URL
+	URLSecret corev1.ObjectReference `json:"urlsecret,omitempty"`
+
+	// Channel holds the Slack channel the alerts are posted to, ex. #alerts, the default channel of the webhook if unset
+	Channel string `json:"channel,omitempty"`
+}
+
+type AlertChannelPagerDuty struct {
+	// ServiceKey holds the integration key of the PagerDuty service, use ServiceKeySecret to keep it out of the spec
+	ServiceKey string `json:"servicekey,omitempty"`
+
+	// ServiceKeySecret determines where the secret ref is to pull the PagerDuty integration key from, it takes
+	// precedence over ServiceKey
+	ServiceKeySecret corev1.ObjectReference `json:"servicekeysecret,omitempty"`
+
+	// ServiceName holds the name of the PagerDuty service, it's only shown in the checklyhq.com UI
+	ServiceName string `json:"servicename,omitempty"`
+
+	// Account holds the name of the PagerDuty account, ex. the subdomain of https://example.pagerduty.com
+	Account string `json:"account,omitempty"`
+}
+
+type AlertChannelSMS struct {
+	// Number holds the phone number the text messages are sent to in E.164 format, ex. +14155550123
+	Number string `json:"number,omitempty"`
+
+	// Name holds the name of the recipient shown in the checklyhq.com UI, the name of the AlertChannel if unset
+	Name string `json:"name,omitempty"`
+}
+
+type AlertChannelPhone struct {
+	// Number holds the phone number which is called in E.164 format, ex. +14155550123
+	Number string `json:"number,omitempty"`
+
+	// Name holds the name of the recipient shown in the checklyhq.com UI, the name of the AlertChannel if unset
+	Name string `json:"name,omitempty"`
+}
+
+type AlertChannelWebhook struct {
+	// URL determines where the webhook requests are sent to, ex. https://foo.bar/alerts
+	URL string `json:"url,omitempty"`
+
+	// Method holds the HTTP method used for the webhook requests, default POST
+	Method string `json:"method,omitempty"`
+
+	// Template holds the body of the webhook request, see https://www.checklyhq.com/docs/alerting-and-retries/webhooks/ for the available variables
+	// +kubebuilder:validation:XValidation:rule="size(bytes(self)) <= 65536",messageExpression="'template is ' + string(size(bytes(self))) + ' bytes, over the limit of 65536 bytes'"
+	Template string `json:"template,omitempty"`
+
+	// DedupKey holds a template expression, ex. "{{CHECK_ID}}-{{ALERT_TYPE}}", which is added to the request body as "dedupKey" so repeated alerts for the same check can be collapsed by the receiver
+	DedupKey string `json:"dedupkey,omitempty"`
+
+	// Headers holds the HTTP headers added to the webhook requests
+	// +optional
+	Headers []AlertChannelKeyValue `json:"headers,omitempty"`
+
+	// QueryParameters holds the query parameters added to the webhook URL
+	// +optional
+	QueryParameters []AlertChannelKeyValue `json:"queryparameters,omitempty"`
+
+	// ClientCertSecret references a kubernetes.io/tls secret holding the client certificate and key for receivers
+	// requiring mutual TLS, the operator checks the pair is well-formed and not expired
+	// +optional
+	ClientCertSecret corev1.ObjectReference `json:"clientcertsecret,omitempty"`
+}
+
+// IsZero determines if none of the webhook fields are set
+func (w AlertChannelWebhook) IsZero() bool {
+	return w.URL == "" && w.Method == "" && w.Template == "" && w.DedupKey == "" &&
+		len(w.Headers) == 0 && len(w.QueryParameters) == 0 && w.ClientCertSecret == (corev1.ObjectReference{})
+}
+
+// AlertChannelKeyValue holds a header or query parameter of the webhook requests
+type AlertChannelKeyValue struct {
+	// Key holds the name of the header or query parameter
+	Key string `json:"key"`
+
+	// Value holds the value of the header or query parameter
+	Value string `json:"value,omitempty"`
+}
+
 // AlertChannelStatus defines the observed state of AlertChannel
 type AlertChannelStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 	ID int64 `json:"id"`
+
+	// ConfigHash holds the hash of the alert configuration last synced to checklyhq.com
+	ConfigHash string `json:"confighash,omitempty"`
+
+	// ChecklyName holds the name of the alert channel in checklyhq.com if it differs from the resource name, ex.
+	// because the resource name was already taken
+	ChecklyName string `json:"checklyname,omitempty"`
+
+	// AccountID holds the checklyhq.com account the AlertChannel was created in, empty for the operator account
+	AccountID string `json:"accountid,omitempty"`
+
+	// SyncedGeneration is the generation of the AlertChannel last synced to checklyhq.com
+	SyncedGeneration int64 `json:"syncedgeneration,omitempty"`
+
+	// SyncedAt is the time SyncedGeneration was synced to checklyhq.com
+	SyncedAt *metav1.Time `json:"syncedat,omitempty"`
+
+	// DriftCheckedAt is the time the AlertChannel was last compared to or written to checklyhq.com
+	DriftCheckedAt *metav1.Time `json:"driftcheckedat,omitempty"`
+
+	// ObservedGeneration is the generation of the AlertChannel last reconciled successfully
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the AlertChannel
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+//+kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type==\"Synced\")].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:scope=Cluster
 
@@ -0,0 +1,56 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AlertPolicySpec defines the organisation defaults of the AlertChannels referencing the AlertPolicy
+type AlertPolicySpec struct {
+	// Defaults holds the fields AlertChannels referencing the policy inherit when they don't set them, the same way
+	// they inherit from a parent, ex. the escalation tier or sendfailure. Its parentref and policyref are ignored.
+	Defaults AlertChannelSpec `json:"defaults,omitempty"`
+
+	// RequiredTypes holds the alert channel types AlertChannels referencing the policy have to configure one of, ex.
+	// opsgenie and pagerduty, any type is accepted if unset
+	RequiredTypes []string `json:"requiredtypes,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+
+// AlertPolicy is the Schema for the alertpolicies API
+type AlertPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AlertPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AlertPolicyList contains a list of AlertPolicy
+type AlertPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AlertPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AlertPolicy{}, &AlertPolicyList{})
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -45,6 +46,20 @@ type ApiCheckSpec struct {
 
 	// Group determines in which group does the check belong to
 	Group string `json:"group"`
+
+	// AlertChannelSubscriptions determines where to send the alerts of the check, on top of the alert channels of its
+	// group
+	AlertChannelSubscriptions []AlertChannelSubscription `json:"alertchannelsubscriptions,omitempty"`
+
+	// SetupSnippet holds the name of the Snippet the check runs before its request
+	SetupSnippet string `json:"setupsnippet,omitempty"`
+
+	// TeardownSnippet holds the name of the Snippet the check runs after its request
+	TeardownSnippet string `json:"teardownsnippet,omitempty"`
+
+	// Credentials references a secret in the namespace of the check holding the "apikey" and "accountid" of the
+	// checklyhq.com account the check is managed in, the operator account is used when unset
+	Credentials corev1.LocalObjectReference `json:"credentials,omitempty"`
 }
 
 // ApiCheckStatus defines the observed state of ApiCheck
@@ -57,6 +72,18 @@ type ApiCheckStatus struct {
 
 	// GroupID holds the ID of the group where the check belongs to
 	GroupID int64 `json:"groupId"`
+
+	// AccountID holds the checklyhq.com account the check was created in, empty for the operator account
+	AccountID string `json:"accountid,omitempty"`
+
+	// ObservedGeneration is the generation of the ApiCheck last reconciled successfully
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the ApiCheck
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -64,6 +91,7 @@ type ApiCheckStatus struct {
 //+kubebuilder:printcolumn:name="Status code",type="string",JSONPath=".spec.success",description="Expected status code"
 //+kubebuilder:printcolumn:name="Muted",type="boolean",JSONPath=".spec.muted"
 //+kubebuilder:printcolumn:name="Group",type="string",JSONPath=".spec.group"
+//+kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type==\"Synced\")].status"
 //+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 //+kubebuilder:subresource:status
 
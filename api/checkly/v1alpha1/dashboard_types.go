@@ -0,0 +1,93 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DashboardSpec defines the desired state of Dashboard
+type DashboardSpec struct {
+	// CustomURL holds the subdomain the dashboard is published on, ex. status for status.checkly-dashboards.com, it has
+	// to be unique across all checklyhq.com accounts
+	CustomURL string `json:"customurl"`
+
+	// CustomDomain holds a domain of your own the dashboard is published on as well, ex. status.foo.bar
+	CustomDomain string `json:"customdomain,omitempty"`
+
+	// Tags selects the checks shown on the dashboard by their checklyhq.com tags, all checks are shown when unset
+	Tags []string `json:"tags,omitempty"`
+
+	// Width determines the width of the dashboard, one of FULL or 960PX, default FULL
+	Width string `json:"width,omitempty"`
+
+	// RefreshRate determines how often the dashboard refreshes in seconds, one of 60, 300 or 600, default 60
+	RefreshRate int `json:"refreshrate,omitempty"`
+
+	// Paginate spreads the checks across pages the dashboard cycles through, default false
+	Paginate bool `json:"paginate,omitempty"`
+
+	// PaginationRate determines how often the dashboard turns the page in seconds, one of 30, 60 or 300, default 60
+	PaginationRate int `json:"paginationrate,omitempty"`
+
+	// HideTags hides the tags of the checks on the dashboard, default false
+	HideTags bool `json:"hidetags,omitempty"`
+}
+
+// DashboardStatus defines the observed state of Dashboard
+type DashboardStatus struct {
+	// ID holds the checklyhq.com ID of the dashboard
+	ID string `json:"id,omitempty"`
+
+	// ObservedGeneration is the generation of the Dashboard last reconciled successfully
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the Dashboard
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="URL",type="string",JSONPath=".spec.customurl"
+//+kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type==\"Synced\")].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+
+// Dashboard is the Schema for the dashboards API
+type Dashboard struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DashboardSpec   `json:"spec,omitempty"`
+	Status DashboardStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DashboardList contains a list of Dashboard
+type DashboardList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Dashboard `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Dashboard{}, &DashboardList{})
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -36,6 +37,36 @@ type GroupSpec struct {
 
 	// AlertChannels determines where to send alerts
 	AlertChannels []string `json:"alertchannel,omitempty"`
+
+	// AlertChannelSubscriptions determines where to send alerts, unlike AlertChannels the subscriptions can be
+	// deactivated
+	AlertChannelSubscriptions []AlertChannelSubscription `json:"alertchannelsubscriptions,omitempty"`
+
+	// Routes spread the alerts of the checks of the group across AlertChannels, the first route matching a check
+	// subscribes it to one of the AlertChannels of the route, on top of the alert channels of the group
+	Routes []AlertRoute `json:"routes,omitempty"`
+
+	// Credentials references a secret holding the "apikey" and "accountid" of the checklyhq.com account the group is
+	// managed in, the operator account is used when unset
+	Credentials corev1.SecretReference `json:"credentials,omitempty"`
+}
+
+// AlertRoute subscribes the checks matching its labels to one of its AlertChannels, picked by weight
+type AlertRoute struct {
+	// MatchLabels selects the checks of the group by their labels, an empty selector matches every check
+	MatchLabels map[string]string `json:"matchlabels,omitempty"`
+
+	// AlertChannels lists the AlertChannels the checks are spread across, their weights have to add up to 100
+	AlertChannels []WeightedAlertChannel `json:"alertchannels"`
+}
+
+// WeightedAlertChannel is an AlertChannel of a route with the share of checks routed to it
+type WeightedAlertChannel struct {
+	// Name holds the name of the AlertChannel resource
+	Name string `json:"name"`
+
+	// Weight determines the percentage of the checks of the route subscribed to the AlertChannel
+	Weight int `json:"weight"`
 }
 
 // GroupStatus defines the observed state of Group
@@ -45,9 +76,23 @@ type GroupStatus struct {
 
 	// ID holds the ID of the created checklyhq.com group
 	ID int64 `json:"ID"`
+
+	// AccountID holds the checklyhq.com account the group was created in, empty for the operator account
+	AccountID string `json:"accountid,omitempty"`
+
+	// ObservedGeneration is the generation of the Group last reconciled successfully
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the Group
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type==\"Synced\")].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:scope=Cluster
 
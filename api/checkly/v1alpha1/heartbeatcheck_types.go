@@ -0,0 +1,91 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HeartbeatCheckSpec defines the desired state of HeartbeatCheck
+type HeartbeatCheckSpec struct {
+	// Period determines how often a ping is expected, in PeriodUnit
+	Period int `json:"period"`
+
+	// PeriodUnit holds the unit of the period, one of seconds, minutes, hours or days
+	PeriodUnit string `json:"periodunit"`
+
+	// Grace determines how late a ping may arrive before the check fails, in GraceUnit
+	Grace int `json:"grace,omitempty"`
+
+	// GraceUnit holds the unit of the grace period, one of seconds, minutes, hours or days, default PeriodUnit
+	GraceUnit string `json:"graceunit,omitempty"`
+
+	// Muted determines if the created alert is muted or not, default false
+	Muted bool `json:"muted,omitempty"`
+
+	// AlertChannelSubscriptions determines where to send the alerts of the check
+	AlertChannelSubscriptions []AlertChannelSubscription `json:"alertchannelsubscriptions,omitempty"`
+}
+
+// HeartbeatCheckStatus defines the observed state of HeartbeatCheck
+type HeartbeatCheckStatus struct {
+	// ID holds the checklyhq.com internal ID of the check
+	ID string `json:"id,omitempty"`
+
+	// PingTokenSecret holds the name of the secret the operator keeps the ping token and URL of the check in, the
+	// token is sensitive and not part of the status
+	PingTokenSecret string `json:"pingtokensecret,omitempty"`
+
+	// ObservedGeneration is the generation of the HeartbeatCheck last reconciled successfully
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the HeartbeatCheck
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Period",type="integer",JSONPath=".spec.period"
+//+kubebuilder:printcolumn:name="Unit",type="string",JSONPath=".spec.periodunit"
+//+kubebuilder:printcolumn:name="Muted",type="boolean",JSONPath=".spec.muted"
+//+kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type==\"Synced\")].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+//+kubebuilder:subresource:status
+
+// HeartbeatCheck is the Schema for the heartbeatchecks API
+type HeartbeatCheck struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HeartbeatCheckSpec   `json:"spec,omitempty"`
+	Status HeartbeatCheckStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HeartbeatCheckList contains a list of HeartbeatCheck
+type HeartbeatCheckList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HeartbeatCheck `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HeartbeatCheck{}, &HeartbeatCheckList{})
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MaintenanceWindowSpec defines the desired state of MaintenanceWindow
+type MaintenanceWindowSpec struct {
+	// StartsAt holds when the window starts, ex. 2024-01-31T22:00, in Timezone unless it carries an offset
+	StartsAt string `json:"startsat"`
+
+	// EndsAt holds when the window ends, it has to be after StartsAt
+	EndsAt string `json:"endsat"`
+
+	// RepeatInterval determines after how many RepeatUnits the window repeats, the window doesn't repeat when unset
+	RepeatInterval int `json:"repeatinterval,omitempty"`
+
+	// RepeatUnit holds the unit of the RepeatInterval, one of DAY, WEEK or MONTH
+	RepeatUnit string `json:"repeatunit,omitempty"`
+
+	// RepeatEndsAt holds when the window stops repeating, it repeats indefinitely when unset
+	RepeatEndsAt string `json:"repeatendsat,omitempty"`
+
+	// Timezone holds the IANA timezone of the times without an offset, ex. Europe/London, default the timezone of the
+	// operator
+	Timezone string `json:"timezone,omitempty"`
+
+	// Tags selects the checks and groups muted during the window by their checklyhq.com tags
+	Tags []string `json:"tags"`
+}
+
+// MaintenanceWindowStatus defines the observed state of MaintenanceWindow
+type MaintenanceWindowStatus struct {
+	// ID holds the checklyhq.com internal ID of the maintenance window
+	ID int64 `json:"id,omitempty"`
+
+	// ObservedGeneration is the generation of the MaintenanceWindow last reconciled successfully
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the MaintenanceWindow
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Starts",type="string",JSONPath=".spec.startsat"
+//+kubebuilder:printcolumn:name="Ends",type="string",JSONPath=".spec.endsat"
+//+kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type==\"Synced\")].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+
+// MaintenanceWindow is the Schema for the maintenancewindows API
+type MaintenanceWindow struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MaintenanceWindowSpec   `json:"spec,omitempty"`
+	Status MaintenanceWindowStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// MaintenanceWindowList contains a list of MaintenanceWindow
+type MaintenanceWindowList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MaintenanceWindow `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MaintenanceWindow{}, &MaintenanceWindowList{})
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PrivateLocationSpec defines the desired state of PrivateLocation
+type PrivateLocationSpec struct {
+	// SlugName holds the unique identifier checks reference the private location by, ex. eu-datacenter
+	SlugName string `json:"slugname"`
+
+	// Icon determines the icon shown for the private location in checklyhq.com, default location
+	Icon string `json:"icon,omitempty"`
+}
+
+// PrivateLocationStatus defines the observed state of PrivateLocation
+type PrivateLocationStatus struct {
+	// ID holds the checklyhq.com ID of the private location
+	ID string `json:"id,omitempty"`
+
+	// KeySecret holds the name of the secret the operator keeps the agent API keys of the private location in, the
+	// keys are sensitive and not part of the status
+	KeySecret string `json:"keysecret,omitempty"`
+
+	// ObservedGeneration is the generation of the PrivateLocation last reconciled successfully
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the PrivateLocation
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Slug",type="string",JSONPath=".spec.slugname"
+//+kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type==\"Synced\")].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+//+kubebuilder:subresource:status
+
+// PrivateLocation is the Schema for the privatelocations API
+type PrivateLocation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PrivateLocationSpec   `json:"spec,omitempty"`
+	Status PrivateLocationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// PrivateLocationList contains a list of PrivateLocation
+type PrivateLocationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PrivateLocation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PrivateLocation{}, &PrivateLocationList{})
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SnippetSpec defines the desired state of Snippet
+type SnippetSpec struct {
+	// Script holds the code of the snippet, checks referencing it run it as their setup or teardown script
+	Script string `json:"script"`
+}
+
+// SnippetStatus defines the observed state of Snippet
+type SnippetStatus struct {
+	// ID holds the checklyhq.com internal ID of the snippet
+	ID int64 `json:"id,omitempty"`
+
+	// ObservedGeneration is the generation of the Snippet last reconciled successfully
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the Snippet
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="ID",type="integer",JSONPath=".status.id"
+//+kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type==\"Synced\")].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+
+// Snippet is the Schema for the snippets API
+type Snippet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SnippetSpec   `json:"spec,omitempty"`
+	Status SnippetStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SnippetList contains a list of Snippet
+type SnippetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Snippet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Snippet{}, &SnippetList{})
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Variable is a checklyhq.com environment variable shared by all checks of the account
+type Variable struct {
+	// Key holds the name of the variable, ex. BASE_URL
+	Key string `json:"key"`
+
+	// Value holds the value of the variable, use ValueSecret to keep it out of the spec
+	Value string `json:"value,omitempty"`
+
+	// ValueSecret determines where the secret ref is to pull the value of the variable from, it takes precedence over
+	// Value. Rotating the secret updates the variable in checklyhq.com.
+	ValueSecret corev1.ObjectReference `json:"valuesecret,omitempty"`
+
+	// Locked hides the value of the variable in the checklyhq.com UI, default false
+	Locked bool `json:"locked,omitempty"`
+}
+
+// VariableGroupSpec defines the desired state of VariableGroup
+type VariableGroupSpec struct {
+	// Variables holds the environment variables of the group, their keys have to be unique across all VariableGroups
+	Variables []Variable `json:"variables"`
+}
+
+// VariableGroupStatus defines the observed state of VariableGroup
+type VariableGroupStatus struct {
+	// Keys holds the keys of the checklyhq.com environment variables managed by the group
+	Keys []string `json:"keys,omitempty"`
+
+	// AppliedHash holds the keyed hash of the variables last synced to checklyhq.com, the values can't be recovered from it
+	AppliedHash string `json:"appliedhash,omitempty"`
+
+	// ObservedGeneration is the generation of the VariableGroup last reconciled successfully
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the VariableGroup
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type==\"Synced\")].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+
+// VariableGroup is the Schema for the variablegroups API
+type VariableGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VariableGroupSpec   `json:"spec,omitempty"`
+	Status VariableGroupStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// VariableGroupList contains a list of VariableGroup
+type VariableGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VariableGroup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VariableGroup{}, &VariableGroupList{})
+}
@@ -17,19 +17,27 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	// Embed the IANA timezone database, the distroless image doesn't necessarily ship one.
+	_ "time/tzdata"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -37,8 +45,16 @@ import (
 	"github.com/checkly/checkly-go-sdk"
 
 	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	external "github.com/checkly/checkly-operator/external/checkly"
+	"github.com/checkly/checkly-operator/internal/audit"
 	checklycontrollers "github.com/checkly/checkly-operator/internal/controller/checkly"
+	corecontrollers "github.com/checkly/checkly-operator/internal/controller/core"
 	networkingcontrollers "github.com/checkly/checkly-operator/internal/controller/networking"
+	"github.com/checkly/checkly-operator/internal/logging"
+	"github.com/checkly/checkly-operator/internal/mapping"
+	"github.com/checkly/checkly-operator/internal/metrics"
+	"github.com/checkly/checkly-operator/internal/notify"
+	webhookchecklyv1alpha1 "github.com/checkly/checkly-operator/internal/webhook/checkly/v1alpha1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -47,6 +63,15 @@ var (
 	setupLog = ctrl.Log.WithName("setup")
 )
 
+// version of the operator, set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
+// checklyAPIURLs holds the checklyhq.com API endpoint of each data residency region
+var checklyAPIURLs = map[string]string{
+	"us": "https://api.checklyhq.com",
+	"eu": "https://api.eu.checklyhq.com",
+}
+
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
@@ -59,22 +84,238 @@ func main() {
 	var enableLeaderElection bool
 	var probeAddr string
 	var controllerDomain string
+	var metricsTeamLabel string
+	var metricsNamespaceLabel bool
+	var mode string
+	var policyConfigMapRef string
+	var quotaConfigMapRef string
+	var quotaLabel string
+	var enableAuditLog bool
+	var auditLogPath string
+	var mappingConfigMapRef string
+	var requeueHigh time.Duration
+	var requeueMedium time.Duration
+	var requeueLow time.Duration
+	var maxPendingAge time.Duration
+	var detectDuplicates bool
+	var finalizerTimeout time.Duration
+	var postSyncWebhook string
+	var validateTemplates bool
+	var checklyRegion string
+	var checklyAPIURL string
+	var verifyWrites bool
+	var verifiedReady bool
+	var changeEvents bool
+	var maxIdleConns int
+	var escalationTiersValue string
+	var unknownFields string
+	var deleteQPS float64
+	var apiMaxRetries int
+	var confirmChanges bool
+	var parityLabel string
+	var secretCacheTTL time.Duration
+	var validateOpsGenie bool
+	var blockInternalWebhooks bool
+	var webhookURLAllowlist string
+	var driftInterval time.Duration
+	var alertChannelResync time.Duration
+	var apiCheckResync time.Duration
+	var groupResync time.Duration
+	var heartbeatCheckResync time.Duration
+	var snippetResync time.Duration
+	var privateLocationResync time.Duration
+	var maintenanceWindowResync time.Duration
+	var dashboardResync time.Duration
+	var variableGroupResync time.Duration
+	var retryBaseDelay time.Duration
+	var retryMaxDelay time.Duration
+	var rolloutLabel string
+	var canarySoak time.Duration
+	var enableWebhooks bool
+	var logSampling int
+	var skipFinalizerValue string
+	var recreateTypesValue string
+	var cleanupFinalizers bool
+	var reconcileSummary bool
+	var listPageSize int
+	var validation string
+	var defaultTimezone string
+	var clusterName string
+	var userAgent string
+	var breakerThreshold int
+	var breakerCooldown time.Duration
+	var tagMappingValue string
+	var nameCollision string
+	var nameCharset string
+	var includeArchived bool
+	var resourceCredentials bool
+	var fallbackChannel string
+	var workers int
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&controllerDomain, "controller-domain", "k8s.checklyhq.com", "Domain to use for annotations and finalizers.")
+	flag.StringVar(&metricsTeamLabel, "metrics-team-label", "team", "Label of the resources used to populate the team label of the reconcile metrics.")
+	flag.BoolVar(&metricsNamespaceLabel, "metrics-namespace-label", true, "Populate the namespace label of the reconcile metrics, disable it to reduce cardinality.")
+	flag.StringVar(&mode, "mode", "sync", "Operation mode, either \"sync\" or \"create-only\". In create-only mode resources are created in checklyhq.com but never updated or deleted.")
+	flag.StringVar(&policyConfigMapRef, "policy-configmap", "", "Namespace and name of the ConfigMap holding CEL policies AlertChannels are validated against, ex. checkly-operator-system/alertchannel-policies.")
+	flag.StringVar(&quotaConfigMapRef, "quota-configmap", "", "Namespace and name of the ConfigMap holding the maximum number of AlertChannels per value of the quota label, ex. checkly-operator-system/alertchannel-quotas.")
+	flag.StringVar(&quotaLabel, "quota-label", "team", "Label of the AlertChannels whose value the quotas of the quota ConfigMap apply to.")
+	flag.StringVar(&mappingConfigMapRef, "mapping-configmap", "", "Namespace and name of the ConfigMap the checklyhq.com IDs are mapped to resource names in, ex. checkly-operator-system/checkly-ids.")
+	flag.BoolVar(&enableAuditLog, "enable-audit-log", false, "Write an audit record for every change made to checklyhq.com resources.")
+	flag.StringVar(&auditLogPath, "audit-log-path", "", "File the audit records are appended to, defaults to stdout.")
+	flag.DurationVar(&requeueHigh, "requeue-high", 5*time.Minute, "Interval AlertChannels with the high priority annotation are re-synced after, 0 disables it.")
+	flag.DurationVar(&requeueMedium, "requeue-medium", time.Hour, "Interval AlertChannels with the medium priority annotation are re-synced after, 0 disables it.")
+	flag.DurationVar(&requeueLow, "requeue-low", 24*time.Hour, "Interval AlertChannels with the low priority annotation are re-synced after, 0 disables it.")
+	flag.DurationVar(&maxPendingAge, "max-pending-age", 10*time.Minute, "Age after which AlertChannels not yet synced to checklyhq.com are flagged with the StalePending condition, 0 disables it.")
+	flag.BoolVar(&detectDuplicates, "detect-duplicates", false, "Emit a warning event for AlertChannels with the same configuration as other AlertChannels.")
+	flag.DurationVar(&finalizerTimeout, "finalizer-timeout", 0, "Time after which the finalizer of a resource is removed if deleting it from checklyhq.com keeps failing, 0 disables it.")
+	flag.StringVar(&postSyncWebhook, "post-sync-webhook", "", "URL a summary of every change made to checklyhq.com resources is posted to.")
+	flag.BoolVar(&validateTemplates, "validate-webhook-templates", false, "Render webhook templates against a sample alert and reject AlertChannels whose template doesn't render to valid JSON.")
+	flag.StringVar(&checklyRegion, "checkly-region", "us", "Data residency region of the checklyhq.com account, either \"us\" or \"eu\", determines the API endpoint.")
+	flag.StringVar(&checklyAPIURL, "checkly-api-url", "", "Base URL of the checklyhq.com API, overrides the endpoint of the region.")
+	flag.BoolVar(&verifiedReady, "ready-requires-verification", false, "Only mark AlertChannels Ready once they're read back from checklyhq.com and match the spec, implies --verify-writes.")
+	flag.BoolVar(&verifyWrites, "verify-writes", false, "Read AlertChannels back from checklyhq.com after creating or updating them and retry the write if the change didn't persist, doubles the API calls.")
+	flag.BoolVar(&changeEvents, "change-events", false, "Emit an event listing the changed fields when an AlertChannel is updated in checklyhq.com, reads the AlertChannel before every update.")
+	flag.IntVar(&maxIdleConns, "max-idle-conns", 100, "Size of the idle connection pool shared by all reconcilers for the checklyhq.com API.")
+	flag.StringVar(&escalationTiersValue, "escalation-tiers", "", "Comma separated escalation tiers AlertChannels can be assigned to, with the delay groups alerting to them escalate after, ex. tier1=0m,tier2=15m.")
+	flag.StringVar(&unknownFields, "unknown-fields", checklycontrollers.UnknownFieldsWarn, "Handling of AlertChannel spec fields unknown to the operator, either \"ignore\", \"warn\" (report them in the UnknownFields condition) or \"reject\" (report them and don't sync the AlertChannel).")
+	flag.IntVar(&apiMaxRetries, "api-max-retries", 3, "Number of times a create, update or delete call to the checklyhq.com API is retried with exponential back-off if it fails with a rate limit, server error or timeout, 0 disables retries.")
+	flag.Float64Var(&deleteQPS, "delete-qps", 0, "Maximum number of delete calls per second made to the checklyhq.com API, independent of creates and updates, 0 disables the limit.")
+	flag.BoolVar(&confirmChanges, "confirm-destructive-changes", false, "Hold AlertChannel updates changing where alerts are sent to, ex. the webhook URL, until they're confirmed with the confirm-destructive annotation.")
+	flag.StringVar(&parityLabel, "parity-label", "", "Label identifying the same AlertChannel across environments, AlertChannels sharing its value are expected to set the same fields.")
+	flag.DurationVar(&secretCacheTTL, "secret-cache-ttl", 0, "Time the values of secrets referenced by AlertChannels are cached for, changed secrets are re-read right away, 0 disables the cache.")
+	flag.BoolVar(&blockInternalWebhooks, "block-internal-webhook-urls", false, "Reject AlertChannels whose webhook URL resolves to a private, loopback or link-local address, ex. the cloud metadata endpoint 169.254.169.254.")
+	flag.StringVar(&webhookURLAllowlist, "webhook-url-allowlist", "", "Comma separated CIDRs or IPs webhook URLs may resolve to despite --block-internal-webhook-urls, ex. 10.20.0.0/16,192.168.1.10.")
+	flag.BoolVar(&validateOpsGenie, "validate-opsgenie-keys", false, "Check OpsGenie API keys against the OpsGenie API before syncing AlertChannels, requires access to api.opsgenie.com or api.eu.opsgenie.com.")
+	flag.DurationVar(&driftInterval, "drift-check-interval", 10*time.Minute, "Interval synced resources are compared to checklyhq.com after and updated if they differ, the priority annotation of AlertChannels takes precedence, 0 disables it.")
+	flag.DurationVar(&alertChannelResync, "alertchannel-resync", 0, "Interval synced AlertChannels are re-synced after, 0 uses the drift check interval.")
+	flag.DurationVar(&apiCheckResync, "apicheck-resync", 0, "Interval synced ApiChecks are re-synced after, 0 uses the drift check interval.")
+	flag.DurationVar(&groupResync, "group-resync", 0, "Interval synced Groups are re-synced after, 0 uses the drift check interval.")
+	flag.DurationVar(&heartbeatCheckResync, "heartbeatcheck-resync", 0, "Interval synced HeartbeatChecks are re-synced after, 0 uses the drift check interval.")
+	flag.DurationVar(&snippetResync, "snippet-resync", 0, "Interval synced Snippets are re-synced after, 0 uses the drift check interval.")
+	flag.DurationVar(&privateLocationResync, "privatelocation-resync", 0, "Interval synced PrivateLocations are re-synced after, 0 uses the drift check interval.")
+	flag.DurationVar(&maintenanceWindowResync, "maintenancewindow-resync", 0, "Interval synced MaintenanceWindows are re-synced after, 0 uses the drift check interval.")
+	flag.DurationVar(&dashboardResync, "dashboard-resync", 0, "Interval synced Dashboards are re-synced after, 0 uses the drift check interval.")
+	flag.DurationVar(&variableGroupResync, "variablegroup-resync", 0, "Interval synced VariableGroups are re-synced after, 0 uses the drift check interval.")
+	flag.DurationVar(&retryBaseDelay, "retry-base-delay", 5*time.Millisecond, "Delay of the first retry of a failed reconciliation, it doubles with every further failure.")
+	flag.DurationVar(&retryMaxDelay, "retry-max-delay", 1000*time.Second, "Maximum delay between the retries of a failed reconciliation.")
+	flag.StringVar(&rolloutLabel, "rollout-label", "", "Label grouping AlertChannels into rollout cohorts, changes to a cohort are only synced once its canaries synced them and soaked.")
+	flag.StringVar(&clusterName, "cluster-name", "", "Name of the cluster the operator runs in, included in the user agent of the checklyhq.com API calls.")
+	flag.StringVar(&userAgent, "user-agent", "", "User agent of the checklyhq.com API calls, defaults to checkly-operator/<version> followed by the cluster name.")
+	flag.IntVar(&breakerThreshold, "circuit-breaker-threshold", 0, "Number of consecutive failed checklyhq.com API calls after which the API is considered unavailable and resources back off, 0 disables it.")
+	flag.DurationVar(&breakerCooldown, "circuit-breaker-cooldown", time.Minute, "Time after which a probe is sent to the unavailable checklyhq.com API, it doubles with every failed probe up to 10 minutes.")
+	flag.StringVar(&tagMappingValue, "tag-mapping", "", "Comma separated resource fields mapped onto checklyhq.com tags of checks and groups, ex. metadata.labels.team=team tags them team:<value>.")
+	flag.StringVar(&nameCollision, "name-collision", checklycontrollers.NameCollisionIgnore, "Handling of new AlertChannels whose name is already taken in checklyhq.com, either \"ignore\" (create a duplicate), \"adopt\" (take over the existing alert channel), \"reject\" (don't sync the AlertChannel) or \"suffix\" (create it as <name>-2).")
+	flag.StringVar(&nameCharset, "alertchannel-name-charset", external.NameCharsetAny, "Characters AlertChannel names may contain, for integrations which can't handle unicode names, either \"any\", \"ascii\" (printable ASCII) or \"latin1\" (printable ISO-8859-1). AlertChannels with other characters in their name are not synced.")
+	flag.BoolVar(&includeArchived, "name-collision-include-archived", false, "Consider archived and soft-deleted checklyhq.com alert channels in the name collision handling, they're skipped by default so AlertChannels aren't bound to defunct alert channels.")
+	flag.BoolVar(&resourceCredentials, "resource-credentials", false, "Allow AlertChannels, ApiChecks and Groups to reference a secret with the API key and account ID of another checklyhq.com account they're managed in.")
+	flag.StringVar(&fallbackChannel, "fallback-alert-channel", "", "Name of the AlertChannel checks alert through while an AlertChannel they subscribe to is missing, not synced or failing to sync, it's removed once they recover.")
+	flag.IntVar(&workers, "max-concurrent-reconciles", 1, "Number of reconcile workers of each checklyhq.com resource controller, raise it if the checkly_operator_worker_utilization metric stays at 1.")
+	flag.StringVar(&defaultTimezone, "default-timezone", "UTC", "IANA timezone of the scheduled features of resources which don't set one, ex. Europe/London.")
+	flag.StringVar(&validation, "validation-failure", checklycontrollers.ValidationBlock, "Handling of AlertChannels failing validation, either \"block\" (don't sync them) or \"best-effort\" (sync a sanitized spec and report the fixes in the Sanitized condition).")
+	flag.IntVar(&listPageSize, "list-page-size", 100, "Number of resources requested per page when listing them from the checklyhq.com API, at most 100.")
+	flag.StringVar(&recreateTypesValue, "recreate-alertchannel-types", "", "Comma separated list of alert channel types whose changes are applied by replacing the checklyhq.com alert channel instead of updating it, ex. webhook,opsgenie. Overridden per AlertChannel by the update-strategy annotation.")
+	flag.StringVar(&skipFinalizerValue, "skip-finalizer", "", "Comma separated list of kinds whose resources are deleted without a finalizer, leaving them in place in checklyhq.com, ex. apicheck,group. Valid kinds are alertchannel, apicheck, group, heartbeatcheck, maintenancewindow, snippet, variablegroup, dashboard and privatelocation.")
+	flag.BoolVar(&cleanupFinalizers, "cleanup-finalizers", false, "Remove the finalizer of the operator from every resource it manages and exit without starting the controllers, so resources can be deleted once the operator is uninstalled. Resources are left in place in checklyhq.com.")
+	flag.BoolVar(&reconcileSummary, "reconcile-summary", false, "Log a single line per reconciliation summarizing its outcome (created, updated, deleted, noop or failed), the checklyhq.com ID and duration.")
+	flag.DurationVar(&canarySoak, "canary-soak", 10*time.Minute, "Time the canaries of a rollout cohort have to stay healthy after syncing before the rest of the cohort follows.")
+	flag.IntVar(&logSampling, "log-sampling", 0, "Log only 1 in N identical messages after the first one every second, so bursts during mass reconciles don't drown the other logs. Errors are always logged, 0 disables sampling.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false, "Serve the validating admission webhook rejecting AlertChannels which don't configure exactly one alert channel type with its required fields. Requires the webhook serving certificate, see config/webhook.")
 	opts := zap.Options{
 		// Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
+	opts.ZapOpts = append(opts.ZapOpts, logging.Sampling(logSampling, logging.SamplingTick))
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
 	setupLog.Info("Controller domain setup", "value", controllerDomain)
 
+	// Cleanup mode runs before anything talks to checklyhq.com, it only patches the resources in the cluster
+	if cleanupFinalizers {
+		c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+		if err != nil {
+			setupLog.Error(err, "unable to create client")
+			os.Exit(1)
+		}
+		removed, err := checklycontrollers.CleanupFinalizers(ctrl.LoggerInto(context.Background(), setupLog), c, controllerDomain)
+		if err != nil {
+			setupLog.Error(err, "unable to clean up finalizers", "removed", removed)
+			os.Exit(1)
+		}
+		setupLog.Info("Finalizers cleaned up, exiting", "removed", removed)
+		os.Exit(0)
+	}
+
+	if mode != "sync" && mode != "create-only" {
+		setupLog.Error(fmt.Errorf("unknown mode %q", mode), "invalid mode, valid options are sync and create-only")
+		os.Exit(1)
+	}
+	createOnly := mode == "create-only"
+	metrics.SetPaused(metrics.PausedCreateOnly, createOnly)
+	setupLog.Info("Operation mode setup", "value", mode)
+
+	var err error
+	var policyConfigMap types.NamespacedName
+	if policyConfigMapRef != "" {
+		policyConfigMap, err = parseNamespacedName(policyConfigMapRef)
+		if err != nil {
+			setupLog.Error(err, "policy ConfigMap has to be in the namespace/name format")
+			os.Exit(1)
+		}
+		setupLog.Info("Policy ConfigMap setup", "value", policyConfigMap)
+	}
+
+	var quotaConfigMap types.NamespacedName
+	if quotaConfigMapRef != "" {
+		quotaConfigMap, err = parseNamespacedName(quotaConfigMapRef)
+		if err != nil {
+			setupLog.Error(err, "quota ConfigMap has to be in the namespace/name format")
+			os.Exit(1)
+		}
+		setupLog.Info("Quota ConfigMap setup", "value", quotaConfigMap, "label", quotaLabel)
+	}
+
+	var mappingConfigMap types.NamespacedName
+	if mappingConfigMapRef != "" {
+		mappingConfigMap, err = parseNamespacedName(mappingConfigMapRef)
+		if err != nil {
+			setupLog.Error(err, "mapping ConfigMap has to be in the namespace/name format")
+			os.Exit(1)
+		}
+		setupLog.Info("Mapping ConfigMap setup", "value", mappingConfigMap)
+	}
+
+	var auditLog *audit.Logger
+	var auditFile *os.File
+	if enableAuditLog {
+		auditOutput := os.Stdout
+		if auditLogPath != "" {
+			file, err := os.OpenFile(auditLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+			if err != nil {
+				setupLog.Error(err, "unable to open audit log file")
+				os.Exit(1)
+			}
+			auditFile = file
+			auditOutput = file
+		}
+		auditLog = audit.New(auditOutput)
+		setupLog.Info("Audit log enabled", "path", auditLogPath)
+	}
+
+	var notifier *notify.Notifier
+	if postSyncWebhook != "" {
+		notifier = notify.New(postSyncWebhook)
+		setupLog.Info("Post sync webhook setup", "value", postSyncWebhook)
+	}
+
+	metrics.TeamLabel = metricsTeamLabel
+	metrics.NamespaceLabels = metricsNamespaceLabel
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
 		Metrics: metricsserver.Options{
@@ -89,27 +330,164 @@ func main() {
 		os.Exit(1)
 	}
 
-	baseUrl := "https://api.checklyhq.com"
+	switch unknownFields {
+	case checklycontrollers.UnknownFieldsIgnore, checklycontrollers.UnknownFieldsWarn, checklycontrollers.UnknownFieldsReject:
+	default:
+		setupLog.Error(fmt.Errorf("unknown value %q", unknownFields), "invalid unknown-fields option, valid options are ignore, warn and reject")
+		os.Exit(1)
+	}
+
+	switch nameCollision {
+	case checklycontrollers.NameCollisionIgnore, checklycontrollers.NameCollisionAdopt, checklycontrollers.NameCollisionReject, checklycontrollers.NameCollisionSuffix:
+	default:
+		setupLog.Error(fmt.Errorf("unknown value %q", nameCollision), "invalid name-collision option, valid options are ignore, adopt, reject and suffix")
+		os.Exit(1)
+	}
+
+	switch nameCharset {
+	case external.NameCharsetAny, external.NameCharsetASCII, external.NameCharsetLatin1:
+	default:
+		setupLog.Error(fmt.Errorf("unknown value %q", nameCharset), "invalid alertchannel-name-charset option, valid options are any, ascii and latin1")
+		os.Exit(1)
+	}
+
+	switch validation {
+	case checklycontrollers.ValidationBlock, checklycontrollers.ValidationBestEffort:
+	default:
+		setupLog.Error(fmt.Errorf("unknown value %q", validation), "invalid validation-failure option, valid options are block and best-effort")
+		os.Exit(1)
+	}
+
+	var secretCache *checklycontrollers.SecretCache
+	if secretCacheTTL > 0 {
+		secretCache = checklycontrollers.NewSecretCache(mgr.GetAPIReader(), secretCacheTTL)
+	}
+
+	tagMapping, err := checklycontrollers.ParseTagMapping(tagMappingValue)
+	if err != nil {
+		setupLog.Error(err, "invalid tag mapping")
+		os.Exit(1)
+	}
+
+	checklycontrollers.DefaultTimezone, err = checklycontrollers.ParseTimezone(defaultTimezone)
+	if err != nil {
+		setupLog.Error(err, "invalid default timezone")
+		os.Exit(1)
+	}
+
+	err = checklycontrollers.ValidateRetryDelays(retryBaseDelay, retryMaxDelay)
+	if err != nil {
+		setupLog.Error(err, "invalid retry delays")
+		os.Exit(1)
+	}
+
+	skipFinalizer, err := checklycontrollers.ParseSkipFinalizer(skipFinalizerValue)
+	if err != nil {
+		setupLog.Error(err, "invalid skip-finalizer option")
+		os.Exit(1)
+	}
+
+	recreateTypes, err := checklycontrollers.ParseRecreateTypes(recreateTypesValue)
+	if err != nil {
+		setupLog.Error(err, "invalid recreate-alertchannel-types option")
+		os.Exit(1)
+	}
+
+	escalationTiers, err := checklycontrollers.ParseEscalationTiers(escalationTiersValue)
+	if err != nil {
+		setupLog.Error(err, "invalid escalation tiers")
+		os.Exit(1)
+	}
+
+	var webhookURLPolicy *external.WebhookURLPolicy
+	if blockInternalWebhooks {
+		webhookURLPolicy, err = external.NewWebhookURLPolicy(webhookURLAllowlist)
+		if err != nil {
+			setupLog.Error(err, "invalid webhook URL allowlist")
+			os.Exit(1)
+		}
+	}
+
+	var idMapping *mapping.ConfigMap
+	if mappingConfigMap.Name != "" {
+		idMapping = mapping.New(mgr.GetClient(), mappingConfigMap)
+	}
+
+	baseUrl, ok := checklyAPIURLs[checklyRegion]
+	if !ok {
+		setupLog.Error(fmt.Errorf("unknown region %q", checklyRegion), "invalid checklyhq.com region, valid options are us and eu")
+		os.Exit(1)
+	}
+	if checklyAPIURL != "" {
+		baseUrl = checklyAPIURL
+	}
+	setupLog.Info("checklyhq.com API setup", "region", checklyRegion, "url", baseUrl)
+
 	apiKey := os.Getenv("CHECKLY_API_KEY")
 	if apiKey == "" {
 		setupLog.Error(errors.New("checklyhq.com API key environment variable is undefined"), "checklyhq.com credentials missing")
 		os.Exit(1)
 	}
 
+	// Hashes of secret values kept in the cluster are keyed with the API key, only the operator can compute them
+	hashKey := []byte(apiKey)
+
 	accountId := os.Getenv("CHECKLY_ACCOUNT_ID")
 	if accountId == "" {
 		setupLog.Error(errors.New("checklyhq.com Account ID environment variable is undefined"), "checklyhq.com credentials missing")
 		os.Exit(1)
 	}
 
-	client := checkly.NewClient(
+	if maxIdleConns < 1 {
+		setupLog.Error(fmt.Errorf("invalid value %d", maxIdleConns), "max-idle-conns has to be at least 1")
+		os.Exit(1)
+	}
+
+	if userAgent == "" {
+		userAgent = external.UserAgent(version, clusterName)
+	}
+
+	// During an outage API calls fail fast instead of piling up, all reconcilers back off together
+	breaker := external.NewCircuitBreaker(breakerThreshold, breakerCooldown)
+	httpClient := external.NewHTTPClient(maxIdleConns, userAgent)
+	deprecations := external.NewDeprecationTracker()
+	httpClient.Transport = breaker.Transport(external.InstrumentTransport(external.RetryAfterTransport(deprecations.Transport(httpClient.Transport))))
+
+	// A single client is shared by all reconcilers, the http.Client and its transport are safe for concurrent use
+	checklyClient := checkly.NewClient(
 		baseUrl,
 		apiKey,
-		nil, //custom http client, defaults to http.DefaultClient
+		httpClient,
 		nil, //io.Writer to output debug messages
 	)
 
-	client.SetAccountId(accountId)
+	checklyClient.SetAccountId(accountId)
+
+	// The SDK doesn't list alert channels, which is required to find alert channels created outside the operator
+	var directory *external.AlertChannelDirectory
+	if nameCollision != checklycontrollers.NameCollisionIgnore {
+		directory = external.NewAlertChannelDirectory(baseUrl, apiKey, accountId, listPageSize, includeArchived, httpClient)
+	}
+
+	// Resync intervals of the individual kinds fall back to the drift check interval
+	for _, resync := range []*time.Duration{&alertChannelResync, &apiCheckResync, &groupResync, &heartbeatCheckResync, &snippetResync, &privateLocationResync, &maintenanceWindowResync, &dashboardResync, &variableGroupResync} {
+		if *resync == 0 {
+			*resync = driftInterval
+		}
+	}
+
+	// Deletes are throttled separately, tearing down an environment deletes many resources at once. Retried calls go
+	// through the throttle again.
+	wrapClient := func(c checkly.Client) checkly.Client {
+		return external.NewRetryingClient(external.NewDeleteRateLimitedClient(c, deleteQPS), apiMaxRetries)
+	}
+	apiClient := wrapClient(checklyClient)
+
+	// Resources with their own credentials are managed with clients of their accounts, sharing the HTTP client
+	var accounts *external.Accounts
+	if resourceCredentials {
+		accounts = external.NewAccounts(baseUrl, httpClient, wrapClient)
+	}
 
 	if err = (&networkingcontrollers.IngressReconciler{
 		Client:           mgr.GetClient(),
@@ -119,20 +497,188 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "Ingress")
 		os.Exit(1)
 	}
+	if err = (&corecontrollers.ServiceReconciler{
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		ControllerDomain: controllerDomain,
+		Recorder:         mgr.GetEventRecorderFor("service-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Service")
+		os.Exit(1)
+	}
 	if err = (&checklycontrollers.ApiCheckReconciler{
 		Client:           mgr.GetClient(),
 		Scheme:           mgr.GetScheme(),
-		ApiClient:        client,
+		ApiClient:        apiClient,
 		ControllerDomain: controllerDomain,
+		CreateOnly:       createOnly,
+		SkipFinalizer:    skipFinalizer["apicheck"],
+		ReconcileSummary: reconcileSummary,
+		Audit:            auditLog,
+		Mapping:          idMapping,
+		Notifier:         notifier,
+		FinalizerTimeout: finalizerTimeout,
+		DriftInterval:    apiCheckResync,
+		RetryLimiter:     checklycontrollers.RetryRateLimiter(retryBaseDelay, retryMaxDelay),
+		Breaker:          breaker,
+		Deprecations:     deprecations,
+		Accounts:         accounts,
+		Workers:          workers,
+		TagMapping:       tagMapping,
+		FallbackChannel:  fallbackChannel,
+		Recorder:         mgr.GetEventRecorderFor("apicheck-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ApiCheck")
 		os.Exit(1)
 	}
+	if err = (&checklycontrollers.HeartbeatCheckReconciler{
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		ApiClient:        apiClient,
+		ControllerDomain: controllerDomain,
+		CreateOnly:       createOnly,
+		SkipFinalizer:    skipFinalizer["heartbeatcheck"],
+		ReconcileSummary: reconcileSummary,
+		Audit:            auditLog,
+		Notifier:         notifier,
+		FinalizerTimeout: finalizerTimeout,
+		DriftInterval:    heartbeatCheckResync,
+		RetryLimiter:     checklycontrollers.RetryRateLimiter(retryBaseDelay, retryMaxDelay),
+		Breaker:          breaker,
+		Deprecations:     deprecations,
+		Workers:          workers,
+		Recorder:         mgr.GetEventRecorderFor("heartbeatcheck-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "HeartbeatCheck")
+		os.Exit(1)
+	}
+	if err = (&checklycontrollers.MaintenanceWindowReconciler{
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		ApiClient:        apiClient,
+		ControllerDomain: controllerDomain,
+		CreateOnly:       createOnly,
+		SkipFinalizer:    skipFinalizer["maintenancewindow"],
+		ReconcileSummary: reconcileSummary,
+		Audit:            auditLog,
+		Notifier:         notifier,
+		FinalizerTimeout: finalizerTimeout,
+		DriftInterval:    maintenanceWindowResync,
+		RetryLimiter:     checklycontrollers.RetryRateLimiter(retryBaseDelay, retryMaxDelay),
+		Breaker:          breaker,
+		Deprecations:     deprecations,
+		Workers:          workers,
+		Recorder:         mgr.GetEventRecorderFor("maintenancewindow-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "MaintenanceWindow")
+		os.Exit(1)
+	}
+	if err = (&checklycontrollers.SnippetReconciler{
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		ApiClient:        apiClient,
+		ControllerDomain: controllerDomain,
+		CreateOnly:       createOnly,
+		SkipFinalizer:    skipFinalizer["snippet"],
+		ReconcileSummary: reconcileSummary,
+		Audit:            auditLog,
+		Notifier:         notifier,
+		FinalizerTimeout: finalizerTimeout,
+		DriftInterval:    snippetResync,
+		RetryLimiter:     checklycontrollers.RetryRateLimiter(retryBaseDelay, retryMaxDelay),
+		Breaker:          breaker,
+		Deprecations:     deprecations,
+		Workers:          workers,
+		Recorder:         mgr.GetEventRecorderFor("snippet-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Snippet")
+		os.Exit(1)
+	}
+	if err = (&checklycontrollers.DashboardReconciler{
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		ApiClient:        apiClient,
+		ControllerDomain: controllerDomain,
+		CreateOnly:       createOnly,
+		SkipFinalizer:    skipFinalizer["dashboard"],
+		ReconcileSummary: reconcileSummary,
+		Audit:            auditLog,
+		Notifier:         notifier,
+		FinalizerTimeout: finalizerTimeout,
+		DriftInterval:    dashboardResync,
+		RetryLimiter:     checklycontrollers.RetryRateLimiter(retryBaseDelay, retryMaxDelay),
+		Breaker:          breaker,
+		Deprecations:     deprecations,
+		Workers:          workers,
+		Recorder:         mgr.GetEventRecorderFor("dashboard-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Dashboard")
+		os.Exit(1)
+	}
+	if err = (&checklycontrollers.PrivateLocationReconciler{
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		ApiClient:        apiClient,
+		ControllerDomain: controllerDomain,
+		CreateOnly:       createOnly,
+		SkipFinalizer:    skipFinalizer["privatelocation"],
+		ReconcileSummary: reconcileSummary,
+		Audit:            auditLog,
+		Notifier:         notifier,
+		FinalizerTimeout: finalizerTimeout,
+		DriftInterval:    privateLocationResync,
+		RetryLimiter:     checklycontrollers.RetryRateLimiter(retryBaseDelay, retryMaxDelay),
+		Breaker:          breaker,
+		Deprecations:     deprecations,
+		Workers:          workers,
+		Recorder:         mgr.GetEventRecorderFor("privatelocation-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PrivateLocation")
+		os.Exit(1)
+	}
+	if err = (&checklycontrollers.VariableGroupReconciler{
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		ApiClient:        apiClient,
+		ControllerDomain: controllerDomain,
+		CreateOnly:       createOnly,
+		SkipFinalizer:    skipFinalizer["variablegroup"],
+		ReconcileSummary: reconcileSummary,
+		Audit:            auditLog,
+		Notifier:         notifier,
+		FinalizerTimeout: finalizerTimeout,
+		DriftInterval:    variableGroupResync,
+		RetryLimiter:     checklycontrollers.RetryRateLimiter(retryBaseDelay, retryMaxDelay),
+		Breaker:          breaker,
+		Deprecations:     deprecations,
+		Workers:          workers,
+		HashKey:          hashKey,
+		Recorder:         mgr.GetEventRecorderFor("variablegroup-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "VariableGroup")
+		os.Exit(1)
+	}
 	if err = (&checklycontrollers.GroupReconciler{
 		Client:           mgr.GetClient(),
 		Scheme:           mgr.GetScheme(),
-		ApiClient:        client,
+		ApiClient:        apiClient,
 		ControllerDomain: controllerDomain,
+		CreateOnly:       createOnly,
+		SkipFinalizer:    skipFinalizer["group"],
+		ReconcileSummary: reconcileSummary,
+		Audit:            auditLog,
+		Mapping:          idMapping,
+		Notifier:         notifier,
+		FinalizerTimeout: finalizerTimeout,
+		DriftInterval:    groupResync,
+		RetryLimiter:     checklycontrollers.RetryRateLimiter(retryBaseDelay, retryMaxDelay),
+		Breaker:          breaker,
+		Deprecations:     deprecations,
+		Accounts:         accounts,
+		Workers:          workers,
+		TagMapping:       tagMapping,
+		EscalationTiers:  escalationTiers,
+		Recorder:         mgr.GetEventRecorderFor("group-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Group")
 		os.Exit(1)
@@ -140,12 +686,61 @@ func main() {
 	if err = (&checklycontrollers.AlertChannelReconciler{
 		Client:           mgr.GetClient(),
 		Scheme:           mgr.GetScheme(),
-		ApiClient:        client,
+		ApiClient:        apiClient,
 		ControllerDomain: controllerDomain,
+		CreateOnly:       createOnly,
+		SkipFinalizer:    skipFinalizer["alertchannel"],
+		ReconcileSummary: reconcileSummary,
+		Audit:            auditLog,
+		Mapping:          idMapping,
+		Notifier:         notifier,
+		FinalizerTimeout: finalizerTimeout,
+		DriftInterval:    alertChannelResync,
+		RetryLimiter:     checklycontrollers.RetryRateLimiter(retryBaseDelay, retryMaxDelay),
+		Breaker:          breaker,
+		Deprecations:     deprecations,
+		Accounts:         accounts,
+		Workers:          workers,
+		PolicyConfigMap:  policyConfigMap,
+		QuotaConfigMap:   quotaConfigMap,
+		QuotaLabel:       quotaLabel,
+		RequeueIntervals: map[string]time.Duration{
+			checklycontrollers.PriorityHigh:   requeueHigh,
+			checklycontrollers.PriorityMedium: requeueMedium,
+			checklycontrollers.PriorityLow:    requeueLow,
+		},
+		MaxPendingAge:    maxPendingAge,
+		DetectDuplicates: detectDuplicates,
+		ValidateTemplate: validateTemplates,
+		VerifyWrites:     verifyWrites || verifiedReady,
+		VerifiedReady:    verifiedReady,
+		ChangeEvents:     changeEvents,
+		EscalationTiers:  escalationTiers,
+		UnknownFields:    unknownFields,
+		ConfirmChanges:   confirmChanges,
+		ParityLabel:      parityLabel,
+		SecretCache:      secretCache,
+		ValidateOpsGenie: validateOpsGenie,
+		WebhookURLPolicy: webhookURLPolicy,
+		NameCollision:    nameCollision,
+		NameCharset:      nameCharset,
+		Validation:       validation,
+		Directory:        directory,
+		RecreateTypes:    recreateTypes,
+		RolloutLabel:     rolloutLabel,
+		CanarySoak:       canarySoak,
+		HashKey:          hashKey,
+		Recorder:         mgr.GetEventRecorderFor("alertchannel-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AlertChannel")
 		os.Exit(1)
 	}
+	if enableWebhooks {
+		if err = webhookchecklyv1alpha1.SetupAlertChannelWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "AlertChannel")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	setupLog.V(1).Info("starting health endpoint")
@@ -161,8 +756,29 @@ func main() {
 	}
 
 	setupLog.V(1).Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctrl.SetupSignalHandler())
+
+	// Deferred calls don't run on os.Exit, the audit log file is flushed and closed once the manager stopped
+	if auditFile != nil {
+		if syncErr := auditFile.Sync(); syncErr != nil {
+			setupLog.Error(syncErr, "unable to flush audit log file")
+		}
+		if closeErr := auditFile.Close(); closeErr != nil {
+			setupLog.Error(closeErr, "unable to close audit log file")
+		}
+	}
+
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// parseNamespacedName parses a namespace/name reference supplied as a runtime option
+func parseNamespacedName(value string) (types.NamespacedName, error) {
+	namespace, name, found := strings.Cut(value, "/")
+	if !found || namespace == "" || name == "" {
+		return types.NamespacedName{}, fmt.Errorf("invalid value %q", value)
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
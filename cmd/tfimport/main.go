@@ -0,0 +1,65 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// tfimport converts the checkly_alert_channel resources of a Terraform state file into AlertChannel manifests which
+// adopt the existing alert channels, ex.
+//
+//	terraform state pull | go run ./cmd/tfimport > alertchannels.yaml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/checkly/checkly-operator/internal/tfimport"
+)
+
+func main() {
+	var statePath string
+	var opts tfimport.Options
+	flag.StringVar(&statePath, "state", "-", "Path of the Terraform state file, - reads it from stdin, ex. piped from terraform state pull.")
+	flag.StringVar(&opts.ControllerDomain, "controller-domain", "k8s.checklyhq.com", "Prefix of the pin-id annotation, it has to match the --controller-domain of the operator.")
+	flag.StringVar(&opts.SecretNamespace, "secret-namespace", "default", "Namespace of the secrets holding the credentials of the alert channels.")
+	flag.Parse()
+
+	var state io.Reader = os.Stdin
+	if statePath != "-" {
+		f, err := os.Open(statePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		state = f
+	}
+
+	result, err := tfimport.Import(state, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	manifests, err := result.Manifests()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(manifests)
+
+	fmt.Fprintf(os.Stderr, "Imported %d alert channels and %d secrets\n", len(result.AlertChannels), len(result.Secrets))
+}
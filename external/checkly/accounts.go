@@ -0,0 +1,72 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/checkly/checkly-go-sdk"
+)
+
+// Accounts builds the checkly clients of the accounts resources hold their own API credentials for. The clients share
+// the HTTP client of the operator account and are wrapped like its client, so throttling, retries and the circuit
+// breaker apply to every account.
+type Accounts struct {
+	baseURL    string
+	httpClient *http.Client
+	wrap       func(checkly.Client) checkly.Client
+
+	mu      sync.Mutex
+	clients map[accountCredentials]checkly.Client
+}
+
+type accountCredentials struct {
+	apiKey    string
+	accountID string
+}
+
+// NewAccounts returns Accounts building clients for the API at baseURL, wrap is applied to every client built
+func NewAccounts(baseURL string, httpClient *http.Client, wrap func(checkly.Client) checkly.Client) *Accounts {
+	return &Accounts{
+		baseURL:    baseURL,
+		httpClient: httpClient,
+		wrap:       wrap,
+		clients:    map[accountCredentials]checkly.Client{},
+	}
+}
+
+// Client returns the client of the account, clients are built once per set of credentials and reused afterwards
+func (a *Accounts) Client(apiKey string, accountID string) checkly.Client {
+	key := accountCredentials{apiKey: apiKey, accountID: accountID}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if client, ok := a.clients[key]; ok {
+		return client
+	}
+
+	var client checkly.Client = checkly.NewClient(a.baseURL, apiKey, a.httpClient, nil)
+	client.SetAccountId(accountID)
+	if a.wrap != nil {
+		client = a.wrap(client)
+	}
+	a.clients[key] = client
+
+	return client
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/checkly/checkly-go-sdk"
+)
+
+func TestAccountsClient(t *testing.T) {
+	var authorization, account string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		account = r.Header.Get("x-checkly-account")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 42}`))
+	}))
+	defer server.Close()
+
+	wrapped := 0
+	accounts := NewAccounts(server.URL, server.Client(), func(client checkly.Client) checkly.Client {
+		wrapped++
+		return client
+	})
+
+	client := accounts.Client("key", "account")
+	_, err := client.GetAlertChannel(context.Background(), 42)
+	if err != nil {
+		t.Fatalf("Expected no error, got %e", err)
+	}
+	if authorization != "Bearer key" || account != "account" {
+		t.Errorf("Expected the account credentials to be sent, got %q and %q", authorization, account)
+	}
+
+	// Clients are reused for the same credentials
+	if accounts.Client("key", "account") != client {
+		t.Error("Expected the client of the credentials to be reused")
+	}
+	if accounts.Client("other", "account") == client {
+		t.Error("Expected a new client for other credentials")
+	}
+	if wrapped != 2 {
+		t.Errorf("Expected every client to be wrapped once, got %d", wrapped)
+	}
+}
@@ -2,20 +2,444 @@ package external
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/checkly/checkly-go-sdk"
 	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 )
 
 func checklyAlertChannel(alertChannel *checklyv1alpha1.AlertChannel, opsGenieConfig checkly.AlertChannelOpsgenie) (ac checkly.AlertChannel, err error) {
-	sslExpiry := false
+	err = ValidateAlertChannelType(alertChannel.Spec)
+	if err != nil {
+		return
+	}
+
+	ac, err = structuredAlertChannel(alertChannel, opsGenieConfig)
+	if err != nil {
+		return
+	}
+
+	err = applyRawConfig(&ac, alertChannel.Spec.RawConfig)
+	if err != nil {
+		return
+	}
+
+	sortKeyValues(&ac)
+	err = validateKeyValues(ac)
+	if err != nil {
+		return
+	}
+
+	err = validateSlack(ac)
+	if err != nil {
+		return
+	}
+
+	err = validatePhoneNumbers(ac)
+	return
+}
+
+// slackChannel matches a channel name, ex. #alerts, or a channel ID, ex. C0123456789
+var slackChannel = regexp.MustCompile(`^(#[a-z0-9][a-z0-9._-]{0,79}|[CGD][A-Z0-9]{8,})$`)
+
+// validateSlack rejects Slack alert channels posting to a malformed channel or to a webhook outside of Slack, which
+// checklyhq.com accepts but only fail once an alert is sent
+func validateSlack(ac checkly.AlertChannel) (err error) {
+	if ac.Slack == nil {
+		return
+	}
+
+	if ac.Slack.Channel != "" && !slackChannel.MatchString(ac.Slack.Channel) {
+		return fmt.Errorf("slack channel %q has to be a lowercase channel name starting with #, ex. #alerts, or a channel ID, ex. C0123456789", ac.Slack.Channel)
+	}
+
+	webhookURL, err := url.Parse(ac.Slack.WebhookURL)
+	if err != nil || webhookURL.Scheme != "https" || webhookURL.Hostname() != "hooks.slack.com" {
+		return fmt.Errorf("slack webhook URL %q has to be a https://hooks.slack.com incoming webhook", ac.Slack.WebhookURL)
+	}
+	return nil
+}
+
+// ValidateAlertChannelType rejects specs configuring more than one alert channel type, only one of them would be synced
+// and the others silently dropped
+func ValidateAlertChannelType(spec checklyv1alpha1.AlertChannelSpec) error {
+	types := alertChannelTypes(spec)
+	if len(types) > 1 {
+		return fmt.Errorf("alert channel can only configure one of email, opsgenie, webhook, slack, pagerduty, sms and phone, got %s", strings.Join(types, " and "))
+	}
+	return nil
+}
+
+// ValidateAlertChannelFields rejects specs which don't configure exactly one alert channel type with the fields the
+// type requires, ex. the URL of a webhook. Specs inheriting from a parent or an AlertPolicy are only checked once
+// resolved, as the type and its fields may be inherited. A spec only setting rawconfig configures a type the spec
+// doesn't model, its contents aren't validated.
+func ValidateAlertChannelFields(spec checklyv1alpha1.AlertChannelSpec) error {
+	types := alertChannelTypes(spec)
+	if len(types) == 0 && strings.TrimSpace(spec.RawConfig) != "" {
+		return nil
+	}
+	if len(types) == 0 {
+		return fmt.Errorf("alert channel has to configure one of email, opsgenie, webhook, slack, pagerduty, sms, phone and rawconfig")
+	}
+	err := ValidateAlertChannelType(spec)
+	if err != nil {
+		return err
+	}
+
+	var missing string
+	switch types[0] {
+	case "email":
+		if spec.Email.Address == "" {
+			missing = "email.address"
+		}
+	case "opsgenie":
+		if spec.OpsGenie.APISecret.Name == "" {
+			missing = "opsgenie.apisecret"
+		}
+	case "webhook":
+		if spec.Webhook.URL == "" {
+			missing = "webhook.url"
+		}
+	case "slack":
+		if spec.Slack.URL == "" && spec.Slack.URLSecret.Name == "" {
+			missing = "slack.url or slack.urlsecret"
+		}
+	case "pagerduty":
+		if spec.PagerDuty.ServiceKey == "" && spec.PagerDuty.ServiceKeySecret.Name == "" {
+			missing = "pagerduty.servicekey or pagerduty.servicekeysecret"
+		}
+	case "sms":
+		if spec.SMS.Number == "" {
+			missing = "sms.number"
+		}
+	case "phone":
+		if spec.Phone.Number == "" {
+			missing = "phone.number"
+		}
+	}
+	if missing != "" {
+		return fmt.Errorf("%s alert channel requires %s", types[0], missing)
+	}
+	return nil
+}
+
+// Character sets alert channel names can be restricted to, for integrations which can't handle unicode names
+const (
+	// NameCharsetAny allows any name checklyhq.com accepts
+	NameCharsetAny = "any"
+	// NameCharsetASCII allows printable ASCII characters only, ex. no emoji or accented letters
+	NameCharsetASCII = "ascii"
+	// NameCharsetLatin1 allows printable ISO-8859-1 characters, accented latin letters included
+	NameCharsetLatin1 = "latin1"
+)
+
+// ValidateAlertChannelNames rejects alert channels whose names shown in checklyhq.com and passed on to integrations,
+// the alert channel name and the recipient and service names, have characters outside of the charset
+func ValidateAlertChannelNames(alertChannel *checklyv1alpha1.AlertChannel, charset string) error {
+	var limit rune
+	switch charset {
+	case NameCharsetASCII:
+		limit = unicode.MaxASCII
+	case NameCharsetLatin1:
+		limit = unicode.MaxLatin1
+	default:
+		return nil
+	}
+
+	names := []struct {
+		field string
+		value string
+	}{
+		{"name", AlertChannelName(alertChannel)},
+		{"sms.name", alertChannel.Spec.SMS.Name},
+		{"phone.name", alertChannel.Spec.Phone.Name},
+		{"pagerduty.servicename", alertChannel.Spec.PagerDuty.ServiceName},
+	}
+	for _, name := range names {
+		for position, character := range []rune(name.value) {
+			if character > limit || !unicode.IsPrint(character) {
+				return fmt.Errorf("%s %q contains %q at position %d, only printable %s characters are allowed", name.field, name.value, character, position+1, charset)
+			}
+		}
+	}
+	return nil
+}
+
+// alertChannelTypes returns the alert channel types configured by the spec, ex. opsgenie
+func alertChannelTypes(spec checklyv1alpha1.AlertChannelSpec) (types []string) {
+	if spec.Email != (checkly.AlertChannelEmail{}) {
+		types = append(types, "email")
+	}
+	if spec.OpsGenie != (checklyv1alpha1.AlertChannelOpsGenie{}) {
+		types = append(types, "opsgenie")
+	}
+	if !spec.Webhook.IsZero() {
+		types = append(types, "webhook")
+	}
+	if spec.Slack != (checklyv1alpha1.AlertChannelSlack{}) {
+		types = append(types, "slack")
+	}
+	if spec.PagerDuty != (checklyv1alpha1.AlertChannelPagerDuty{}) {
+		types = append(types, "pagerduty")
+	}
+	if spec.SMS != (checklyv1alpha1.AlertChannelSMS{}) {
+		types = append(types, "sms")
+	}
+	if spec.Phone != (checklyv1alpha1.AlertChannelPhone{}) {
+		types = append(types, "phone")
+	}
+
+	return
+}
+
+// AlertChannelType returns the type of alert channel configured by the spec, ex. opsgenie, it's empty if the spec
+// doesn't configure any
+func AlertChannelType(spec checklyv1alpha1.AlertChannelSpec) string {
+	types := alertChannelTypes(spec)
+	if len(types) == 0 {
+		return ""
+	}
+	return types[0]
+}
+
+// ApplyAlertPolicy fills the unset fields of the spec with the defaults of the AlertPolicy, the same way they're
+// inherited from a parent, and makes sure the spec configures one of the types required by the policy
+func ApplyAlertPolicy(policy checklyv1alpha1.AlertPolicySpec, spec checklyv1alpha1.AlertChannelSpec) (checklyv1alpha1.AlertChannelSpec, error) {
+	spec = InheritAlertChannelSpec(policy.Defaults, spec)
+	if len(policy.RequiredTypes) == 0 {
+		return spec, nil
+	}
+
+	for _, configured := range alertChannelTypes(spec) {
+		for _, required := range policy.RequiredTypes {
+			if strings.EqualFold(configured, required) {
+				return spec, nil
+			}
+		}
+	}
+	return spec, fmt.Errorf("alert policy requires one of %s, got %s", strings.Join(policy.RequiredTypes, ", "), checkValueString(strings.Join(alertChannelTypes(spec), " and "), "none"))
+}
+
+// e164Number matches phone numbers in E.164 format, ex. +14155550123
+var e164Number = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// validatePhoneNumbers rejects SMS and phone call alert channels with a number checklyhq.com can't reach, a typo would
+// otherwise only surface when the on-call person isn't paged
+func validatePhoneNumbers(ac checkly.AlertChannel) (err error) {
+	if ac.SMS != nil && !e164Number.MatchString(ac.SMS.Number) {
+		return fmt.Errorf("sms number %q has to be in E.164 format, ex. +14155550123", ac.SMS.Number)
+	}
+	if ac.CALL != nil && !e164Number.MatchString(ac.CALL.Number) {
+		return fmt.Errorf("phone number %q has to be in E.164 format, ex. +14155550123", ac.CALL.Number)
+	}
+	return nil
+}
+
+// validateKeyValues rejects webhooks setting the same header or query parameter more than once, which is almost always
+// a copy-paste mistake, header names are case-insensitive
+func validateKeyValues(ac checkly.AlertChannel) (err error) {
+	if ac.Webhook == nil {
+		return
+	}
+
+	headers := map[string]bool{}
+	for _, header := range ac.Webhook.Headers {
+		key := http.CanonicalHeaderKey(header.Key)
+		if headers[key] {
+			return fmt.Errorf("webhook header %q is set more than once", header.Key)
+		}
+		headers[key] = true
+	}
+
+	parameters := map[string]bool{}
+	for _, parameter := range ac.Webhook.QueryParameters {
+		if parameters[parameter.Key] {
+			return fmt.Errorf("webhook query parameter %q is set more than once", parameter.Key)
+		}
+		parameters[parameter.Key] = true
+	}
+
+	return
+}
+
+// SanitizeAlertChannelSpec drops the webhook headers and query parameters set more than once, keeping the last
+// occurrence, so an otherwise valid spec can be synced on a best-effort basis. It returns a description of each fix.
+func SanitizeAlertChannelSpec(spec *checklyv1alpha1.AlertChannelSpec) (fixes []string) {
+	var dropped []string
+	spec.Webhook.Headers, dropped = lastKeyValues(spec.Webhook.Headers, http.CanonicalHeaderKey)
+	for _, key := range dropped {
+		fixes = append(fixes, fmt.Sprintf("dropped duplicate webhook header %q", key))
+	}
+
+	spec.Webhook.QueryParameters, dropped = lastKeyValues(spec.Webhook.QueryParameters, func(key string) string { return key })
+	for _, key := range dropped {
+		fixes = append(fixes, fmt.Sprintf("dropped duplicate webhook query parameter %q", key))
+	}
+	return
+}
+
+// lastKeyValues returns the headers or query parameters with only the last occurrence of each normalized key, and the
+// keys of the dropped ones
+func lastKeyValues(keyValues []checklyv1alpha1.AlertChannelKeyValue, normalize func(string) string) (kept []checklyv1alpha1.AlertChannelKeyValue, dropped []string) {
+	last := map[string]int{}
+	for i, keyValue := range keyValues {
+		last[normalize(keyValue.Key)] = i
+	}
+
+	for i, keyValue := range keyValues {
+		if last[normalize(keyValue.Key)] != i {
+			dropped = append(dropped, keyValue.Key)
+			continue
+		}
+		kept = append(kept, keyValue)
+	}
+	return
+}
+
+// sortKeyValues orders the webhook headers and query parameters by key, so an unchanged alert channel always
+// results in the same request and doesn't show up as changed when compared with the API
+func sortKeyValues(ac *checkly.AlertChannel) {
+	if ac.Webhook == nil {
+		return
+	}
+
+	for _, keyValues := range [][]checkly.KeyValue{ac.Webhook.Headers, ac.Webhook.QueryParameters} {
+		sort.SliceStable(keyValues, func(i, j int) bool {
+			if keyValues[i].Key != keyValues[j].Key {
+				return keyValues[i].Key < keyValues[j].Key
+			}
+			return keyValues[i].Value < keyValues[j].Value
+		})
+	}
+}
+
+// checklyKeyValues converts the headers or query parameters of the spec to the SDK representation
+func checklyKeyValues(keyValues []checklyv1alpha1.AlertChannelKeyValue) (converted []checkly.KeyValue) {
+	for _, keyValue := range keyValues {
+		converted = append(converted, checkly.KeyValue{Key: keyValue.Key, Value: keyValue.Value})
+	}
+	return
+}
+
+// applyRawConfig merges the user supplied JSON onto the alert channel, top level attributes (ex. sslExpiry) are set on
+// the alert channel itself, while the keys of the "config" object are merged onto the type specific configuration.
+// Apart from being valid JSON the contents are not validated, checklyhq.com is the judge of them. The SDK only sends
+// the attributes it models, rawconfig setting others is rejected instead of having them silently dropped. The ID and
+// the type of a structured alert channel can't be changed through rawconfig.
+func applyRawConfig(ac *checkly.AlertChannel, rawConfig string) (err error) {
+	if strings.TrimSpace(rawConfig) == "" {
+		return
+	}
+
+	var raw map[string]json.RawMessage
+	err = json.Unmarshal([]byte(rawConfig), &raw)
+	if err != nil {
+		return fmt.Errorf("rawconfig is not a valid JSON object: %w", err)
+	}
+	if _, ok := raw["id"]; ok {
+		return fmt.Errorf("rawconfig can not set the id of the alert channel")
+	}
+	if _, ok := raw["type"]; ok && ac.Type != "" {
+		return fmt.Errorf("rawconfig can not set the type of the %s alert channel", ac.Type)
+	}
+
+	var config map[string]json.RawMessage
+	if value, ok := raw["config"]; ok {
+		err = json.Unmarshal(value, &config)
+		if err != nil {
+			return fmt.Errorf("rawconfig config is not a valid JSON object: %w", err)
+		}
+	}
+
+	// The type specific configuration is excluded from the JSON representation of the SDK object
+	err = json.Unmarshal([]byte(rawConfig), ac)
+	if err != nil {
+		return fmt.Errorf("rawconfig is not a valid JSON object: %w", err)
+	}
+	dropped := unmodeledKeys(raw, checkly.AlertChannel{}, "")
+
+	if len(config) != 0 {
+		merged := ac.GetConfig()
+		if merged == nil {
+			merged = map[string]interface{}{}
+		}
+		for key, value := range config {
+			merged[key] = value
+		}
+
+		var configJSON []byte
+		configJSON, err = json.Marshal(merged)
+		if err != nil {
+			return
+		}
+		var typedConfig interface{}
+		typedConfig, err = checkly.AlertChannelConfigFromJSON(ac.Type, configJSON)
+		if err != nil {
+			return fmt.Errorf("rawconfig config can not be applied to alert channel type %q: %w", ac.Type, err)
+		}
+		ac.SetConfig(typedConfig)
+		dropped = append(dropped, unmodeledKeys(config, typedConfig, "config.")...)
+	}
+
+	if len(dropped) != 0 {
+		sort.Strings(dropped)
+		return fmt.Errorf("rawconfig sets %s, which can't be sent to checklyhq.com for alert channel type %q", strings.Join(dropped, ", "), ac.Type)
+	}
+	return
+}
+
+// unmodeledKeys returns the keys of the JSON object which aren't attributes of the SDK type, with the prefix
+func unmodeledKeys(object map[string]json.RawMessage, typed interface{}, prefix string) (keys []string) {
+	modeled := map[string]bool{"config": prefix == ""}
+	t := reflect.Indirect(reflect.ValueOf(typed)).Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			modeled[name] = true
+		}
+	}
+
+	for key := range object {
+		if !modeled[key] {
+			keys = append(keys, prefix+key)
+		}
+	}
+	return
+}
+
+// structuredAlertChannel builds the alert channel from the modelled fields of the spec
+func structuredAlertChannel(alertChannel *checklyv1alpha1.AlertChannel, opsGenieConfig checkly.AlertChannelOpsgenie) (ac checkly.AlertChannel, err error) {
+	sslExpiry := alertChannel.Spec.SSLExpiry
+	sslExpiryThreshold := checkValueInt(alertChannel.Spec.SSLExpiryThreshold, 30)
+	if sslExpiryThreshold < 1 || sslExpiryThreshold > 30 {
+		err = fmt.Errorf("sslexpirythreshold has to be between 1 and 30 days, got %d", sslExpiryThreshold)
+		return
+	}
 
 	ac = checkly.AlertChannel{
 		SendRecovery: &alertChannel.Spec.SendRecovery,
 		SendFailure:  &alertChannel.Spec.SendFailure,
+		SendDegraded: &alertChannel.Spec.SendDegraded,
 		SSLExpiry:    &sslExpiry,
 	}
+	// The threshold is left to the API unless SSL expiry alerts are enabled
+	if sslExpiry {
+		ac.SSLExpiryThreshold = &sslExpiryThreshold
+	}
 
 	if opsGenieConfig != (checkly.AlertChannelOpsgenie{}) {
 		ac.Type = "OPSGENIE" // Type has to be all caps, see https://developers.checklyhq.com/reference/postv1alertchannels
@@ -30,9 +454,495 @@ func checklyAlertChannel(alertChannel *checklyv1alpha1.AlertChannel, opsGenieCon
 		}
 		return
 	}
+
+	if !alertChannel.Spec.Webhook.IsZero() {
+		var webhookURL string
+		webhookURL, err = normalizeWebhookURL(alertChannel.Spec.Webhook.URL)
+		if err != nil {
+			return
+		}
+
+		var template string
+		template, err = webhookTemplate(alertChannel.Spec.Webhook)
+		if err != nil {
+			return
+		}
+		ac.Type = "WEBHOOK" // Type has to be all caps, see https://developers.checklyhq.com/reference/postv1alertchannels
+		ac.Webhook = &checkly.AlertChannelWebhook{
+			Name:     AlertChannelName(alertChannel),
+			URL:      webhookURL,
+			Method:   checkValueString(alertChannel.Spec.Webhook.Method, http.MethodPost),
+			Template: template,
+
+			Headers:         checklyKeyValues(alertChannel.Spec.Webhook.Headers),
+			QueryParameters: checklyKeyValues(alertChannel.Spec.Webhook.QueryParameters),
+		}
+		return
+	}
+
+	// The URL of Slack alert channels referencing a secret is filled in by the reconciler
+	if alertChannel.Spec.Slack != (checklyv1alpha1.AlertChannelSlack{}) {
+		if strings.TrimSpace(alertChannel.Spec.Slack.URL) == "" {
+			err = fmt.Errorf("slack webhook URL is required, set url or urlsecret")
+			return
+		}
+
+		ac.Type = "SLACK" // Type has to be all caps, see https://developers.checklyhq.com/reference/postv1alertchannels
+		ac.Slack = &checkly.AlertChannelSlack{
+			WebhookURL: strings.TrimSpace(alertChannel.Spec.Slack.URL),
+			Channel:    alertChannel.Spec.Slack.Channel,
+		}
+		return
+	}
+
+	// The service key of PagerDuty alert channels referencing a secret is filled in by the reconciler
+	if alertChannel.Spec.PagerDuty != (checklyv1alpha1.AlertChannelPagerDuty{}) {
+		if strings.TrimSpace(alertChannel.Spec.PagerDuty.ServiceKey) == "" {
+			err = fmt.Errorf("pagerduty service key is required, set servicekey or servicekeysecret")
+			return
+		}
+
+		ac.Type = "PAGERDUTY" // Type has to be all caps, see https://developers.checklyhq.com/reference/postv1alertchannels
+		ac.Pagerduty = &checkly.AlertChannelPagerduty{
+			ServiceKey:  strings.TrimSpace(alertChannel.Spec.PagerDuty.ServiceKey),
+			ServiceName: alertChannel.Spec.PagerDuty.ServiceName,
+			Account:     alertChannel.Spec.PagerDuty.Account,
+		}
+		return
+	}
+
+	if alertChannel.Spec.SMS != (checklyv1alpha1.AlertChannelSMS{}) {
+		ac.Type = "SMS" // Type has to be all caps, see https://developers.checklyhq.com/reference/postv1alertchannels
+		ac.SMS = &checkly.AlertChannelSMS{
+			Name:   checkValueString(alertChannel.Spec.SMS.Name, AlertChannelName(alertChannel)),
+			Number: strings.TrimSpace(alertChannel.Spec.SMS.Number),
+		}
+		return
+	}
+
+	if alertChannel.Spec.Phone != (checklyv1alpha1.AlertChannelPhone{}) {
+		ac.Type = "CALL" // Type has to be all caps, see https://developers.checklyhq.com/reference/postv1alertchannels
+		ac.CALL = &checkly.AlertChannelCall{
+			Name:   checkValueString(alertChannel.Spec.Phone.Name, AlertChannelName(alertChannel)),
+			Number: strings.TrimSpace(alertChannel.Spec.Phone.Number),
+		}
+		return
+	}
+	return
+}
+
+// normalizeWebhookURL trims the whitespace pasted along with webhook URLs and lower-cases the scheme and host, URLs
+// without an http(s) scheme or a host are rejected, checklyhq.com would accept them but never deliver an alert
+func normalizeWebhookURL(rawURL string) (normalized string, err error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", fmt.Errorf("webhook URL is required")
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("webhook URL %q is invalid: %w", rawURL, err)
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return "", fmt.Errorf("webhook URL %q has to start with https:// or http://", rawURL)
+	}
+	if parsed.Host == "" {
+		return "", fmt.Errorf("webhook URL %q has no host", rawURL)
+	}
+	parsed.Host = strings.ToLower(parsed.Host)
+
+	return parsed.String(), nil
+}
+
+// templateVariable matches a single handlebars expression, ex. {{CHECK_ID}}
+var templateVariable = regexp.MustCompile(`{{\s*([^{}]*?)\s*}}`)
+
+// webhookTemplateVariables holds the variables checklyhq.com substitutes in webhook templates, with the sample values
+// templates are rendered with during validation, see https://www.checklyhq.com/docs/alerting-and-retries/webhooks/#using-variables
+var webhookTemplateVariables = map[string]string{
+	"ALERT_TITLE":                    "Sample check has failed",
+	"ALERT_TYPE":                     "ALERT_FAILURE",
+	"CHECK_NAME":                     "Sample check",
+	"CHECK_ID":                       "00000000-0000-0000-0000-000000000000",
+	"CHECK_TYPE":                     "API",
+	"CHECK_RESULT_ID":                "00000000-0000-0000-0000-000000000001",
+	"CHECK_ERROR_MESSAGE":            "Response status code 500 does not match 200",
+	"GROUP_NAME":                     "Sample group",
+	"RESPONSE_TIME":                  "123",
+	"API_CHECK_RESPONSE_STATUS_CODE": "500",
+	"API_CHECK_RESPONSE_STATUS_TEXT": "Internal Server Error",
+	"RUN_LOCATION":                   "eu-west-1",
+	"RESULT_LINK":                    "https://app.checklyhq.com/checks/00000000-0000-0000-0000-000000000000",
+	"SSL_DAYS_REMAINING":             "14",
+	"SSL_CHECK_DOMAIN":               "foo.bar",
+	"STARTED_AT":                     "2022-01-01T00:00:00.000Z",
+	"TAGS":                           "sample",
+	"$RANDOM_NUMBER":                 "42",
+	"$UUID":                          "00000000-0000-0000-0000-000000000002",
+}
+
+// validateDedupKey makes sure the dedup key expression only references variables checklyhq.com knows about
+func validateDedupKey(dedupKey string) (err error) {
+	if strings.TrimSpace(dedupKey) == "" {
+		return fmt.Errorf("dedup key can not be blank")
+	}
+
+	remainder := templateVariable.ReplaceAllString(dedupKey, "")
+	if strings.Contains(remainder, "{{") || strings.Contains(remainder, "}}") {
+		return fmt.Errorf("dedup key %q has unbalanced braces", dedupKey)
+	}
+
+	matches := templateVariable.FindAllStringSubmatch(dedupKey, -1)
+	if len(matches) == 0 {
+		return fmt.Errorf("dedup key %q does not reference any variables, every alert would share the same key", dedupKey)
+	}
+
+	for _, match := range matches {
+		if _, ok := webhookTemplateVariables[match[1]]; !ok {
+			return fmt.Errorf("dedup key %q references unknown variable %q", dedupKey, match[1])
+		}
+	}
+
+	return
+}
+
+// WebhookTemplateMaxBytes is the size limit of webhook templates in checklyhq.com, larger ones are rejected by the API
+const WebhookTemplateMaxBytes = 65536
+
+// validateTemplateSize rejects webhook templates over the size limit of checklyhq.com, with their size
+func validateTemplateSize(template string) error {
+	if len(template) > WebhookTemplateMaxBytes {
+		return fmt.Errorf("webhook template is %d bytes, over the limit of %d bytes", len(template), WebhookTemplateMaxBytes)
+	}
+	return nil
+}
+
+// webhookTemplate returns the body template of the webhook, with the dedup key added to it if one is configured
+func webhookTemplate(webhook checklyv1alpha1.AlertChannelWebhook) (template string, err error) {
+	template = webhook.Template
+	if webhook.DedupKey == "" {
+		err = validateTemplateSize(template)
+		return
+	}
+
+	err = validateDedupKey(webhook.DedupKey)
+	if err != nil {
+		return
+	}
+
+	body := make(map[string]interface{})
+	if strings.TrimSpace(webhook.Template) != "" {
+		err = json.Unmarshal([]byte(webhook.Template), &body)
+		if err != nil {
+			err = fmt.Errorf("webhook template has to be a JSON object when a dedup key is set: %w", err)
+			return
+		}
+	}
+	body["dedupKey"] = webhook.DedupKey
+
+	rendered, err := json.Marshal(body)
+	if err != nil {
+		return
+	}
+	template = string(rendered)
+
+	// The dedup key adds to the size of the template
+	err = validateTemplateSize(template)
+
+	return
+}
+
+// renderWebhookTemplate substitutes the template variables with their sample values, block helpers like
+// {{#each TAGS}} are dropped as only their content is of interest for validation
+func renderWebhookTemplate(template string) (rendered string, err error) {
+	rendered = templateVariable.ReplaceAllStringFunc(template, func(expression string) string {
+		variable := templateVariable.FindStringSubmatch(expression)[1]
+		if strings.HasPrefix(variable, "#") || strings.HasPrefix(variable, "/") || variable == "else" {
+			return ""
+		}
+		if variable == "this" {
+			return "sample"
+		}
+
+		value, ok := webhookTemplateVariables[variable]
+		if !ok && err == nil {
+			err = fmt.Errorf("webhook template references unknown variable %q", variable)
+		}
+		return value
+	})
+
+	return
+}
+
+// ValidateWebhookTemplate renders the webhook template, with the dedup key added, against a sample alert and makes
+// sure the result is a non-empty JSON document
+func ValidateWebhookTemplate(webhook checklyv1alpha1.AlertChannelWebhook) (err error) {
+	template, err := webhookTemplate(webhook)
+	if err != nil {
+		return
+	}
+
+	rendered, err := renderWebhookTemplate(template)
+	if err != nil {
+		return
+	}
+
+	if strings.TrimSpace(rendered) == "" {
+		return fmt.Errorf("webhook template renders to an empty body")
+	}
+
+	if !json.Valid([]byte(rendered)) {
+		return fmt.Errorf("webhook template does not render to valid JSON: %s", rendered)
+	}
+
+	return validateTemplateSecrets(webhook, template)
+}
+
+// credentialPatterns match credentials which shouldn't be sent in plaintext in the body of webhook requests
+var credentialPatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"bearer or basic credential", regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=-]{16,}`)},
+	{"Slack webhook URL", regexp.MustCompile(`hooks\.slack\.com/services/[A-Za-z0-9]+/[A-Za-z0-9]+`)},
+	{"checklyhq.com API key", regexp.MustCompile(`\bcu_[A-Za-z0-9]{20,}`)},
+}
+
+// credentialHeader matches the names of headers carrying credentials
+var credentialHeader = regexp.MustCompile(`(?i)auth|token|secret|key|password|cookie`)
+
+// validateTemplateSecrets flags templates embedding credentials in plaintext, ex. the value of the Authorization
+// header or a private key, these belong in the headers of the request and not in its body
+func validateTemplateSecrets(webhook checklyv1alpha1.AlertChannelWebhook, template string) error {
+	for _, header := range webhook.Headers {
+		// Short values like "yes" would be flagged wherever they appear
+		if credentialHeader.MatchString(header.Key) && len(header.Value) >= 8 && strings.Contains(template, header.Value) {
+			return fmt.Errorf("webhook template embeds the value of the %q header, credentials belong in headers only", header.Key)
+		}
+	}
+
+	for _, credential := range credentialPatterns {
+		if credential.pattern.MatchString(template) {
+			return fmt.Errorf("webhook template embeds what looks like a %s, credentials belong in headers only", credential.name)
+		}
+	}
+	return nil
+}
+
+// AlertChannelName returns the name of the alert channel in checklyhq.com, the resource name unless it was taken
+func AlertChannelName(alertChannel *checklyv1alpha1.AlertChannel) string {
+	return checkValueString(alertChannel.Status.ChecklyName, alertChannel.Name)
+}
+
+// InheritAlertChannelSpec returns the child spec with the unset fields filled from the parent spec. The alert
+// configuration of the parent is only inherited if the child configures the same type or none at all, boolean
+// fields are inherited when they're enabled on the parent.
+func InheritAlertChannelSpec(parent checklyv1alpha1.AlertChannelSpec, child checklyv1alpha1.AlertChannelSpec) (spec checklyv1alpha1.AlertChannelSpec) {
+	spec = child
+	spec.SendRecovery = child.SendRecovery || parent.SendRecovery
+	spec.SendFailure = child.SendFailure || parent.SendFailure
+	spec.SendDegraded = child.SendDegraded || parent.SendDegraded
+	spec.SSLExpiry = child.SSLExpiry || parent.SSLExpiry
+	spec.SSLExpiryThreshold = checkValueInt(child.SSLExpiryThreshold, parent.SSLExpiryThreshold)
+
+	childConfigured := child.OpsGenie != (checklyv1alpha1.AlertChannelOpsGenie{}) ||
+		child.Email != (checkly.AlertChannelEmail{}) ||
+		!child.Webhook.IsZero() ||
+		child.Slack != (checklyv1alpha1.AlertChannelSlack{}) ||
+		child.PagerDuty != (checklyv1alpha1.AlertChannelPagerDuty{}) ||
+		child.SMS != (checklyv1alpha1.AlertChannelSMS{}) ||
+		child.Phone != (checklyv1alpha1.AlertChannelPhone{})
+
+	if !childConfigured || child.OpsGenie != (checklyv1alpha1.AlertChannelOpsGenie{}) {
+		if spec.OpsGenie.APISecret == (corev1.ObjectReference{}) {
+			spec.OpsGenie.APISecret = parent.OpsGenie.APISecret
+		}
+		spec.OpsGenie.Region = checkValueString(child.OpsGenie.Region, parent.OpsGenie.Region)
+		spec.OpsGenie.Priority = checkValueString(child.OpsGenie.Priority, parent.OpsGenie.Priority)
+	}
+
+	if !childConfigured || child.Email != (checkly.AlertChannelEmail{}) {
+		spec.Email.Address = checkValueString(child.Email.Address, parent.Email.Address)
+	}
+
+	if !childConfigured || !child.Webhook.IsZero() {
+		spec.Webhook.URL = checkValueString(child.Webhook.URL, parent.Webhook.URL)
+		spec.Webhook.Method = checkValueString(child.Webhook.Method, parent.Webhook.Method)
+		spec.Webhook.Template = checkValueString(child.Webhook.Template, parent.Webhook.Template)
+		spec.Webhook.DedupKey = checkValueString(child.Webhook.DedupKey, parent.Webhook.DedupKey)
+		if len(child.Webhook.Headers) == 0 {
+			spec.Webhook.Headers = parent.Webhook.Headers
+		}
+		if len(child.Webhook.QueryParameters) == 0 {
+			spec.Webhook.QueryParameters = parent.Webhook.QueryParameters
+		}
+		if child.Webhook.ClientCertSecret == (corev1.ObjectReference{}) {
+			spec.Webhook.ClientCertSecret = parent.Webhook.ClientCertSecret
+		}
+	}
+
+	if !childConfigured || child.Slack != (checklyv1alpha1.AlertChannelSlack{}) {
+		if spec.Slack.URLSecret == (corev1.ObjectReference{}) && child.Slack.URL == "" {
+			spec.Slack.URLSecret = parent.Slack.URLSecret
+		}
+		spec.Slack.URL = checkValueString(child.Slack.URL, parent.Slack.URL)
+		spec.Slack.Channel = checkValueString(child.Slack.Channel, parent.Slack.Channel)
+	}
+
+	if !childConfigured || child.PagerDuty != (checklyv1alpha1.AlertChannelPagerDuty{}) {
+		if spec.PagerDuty.ServiceKeySecret == (corev1.ObjectReference{}) && child.PagerDuty.ServiceKey == "" {
+			spec.PagerDuty.ServiceKeySecret = parent.PagerDuty.ServiceKeySecret
+		}
+		spec.PagerDuty.ServiceKey = checkValueString(child.PagerDuty.ServiceKey, parent.PagerDuty.ServiceKey)
+		spec.PagerDuty.ServiceName = checkValueString(child.PagerDuty.ServiceName, parent.PagerDuty.ServiceName)
+		spec.PagerDuty.Account = checkValueString(child.PagerDuty.Account, parent.PagerDuty.Account)
+	}
+
+	if !childConfigured || child.SMS != (checklyv1alpha1.AlertChannelSMS{}) {
+		spec.SMS.Number = checkValueString(child.SMS.Number, parent.SMS.Number)
+		spec.SMS.Name = checkValueString(child.SMS.Name, parent.SMS.Name)
+	}
+
+	if !childConfigured || child.Phone != (checklyv1alpha1.AlertChannelPhone{}) {
+		spec.Phone.Number = checkValueString(child.Phone.Number, parent.Phone.Number)
+		spec.Phone.Name = checkValueString(child.Phone.Name, parent.Phone.Name)
+	}
+
+	spec.Tier = checkValueString(child.Tier, parent.Tier)
+	spec.PolicyRef = checkValueString(child.PolicyRef, parent.PolicyRef)
+	spec.RawConfig = checkValueString(child.RawConfig, parent.RawConfig)
+
+	return
+}
+
+// AlertChannelConfigHash returns a hash of the alert configuration of the spec, functionally identical alert channels
+// share the same hash regardless of their name or parent
+func AlertChannelConfigHash(spec checklyv1alpha1.AlertChannelSpec) string {
+	spec.ParentRef = ""
+	spec.PolicyRef = ""
+	spec.Groups = nil
+
+	// The URL and service key resolved from a secret aren't part of the configuration, the secret references are
+	if spec.Slack.URLSecret != (corev1.ObjectReference{}) {
+		spec.Slack.URL = ""
+	}
+	if spec.PagerDuty.ServiceKeySecret != (corev1.ObjectReference{}) {
+		spec.PagerDuty.ServiceKey = ""
+	}
+
+	// The order of the headers and query parameters doesn't change the alert channel
+	spec.Webhook.Headers = sortedKeyValues(spec.Webhook.Headers)
+	spec.Webhook.QueryParameters = sortedKeyValues(spec.Webhook.QueryParameters)
+
+	// The spec only holds strings, bools and structs and lists of them, marshalling can't fail
+	config, _ := json.Marshal(spec)
+	sum := sha256.Sum256(config)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// AlertChannelAppliedHash returns a hash of the payload the alert channel is synced to checklyhq.com with, including
+// the resolved secret values, and of the ID it's synced to, so a rotated secret or a newly bound ID changes it. It's an
+// HMAC keyed with a key held by the operator, readers of the resource can't guess the secret values by hashing candidates.
+func AlertChannelAppliedHash(key []byte, alertChannel *checklyv1alpha1.AlertChannel, opsGenieConfig checkly.AlertChannelOpsgenie, ID int64) (string, error) {
+	ac, err := checklyAlertChannel(alertChannel, opsGenieConfig)
+	if err != nil {
+		return "", err
+	}
+
+	// The type specific configuration is only sent as config, it's left out when marshalling the alert channel
+	payload, err := json.Marshal(struct {
+		checkly.AlertChannel
+		Config map[string]interface{} `json:"config"`
+	}{ac, ac.GetConfig()})
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(append([]byte(fmt.Sprintf("%d:", ID)), payload...))
+
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// sortedKeyValues returns a copy of the headers or query parameters ordered by key, the spec is left untouched
+func sortedKeyValues(keyValues []checklyv1alpha1.AlertChannelKeyValue) (sorted []checklyv1alpha1.AlertChannelKeyValue) {
+	sorted = append(sorted, keyValues...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Key != sorted[j].Key {
+			return sorted[i].Key < sorted[j].Key
+		}
+		return sorted[i].Value < sorted[j].Value
+	})
 	return
 }
 
+// opsGenieAPIKey matches the format of OpsGenie API keys, which are UUIDs
+var opsGenieAPIKey = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// pagerDutyServiceKey matches the format of PagerDuty integration keys
+var pagerDutyServiceKey = regexp.MustCompile(`^[0-9a-zA-Z]{32}$`)
+
+// secretValidators holds the format checks of the secret values used by each alert channel type
+var secretValidators = map[string]func(value string) error{
+	"OPSGENIE": func(value string) error {
+		if !opsGenieAPIKey.MatchString(value) {
+			return fmt.Errorf("OpsGenie API key has to be a UUID, ex. 01234567-89ab-cdef-0123-456789abcdef")
+		}
+		return nil
+	},
+	"PAGERDUTY": func(value string) error {
+		if !pagerDutyServiceKey.MatchString(value) {
+			return fmt.Errorf("PagerDuty integration key has to be 32 letters and digits")
+		}
+		return nil
+	},
+}
+
+// ValidateSecretValue makes sure the secret value has the shape expected by the alert channel type,
+// types without a validator accept any non-empty value
+func ValidateSecretValue(channelType string, value string) (err error) {
+	validator, ok := secretValidators[channelType]
+	if !ok {
+		return
+	}
+
+	return validator(value)
+}
+
+// RedactedAlertChannelSpec returns a copy of the resolved spec without the values read from secrets, so it can be
+// written to the audit log and the change notifications. The secret references are kept to tell where they came from.
+func RedactedAlertChannelSpec(spec checklyv1alpha1.AlertChannelSpec) checklyv1alpha1.AlertChannelSpec {
+	redacted := *spec.DeepCopy()
+	if redacted.Slack.URLSecret != (corev1.ObjectReference{}) {
+		redacted.Slack.URL = "https://hooks.slack.com/REDACTED"
+	}
+	if redacted.PagerDuty.ServiceKeySecret != (corev1.ObjectReference{}) {
+		redacted.PagerDuty.ServiceKey = "REDACTED"
+	}
+	return redacted
+}
+
+// AlertChannelPayload returns the alert channel sent to the checklyhq.com API, the OpsGenie API key, the Slack
+// webhook URL and the PagerDuty service key read from a secret are redacted so the payload can be logged
+func AlertChannelPayload(alertChannel *checklyv1alpha1.AlertChannel, opsGenieConfig checkly.AlertChannelOpsgenie) (ac checkly.AlertChannel, err error) {
+	if opsGenieConfig.APIKey != "" {
+		opsGenieConfig.APIKey = "REDACTED"
+	}
+	if alertChannel.Spec.Slack.URLSecret != (corev1.ObjectReference{}) {
+		alertChannel = alertChannel.DeepCopy()
+		alertChannel.Spec.Slack.URL = "https://hooks.slack.com/REDACTED"
+	}
+	if alertChannel.Spec.PagerDuty.ServiceKeySecret != (corev1.ObjectReference{}) {
+		alertChannel = alertChannel.DeepCopy()
+		alertChannel.Spec.PagerDuty.ServiceKey = "REDACTED"
+	}
+
+	return checklyAlertChannel(alertChannel, opsGenieConfig)
+}
+
 func CreateAlertChannel(alertChannel *checklyv1alpha1.AlertChannel, opsGenieConfig checkly.AlertChannelOpsgenie, client checkly.Client) (ID int64, err error) {
 
 	ac, err := checklyAlertChannel(alertChannel, opsGenieConfig)
@@ -70,6 +980,148 @@ func UpdateAlertChannel(alertChannel *checklyv1alpha1.AlertChannel, opsGenieConf
 	return
 }
 
+// secretConfigKeys holds the alert channel config keys holding secrets, which the API may mask in responses
+var secretConfigKeys = map[string]bool{
+	"apiKey":        true,
+	"webhookSecret": true,
+	"serviceKey":    true,
+}
+
+// AlertChannelChange describes an attribute of an alert channel which differs between checklyhq.com and the desired state
+type AlertChannelChange struct {
+	Field string
+	From  interface{}
+	To    interface{}
+}
+
+func (c AlertChannelChange) String() string {
+	from, _ := json.Marshal(c.From)
+	to, _ := json.Marshal(c.To)
+	return fmt.Sprintf("%s: %s -> %s", c.Field, from, to)
+}
+
+// destructiveFields holds the alert channel attributes which redirect alerts elsewhere when changed
+var destructiveFields = map[string]bool{
+	"type":           true,
+	"config.url":     true,
+	"config.address": true,
+	"config.region":  true,
+}
+
+// Masked determines if the change is to a secret the API masks in its responses, which can't be compared
+func (c AlertChannelChange) Masked() bool {
+	return strings.HasPrefix(c.Field, "config.") && secretConfigKeys[strings.TrimPrefix(c.Field, "config.")]
+}
+
+// Destructive determines if the change redirects alerts elsewhere, ex. a changed webhook URL or email address
+func (c AlertChannelChange) Destructive() bool {
+	return destructiveFields[c.Field]
+}
+
+// VerifyAlertChannel reads the alert channel back from checklyhq.com and returns an error listing the attributes which
+// don't match the desired state, ex. because an update was silently ignored
+func VerifyAlertChannel(alertChannel *checklyv1alpha1.AlertChannel, opsGenieConfig checkly.AlertChannelOpsgenie, client checkly.Client) (err error) {
+	changes, err := AlertChannelChanges(alertChannel, opsGenieConfig, client)
+	if err != nil {
+		return
+	}
+
+	var mismatches []string
+	for _, change := range changes {
+		mismatches = append(mismatches, change.Field)
+	}
+	if len(mismatches) != 0 {
+		err = fmt.Errorf("checkly AlertChannel %d does not match the desired state: %s", alertChannel.Status.ID, strings.Join(mismatches, ", "))
+	}
+
+	return
+}
+
+// AlertChannelChanges reads the alert channel from checklyhq.com and returns the attributes which differ from the
+// desired state, secrets the API masks are left out and the Slack webhook URL is redacted
+func AlertChannelChanges(alertChannel *checklyv1alpha1.AlertChannel, opsGenieConfig checkly.AlertChannelOpsgenie, client checkly.Client) (changes []AlertChannelChange, err error) {
+	want, err := checklyAlertChannel(alertChannel, opsGenieConfig)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	got, err := client.GetAlertChannel(ctx, alertChannel.Status.ID)
+	if err != nil {
+		return
+	}
+
+	// The API doesn't guarantee the order of the headers and query parameters either
+	sortKeyValues(got)
+	changes = alertChannelChanges(want, *got)
+	return
+}
+
+// alertChannelChanges returns the attributes of the wanted alert channel which differ in the one read from the API,
+// attributes the API adds on its own are ignored
+func alertChannelChanges(want checkly.AlertChannel, got checkly.AlertChannel) (changes []AlertChannelChange) {
+	if want.Type != got.Type {
+		changes = append(changes, AlertChannelChange{"type", got.Type, want.Type})
+	}
+
+	flags := []struct {
+		field string
+		want  *bool
+		got   *bool
+	}{
+		{"sendRecovery", want.SendRecovery, got.SendRecovery},
+		{"sendFailure", want.SendFailure, got.SendFailure},
+		{"sendDegraded", want.SendDegraded, got.SendDegraded},
+		{"sslExpiry", want.SSLExpiry, got.SSLExpiry},
+	}
+	for _, flag := range flags {
+		// A nil wanted value is left to the API
+		if flag.want != nil && (flag.got == nil || *flag.want != *flag.got) {
+			changes = append(changes, AlertChannelChange{flag.field, flag.got, *flag.want})
+		}
+	}
+	if want.SSLExpiryThreshold != nil && (got.SSLExpiryThreshold == nil || *want.SSLExpiryThreshold != *got.SSLExpiryThreshold) {
+		changes = append(changes, AlertChannelChange{"sslExpiryThreshold", got.SSLExpiryThreshold, *want.SSLExpiryThreshold})
+	}
+
+	gotConfig := got.GetConfig()
+	wantConfig := want.GetConfig()
+	keys := make([]string, 0, len(wantConfig))
+	for key := range wantConfig {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		// Unset values may be defaulted by the API, masked secrets can't be compared and would always differ, their
+		// rotation is caught by the applied hash instead
+		if wantConfig[key] == nil || wantConfig[key] == "" || secretConfigKeys[key] || reflect.DeepEqual(wantConfig[key], gotConfig[key]) {
+			continue
+		}
+
+		change := AlertChannelChange{"config." + key, gotConfig[key], wantConfig[key]}
+		// Slack webhook URLs hold the credentials of the webhook
+		if want.Type == "SLACK" && key == "url" {
+			change.From = "REDACTED"
+			change.To = "REDACTED"
+		}
+		changes = append(changes, change)
+	}
+
+	return
+}
+
+// AlertChannelExists makes sure the checklyhq.com alert channel exists, ex. before a resource is bound to it
+func AlertChannelExists(ID int64, client checkly.Client) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	_, err = client.GetAlertChannel(ctx, ID)
+
+	return
+}
+
 func DeleteAlertChannel(alertChannel *checklyv1alpha1.AlertChannel, client checkly.Client) (err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
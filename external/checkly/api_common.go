@@ -16,7 +16,12 @@ limitations under the License.
 
 package external
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+
+	"github.com/checkly/checkly-go-sdk"
+)
 
 func checkValueString(x string, y string) (value string) {
 	if x == "" {
@@ -53,3 +58,60 @@ func getTags(labels map[string]string) (tags []string) {
 
 	return
 }
+
+// sameStrings determines if both lists hold the same values, regardless of their order
+func sameStrings(x []string, y []string) bool {
+	x = slices.Clone(x)
+	y = slices.Clone(y)
+	slices.Sort(x)
+	slices.Sort(y)
+	return slices.Equal(x, y)
+}
+
+// sameSubscriptions determines if both lists hold the same alert channel subscriptions, regardless of their order
+func sameSubscriptions(x []checkly.AlertChannelSubscription, y []checkly.AlertChannelSubscription) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for _, subscription := range x {
+		if !slices.Contains(y, subscription) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameAlertSettings determines if both alert settings escalate and remind alike, the settings the operator doesn't set
+// are ignored
+func sameAlertSettings(x checkly.AlertSettings, y checkly.AlertSettings) bool {
+	return x.EscalationType == y.EscalationType &&
+		x.RunBasedEscalation == y.RunBasedEscalation &&
+		x.TimeBasedEscalation == y.TimeBasedEscalation &&
+		x.Reminders == y.Reminders
+}
+
+// withoutSubscription returns the subscriptions without the ones to the alert channel, it reports if any were removed
+func withoutSubscription(subscriptions []checkly.AlertChannelSubscription, alertChannelID int64) (remaining []checkly.AlertChannelSubscription, removed bool) {
+	for _, subscription := range subscriptions {
+		if subscription.ChannelID == alertChannelID {
+			removed = true
+			continue
+		}
+		remaining = append(remaining, subscription)
+	}
+
+	return
+}
+
+// replaceSubscription points the subscriptions to the old alert channel at the new one, keeping whether they're
+// activated, it reports if any were replaced
+func replaceSubscription(subscriptions []checkly.AlertChannelSubscription, oldID int64, newID int64) (replaced bool) {
+	for i := range subscriptions {
+		if subscriptions[i].ChannelID == oldID {
+			subscriptions[i].ChannelID = newID
+			replaced = true
+		}
+	}
+
+	return
+}
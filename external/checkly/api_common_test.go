@@ -18,6 +18,8 @@ package external
 
 import (
 	"testing"
+
+	"github.com/checkly/checkly-go-sdk"
 )
 
 func TestCheckValueString(t *testing.T) {
@@ -93,3 +95,47 @@ func TestGetTags(t *testing.T) {
 	}
 
 }
+
+func TestWithoutSubscription(t *testing.T) {
+	subscriptions := []checkly.AlertChannelSubscription{
+		{ChannelID: 1, Activated: true},
+		{ChannelID: 2, Activated: true},
+	}
+
+	remaining, removed := withoutSubscription(subscriptions, 1)
+	if !removed {
+		t.Error("Expected the subscription to be removed")
+	}
+	if len(remaining) != 1 || remaining[0].ChannelID != 2 {
+		t.Errorf("Expected only the subscription to 2 to remain, got %v", remaining)
+	}
+
+	remaining, removed = withoutSubscription(subscriptions, 3)
+	if removed {
+		t.Error("Expected nothing to be removed")
+	}
+	if len(remaining) != 2 {
+		t.Errorf("Expected 2 subscriptions, got %d", len(remaining))
+	}
+}
+
+func TestReplaceSubscription(t *testing.T) {
+	subscriptions := []checkly.AlertChannelSubscription{
+		{ChannelID: 1, Activated: false},
+		{ChannelID: 2, Activated: true},
+	}
+
+	if !replaceSubscription(subscriptions, 1, 3) {
+		t.Error("Expected the subscription to be replaced")
+	}
+	if subscriptions[0].ChannelID != 3 || subscriptions[0].Activated {
+		t.Errorf("Expected a deactivated subscription to 3, got %v", subscriptions[0])
+	}
+	if subscriptions[1].ChannelID != 2 {
+		t.Errorf("Expected the subscription to 2 to be kept, got %v", subscriptions[1])
+	}
+
+	if replaceSubscription(subscriptions, 1, 4) {
+		t.Error("Expected nothing to be replaced")
+	}
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/checkly/checkly-operator/internal/metrics"
+)
+
+// ErrChecklyUnavailable is returned instead of calling the checklyhq.com API while the circuit breaker is open
+var ErrChecklyUnavailable = errors.New("checklyhq.com API is unavailable, circuit breaker is open")
+
+// maxBreakerCooldown caps the cooldown, which doubles with every failed probe
+const maxBreakerCooldown = 10 * time.Minute
+
+// CircuitBreaker stops calling the checklyhq.com API after a number of consecutive failures, ex. during an outage.
+// Once the cooldown passed a single request is let through as a probe, if it succeeds the API is called as usual
+// again, otherwise the cooldown doubles. A nil CircuitBreaker never opens.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	backoff   time.Duration
+	openUntil time.Time
+	probing   bool
+	now       func() time.Time
+}
+
+// NewCircuitBreaker returns a circuit breaker opening after threshold consecutive failures for the cooldown, a
+// threshold of 0 disables it
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown, backoff: cooldown, now: time.Now}
+}
+
+// Open determines if API calls are currently rejected, it's false once the cooldown passed and a probe may be sent
+func (b *CircuitBreaker) Open() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.failures >= b.threshold && (b.now().Before(b.openUntil) || b.probing)
+}
+
+// Tripped determines if the API is considered unavailable, which is the case from the moment the breaker opens until
+// a probe succeeds
+func (b *CircuitBreaker) Tripped() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.failures >= b.threshold
+}
+
+// RetryAfter returns the time left until a probe is sent, or the cooldown if that's due already
+func (b *CircuitBreaker) RetryAfter() time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if remaining := b.openUntil.Sub(b.now()); remaining > 0 {
+		return remaining
+	}
+	return b.backoff
+}
+
+// allow determines if a request may be sent, probe is set when it's the one request let through after the cooldown
+func (b *CircuitBreaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return false, nil
+	}
+	if b.now().Before(b.openUntil) || b.probing {
+		return false, ErrChecklyUnavailable
+	}
+	b.probing = true
+	return true, nil
+}
+
+// record counts the outcome of a request
+func (b *CircuitBreaker) record(probe bool, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+	// The operator is idle on purpose while the breaker is tripped, monitoring tells it apart from a stuck one
+	defer func() {
+		metrics.SetPaused(metrics.PausedCircuitOpen, b.failures >= b.threshold)
+	}()
+
+	if !failed {
+		b.failures = 0
+		b.backoff = b.cooldown
+		return
+	}
+
+	b.failures++
+	switch {
+	case b.failures == b.threshold:
+		b.openUntil = b.now().Add(b.backoff)
+	case probe:
+		b.backoff *= 2
+		if b.backoff > maxBreakerCooldown {
+			b.backoff = max(b.cooldown, maxBreakerCooldown)
+		}
+		b.openUntil = b.now().Add(b.backoff)
+	}
+}
+
+// Transport wraps the transport of the checklyhq.com API client, requests fail with ErrChecklyUnavailable without
+// being sent while the breaker is open. Server errors and failed requests count as failures, client errors don't.
+func (b *CircuitBreaker) Transport(next http.RoundTripper) http.RoundTripper {
+	if b == nil {
+		return next
+	}
+	return &breakerTransport{next: next, breaker: b}
+}
+
+type breakerTransport struct {
+	next    http.RoundTripper
+	breaker *CircuitBreaker
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	probe, err := t.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	t.breaker.record(probe, err != nil || resp.StatusCode >= http.StatusInternalServerError)
+	return resp, err
+}
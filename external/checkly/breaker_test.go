@@ -0,0 +1,90 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	if NewCircuitBreaker(0, time.Minute) != nil {
+		t.Error("Expected a threshold of 0 to disable the circuit breaker")
+	}
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreaker(3, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	status := http.StatusServiceUnavailable
+	calls := 0
+	transport := breaker.Transport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: status, Body: http.NoBody}, nil
+	}))
+	req, _ := http.NewRequest(http.MethodGet, "https://api.checklyhq.com/v1/checks", nil)
+
+	for i := 0; i < 3; i++ {
+		_, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Expected no error before the threshold, got %e", err)
+		}
+	}
+	if !breaker.Open() || !breaker.Tripped() {
+		t.Fatal("Expected the breaker to open after 3 failures")
+	}
+
+	_, err := transport.RoundTrip(req)
+	if !errors.Is(err, ErrChecklyUnavailable) || calls != 3 {
+		t.Errorf("Expected the request to be rejected without calling the API, got %v after %d calls", err, calls)
+	}
+
+	// The probe after the cooldown fails, the cooldown doubles
+	now = now.Add(time.Minute)
+	if breaker.Open() {
+		t.Error("Expected a probe to be allowed after the cooldown")
+	}
+	_, _ = transport.RoundTrip(req)
+	if calls != 4 || breaker.RetryAfter() != 2*time.Minute {
+		t.Errorf("Expected a failed probe to double the cooldown, got %d calls and %s", calls, breaker.RetryAfter())
+	}
+
+	// The probe succeeds, the API is called as usual again
+	now = now.Add(2 * time.Minute)
+	status = http.StatusOK
+	_, err = transport.RoundTrip(req)
+	if err != nil || breaker.Tripped() {
+		t.Errorf("Expected a successful probe to close the breaker, got %v", err)
+	}
+
+	// Client errors don't count as failures
+	status = http.StatusBadRequest
+	for i := 0; i < 5; i++ {
+		_, _ = transport.RoundTrip(req)
+	}
+	if breaker.Tripped() {
+		t.Error("Expected client errors to leave the breaker closed")
+	}
+}
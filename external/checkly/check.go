@@ -19,6 +19,7 @@ package external
 import (
 	"context"
 	"net/http"
+	"reflect"
 	"strconv"
 	"time"
 
@@ -27,16 +28,20 @@ import (
 
 // Check is a struct for the internal packages to help put together the checkly check
 type Check struct {
-	Name            string
-	Namespace       string
-	Frequency       int
-	MaxResponseTime int
-	Endpoint        string
-	SuccessCode     string
-	GroupID         int64
-	ID              string
-	Muted           bool
-	Labels          map[string]string
+	Name              string
+	Namespace         string
+	Frequency         int
+	MaxResponseTime   int
+	Endpoint          string
+	SuccessCode       string
+	GroupID           int64
+	SetupSnippetID    int64
+	TeardownSnippetID int64
+	ID                string
+	Muted             bool
+	Labels            map[string]string
+	Tags              []string
+	AlertChannels     []checkly.AlertChannelSubscription
 }
 
 func checklyCheck(apiCheck Check) (check checkly.Check, err error) {
@@ -49,6 +54,7 @@ func checklyCheck(apiCheck Check) (check checkly.Check, err error) {
 	tags := getTags(apiCheck.Labels)
 	tags = append(tags, "checkly-operator")
 	tags = append(tags, apiCheck.Namespace)
+	tags = append(tags, apiCheck.Tags...)
 
 	alertSettings := checkly.AlertSettings{
 		EscalationType: checkly.RunBased,
@@ -85,6 +91,8 @@ func checklyCheck(apiCheck Check) (check checkly.Check, err error) {
 		AlertSettings:          alertSettings,
 		UseGlobalAlertSettings: false,
 		GroupID:                apiCheck.GroupID,
+		SetupSnippetID:         apiCheck.SetupSnippetID,
+		TearDownSnippetID:      apiCheck.TeardownSnippetID,
 		Request: checkly.Request{
 			Method:  http.MethodGet,
 			URL:     apiCheck.Endpoint,
@@ -112,6 +120,9 @@ func checklyCheck(apiCheck Check) (check checkly.Check, err error) {
 		},
 	}
 
+	// Without subscriptions the field is left out of the request, so the ones added in the checklyhq.com UI are kept
+	check.AlertChannelSubscriptions = apiCheck.AlertChannels
+
 	return
 }
 
@@ -163,6 +174,69 @@ func Delete(ID string, client checkly.Client) (err error) {
 	return
 }
 
+// Exists makes sure the checklyhq.com check exists, ex. before a resource is bound to it
+func Exists(ID string, client checkly.Client) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	_, err = client.GetCheck(ctx, ID)
+
+	return
+}
+
+// CheckDrift reads the check from checklyhq.com and returns the attributes which differ from the desired state, ex.
+// because they were edited in the checklyhq.com UI. Attributes the operator doesn't set are ignored.
+func CheckDrift(apiCheck Check, client checkly.Client) (fields []string, err error) {
+	want, err := checklyCheck(apiCheck)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	got, err := client.GetCheck(ctx, apiCheck.ID)
+	if err != nil {
+		return
+	}
+
+	fields = checkDrift(want, *got)
+	return
+}
+
+// checkDrift returns the attributes of the wanted check which differ in the one read from the API
+func checkDrift(want checkly.Check, got checkly.Check) (fields []string) {
+	attributes := []struct {
+		field string
+		equal bool
+	}{
+		{"name", want.Name == got.Name},
+		{"frequency", want.Frequency == got.Frequency},
+		{"maxResponseTime", want.MaxResponseTime == got.MaxResponseTime},
+		{"degradedResponseTime", want.DegradedResponseTime == got.DegradedResponseTime},
+		{"activated", want.Activated == got.Activated},
+		{"muted", want.Muted == got.Muted},
+		{"shouldFail", want.ShouldFail == got.ShouldFail},
+		{"groupId", want.GroupID == got.GroupID},
+		{"setupSnippetId", want.SetupSnippetID == got.SetupSnippetID},
+		{"tearDownSnippetId", want.TearDownSnippetID == got.TearDownSnippetID},
+		{"tags", sameStrings(want.Tags, got.Tags)},
+		{"alertSettings", sameAlertSettings(want.AlertSettings, got.AlertSettings)},
+		{"request.method", want.Request.Method == got.Request.Method},
+		{"request.url", want.Request.URL == got.Request.URL},
+		{"request.assertions", reflect.DeepEqual(want.Request.Assertions, got.Request.Assertions)},
+		// Without subscriptions the ones added in the checklyhq.com UI are kept, see checklyCheck
+		{"alertChannelSubscriptions", len(want.AlertChannelSubscriptions) == 0 || sameSubscriptions(want.AlertChannelSubscriptions, got.AlertChannelSubscriptions)},
+	}
+	for _, attribute := range attributes {
+		if !attribute.equal {
+			fields = append(fields, attribute.field)
+		}
+	}
+
+	return
+}
+
 func shouldFail(successCode string) (bool, error) {
 	code, err := strconv.Atoi(successCode)
 	if err != nil {
@@ -174,3 +248,44 @@ func shouldFail(successCode string) (bool, error) {
 		return true, nil
 	}
 }
+
+// Unsubscribe removes the alert channel subscription of the checklyhq.com check, it reports if the check was subscribed
+func Unsubscribe(ID string, alertChannelID int64, client checkly.Client) (unsubscribed bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	check, err := client.GetCheck(ctx, ID)
+	if err != nil {
+		return
+	}
+
+	check.AlertChannelSubscriptions, unsubscribed = withoutSubscription(check.AlertChannelSubscriptions, alertChannelID)
+	if !unsubscribed {
+		return
+	}
+
+	_, err = client.UpdateCheck(ctx, ID, *check)
+
+	return
+}
+
+// Resubscribe moves the alert channel subscription of the checklyhq.com check to another alert channel, it reports if
+// the check was subscribed
+func Resubscribe(ID string, oldAlertChannelID int64, newAlertChannelID int64, client checkly.Client) (resubscribed bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	check, err := client.GetCheck(ctx, ID)
+	if err != nil {
+		return
+	}
+
+	resubscribed = replaceSubscription(check.AlertChannelSubscriptions, oldAlertChannelID, newAlertChannelID)
+	if !resubscribed {
+		return
+	}
+
+	_, err = client.UpdateCheck(ctx, ID, *check)
+
+	return
+}
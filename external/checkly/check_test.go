@@ -34,10 +34,15 @@ func TestChecklyCheck(t *testing.T) {
 		Endpoint:        "https://foo.bar/baz",
 		SuccessCode:     "403",
 		Muted:           true,
+		AlertChannels:   []checkly.AlertChannelSubscription{{ChannelID: 1, Activated: true}},
 	}
 
 	testData, _ := checklyCheck(data1)
 
+	if len(testData.AlertChannelSubscriptions) != 1 || testData.AlertChannelSubscriptions[0] != data1.AlertChannels[0] {
+		t.Errorf("Expected %v, got %v", data1.AlertChannels, testData.AlertChannelSubscriptions)
+	}
+
 	if testData.Name != data1.Name {
 		t.Errorf("Expected %s, got %s", data1.Name, testData.Name)
 	}
@@ -247,3 +252,38 @@ func TestShouldFail(t *testing.T) {
 	}
 
 }
+
+func TestCheckDrift(t *testing.T) {
+	want, err := checklyCheck(Check{
+		Name:        "foo",
+		Namespace:   "bar",
+		Endpoint:    "https://foo.bar/baz",
+		SuccessCode: "200",
+		GroupID:     1,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	got := want
+	got.ID = "2"
+	got.Tags = []string{"bar", "checkly-operator"}
+	// Subscriptions added in the checklyhq.com UI are kept when the check doesn't set any
+	got.AlertChannelSubscriptions = []checkly.AlertChannelSubscription{{ChannelID: 3, Activated: true}}
+	if fields := checkDrift(want, got); len(fields) != 0 {
+		t.Errorf("Expected no drift, got %v", fields)
+	}
+
+	got.Frequency = 60
+	got.Request.URL = "https://foo.bar/qux"
+	fields := checkDrift(want, got)
+	if len(fields) != 2 || fields[0] != "frequency" || fields[1] != "request.url" {
+		t.Errorf("Expected frequency and request.url to drift, got %v", fields)
+	}
+
+	want.AlertChannelSubscriptions = []checkly.AlertChannelSubscription{{ChannelID: 4, Activated: true}}
+	fields = checkDrift(want, got)
+	if len(fields) != 3 || fields[2] != "alertChannelSubscriptions" {
+		t.Errorf("Expected the subscriptions to drift, got %v", fields)
+	}
+}
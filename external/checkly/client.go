@@ -0,0 +1,65 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// NewHTTPClient returns the HTTP client shared by all reconcilers talking to the checklyhq.com API. All requests go to
+// a single host, so the idle connection pool is sized per host as well, the default of 2 idle connections per host
+// makes concurrent reconciles open a new connection for most requests. Requests carry the user agent unless it's empty.
+func NewHTTPClient(maxIdleConns int, userAgent string) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = maxIdleConns
+	transport.MaxIdleConnsPerHost = maxIdleConns
+
+	var roundTripper http.RoundTripper = transport
+	if userAgent != "" {
+		roundTripper = &userAgentTransport{next: transport, userAgent: userAgent}
+	}
+
+	return &http.Client{
+		Transport: roundTripper,
+		Timeout:   time.Second * 30,
+	}
+}
+
+// UserAgent returns the user agent identifying the operator in the checklyhq.com access logs, ex.
+// checkly-operator/0.0.1 (cluster prod-eu)
+func UserAgent(version string, clusterName string) string {
+	userAgent := fmt.Sprintf("checkly-operator/%s", version)
+	if clusterName != "" {
+		userAgent = fmt.Sprintf("%s (cluster %s)", userAgent, clusterName)
+	}
+	return userAgent
+}
+
+// userAgentTransport sets the User-Agent header of the requests, the SDK doesn't offer a way to set it
+type userAgentTransport struct {
+	next      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.next.RoundTrip(req)
+}
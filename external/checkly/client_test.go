@@ -0,0 +1,67 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	client := NewHTTPClient(50, "")
+
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Expected an *http.Transport, got %T", client.Transport)
+	}
+
+	if transport.MaxIdleConns != 50 || transport.MaxIdleConnsPerHost != 50 {
+		t.Errorf("Expected the idle connection pool to be sized 50, got %d and %d per host", transport.MaxIdleConns, transport.MaxIdleConnsPerHost)
+	}
+
+	if transport == http.DefaultTransport {
+		t.Error("Expected the default transport to be left untouched")
+	}
+}
+
+func TestNewHTTPClientUserAgent(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+	}))
+	defer server.Close()
+
+	expected := UserAgent("1.2.3", "prod-eu")
+	if expected != "checkly-operator/1.2.3 (cluster prod-eu)" {
+		t.Errorf("Expected the user agent to include the version and cluster name, got %s", expected)
+	}
+
+	resp, err := NewHTTPClient(1, expected).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %e", err)
+	}
+	resp.Body.Close()
+
+	if userAgent != expected {
+		t.Errorf("Expected user agent %s, got %s", expected, userAgent)
+	}
+
+	if UserAgent("1.2.3", "") != "checkly-operator/1.2.3" {
+		t.Errorf("Expected the cluster to be left out when it's unset, got %s", UserAgent("1.2.3", ""))
+	}
+}
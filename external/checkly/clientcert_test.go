@@ -0,0 +1,89 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClientCertificate returns a PEM encoded self-signed client certificate and key valid for the supplied period
+func testClientCertificate(t *testing.T, notBefore time.Time, notAfter time.Time) (certPEM string, keyPEM string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Expected no error, got %e", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "checkly"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Expected no error, got %e", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Expected no error, got %e", err)
+	}
+
+	certPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return
+}
+
+func TestValidateClientCertificate(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	certPEM, keyPEM := testClientCertificate(t, now.Add(-time.Hour), now.Add(24*time.Hour))
+
+	err := ValidateClientCertificate(certPEM, keyPEM, now)
+	if err != nil {
+		t.Errorf("Expected no error, got %e", err)
+	}
+
+	_, otherKeyPEM := testClientCertificate(t, now.Add(-time.Hour), now.Add(24*time.Hour))
+	err = ValidateClientCertificate(certPEM, otherKeyPEM, now)
+	if err == nil || !strings.Contains(err.Error(), "not a valid pair") {
+		t.Errorf("Expected error for a mismatched key, got %v", err)
+	}
+
+	err = ValidateClientCertificate("foo", keyPEM, now)
+	if err == nil {
+		t.Error("Expected error for a malformed certificate, got none")
+	}
+
+	err = ValidateClientCertificate(certPEM, keyPEM, now.Add(48*time.Hour))
+	if err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("Expected error for an expired certificate, got %v", err)
+	}
+
+	err = ValidateClientCertificate(certPEM, keyPEM, now.Add(-48*time.Hour))
+	if err == nil || !strings.Contains(err.Error(), "not valid before") {
+		t.Errorf("Expected error for a certificate not valid yet, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/checkly/checkly-go-sdk"
+)
+
+// dashboardURL matches the custom URLs checklyhq.com accepts for dashboards, they become a subdomain of
+// checkly-dashboards.com
+var dashboardURL = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// dashboardWidths, dashboardRefreshRates and dashboardPaginationRates hold the values checklyhq.com accepts for the
+// layout of dashboards
+var (
+	dashboardWidths          = []string{"FULL", "960PX"}
+	dashboardRefreshRates    = []int{60, 300, 600}
+	dashboardPaginationRates = []int{30, 60, 300}
+)
+
+// Dashboard is a struct for the internal packages to help put together the checkly dashboard
+type Dashboard struct {
+	Name           string
+	ID             string
+	CustomURL      string
+	CustomDomain   string
+	Tags           []string
+	Width          string
+	RefreshRate    int
+	Paginate       bool
+	PaginationRate int
+	HideTags       bool
+}
+
+// checklyDashboard validates the dashboard and puts together the checkly one, unset layout attributes get the
+// checklyhq.com defaults
+func checklyDashboard(dashboard Dashboard) (d checkly.Dashboard, err error) {
+	if !dashboardURL.MatchString(dashboard.CustomURL) {
+		err = fmt.Errorf("custom URL %q has to be a subdomain of up to 63 lowercase letters, digits and dashes, ex. acme-status", dashboard.CustomURL)
+		return
+	}
+
+	d = checkly.Dashboard{
+		Header:         dashboard.Name,
+		CustomUrl:      dashboard.CustomURL,
+		CustomDomain:   dashboard.CustomDomain,
+		Tags:           dashboard.Tags,
+		Width:          dashboard.Width,
+		RefreshRate:    dashboard.RefreshRate,
+		Paginate:       dashboard.Paginate,
+		PaginationRate: dashboard.PaginationRate,
+		HideTags:       dashboard.HideTags,
+	}
+	if d.Width == "" {
+		d.Width = "FULL"
+	}
+	if d.RefreshRate == 0 {
+		d.RefreshRate = 60
+	}
+	if d.PaginationRate == 0 {
+		d.PaginationRate = 60
+	}
+
+	if !slices.Contains(dashboardWidths, d.Width) {
+		err = fmt.Errorf("width must be one of %v, got %q", dashboardWidths, d.Width)
+		return
+	}
+	if !slices.Contains(dashboardRefreshRates, d.RefreshRate) {
+		err = fmt.Errorf("refresh rate must be one of %v seconds, got %d", dashboardRefreshRates, d.RefreshRate)
+		return
+	}
+	if !slices.Contains(dashboardPaginationRates, d.PaginationRate) {
+		err = fmt.Errorf("pagination rate must be one of %v seconds, got %d", dashboardPaginationRates, d.PaginationRate)
+		return
+	}
+
+	return
+}
+
+// ValidateDashboard reports why the dashboard would be rejected, before it's sent to checklyhq.com
+func ValidateDashboard(dashboard Dashboard) error {
+	_, err := checklyDashboard(dashboard)
+	return err
+}
+
+// CreateDashboard creates a new checklyhq.com dashboard
+func CreateDashboard(dashboard Dashboard, client checkly.Client) (ID string, err error) {
+	d, err := checklyDashboard(dashboard)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	gotDashboard, err := client.CreateDashboard(ctx, d)
+	if err != nil {
+		return
+	}
+
+	return gotDashboard.DashboardID, nil
+}
+
+// UpdateDashboard updates an existing checklyhq.com dashboard
+func UpdateDashboard(dashboard Dashboard, client checkly.Client) (err error) {
+	d, err := checklyDashboard(dashboard)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	_, err = client.UpdateDashboard(ctx, dashboard.ID, d)
+
+	return
+}
+
+// DashboardDrift reads the dashboard from checklyhq.com and returns the attributes which differ from the desired state
+func DashboardDrift(dashboard Dashboard, client checkly.Client) (fields []string, err error) {
+	want, err := checklyDashboard(dashboard)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	got, err := client.GetDashboard(ctx, dashboard.ID)
+	if err != nil {
+		return
+	}
+
+	return dashboardDrift(want, *got), nil
+}
+
+// dashboardDrift compares the attributes of the dashboards the operator manages
+func dashboardDrift(want checkly.Dashboard, got checkly.Dashboard) (fields []string) {
+	if want.Header != got.Header {
+		fields = append(fields, "header")
+	}
+	if want.CustomUrl != got.CustomUrl {
+		fields = append(fields, "customUrl")
+	}
+	if want.CustomDomain != got.CustomDomain {
+		fields = append(fields, "customDomain")
+	}
+	if !slices.Equal(want.Tags, got.Tags) {
+		fields = append(fields, "tags")
+	}
+	if want.Width != got.Width {
+		fields = append(fields, "width")
+	}
+	if want.RefreshRate != got.RefreshRate {
+		fields = append(fields, "refreshRate")
+	}
+	if want.Paginate != got.Paginate {
+		fields = append(fields, "paginate")
+	}
+	if want.PaginationRate != got.PaginationRate {
+		fields = append(fields, "paginationRate")
+	}
+	if want.HideTags != got.HideTags {
+		fields = append(fields, "hideTags")
+	}
+
+	return
+}
+
+// DeleteDashboard deletes an existing checklyhq.com dashboard
+func DeleteDashboard(ID string, client checkly.Client) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	err = client.DeleteDashboard(ctx, ID)
+
+	return
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/checkly/checkly-go-sdk"
+)
+
+func TestChecklyDashboard(t *testing.T) {
+	d, err := checklyDashboard(Dashboard{Name: "status", CustomURL: "acme-status", Tags: []string{"public"}, HideTags: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if d.Header != "status" || d.CustomUrl != "acme-status" || len(d.Tags) != 1 || !d.HideTags {
+		t.Errorf("Unexpected dashboard %+v", d)
+	}
+	if d.Width != "FULL" || d.RefreshRate != 60 || d.PaginationRate != 60 {
+		t.Errorf("Expected the checklyhq.com defaults, got %+v", d)
+	}
+
+	for _, invalid := range []Dashboard{
+		{},
+		{CustomURL: "Acme"},
+		{CustomURL: "acme-"},
+		{CustomURL: "acme.status"},
+		{CustomURL: "acme", Width: "1024PX"},
+		{CustomURL: "acme", RefreshRate: 30},
+		{CustomURL: "acme", Paginate: true, PaginationRate: 10},
+	} {
+		if err = ValidateDashboard(invalid); err == nil {
+			t.Errorf("Expected an error for %+v", invalid)
+		}
+	}
+}
+
+func TestDashboardActions(t *testing.T) {
+	var requests int
+	var deleted bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		response := checkly.Dashboard{ID: 8, DashboardID: "a1b2", CustomUrl: "acme-status"}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/dashboards":
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && r.URL.Path == "/v1/dashboards/a1b2":
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/dashboards/a1b2":
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+	client := checkly.NewClient(server.URL, "key", server.Client(), nil)
+
+	dashboard := Dashboard{Name: "status", CustomURL: "acme-status"}
+	ID, err := CreateDashboard(dashboard, client)
+	if err != nil || ID != "a1b2" {
+		t.Errorf("Expected the dashboard ID, got %q, %v", ID, err)
+	}
+
+	dashboard.ID = ID
+	if err = UpdateDashboard(dashboard, client); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	// An invalid dashboard never reaches the API
+	requests = 0
+	dashboard.CustomURL = "Acme Status"
+	if err = UpdateDashboard(dashboard, client); err == nil || requests != 0 {
+		t.Errorf("Expected an error without requests, got %v and %d requests", err, requests)
+	}
+
+	err = DeleteDashboard(ID, client)
+	if err != nil || !deleted {
+		t.Errorf("Expected the dashboard to be deleted, got %v", err)
+	}
+}
+
+func TestDashboardDrift(t *testing.T) {
+	want := checkly.Dashboard{Header: "Status", CustomUrl: "acme-status", Width: "FULL", RefreshRate: 60, PaginationRate: 60}
+
+	if drift := dashboardDrift(want, want); len(drift) != 0 {
+		t.Errorf("Expected no drift, got %v", drift)
+	}
+
+	got := want
+	got.RefreshRate = 300
+	drift := dashboardDrift(want, got)
+	if len(drift) != 1 || drift[0] != "refreshRate" {
+		t.Errorf("Expected the refresh rate to drift, got %v", drift)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// deprecationHeaders are the response headers checklyhq.com announces deprecations and removals with
+var deprecationHeaders = []string{"Deprecation", "Sunset", "Warning"}
+
+// DeprecationTracker collects the deprecation warnings of the checklyhq.com API, each distinct warning is reported
+// once, so they can be surfaced without flooding the events
+type DeprecationTracker struct {
+	mu      sync.Mutex
+	seen    map[string]bool
+	pending []string
+}
+
+// NewDeprecationTracker returns a tracker without any warnings
+func NewDeprecationTracker() *DeprecationTracker {
+	return &DeprecationTracker{seen: map[string]bool{}}
+}
+
+// Transport returns a RoundTripper recording the deprecation headers of the API responses in the tracker
+func (d *DeprecationTracker) Transport(next http.RoundTripper) http.RoundTripper {
+	return &deprecationTransport{next: next, tracker: d}
+}
+
+// Drain returns the warnings recorded since the last call, it's nil-safe so the tracker is optional
+func (d *DeprecationTracker) Drain() (warnings []string) {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	warnings, d.pending = d.pending, nil
+	return
+}
+
+func (d *DeprecationTracker) record(req *http.Request, header http.Header) {
+	var values []string
+	for _, name := range deprecationHeaders {
+		if value := header.Get(name); value != "" {
+			values = append(values, fmt.Sprintf("%s: %s", name, value))
+		}
+	}
+	if len(values) == 0 {
+		return
+	}
+
+	warning := fmt.Sprintf("checklyhq.com API %s %s is deprecated (%s)", req.Method, endpoint(req.URL.Path), strings.Join(values, ", "))
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.seen[warning] {
+		d.seen[warning] = true
+		d.pending = append(d.pending, warning)
+	}
+}
+
+// apiVersion matches the version segment of the API paths, ex. v1
+var apiVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// endpoint replaces the IDs in the path, so the calls to the same endpoint share their warnings
+func endpoint(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.IndexFunc(segment, unicode.IsDigit) != -1 && !apiVersion.MatchString(segment) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+type deprecationTransport struct {
+	next    http.RoundTripper
+	tracker *DeprecationTracker
+}
+
+func (t *deprecationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err == nil {
+		t.tracker.record(req, resp.Header)
+	}
+	return resp, err
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeprecationTracker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/accounts/me" {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Sunset", "Wed, 01 Jan 2025 00:00:00 GMT")
+		}
+	}))
+	defer server.Close()
+
+	tracker := NewDeprecationTracker()
+	client := &http.Client{Transport: tracker.Transport(http.DefaultTransport)}
+	for _, path := range []string{"/v1/checks/123", "/v1/checks/456", "/v1/accounts/me"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %e", err)
+		}
+		resp.Body.Close()
+	}
+
+	warnings := tracker.Drain()
+	expected := "checklyhq.com API GET /v1/checks/{id} is deprecated (Deprecation: true, Sunset: Wed, 01 Jan 2025 00:00:00 GMT)"
+	if len(warnings) != 1 || warnings[0] != expected {
+		t.Errorf("Expected a single warning for the endpoint, got %v", warnings)
+	}
+
+	// Warnings are reported once
+	resp, _ := client.Get(server.URL + "/v1/checks/789")
+	resp.Body.Close()
+	if warnings = tracker.Drain(); len(warnings) != 0 {
+		t.Errorf("Expected the warning to be reported once, got %v", warnings)
+	}
+
+	var unset *DeprecationTracker
+	if unset.Drain() != nil {
+		t.Error("Expected no warnings without a tracker")
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// maxAlertChannelPageSize is the maximum number of alert channels the API returns per page
+const maxAlertChannelPageSize = 100
+
+// AlertChannelDirectory looks up the alert channels of the checklyhq.com account, including the ones created outside
+// the operator. The SDK doesn't list alert channels, so the API is called directly.
+type AlertChannelDirectory struct {
+	baseURL         string
+	apiKey          string
+	accountID       string
+	pageSize        int
+	includeArchived bool
+	client          *http.Client
+}
+
+// NewAlertChannelDirectory returns a directory of the alert channels of the account, it shares the HTTP client of the
+// SDK so the calls count towards the circuit breaker and carry the user agent. The alert channels are listed pageSize
+// at a time, values outside of 1 to 100 use the maximum of 100. Archived and soft-deleted alert channels are left out
+// unless includeArchived is set.
+func NewAlertChannelDirectory(baseURL string, apiKey string, accountID string, pageSize int, includeArchived bool, client *http.Client) *AlertChannelDirectory {
+	if pageSize <= 0 || pageSize > maxAlertChannelPageSize {
+		pageSize = maxAlertChannelPageSize
+	}
+	return &AlertChannelDirectory{baseURL: baseURL, apiKey: apiKey, accountID: accountID, pageSize: pageSize, includeArchived: includeArchived, client: client}
+}
+
+// AlertChannelNames maps the names of the alert channels in the account onto their IDs, alert channels without a name,
+// ex. email alert channels, are left out
+func (d *AlertChannelDirectory) AlertChannelNames(ctx context.Context) (names map[string][]int64, err error) {
+	names = map[string][]int64{}
+	err = d.EachAlertChannel(ctx, func(id int64, name string) error {
+		if name != "" {
+			names[name] = append(names[name], id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// EachAlertChannel calls fn with the ID and name of every alert channel in the account, page by page, so only a single
+// page is held in memory however big the account is. Archived and soft-deleted alert channels are skipped, unless the
+// directory includes them. An error returned by fn stops the listing.
+func (d *AlertChannelDirectory) EachAlertChannel(ctx context.Context, fn func(id int64, name string) error) error {
+	for page := 1; ; page++ {
+		var alertChannels []struct {
+			ID        int64  `json:"id"`
+			Archived  bool   `json:"archived"`
+			DeletedAt string `json:"deletedAt"`
+			Config    struct {
+				Name string `json:"name"`
+			} `json:"config"`
+		}
+		err := d.get(ctx, fmt.Sprintf("/v1/alert-channels?limit=%d&page=%d", d.pageSize, page), &alertChannels)
+		if err != nil {
+			return err
+		}
+
+		for _, alertChannel := range alertChannels {
+			// Binding to a defunct alert channel would leave the AlertChannel synced to nothing
+			if !d.includeArchived && (alertChannel.Archived || alertChannel.DeletedAt != "") {
+				continue
+			}
+			err = fn(alertChannel.ID, alertChannel.Config.Name)
+			if err != nil {
+				return err
+			}
+		}
+		if len(alertChannels) < d.pageSize {
+			return nil
+		}
+	}
+}
+
+func (d *AlertChannelDirectory) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+d.apiKey)
+	if d.accountID != "" {
+		req.Header.Set("x-checkly-account", d.accountID)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response listing checkly AlertChannels: %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestAlertChannelNames(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" || r.Header.Get("x-checkly-account") != "account" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		// A full first page, so the second page is requested as well
+		if r.URL.Query().Get("page") == "1" {
+			fmt.Fprint(w, "[")
+			for i := 1; i <= maxAlertChannelPageSize; i++ {
+				if i > 1 {
+					fmt.Fprint(w, ",")
+				}
+				fmt.Fprintf(w, `{"id": %d, "type": "EMAIL", "config": {"address": "foo@bar.baz"}}`, i)
+			}
+			fmt.Fprint(w, "]")
+			return
+		}
+		fmt.Fprint(w, `[{"id": 101, "type": "WEBHOOK", "config": {"name": "foo"}}, {"id": 102, "type": "OPSGENIE", "config": {"name": "foo"}}]`)
+	}))
+	defer server.Close()
+
+	names, err := NewAlertChannelDirectory(server.URL, "key", "account", 0, false, server.Client()).AlertChannelNames(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %e", err)
+	}
+	expected := map[string][]int64{"foo": {101, 102}}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+
+	_, err = NewAlertChannelDirectory(server.URL, "invalid", "account", 0, false, server.Client()).AlertChannelNames(context.Background())
+	if err == nil {
+		t.Error("Expected an error for a rejected API key, got none")
+	}
+}
+
+func TestAlertChannelNamesArchived(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"id": 1, "type": "WEBHOOK", "config": {"name": "foo"}},
+			{"id": 2, "type": "WEBHOOK", "archived": true, "config": {"name": "foo"}},
+			{"id": 3, "type": "WEBHOOK", "deletedAt": "2024-01-01T00:00:00.000Z", "config": {"name": "bar"}}
+		]`)
+	}))
+	defer server.Close()
+
+	names, err := NewAlertChannelDirectory(server.URL, "key", "account", 0, false, server.Client()).AlertChannelNames(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %e", err)
+	}
+	expected := map[string][]int64{"foo": {1}}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected archived alert channels to be skipped, got %v", names)
+	}
+
+	names, err = NewAlertChannelDirectory(server.URL, "key", "account", 0, true, server.Client()).AlertChannelNames(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %e", err)
+	}
+	expected = map[string][]int64{"foo": {1, 2}, "bar": {3}}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected archived alert channels to be included, got %v", names)
+	}
+}
+
+func TestEachAlertChannel(t *testing.T) {
+	const total = 5000
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if limit != 40 {
+			t.Errorf("Expected a page size of 40, got %d", limit)
+		}
+
+		fmt.Fprint(w, "[")
+		for id := (page-1)*limit + 1; id <= min(page*limit, total); id++ {
+			if id > (page-1)*limit+1 {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, `{"id": %d, "type": "WEBHOOK", "config": {"name": "foo-%d"}}`, id, id)
+		}
+		fmt.Fprint(w, "]")
+	}))
+	defer server.Close()
+
+	directory := NewAlertChannelDirectory(server.URL, "key", "account", 40, false, server.Client())
+	seen := map[int64]bool{}
+	err := directory.EachAlertChannel(context.Background(), func(id int64, name string) error {
+		if name != fmt.Sprintf("foo-%d", id) {
+			t.Errorf("Expected the name of alert channel %d, got %s", id, name)
+		}
+		seen[id] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %e", err)
+	}
+	if len(seen) != total {
+		t.Errorf("Expected %d alert channels, got %d", total, len(seen))
+	}
+	// 125 full pages and an empty one
+	if requests != total/40+1 {
+		t.Errorf("Expected %d requests, got %d", total/40+1, requests)
+	}
+
+	requests = 0
+	stop := errors.New("stop")
+	err = directory.EachAlertChannel(context.Background(), func(id int64, name string) error {
+		if id == 50 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || requests != 2 {
+		t.Errorf("Expected the listing to stop on the second page, got %v after %d requests", err, requests)
+	}
+
+	if size := NewAlertChannelDirectory(server.URL, "key", "account", 500, false, server.Client()).pageSize; size != maxAlertChannelPageSize {
+		t.Errorf("Expected the page size to be capped at %d, got %d", maxAlertChannelPageSize, size)
+	}
+}
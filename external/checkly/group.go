@@ -24,18 +24,21 @@ import (
 )
 
 type Group struct {
-	Name          string
-	ID            int64
-	Locations     []string
-	Activated     bool
-	AlertChannels []checkly.AlertChannelSubscription
-	Labels        map[string]string
+	Name            string
+	ID              int64
+	Locations       []string
+	Activated       bool
+	AlertChannels   []checkly.AlertChannelSubscription
+	Labels          map[string]string
+	EscalationDelay time.Duration
+	Tags            []string
 }
 
 func checklyGroup(group Group) (check checkly.Group) {
 
 	tags := getTags(group.Labels)
 	tags = append(tags, "checkly-operator")
+	tags = append(tags, group.Tags...)
 
 	alertSettings := checkly.AlertSettings{
 		EscalationType: checkly.RunBased,
@@ -54,6 +57,11 @@ func checklyGroup(group Group) (check checkly.Group) {
 		},
 	}
 
+	if group.EscalationDelay > 0 {
+		alertSettings.EscalationType = checkly.TimeBased
+		alertSettings.TimeBasedEscalation.MinutesFailingThreshold = int(group.EscalationDelay.Minutes())
+	}
+
 	check = checkly.Group{
 		Name:                      group.Name,
 		Activated:                 true,
@@ -103,6 +111,47 @@ func GroupUpdate(group Group, client checkly.Client) (err error) {
 	return
 }
 
+// GroupDrift reads the group from checklyhq.com and returns the attributes which differ from the desired state, ex.
+// because they were edited in the checklyhq.com UI. Attributes the operator doesn't set are ignored.
+func GroupDrift(group Group, client checkly.Client) (fields []string, err error) {
+	want := checklyGroup(group)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	got, err := client.GetGroup(ctx, group.ID)
+	if err != nil {
+		return
+	}
+
+	fields = groupDrift(want, *got)
+	return
+}
+
+// groupDrift returns the attributes of the wanted group which differ in the one read from the API
+func groupDrift(want checkly.Group, got checkly.Group) (fields []string) {
+	attributes := []struct {
+		field string
+		equal bool
+	}{
+		{"name", want.Name == got.Name},
+		{"activated", want.Activated == got.Activated},
+		{"muted", want.Muted == got.Muted},
+		{"concurrency", want.Concurrency == got.Concurrency},
+		{"locations", sameStrings(want.Locations, got.Locations)},
+		{"tags", sameStrings(want.Tags, got.Tags)},
+		{"alertSettings", sameAlertSettings(want.AlertSettings, got.AlertSettings)},
+		{"alertChannelSubscriptions", sameSubscriptions(want.AlertChannelSubscriptions, got.AlertChannelSubscriptions)},
+	}
+	for _, attribute := range attributes {
+		if !attribute.equal {
+			fields = append(fields, attribute.field)
+		}
+	}
+
+	return
+}
+
 func GroupDelete(ID int64, client checkly.Client) (err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
@@ -111,3 +160,78 @@ func GroupDelete(ID int64, client checkly.Client) (err error) {
 
 	return
 }
+
+// GroupExists makes sure the checklyhq.com group exists, ex. before a resource is bound to it
+func GroupExists(ID int64, client checkly.Client) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	_, err = client.GetGroup(ctx, ID)
+
+	return
+}
+
+// GroupAlertsTo determines if the checklyhq.com group is activated and holds an active subscription to the alert channel
+func GroupAlertsTo(ID int64, alertChannelID int64, client checkly.Client) (alerts bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	group, err := client.GetGroup(ctx, ID)
+	if err != nil {
+		return
+	}
+
+	if !group.Activated {
+		return
+	}
+
+	for _, subscription := range group.AlertChannelSubscriptions {
+		if subscription.ChannelID == alertChannelID && subscription.Activated {
+			alerts = true
+			return
+		}
+	}
+
+	return
+}
+
+// GroupUnsubscribe removes the alert channel subscription of the checklyhq.com group, it reports if the group was subscribed
+func GroupUnsubscribe(ID int64, alertChannelID int64, client checkly.Client) (unsubscribed bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	group, err := client.GetGroup(ctx, ID)
+	if err != nil {
+		return
+	}
+
+	group.AlertChannelSubscriptions, unsubscribed = withoutSubscription(group.AlertChannelSubscriptions, alertChannelID)
+	if !unsubscribed {
+		return
+	}
+
+	_, err = client.UpdateGroup(ctx, ID, *group)
+
+	return
+}
+
+// GroupResubscribe moves the alert channel subscription of the checklyhq.com group to another alert channel, it
+// reports if the group was subscribed
+func GroupResubscribe(ID int64, oldAlertChannelID int64, newAlertChannelID int64, client checkly.Client) (resubscribed bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	group, err := client.GetGroup(ctx, ID)
+	if err != nil {
+		return
+	}
+
+	resubscribed = replaceSubscription(group.AlertChannelSubscriptions, oldAlertChannelID, newAlertChannelID)
+	if !resubscribed {
+		return
+	}
+
+	_, err = client.UpdateGroup(ctx, ID, *group)
+
+	return
+}
@@ -16,7 +16,15 @@ limitations under the License.
 
 package external
 
-import "testing"
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/checkly/checkly-go-sdk"
+)
 
 func TestChecklyGroup(t *testing.T) {
 	data := Group{
@@ -29,4 +37,95 @@ func TestChecklyGroup(t *testing.T) {
 	if testData.Name != data.Name {
 		t.Errorf("Expected %s, got %s", data.Name, testData.Name)
 	}
+
+	if testData.AlertSettings.EscalationType != checkly.RunBased {
+		t.Errorf("Expected %s escalation, got %s", checkly.RunBased, testData.AlertSettings.EscalationType)
+	}
+
+	data.EscalationDelay = 15 * time.Minute
+	testData = checklyGroup(data)
+
+	if testData.AlertSettings.EscalationType != checkly.TimeBased {
+		t.Errorf("Expected %s escalation, got %s", checkly.TimeBased, testData.AlertSettings.EscalationType)
+	}
+
+	if testData.AlertSettings.TimeBasedEscalation.MinutesFailingThreshold != 15 {
+		t.Errorf("Expected a 15 minute threshold, got %d", testData.AlertSettings.TimeBasedEscalation.MinutesFailingThreshold)
+	}
+}
+
+func TestGroupAlertsTo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		group := checkly.Group{
+			ID:        1,
+			Activated: r.URL.Path != "/v1/check-groups/2",
+			AlertChannelSubscriptions: []checkly.AlertChannelSubscription{
+				{ChannelID: 3, Activated: true},
+				{ChannelID: 4, Activated: false},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		jsonResp, _ := json.Marshal(group)
+		w.Write(jsonResp)
+	}))
+	defer server.Close()
+
+	client := checkly.NewClient(server.URL, "foobarbaz", nil, nil)
+	client.SetAccountId("1234567890")
+
+	testData := []struct {
+		groupID        int64
+		alertChannelID int64
+		alerts         bool
+	}{
+		{1, 3, true},
+		{1, 4, false},
+		{1, 5, false},
+		{2, 3, false},
+	}
+
+	for _, tt := range testData {
+		alerts, err := GroupAlertsTo(tt.groupID, tt.alertChannelID, client)
+		if err != nil {
+			t.Errorf("Expected no error, got %s", err)
+		}
+		if alerts != tt.alerts {
+			t.Errorf("Expected %t for group %d and alert channel %d, got %t", tt.alerts, tt.groupID, tt.alertChannelID, alerts)
+		}
+	}
+}
+
+func TestGroupDrift(t *testing.T) {
+	group := Group{
+		Name:          "foo",
+		ID:            1,
+		Labels:        map[string]string{"team": "bar"},
+		AlertChannels: []checkly.AlertChannelSubscription{{ChannelID: 3, Activated: true}},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := checklyGroup(group)
+		got.ID = 1
+		// The API doesn't keep the order of the tags, and the group was muted in the checklyhq.com UI
+		got.Tags = []string{"checkly-operator", "team:bar"}
+		got.Muted = true
+		got.AlertSettings.ParallelRunFailureThreshold.Percentage = 10
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		jsonResp, _ := json.Marshal(got)
+		w.Write(jsonResp)
+	}))
+	defer server.Close()
+
+	client := checkly.NewClient(server.URL, "foobarbaz", nil, nil)
+	client.SetAccountId("1234567890")
+
+	fields, err := GroupDrift(group, client)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if len(fields) != 1 || fields[0] != "muted" {
+		t.Errorf("Expected only muted to drift, got %v", fields)
+	}
 }
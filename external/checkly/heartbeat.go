@@ -0,0 +1,189 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/checkly/checkly-go-sdk"
+)
+
+// heartbeatUnits holds the units the periods of heartbeat checks can be expressed in
+var heartbeatUnits = []string{"seconds", "minutes", "hours", "days"}
+
+// Heartbeat is a struct for the internal packages to help put together the checkly heartbeat check
+type Heartbeat struct {
+	Name          string
+	Namespace     string
+	Period        int
+	PeriodUnit    string
+	Grace         int
+	GraceUnit     string
+	ID            string
+	Muted         bool
+	Labels        map[string]string
+	AlertChannels []checkly.AlertChannelSubscription
+}
+
+func checklyHeartbeat(heartbeat Heartbeat) (check checkly.HeartbeatCheck, err error) {
+	graceUnit := checkValueString(heartbeat.GraceUnit, heartbeat.PeriodUnit)
+	if heartbeat.Period <= 0 {
+		err = fmt.Errorf("period must be positive, got %d", heartbeat.Period)
+		return
+	}
+	if heartbeat.Grace < 0 {
+		err = fmt.Errorf("grace must not be negative, got %d", heartbeat.Grace)
+		return
+	}
+	for _, unit := range []string{heartbeat.PeriodUnit, graceUnit} {
+		if !slices.Contains(heartbeatUnits, unit) {
+			err = fmt.Errorf("unit must be one of %v, got %q", heartbeatUnits, unit)
+			return
+		}
+	}
+
+	tags := getTags(heartbeat.Labels)
+	tags = append(tags, "checkly-operator")
+	tags = append(tags, heartbeat.Namespace)
+
+	check = checkly.HeartbeatCheck{
+		Name:      heartbeat.Name,
+		Activated: true,
+		Muted:     heartbeat.Muted,
+		Tags:      tags,
+		AlertSettings: checkly.AlertSettings{
+			EscalationType: checkly.RunBased,
+			RunBasedEscalation: checkly.RunBasedEscalation{
+				FailedRunThreshold: 1,
+			},
+			Reminders: checkly.Reminders{
+				Interval: 5,
+			},
+		},
+		UseGlobalAlertSettings: false,
+		Heartbeat: checkly.Heartbeat{
+			Period:     heartbeat.Period,
+			PeriodUnit: heartbeat.PeriodUnit,
+			Grace:      heartbeat.Grace,
+			GraceUnit:  graceUnit,
+		},
+	}
+
+	// Without subscriptions the field is left out of the request, so the ones added in the checklyhq.com UI are kept
+	check.AlertChannelSubscriptions = heartbeat.AlertChannels
+
+	return
+}
+
+// CreateHeartbeat creates a new checklyhq.com heartbeat check, it returns the ping token generated for it
+func CreateHeartbeat(heartbeat Heartbeat, client checkly.Client) (ID string, pingToken string, err error) {
+	check, err := checklyHeartbeat(heartbeat)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	gotCheck, err := client.CreateHeartbeat(ctx, check)
+	if err != nil {
+		return
+	}
+
+	return gotCheck.ID, gotCheck.Heartbeat.PingToken, nil
+}
+
+// UpdateHeartbeat updates an existing checklyhq.com heartbeat check, it returns the ping token of the check
+func UpdateHeartbeat(heartbeat Heartbeat, client checkly.Client) (pingToken string, err error) {
+	check, err := checklyHeartbeat(heartbeat)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	gotCheck, err := client.UpdateHeartbeat(ctx, heartbeat.ID, check)
+	if err != nil {
+		return
+	}
+
+	return gotCheck.Heartbeat.PingToken, nil
+}
+
+// DeleteHeartbeat deletes an existing checklyhq.com heartbeat check
+func DeleteHeartbeat(ID string, client checkly.Client) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	err = client.DeleteCheck(ctx, ID)
+
+	return
+}
+
+// HeartbeatDrift reads the heartbeat check from checklyhq.com and returns the attributes which differ from the desired
+// state, along with the ping token of the check. Attributes the operator doesn't set are ignored.
+func HeartbeatDrift(heartbeat Heartbeat, client checkly.Client) (fields []string, pingToken string, err error) {
+	want, err := checklyHeartbeat(heartbeat)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	got, err := client.GetHeartbeatCheck(ctx, heartbeat.ID)
+	if err != nil {
+		return
+	}
+
+	return heartbeatDrift(want, *got), got.Heartbeat.PingToken, nil
+}
+
+// heartbeatDrift returns the attributes of the wanted heartbeat check which differ in the one read from the API
+func heartbeatDrift(want checkly.HeartbeatCheck, got checkly.HeartbeatCheck) (fields []string) {
+	attributes := []struct {
+		field string
+		equal bool
+	}{
+		{"name", want.Name == got.Name},
+		{"activated", want.Activated == got.Activated},
+		{"muted", want.Muted == got.Muted},
+		{"tags", sameStrings(want.Tags, got.Tags)},
+		{"heartbeat.period", want.Heartbeat.Period == got.Heartbeat.Period},
+		{"heartbeat.periodUnit", want.Heartbeat.PeriodUnit == got.Heartbeat.PeriodUnit},
+		{"heartbeat.grace", want.Heartbeat.Grace == got.Heartbeat.Grace},
+		{"heartbeat.graceUnit", want.Heartbeat.GraceUnit == got.Heartbeat.GraceUnit},
+		// Without subscriptions the ones added in the checklyhq.com UI are kept, see checklyHeartbeat
+		{"alertChannelSubscriptions", len(want.AlertChannelSubscriptions) == 0 || sameSubscriptions(want.AlertChannelSubscriptions, got.AlertChannelSubscriptions)},
+	}
+	for _, attribute := range attributes {
+		if !attribute.equal {
+			fields = append(fields, attribute.field)
+		}
+	}
+
+	return
+}
+
+// HeartbeatPingURL returns the URL jobs ping to report to the heartbeat check with the ping token
+func HeartbeatPingURL(pingToken string) string {
+	return fmt.Sprintf("https://ping.checklyhq.com/%s", pingToken)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/checkly/checkly-go-sdk"
+)
+
+func TestChecklyHeartbeat(t *testing.T) {
+	heartbeat := Heartbeat{
+		Name:          "backup",
+		Namespace:     "bar",
+		Period:        1,
+		PeriodUnit:    "days",
+		Grace:         30,
+		GraceUnit:     "minutes",
+		AlertChannels: []checkly.AlertChannelSubscription{{ChannelID: 1, Activated: true}},
+	}
+
+	check, err := checklyHeartbeat(heartbeat)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if check.Name != "backup" || check.Heartbeat.Period != 1 || check.Heartbeat.PeriodUnit != "days" || check.Heartbeat.Grace != 30 || check.Heartbeat.GraceUnit != "minutes" {
+		t.Errorf("Unexpected heartbeat check %+v", check)
+	}
+	if len(check.AlertChannelSubscriptions) != 1 {
+		t.Errorf("Expected the subscriptions to be set, got %v", check.AlertChannelSubscriptions)
+	}
+
+	// The grace period defaults to the unit of the period
+	heartbeat.GraceUnit = ""
+	check, _ = checklyHeartbeat(heartbeat)
+	if check.Heartbeat.GraceUnit != "days" {
+		t.Errorf("Expected the grace unit of the period, got %q", check.Heartbeat.GraceUnit)
+	}
+
+	for _, invalid := range []Heartbeat{
+		{Period: 0, PeriodUnit: "days"},
+		{Period: 1, PeriodUnit: "weeks"},
+		{Period: 1, PeriodUnit: "days", Grace: -1},
+		{Period: 1, PeriodUnit: "days", GraceUnit: "fortnights"},
+	} {
+		_, err = checklyHeartbeat(invalid)
+		if err == nil {
+			t.Errorf("Expected an error for %+v", invalid)
+		}
+	}
+}
+
+func TestHeartbeatActions(t *testing.T) {
+	var deleted bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		response := checkly.HeartbeatCheck{
+			ID:        "7",
+			Name:      "backup",
+			Activated: true,
+			Tags:      []string{"checkly-operator", "bar"},
+			Heartbeat: checkly.Heartbeat{Period: 1, PeriodUnit: "hours", GraceUnit: "hours", PingToken: "token"},
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/checks/heartbeat":
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && r.URL.Path == "/v1/checks/heartbeat/7":
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/checks/7":
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/checks/7":
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+	client := checkly.NewClient(server.URL, "key", server.Client(), nil)
+
+	heartbeat := Heartbeat{Name: "backup", Namespace: "bar", Period: 1, PeriodUnit: "hours"}
+	ID, pingToken, err := CreateHeartbeat(heartbeat, client)
+	if err != nil || ID != "7" || pingToken != "token" {
+		t.Errorf("Expected the ID and ping token, got %q, %q, %v", ID, pingToken, err)
+	}
+
+	heartbeat.ID = ID
+	drift, pingToken, err := HeartbeatDrift(heartbeat, client)
+	if err != nil || len(drift) != 0 || pingToken != "token" {
+		t.Errorf("Expected no drift, got %v, %q, %v", drift, pingToken, err)
+	}
+
+	heartbeat.Period = 2
+	drift, _, _ = HeartbeatDrift(heartbeat, client)
+	if len(drift) != 1 || drift[0] != "heartbeat.period" {
+		t.Errorf("Expected the period to drift, got %v", drift)
+	}
+
+	pingToken, err = UpdateHeartbeat(heartbeat, client)
+	if err != nil || pingToken != "token" {
+		t.Errorf("Expected the ping token, got %q, %v", pingToken, err)
+	}
+
+	err = DeleteHeartbeat(ID, client)
+	if err != nil || !deleted {
+		t.Errorf("Expected the heartbeat check to be deleted, got %v", err)
+	}
+}
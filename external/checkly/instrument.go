@@ -0,0 +1,82 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/checkly/checkly-operator/internal/metrics"
+)
+
+// apiKinds maps the checklyhq.com API paths onto the kinds reported by the API request metrics
+var apiKinds = []struct {
+	prefix string
+	kind   string
+}{
+	{"/v1/alert-channels", "AlertChannel"},
+	{"/v1/check-groups", "Group"},
+	{"/v1/checks", "ApiCheck"},
+}
+
+// InstrumentTransport returns a RoundTripper which reports every checklyhq.com API request in the
+// checkly_api_requests_total and checkly_api_request_duration_seconds metrics. Responses with an error status count as
+// failed requests.
+func InstrumentTransport(next http.RoundTripper) http.RoundTripper {
+	return &instrumentedTransport{next: next}
+}
+
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	result := err
+	if err == nil && resp.StatusCode >= http.StatusBadRequest {
+		result = fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+	metrics.ObserveAPIRequest(apiKind(req.URL.Path), apiVerb(req.Method), time.Since(start), result)
+
+	return resp, err
+}
+
+func apiKind(path string) string {
+	for _, kind := range apiKinds {
+		if strings.HasPrefix(path, kind.prefix) {
+			return kind.kind
+		}
+	}
+	return "other"
+}
+
+func apiVerb(method string) string {
+	switch method {
+	case http.MethodPost:
+		return metrics.VerbCreate
+	case http.MethodPut, http.MethodPatch:
+		return metrics.VerbUpdate
+	case http.MethodDelete:
+		return metrics.VerbDelete
+	default:
+		return metrics.VerbGet
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInstrumentTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: InstrumentTransport(http.DefaultTransport)}
+	resp, err := client.Get(server.URL + "/v1/alert-channels/1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %e", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTeapot {
+		t.Errorf("Expected the response to be passed through, got %s", resp.Status)
+	}
+}
+
+func TestAPIKindAndVerb(t *testing.T) {
+	kinds := map[string]string{
+		"/v1/alert-channels":   "AlertChannel",
+		"/v1/alert-channels/3": "AlertChannel",
+		"/v1/check-groups/1":   "Group",
+		"/v1/checks/2":         "ApiCheck",
+		"/v1/accounts/me":      "other",
+	}
+	for path, expected := range kinds {
+		if got := apiKind(path); got != expected {
+			t.Errorf("Expected kind %s for %s, got %s", expected, path, got)
+		}
+	}
+
+	verbs := map[string]string{
+		http.MethodGet:    "get",
+		http.MethodPost:   "create",
+		http.MethodPut:    "update",
+		http.MethodDelete: "delete",
+	}
+	for method, expected := range verbs {
+		if got := apiVerb(method); got != expected {
+			t.Errorf("Expected verb %s for %s, got %s", expected, method, got)
+		}
+	}
+}
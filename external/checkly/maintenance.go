@@ -0,0 +1,199 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/checkly/checkly-go-sdk"
+)
+
+// maintenanceWindowTimeFormat is the format of the times of checklyhq.com maintenance windows
+const maintenanceWindowTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// repeatUnits holds the units maintenance windows can repeat in
+var repeatUnits = []string{"DAY", "WEEK", "MONTH"}
+
+// MaintenanceWindow is a struct for the internal packages to help put together the checkly maintenance window
+type MaintenanceWindow struct {
+	Name           string
+	ID             int64
+	StartsAt       time.Time
+	EndsAt         time.Time
+	RepeatInterval int
+	RepeatUnit     string
+	RepeatEndsAt   time.Time
+	Tags           []string
+}
+
+// checklyMaintenanceWindow validates the maintenance window and puts together the checkly one, the times are sent in
+// UTC
+func checklyMaintenanceWindow(window MaintenanceWindow) (mw checkly.MaintenanceWindow, err error) {
+	if !window.EndsAt.After(window.StartsAt) {
+		err = fmt.Errorf("end %s has to be after the start %s", window.EndsAt.Format(time.RFC3339), window.StartsAt.Format(time.RFC3339))
+		return
+	}
+	if len(window.Tags) == 0 {
+		err = fmt.Errorf("at least one tag has to select the checks and groups of the window")
+		return
+	}
+	if window.RepeatInterval < 0 {
+		err = fmt.Errorf("repeat interval must not be negative, got %d", window.RepeatInterval)
+		return
+	}
+	if window.RepeatInterval > 0 && !slices.Contains(repeatUnits, window.RepeatUnit) {
+		err = fmt.Errorf("repeat unit must be one of %v, got %q", repeatUnits, window.RepeatUnit)
+		return
+	}
+	if window.RepeatInterval == 0 && (window.RepeatUnit != "" || !window.RepeatEndsAt.IsZero()) {
+		err = fmt.Errorf("repeat unit and end need a repeat interval")
+		return
+	}
+	if !window.RepeatEndsAt.IsZero() && !window.RepeatEndsAt.After(window.EndsAt) {
+		err = fmt.Errorf("repeat end %s has to be after the end %s", window.RepeatEndsAt.Format(time.RFC3339), window.EndsAt.Format(time.RFC3339))
+		return
+	}
+
+	mw = checkly.MaintenanceWindow{
+		Name:           window.Name,
+		StartsAt:       window.StartsAt.UTC().Format(maintenanceWindowTimeFormat),
+		EndsAt:         window.EndsAt.UTC().Format(maintenanceWindowTimeFormat),
+		RepeatInterval: window.RepeatInterval,
+		RepeatUnit:     window.RepeatUnit,
+		Tags:           window.Tags,
+	}
+	if !window.RepeatEndsAt.IsZero() {
+		mw.RepeatEndsAt = window.RepeatEndsAt.UTC().Format(maintenanceWindowTimeFormat)
+	}
+
+	return
+}
+
+// ValidateMaintenanceWindow reports why the maintenance window would be rejected, before it's sent to checklyhq.com
+func ValidateMaintenanceWindow(window MaintenanceWindow) error {
+	_, err := checklyMaintenanceWindow(window)
+	return err
+}
+
+// MaintenanceWindowDrift reads the maintenance window from checklyhq.com and returns the attributes which differ from
+// the desired state
+func MaintenanceWindowDrift(window MaintenanceWindow, client checkly.Client) (fields []string, err error) {
+	want, err := checklyMaintenanceWindow(window)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	got, err := client.GetMaintenanceWindow(ctx, window.ID)
+	if err != nil {
+		return
+	}
+
+	return maintenanceWindowDrift(want, *got), nil
+}
+
+// maintenanceWindowDrift compares the maintenance windows, times are compared as instants as checklyhq.com may format
+// them differently
+func maintenanceWindowDrift(want checkly.MaintenanceWindow, got checkly.MaintenanceWindow) (fields []string) {
+	if want.Name != got.Name {
+		fields = append(fields, "name")
+	}
+	if !sameTime(want.StartsAt, got.StartsAt) {
+		fields = append(fields, "startsAt")
+	}
+	if !sameTime(want.EndsAt, got.EndsAt) {
+		fields = append(fields, "endsAt")
+	}
+	if want.RepeatInterval != got.RepeatInterval {
+		fields = append(fields, "repeatInterval")
+	}
+	if want.RepeatUnit != got.RepeatUnit {
+		fields = append(fields, "repeatUnit")
+	}
+	if !sameTime(want.RepeatEndsAt, got.RepeatEndsAt) {
+		fields = append(fields, "repeatEndsAt")
+	}
+	if !slices.Equal(want.Tags, got.Tags) {
+		fields = append(fields, "tags")
+	}
+
+	return
+}
+
+// sameTime determines if both times are the same instant, or both unset
+func sameTime(want string, got string) bool {
+	if want == "" || got == "" {
+		return want == got
+	}
+	wantTime, err := time.Parse(time.RFC3339, want)
+	if err != nil {
+		return false
+	}
+	gotTime, err := time.Parse(time.RFC3339, got)
+	if err != nil {
+		return false
+	}
+	return wantTime.Equal(gotTime)
+}
+
+// CreateMaintenanceWindow creates a new checklyhq.com maintenance window
+func CreateMaintenanceWindow(window MaintenanceWindow, client checkly.Client) (ID int64, err error) {
+	mw, err := checklyMaintenanceWindow(window)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	gotWindow, err := client.CreateMaintenanceWindow(ctx, mw)
+	if err != nil {
+		return
+	}
+
+	return gotWindow.ID, nil
+}
+
+// UpdateMaintenanceWindow updates an existing checklyhq.com maintenance window
+func UpdateMaintenanceWindow(window MaintenanceWindow, client checkly.Client) (err error) {
+	mw, err := checklyMaintenanceWindow(window)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	_, err = client.UpdateMaintenanceWindow(ctx, window.ID, mw)
+
+	return
+}
+
+// DeleteMaintenanceWindow deletes an existing checklyhq.com maintenance window
+func DeleteMaintenanceWindow(ID int64, client checkly.Client) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	err = client.DeleteMaintenanceWindow(ctx, ID)
+
+	return
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/checkly/checkly-go-sdk"
+)
+
+func TestChecklyMaintenanceWindow(t *testing.T) {
+	amsterdam, _ := time.LoadLocation("Europe/Amsterdam")
+	window := MaintenanceWindow{
+		Name:           "release",
+		StartsAt:       time.Date(2024, 1, 10, 22, 0, 0, 0, amsterdam),
+		EndsAt:         time.Date(2024, 1, 10, 23, 30, 0, 0, amsterdam),
+		RepeatInterval: 1,
+		RepeatUnit:     "WEEK",
+		RepeatEndsAt:   time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		Tags:           []string{"payments"},
+	}
+
+	mw, err := checklyMaintenanceWindow(window)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if mw.StartsAt != "2024-01-10T21:00:00.000Z" || mw.EndsAt != "2024-01-10T22:30:00.000Z" || mw.RepeatEndsAt != "2024-06-01T00:00:00.000Z" {
+		t.Errorf("Expected the times in UTC, got %+v", mw)
+	}
+	if mw.Name != "release" || mw.RepeatInterval != 1 || mw.RepeatUnit != "WEEK" || len(mw.Tags) != 1 {
+		t.Errorf("Unexpected maintenance window %+v", mw)
+	}
+
+	start := time.Date(2024, 1, 10, 22, 0, 0, 0, time.UTC)
+	for _, invalid := range []MaintenanceWindow{
+		{StartsAt: start, EndsAt: start, Tags: []string{"a"}},
+		{StartsAt: start, EndsAt: start.Add(-time.Hour), Tags: []string{"a"}},
+		{StartsAt: start, EndsAt: start.Add(time.Hour)},
+		{StartsAt: start, EndsAt: start.Add(time.Hour), Tags: []string{"a"}, RepeatInterval: 1, RepeatUnit: "YEAR"},
+		{StartsAt: start, EndsAt: start.Add(time.Hour), Tags: []string{"a"}, RepeatUnit: "DAY"},
+		{StartsAt: start, EndsAt: start.Add(time.Hour), Tags: []string{"a"}, RepeatInterval: 1, RepeatUnit: "DAY", RepeatEndsAt: start},
+	} {
+		if err = ValidateMaintenanceWindow(invalid); err == nil {
+			t.Errorf("Expected an error for %+v", invalid)
+		}
+	}
+}
+
+func TestMaintenanceWindowActions(t *testing.T) {
+	var requests int
+	var deleted bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		response := checkly.MaintenanceWindow{ID: 3, Name: "release", Tags: []string{"payments"}}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/maintenance-windows":
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && r.URL.Path == "/v1/maintenance-windows/3":
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/maintenance-windows/3":
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+	client := checkly.NewClient(server.URL, "key", server.Client(), nil)
+
+	start := time.Date(2024, 1, 10, 22, 0, 0, 0, time.UTC)
+	window := MaintenanceWindow{Name: "release", StartsAt: start, EndsAt: start.Add(time.Hour), Tags: []string{"payments"}}
+	ID, err := CreateMaintenanceWindow(window, client)
+	if err != nil || ID != 3 {
+		t.Errorf("Expected the ID, got %d, %v", ID, err)
+	}
+
+	window.ID = ID
+	if err = UpdateMaintenanceWindow(window, client); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	// An invalid window never reaches the API
+	requests = 0
+	window.EndsAt = start
+	if err = UpdateMaintenanceWindow(window, client); err == nil || requests != 0 {
+		t.Errorf("Expected an error without requests, got %v and %d requests", err, requests)
+	}
+
+	err = DeleteMaintenanceWindow(ID, client)
+	if err != nil || !deleted {
+		t.Errorf("Expected the maintenance window to be deleted, got %v", err)
+	}
+}
+
+func TestMaintenanceWindowDrift(t *testing.T) {
+	want := checkly.MaintenanceWindow{
+		Name:     "release",
+		StartsAt: "2024-01-01T10:00:00.000Z",
+		EndsAt:   "2024-01-01T12:00:00.000Z",
+		Tags:     []string{"payments"},
+	}
+
+	// The API may format the times differently
+	got := want
+	got.StartsAt = "2024-01-01T10:00:00Z"
+	if drift := maintenanceWindowDrift(want, got); len(drift) != 0 {
+		t.Errorf("Expected no drift, got %v", drift)
+	}
+
+	got.EndsAt = "2024-01-01T14:00:00.000Z"
+	got.Tags = []string{"checkout"}
+	drift := maintenanceWindowDrift(want, got)
+	if len(drift) != 2 || drift[0] != "endsAt" || drift[1] != "tags" {
+		t.Errorf("Expected the end and tags to drift, got %v", drift)
+	}
+}
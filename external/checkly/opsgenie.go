@@ -0,0 +1,72 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// opsGenieAPIURLs holds the OpsGenie API endpoint of each region
+var opsGenieAPIURLs = map[string]string{
+	"US": "https://api.opsgenie.com",
+	"EU": "https://api.eu.opsgenie.com",
+}
+
+var opsGenieClient = &http.Client{Timeout: time.Second * 5}
+
+// ErrOpsGenieKeyRejected is returned when OpsGenie doesn't accept the API key
+var ErrOpsGenieKeyRejected = errors.New("OpsGenie rejected the API key")
+
+// ValidateOpsGenieAPIKey checks the API key against the OpsGenie API of the region, US if unset. An error wrapping
+// ErrOpsGenieKeyRejected means the key is invalid, other errors mean the key couldn't be checked.
+func ValidateOpsGenieAPIKey(ctx context.Context, region string, apiKey string) error {
+	baseURL, ok := opsGenieAPIURLs[strings.ToUpper(checkValueString(region, "US"))]
+	if !ok {
+		return fmt.Errorf("unknown OpsGenie region %q", region)
+	}
+
+	return validateOpsGenieAPIKey(ctx, baseURL, apiKey)
+}
+
+func validateOpsGenieAPIKey(ctx context.Context, baseURL string, apiKey string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/v2/account", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "GenieKey "+apiKey)
+
+	resp, err := opsGenieClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	// Keys of integrations without read access are valid, they're just not allowed to read the account
+	case http.StatusOK, http.StatusForbidden:
+		return nil
+	case http.StatusUnauthorized:
+		return ErrOpsGenieKeyRejected
+	default:
+		return fmt.Errorf("unexpected response from OpsGenie: %s", resp.Status)
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateOpsGenieAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "GenieKey valid":
+			w.WriteHeader(http.StatusOK)
+		case "GenieKey integration":
+			w.WriteHeader(http.StatusForbidden)
+		case "GenieKey outage":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	for _, key := range []string{"valid", "integration"} {
+		if err := validateOpsGenieAPIKey(ctx, server.URL, key); err != nil {
+			t.Errorf("Expected %q to be valid, got %s", key, err)
+		}
+	}
+
+	err := validateOpsGenieAPIKey(ctx, server.URL, "invalid")
+	if !errors.Is(err, ErrOpsGenieKeyRejected) {
+		t.Errorf("Expected the key to be rejected, got %v", err)
+	}
+
+	err = validateOpsGenieAPIKey(ctx, server.URL, "outage")
+	if err == nil || errors.Is(err, ErrOpsGenieKeyRejected) {
+		t.Errorf("Expected an error which doesn't reject the key, got %v", err)
+	}
+
+	err = ValidateOpsGenieAPIKey(ctx, "APAC", "valid")
+	if err == nil {
+		t.Error("Expected an error for an unknown region")
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/checkly/checkly-go-sdk"
+)
+
+// privateLocationSlug matches the slug names checklyhq.com accepts for private locations, checks reference the
+// location by it
+var privateLocationSlug = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,48}[a-z0-9])?$`)
+
+// PrivateLocation is a struct for the internal packages to help put together the checkly private location
+type PrivateLocation struct {
+	Name     string
+	ID       string
+	SlugName string
+	Icon     string
+}
+
+// checklyPrivateLocation validates the private location and puts together the checkly one
+func checklyPrivateLocation(privateLocation PrivateLocation) (pl checkly.PrivateLocation, err error) {
+	if !privateLocationSlug.MatchString(privateLocation.SlugName) {
+		err = fmt.Errorf("slug name %q has to be up to 50 lowercase letters, digits and dashes, ex. eu-datacenter", privateLocation.SlugName)
+		return
+	}
+
+	pl = checkly.PrivateLocation{
+		Name:     privateLocation.Name,
+		SlugName: privateLocation.SlugName,
+		Icon:     privateLocation.Icon,
+	}
+	if pl.Icon == "" {
+		pl.Icon = "location"
+	}
+
+	return
+}
+
+// ValidatePrivateLocation reports why the private location would be rejected, before it's sent to checklyhq.com
+func ValidatePrivateLocation(privateLocation PrivateLocation) error {
+	_, err := checklyPrivateLocation(privateLocation)
+	return err
+}
+
+// CreatePrivateLocation creates a new checklyhq.com private location, the agent API keys are only returned in full
+// on creation
+func CreatePrivateLocation(privateLocation PrivateLocation, client checkly.Client) (ID string, keys []checkly.PrivateLocationKey, err error) {
+	pl, err := checklyPrivateLocation(privateLocation)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	gotPrivateLocation, err := client.CreatePrivateLocation(ctx, pl)
+	if err != nil {
+		return
+	}
+
+	return gotPrivateLocation.ID, gotPrivateLocation.Keys, nil
+}
+
+// PrivateLocationKeys returns the agent API keys of an existing checklyhq.com private location, keys created before
+// are masked
+func PrivateLocationKeys(ID string, client checkly.Client) (keys []checkly.PrivateLocationKey, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	gotPrivateLocation, err := client.GetPrivateLocation(ctx, ID)
+	if err != nil {
+		return
+	}
+
+	return gotPrivateLocation.Keys, nil
+}
+
+// UpdatePrivateLocation updates an existing checklyhq.com private location
+func UpdatePrivateLocation(privateLocation PrivateLocation, client checkly.Client) (err error) {
+	pl, err := checklyPrivateLocation(privateLocation)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	_, err = client.UpdatePrivateLocation(ctx, privateLocation.ID, pl)
+
+	return
+}
+
+// DeletePrivateLocation deletes an existing checklyhq.com private location
+func DeletePrivateLocation(ID string, client checkly.Client) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	err = client.DeletePrivateLocation(ctx, ID)
+
+	return
+}
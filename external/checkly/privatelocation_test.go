@@ -0,0 +1,93 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/checkly/checkly-go-sdk"
+)
+
+func TestChecklyPrivateLocation(t *testing.T) {
+	pl, err := checklyPrivateLocation(PrivateLocation{Name: "datacenter", SlugName: "eu-datacenter"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if pl.Name != "datacenter" || pl.SlugName != "eu-datacenter" || pl.Icon != "location" {
+		t.Errorf("Unexpected private location %+v", pl)
+	}
+
+	for _, invalid := range []PrivateLocation{
+		{},
+		{SlugName: "EU"},
+		{SlugName: "eu-"},
+		{SlugName: "eu_datacenter"},
+	} {
+		if err = ValidatePrivateLocation(invalid); err == nil {
+			t.Errorf("Expected an error for %+v", invalid)
+		}
+	}
+}
+
+func TestPrivateLocationActions(t *testing.T) {
+	var deleted bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		response := checkly.PrivateLocation{ID: "pl1", SlugName: "eu-datacenter", Keys: []checkly.PrivateLocationKey{{Id: "k1", MaskedKey: "pl_...abc"}}}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/private-locations":
+			response.Keys[0].RawKey = "pl_abc"
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/private-locations/pl1":
+		case r.Method == http.MethodPut && r.URL.Path == "/v1/private-locations/pl1":
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/private-locations/pl1":
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+	client := checkly.NewClient(server.URL, "key", server.Client(), nil)
+
+	privateLocation := PrivateLocation{Name: "datacenter", SlugName: "eu-datacenter"}
+	ID, keys, err := CreatePrivateLocation(privateLocation, client)
+	if err != nil || ID != "pl1" || len(keys) != 1 || keys[0].RawKey != "pl_abc" {
+		t.Errorf("Expected the ID and raw key, got %q, %v, %v", ID, keys, err)
+	}
+
+	keys, err = PrivateLocationKeys(ID, client)
+	if err != nil || len(keys) != 1 || keys[0].RawKey != "" {
+		t.Errorf("Expected the masked key, got %v, %v", keys, err)
+	}
+
+	privateLocation.ID = ID
+	if err = UpdatePrivateLocation(privateLocation, client); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	err = DeletePrivateLocation(ID, client)
+	if err != nil || !deleted {
+		t.Errorf("Expected the private location to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/checkly/checkly-go-sdk"
+	"golang.org/x/time/rate"
+)
+
+// deleteRateLimitedClient throttles the delete calls of the wrapped client, so tearing down many resources at once
+// doesn't run into the API rate limits, all other calls pass through unthrottled
+type deleteRateLimitedClient struct {
+	checkly.Client
+	limiter *rate.Limiter
+}
+
+// NewDeleteRateLimitedClient wraps the client to make at most qps delete calls per second, a qps of 0 or less
+// returns the client unchanged
+func NewDeleteRateLimitedClient(client checkly.Client, qps float64) checkly.Client {
+	if qps <= 0 {
+		return client
+	}
+
+	return &deleteRateLimitedClient{
+		Client:  client,
+		limiter: rate.NewLimiter(rate.Limit(qps), 1),
+	}
+}
+
+func (c *deleteRateLimitedClient) wait(ctx context.Context) error {
+	err := c.limiter.Wait(ctx)
+	if err != nil {
+		return fmt.Errorf("delete rate limit: %w", err)
+	}
+	return nil
+}
+
+func (c *deleteRateLimitedClient) Delete(ctx context.Context, ID string) error {
+	err := c.wait(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Client.Delete(ctx, ID)
+}
+
+func (c *deleteRateLimitedClient) DeleteCheck(ctx context.Context, ID string) error {
+	err := c.wait(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Client.DeleteCheck(ctx, ID)
+}
+
+func (c *deleteRateLimitedClient) DeleteGroup(ctx context.Context, ID int64) error {
+	err := c.wait(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Client.DeleteGroup(ctx, ID)
+}
+
+func (c *deleteRateLimitedClient) DeleteAlertChannel(ctx context.Context, ID int64) error {
+	err := c.wait(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Client.DeleteAlertChannel(ctx, ID)
+}
+
+func (c *deleteRateLimitedClient) DeleteMaintenanceWindow(ctx context.Context, ID int64) error {
+	err := c.wait(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Client.DeleteMaintenanceWindow(ctx, ID)
+}
+
+func (c *deleteRateLimitedClient) DeleteSnippet(ctx context.Context, ID int64) error {
+	err := c.wait(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Client.DeleteSnippet(ctx, ID)
+}
+
+func (c *deleteRateLimitedClient) DeleteEnvironmentVariable(ctx context.Context, key string) error {
+	err := c.wait(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Client.DeleteEnvironmentVariable(ctx, key)
+}
+
+func (c *deleteRateLimitedClient) DeleteDashboard(ctx context.Context, ID string) error {
+	err := c.wait(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Client.DeleteDashboard(ctx, ID)
+}
+
+func (c *deleteRateLimitedClient) DeletePrivateLocation(ctx context.Context, ID string) error {
+	err := c.wait(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Client.DeletePrivateLocation(ctx, ID)
+}
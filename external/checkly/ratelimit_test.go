@@ -0,0 +1,81 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/checkly/checkly-go-sdk"
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+func TestNewDeleteRateLimitedClient(t *testing.T) {
+	var deletes, gets atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodDelete:
+			deletes.Add(1)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			gets.Add(1)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"id": 3}`))
+		}
+	}))
+	defer server.Close()
+
+	client := checkly.NewClient(server.URL, "foobarbaz", nil, nil)
+	client.SetAccountId("1234567890")
+
+	if NewDeleteRateLimitedClient(client, 0) != client {
+		t.Error("Expected the client to be returned unchanged without a limit")
+	}
+
+	limited := NewDeleteRateLimitedClient(client, 10)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		err := DeleteAlertChannel(&checklyv1alpha1.AlertChannel{Status: checklyv1alpha1.AlertChannelStatus{ID: 3}}, limited)
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Expected the deletes to be throttled to 10 per second, took %s", elapsed)
+	}
+
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := limited.GetAlertChannel(context.Background(), 3)
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected reads not to be throttled, took %s", elapsed)
+	}
+
+	if deletes.Load() != 3 || gets.Load() != 3 {
+		t.Errorf("Expected 3 deletes and 3 reads, got %d and %d", deletes.Load(), gets.Load())
+	}
+}
@@ -0,0 +1,434 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/checkly/checkly-go-sdk"
+)
+
+// apiRetryBaseDelay is the delay of the first retry of a transient API error, it doubles with every further retry
+const apiRetryBaseDelay = 100 * time.Millisecond
+
+// responseStatus matches the status of the errors the SDK returns for unexpected responses
+var responseStatus = regexp.MustCompile(`unexpected response status:? (\d+)`)
+
+// RateLimitError is returned for calls rejected by the checklyhq.com rate limit which tell when to come back
+type RateLimitError struct {
+	// RetryAfter holds the delay the API asked for in the Retry-After header
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter returns the delay the checklyhq.com API asked for, if the call failed because of the rate limit
+func RetryAfter(err error) (time.Duration, bool) {
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return rateLimitErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+type retryAfterKey struct{}
+
+// RetryAfterTransport returns a RoundTripper which records the Retry-After header of rate limited responses, so the
+// retrying client can return a RateLimitError, the SDK only passes on the status and body of failed calls
+func RetryAfterTransport(next http.RoundTripper) http.RoundTripper {
+	return &retryAfterTransport{next: next, now: time.Now}
+}
+
+type retryAfterTransport struct {
+	next http.RoundTripper
+	now  func() time.Time
+}
+
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		return resp, err
+	}
+
+	retryAfter, ok := req.Context().Value(retryAfterKey{}).(*time.Duration)
+	if ok {
+		*retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), t.now())
+	}
+	return resp, err
+}
+
+// parseRetryAfter returns the delay of a Retry-After header, which holds either seconds or an HTTP date, it's 0 if
+// the header is missing or invalid
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
+
+// retryingClient retries the calls of the wrapped client which fail with a transient error, rate limited calls with
+// a Retry-After header fail right away with a RateLimitError instead, all other calls pass through
+type retryingClient struct {
+	checkly.Client
+	maxRetries int
+	baseDelay  time.Duration
+}
+
+// NewRetryingClient wraps the client to retry create, read, update and delete calls up to maxRetries times with an
+// exponential back-off and jitter, if they fail with a rate limit, a server error or a timeout. Creates are only
+// retried if the API rejected them with 429 or 503, a create which timed out or failed with another server error may
+// have succeeded and retrying it would create a duplicate. Client errors, ex. failed validations, are never retried,
+// neither are rate limited calls telling when to come back, they return a RateLimitError. A maxRetries of 0 or less
+// disables the retries. The deadline of the context bounds all attempts together, an attempt which would be retried
+// after timing out gets half of the time left, so there's time for the retries. Cancelling the context or reaching its
+// deadline stops the retries. The Retry-After header is only known if the HTTP client of the SDK uses the RetryAfterTransport.
+func NewRetryingClient(client checkly.Client, maxRetries int) checkly.Client {
+	return &retryingClient{
+		Client:     client,
+		maxRetries: maxRetries,
+		baseDelay:  apiRetryBaseDelay,
+	}
+}
+
+// transient determines if the error returned by the SDK is worth retrying, creates only if the API didn't process them
+func transient(err error, create bool) bool {
+	message := err.Error()
+	if match := responseStatus.FindStringSubmatch(message); match != nil {
+		status, _ := strconv.Atoi(match[1])
+		if create {
+			return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+		}
+		return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+	}
+
+	// The SDK doesn't wrap errors of the HTTP client, timeouts are told apart by their message
+	timeout := strings.Contains(message, "timeout") || strings.Contains(message, context.DeadlineExceeded.Error())
+	return !create && strings.HasPrefix(message, "HTTP request failed with") && timeout
+}
+
+func (c *retryingClient) retry(ctx context.Context, create bool, call func(ctx context.Context) error) error {
+	var retryAfter time.Duration
+	ctx = context.WithValue(ctx, retryAfterKey{}, &retryAfter)
+
+	delay := c.baseDelay
+	for attempt := 0; ; attempt++ {
+		retryAfter = 0
+		// Timed out creates aren't retried, they get all the time there is
+		err := callAttempt(ctx, !create && attempt < c.maxRetries, call)
+		if err != nil && retryAfter > 0 {
+			return &RateLimitError{RetryAfter: retryAfter, Err: err}
+		}
+		if err == nil || attempt >= c.maxRetries || !transient(err, create) {
+			return err
+		}
+
+		// Sleep between half and the full delay, so resources failing at the same time don't retry in lockstep
+		jittered := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= jittered {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(jittered):
+		}
+		delay *= 2
+	}
+}
+
+// callAttempt makes a single attempt of the call within the deadline of the caller, an attempt which is split off
+// only gets half of the time left before the deadline
+func callAttempt(ctx context.Context, split bool, call func(ctx context.Context) error) error {
+	deadline, ok := ctx.Deadline()
+	if !split || !ok {
+		return call(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Until(deadline)/2)
+	defer cancel()
+	return call(ctx)
+}
+
+func (c *retryingClient) Create(ctx context.Context, check checkly.Check) (got *checkly.Check, err error) {
+	err = c.retry(ctx, true, func(ctx context.Context) error {
+		got, err = c.Client.Create(ctx, check)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) Update(ctx context.Context, ID string, check checkly.Check) (got *checkly.Check, err error) {
+	err = c.retry(ctx, false, func(ctx context.Context) error {
+		got, err = c.Client.Update(ctx, ID, check)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) GetCheck(ctx context.Context, ID string) (got *checkly.Check, err error) {
+	err = c.retry(ctx, false, func(ctx context.Context) error {
+		got, err = c.Client.GetCheck(ctx, ID)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) UpdateCheck(ctx context.Context, ID string, check checkly.Check) (got *checkly.Check, err error) {
+	err = c.retry(ctx, false, func(ctx context.Context) error {
+		got, err = c.Client.UpdateCheck(ctx, ID, check)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) Delete(ctx context.Context, ID string) error {
+	return c.retry(ctx, false, func(ctx context.Context) error {
+		return c.Client.Delete(ctx, ID)
+	})
+}
+
+func (c *retryingClient) DeleteCheck(ctx context.Context, ID string) error {
+	return c.retry(ctx, false, func(ctx context.Context) error {
+		return c.Client.DeleteCheck(ctx, ID)
+	})
+}
+
+func (c *retryingClient) CreateHeartbeat(ctx context.Context, check checkly.HeartbeatCheck) (got *checkly.HeartbeatCheck, err error) {
+	err = c.retry(ctx, true, func(ctx context.Context) error {
+		got, err = c.Client.CreateHeartbeat(ctx, check)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) GetHeartbeatCheck(ctx context.Context, ID string) (got *checkly.HeartbeatCheck, err error) {
+	err = c.retry(ctx, false, func(ctx context.Context) error {
+		got, err = c.Client.GetHeartbeatCheck(ctx, ID)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) UpdateHeartbeat(ctx context.Context, ID string, check checkly.HeartbeatCheck) (got *checkly.HeartbeatCheck, err error) {
+	err = c.retry(ctx, false, func(ctx context.Context) error {
+		got, err = c.Client.UpdateHeartbeat(ctx, ID, check)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) CreateMaintenanceWindow(ctx context.Context, mw checkly.MaintenanceWindow) (got *checkly.MaintenanceWindow, err error) {
+	err = c.retry(ctx, true, func(ctx context.Context) error {
+		got, err = c.Client.CreateMaintenanceWindow(ctx, mw)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) UpdateMaintenanceWindow(ctx context.Context, ID int64, mw checkly.MaintenanceWindow) (got *checkly.MaintenanceWindow, err error) {
+	err = c.retry(ctx, false, func(ctx context.Context) error {
+		got, err = c.Client.UpdateMaintenanceWindow(ctx, ID, mw)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) DeleteMaintenanceWindow(ctx context.Context, ID int64) error {
+	return c.retry(ctx, false, func(ctx context.Context) error {
+		return c.Client.DeleteMaintenanceWindow(ctx, ID)
+	})
+}
+
+func (c *retryingClient) CreateSnippet(ctx context.Context, snippet checkly.Snippet) (got *checkly.Snippet, err error) {
+	err = c.retry(ctx, true, func(ctx context.Context) error {
+		got, err = c.Client.CreateSnippet(ctx, snippet)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) GetSnippet(ctx context.Context, ID int64) (got *checkly.Snippet, err error) {
+	err = c.retry(ctx, false, func(ctx context.Context) error {
+		got, err = c.Client.GetSnippet(ctx, ID)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) UpdateSnippet(ctx context.Context, ID int64, snippet checkly.Snippet) (got *checkly.Snippet, err error) {
+	err = c.retry(ctx, false, func(ctx context.Context) error {
+		got, err = c.Client.UpdateSnippet(ctx, ID, snippet)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) DeleteSnippet(ctx context.Context, ID int64) error {
+	return c.retry(ctx, false, func(ctx context.Context) error {
+		return c.Client.DeleteSnippet(ctx, ID)
+	})
+}
+
+func (c *retryingClient) CreateDashboard(ctx context.Context, dashboard checkly.Dashboard) (got *checkly.Dashboard, err error) {
+	err = c.retry(ctx, true, func(ctx context.Context) error {
+		got, err = c.Client.CreateDashboard(ctx, dashboard)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) UpdateDashboard(ctx context.Context, ID string, dashboard checkly.Dashboard) (got *checkly.Dashboard, err error) {
+	err = c.retry(ctx, false, func(ctx context.Context) error {
+		got, err = c.Client.UpdateDashboard(ctx, ID, dashboard)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) DeleteDashboard(ctx context.Context, ID string) error {
+	return c.retry(ctx, false, func(ctx context.Context) error {
+		return c.Client.DeleteDashboard(ctx, ID)
+	})
+}
+
+func (c *retryingClient) CreatePrivateLocation(ctx context.Context, pl checkly.PrivateLocation) (got *checkly.PrivateLocation, err error) {
+	err = c.retry(ctx, true, func(ctx context.Context) error {
+		got, err = c.Client.CreatePrivateLocation(ctx, pl)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) GetPrivateLocation(ctx context.Context, ID string) (got *checkly.PrivateLocation, err error) {
+	err = c.retry(ctx, false, func(ctx context.Context) error {
+		got, err = c.Client.GetPrivateLocation(ctx, ID)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) UpdatePrivateLocation(ctx context.Context, ID string, pl checkly.PrivateLocation) (got *checkly.PrivateLocation, err error) {
+	err = c.retry(ctx, false, func(ctx context.Context) error {
+		got, err = c.Client.UpdatePrivateLocation(ctx, ID, pl)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) DeletePrivateLocation(ctx context.Context, ID string) error {
+	return c.retry(ctx, false, func(ctx context.Context) error {
+		return c.Client.DeletePrivateLocation(ctx, ID)
+	})
+}
+
+func (c *retryingClient) CreateEnvironmentVariable(ctx context.Context, ev checkly.EnvironmentVariable) (got *checkly.EnvironmentVariable, err error) {
+	err = c.retry(ctx, true, func(ctx context.Context) error {
+		got, err = c.Client.CreateEnvironmentVariable(ctx, ev)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) UpdateEnvironmentVariable(ctx context.Context, key string, ev checkly.EnvironmentVariable) (got *checkly.EnvironmentVariable, err error) {
+	err = c.retry(ctx, false, func(ctx context.Context) error {
+		got, err = c.Client.UpdateEnvironmentVariable(ctx, key, ev)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) DeleteEnvironmentVariable(ctx context.Context, key string) error {
+	return c.retry(ctx, false, func(ctx context.Context) error {
+		return c.Client.DeleteEnvironmentVariable(ctx, key)
+	})
+}
+
+func (c *retryingClient) CreateGroup(ctx context.Context, group checkly.Group) (got *checkly.Group, err error) {
+	err = c.retry(ctx, true, func(ctx context.Context) error {
+		got, err = c.Client.CreateGroup(ctx, group)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) GetGroup(ctx context.Context, ID int64) (got *checkly.Group, err error) {
+	err = c.retry(ctx, false, func(ctx context.Context) error {
+		got, err = c.Client.GetGroup(ctx, ID)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) UpdateGroup(ctx context.Context, ID int64, group checkly.Group) (got *checkly.Group, err error) {
+	err = c.retry(ctx, false, func(ctx context.Context) error {
+		got, err = c.Client.UpdateGroup(ctx, ID, group)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) DeleteGroup(ctx context.Context, ID int64) error {
+	return c.retry(ctx, false, func(ctx context.Context) error {
+		return c.Client.DeleteGroup(ctx, ID)
+	})
+}
+
+func (c *retryingClient) CreateAlertChannel(ctx context.Context, ac checkly.AlertChannel) (got *checkly.AlertChannel, err error) {
+	err = c.retry(ctx, true, func(ctx context.Context) error {
+		got, err = c.Client.CreateAlertChannel(ctx, ac)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) GetAlertChannel(ctx context.Context, ID int64) (got *checkly.AlertChannel, err error) {
+	err = c.retry(ctx, false, func(ctx context.Context) error {
+		got, err = c.Client.GetAlertChannel(ctx, ID)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) UpdateAlertChannel(ctx context.Context, ID int64, ac checkly.AlertChannel) (got *checkly.AlertChannel, err error) {
+	err = c.retry(ctx, false, func(ctx context.Context) error {
+		got, err = c.Client.UpdateAlertChannel(ctx, ID, ac)
+		return err
+	})
+	return
+}
+
+func (c *retryingClient) DeleteAlertChannel(ctx context.Context, ID int64) error {
+	return c.retry(ctx, false, func(ctx context.Context) error {
+		return c.Client.DeleteAlertChannel(ctx, ID)
+	})
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/checkly/checkly-go-sdk"
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+func TestTransient(t *testing.T) {
+	errs := map[string]bool{
+		`unexpected response status 429: "too many requests"`:                                              true,
+		`unexpected response status 503: "unavailable"`:                                                    true,
+		`unexpected response status 400: "validation failed"`:                                              false,
+		`unexpected response status 404: "not found"`:                                                      false,
+		`HTTP request failed with: Post "https://api.checklyhq.com/v1/checks": i/o timeout`:                true,
+		`HTTP request failed with: Post "https://api.checklyhq.com/v1/checks": no such host`:               false,
+		`HTTP request failed with: Get "https://api.checklyhq.com/v1/checks/1": context deadline exceeded`: true,
+		`HTTP request failed with: Get "https://api.checklyhq.com/v1/checks/1": context canceled`:          false,
+		`HTTP request failed with: checklyhq.com API is unavailable, circuit breaker is open`:              false,
+		`decoding error for data {}: unexpected end of JSON input`:                                         false,
+	}
+	for err, expected := range errs {
+		if got := transient(errors.New(err), false); got != expected {
+			t.Errorf("Expected transient to be %t for %s, got %t", expected, err, got)
+		}
+	}
+
+	// A create which may have been processed would be duplicated by a retry
+	createErrs := map[string]bool{
+		`unexpected response status: 429, res: "too many requests"`:                          true,
+		`unexpected response status: 503, res: "unavailable"`:                                true,
+		`unexpected response status 500: "internal server error"`:                            false,
+		`unexpected response status: 502, res: "bad gateway"`:                                false,
+		`HTTP request failed with: Post "https://api.checklyhq.com/v1/checks": i/o timeout`:  false,
+		`HTTP request failed with: Post "https://api.checklyhq.com/v1/checks": no such host`: false,
+	}
+	for err, expected := range createErrs {
+		if got := transient(errors.New(err), true); got != expected {
+			t.Errorf("Expected transient to be %t for the create failing with %s, got %t", expected, err, got)
+		}
+	}
+}
+
+func TestNewRetryingClient(t *testing.T) {
+	var calls atomic.Int32
+	var failures int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.URL.Path == "/v1/alert-channels/4" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := checkly.NewClient(server.URL, "foobarbaz", nil, nil)
+	client.SetAccountId("1234567890")
+
+	retrying := &retryingClient{Client: client, maxRetries: 3, baseDelay: time.Millisecond}
+	ac := &checklyv1alpha1.AlertChannel{Status: checklyv1alpha1.AlertChannelStatus{ID: 3}}
+
+	failures = 2
+	err := DeleteAlertChannel(ac, retrying)
+	if err != nil || calls.Load() != 3 {
+		t.Errorf("Expected the call to succeed on the third attempt, got %v after %d calls", err, calls.Load())
+	}
+
+	calls.Store(0)
+	failures = 10
+	err = DeleteAlertChannel(ac, retrying)
+	if err == nil || calls.Load() != 4 {
+		t.Errorf("Expected the call to fail after 3 retries, got %v after %d calls", err, calls.Load())
+	}
+
+	calls.Store(0)
+	failures = 10
+	err = DeleteAlertChannel(ac, NewRetryingClient(client, 0))
+	if err == nil || calls.Load() != 1 {
+		t.Errorf("Expected no retries, got %v after %d calls", err, calls.Load())
+	}
+
+	// Client errors are never retried
+	calls.Store(0)
+	failures = 0
+	err = DeleteAlertChannel(&checklyv1alpha1.AlertChannel{Status: checklyv1alpha1.AlertChannelStatus{ID: 4}}, retrying)
+	if err == nil || calls.Load() != 1 {
+		t.Errorf("Expected the client error to be returned right away, got %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestRetryingClientTimeout(t *testing.T) {
+	var calls atomic.Int32
+	var hang atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 || hang.Load() {
+			// The first attempt times out
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := checkly.NewClient(server.URL, "foobarbaz", server.Client(), nil)
+	retrying := &retryingClient{Client: client, maxRetries: 3, baseDelay: time.Millisecond}
+
+	// The attempt which timed out only took half of the time of the caller, the retry has the other half
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := retrying.DeleteAlertChannel(ctx, 3)
+	if err != nil || calls.Load() != 2 {
+		t.Errorf("Expected the call to succeed on the second attempt, got %v after %d calls", err, calls.Load())
+	}
+
+	// The retries never outlast the deadline of the caller
+	calls.Store(0)
+	hang.Store(true)
+	start := time.Now()
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = retrying.DeleteAlertChannel(ctx, 3)
+	if err == nil || calls.Load() < 2 || time.Since(start) > 150*time.Millisecond {
+		t.Errorf("Expected the retries to stop at the deadline, got %v after %d calls in %s", err, calls.Load(), time.Since(start))
+	}
+	hang.Store(false)
+
+	// Cancelling the context stops the retries
+	calls.Store(0)
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	err = retrying.DeleteAlertChannel(ctx, 3)
+	if err == nil || calls.Load() != 1 {
+		t.Errorf("Expected the cancelled call not to be retried, got %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestRetryingClientCreate(t *testing.T) {
+	var calls atomic.Int32
+	var status int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(status)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 3, "type": "EMAIL", "config": {"address": "foo@bar.baz"}}`))
+	}))
+	defer server.Close()
+
+	client := checkly.NewClient(server.URL, "foobarbaz", server.Client(), nil)
+	retrying := &retryingClient{Client: client, maxRetries: 3, baseDelay: time.Millisecond}
+	ac := checkly.AlertChannel{Type: checkly.AlertTypeEmail, Email: &checkly.AlertChannelEmail{Address: "foo@bar.baz"}}
+
+	// The API didn't process the create
+	status = http.StatusServiceUnavailable
+	_, err := retrying.CreateAlertChannel(context.Background(), ac)
+	if err != nil || calls.Load() != 2 {
+		t.Errorf("Expected the create to be retried, got %v after %d calls", err, calls.Load())
+	}
+
+	// The create may have succeeded, retrying it could create a duplicate
+	calls.Store(0)
+	status = http.StatusBadGateway
+	_, err = retrying.CreateAlertChannel(context.Background(), ac)
+	if err == nil || calls.Load() != 1 {
+		t.Errorf("Expected the create not to be retried, got %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/v1/alert-channels/3" {
+			w.Header().Set("Retry-After", "30")
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	httpClient := server.Client()
+	httpClient.Transport = RetryAfterTransport(httpClient.Transport)
+	client := NewRetryingClient(checkly.NewClient(server.URL, "foobarbaz", httpClient, nil), 3)
+	client.(*retryingClient).baseDelay = time.Millisecond
+
+	// Calls telling when to come back aren't retried
+	err := DeleteAlertChannel(&checklyv1alpha1.AlertChannel{Status: checklyv1alpha1.AlertChannelStatus{ID: 3}}, client)
+	retryAfter, ok := RetryAfter(err)
+	if !ok || retryAfter != 30*time.Second || calls.Load() != 1 {
+		t.Errorf("Expected a rate limit error asking to retry after 30s, got %v (%s) after %d calls", err, retryAfter, calls.Load())
+	}
+
+	calls.Store(0)
+	err = DeleteAlertChannel(&checklyv1alpha1.AlertChannel{Status: checklyv1alpha1.AlertChannelStatus{ID: 4}}, client)
+	if _, ok = RetryAfter(err); ok || err == nil || calls.Load() != 4 {
+		t.Errorf("Expected the rate limit without Retry-After to be retried, got %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	values := map[string]time.Duration{
+		"30":                            30 * time.Second,
+		"Mon, 01 Jan 2024 12:01:00 GMT": time.Minute,
+		"Mon, 01 Jan 2024 11:00:00 GMT": 0,
+		"-5":                            0,
+		"":                              0,
+		"soon":                          0,
+	}
+	for value, expected := range values {
+		if got := parseRetryAfter(value, now); got != expected {
+			t.Errorf("Expected %s for %q, got %s", expected, value, got)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/checkly/checkly-go-sdk"
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+var testAlertClient = &http.Client{Timeout: time.Second * 10}
+
+// defaultTestAlertTemplate is the body of test alerts sent to webhooks without a template
+const defaultTestAlertTemplate = `{"title": "{{ALERT_TITLE}}", "type": "{{ALERT_TYPE}}", "check": "{{CHECK_NAME}}", "link": "{{RESULT_LINK}}"}`
+
+// ErrTestAlertUnsupported is returned for alert channel types the operator can't send a test alert to
+var ErrTestAlertUnsupported = errors.New("test alerts are only supported for webhook alert channels")
+
+// SendTestAlert sends a sample alert to the webhook of the alert channel the way checklyhq.com would, with its method,
+// headers, query parameters and the template rendered against a sample alert. The checklyhq.com API has no endpoint
+// triggering test alerts, so the request is made by the operator. Responses outside of 2xx are returned as errors.
+func SendTestAlert(ctx context.Context, alertChannel *checklyv1alpha1.AlertChannel) (err error) {
+	ac, err := checklyAlertChannel(alertChannel, checkly.AlertChannelOpsgenie{})
+	if err != nil {
+		return
+	}
+	if ac.Webhook == nil {
+		return ErrTestAlertUnsupported
+	}
+
+	template := checkValueString(ac.Webhook.Template, defaultTestAlertTemplate)
+	body, err := renderWebhookTemplate(template)
+	if err != nil {
+		return
+	}
+
+	webhookURL, err := url.Parse(ac.Webhook.URL)
+	if err != nil {
+		return
+	}
+	query := webhookURL.Query()
+	for _, parameter := range ac.Webhook.QueryParameters {
+		query.Add(parameter.Key, parameter.Value)
+	}
+	webhookURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, ac.Webhook.Method, webhookURL.String(), strings.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, header := range ac.Webhook.Headers {
+		req.Header.Add(header.Key, header.Value)
+	}
+
+	resp, err := testAlertClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded to the test alert with %s", resp.Status)
+	}
+	return nil
+}
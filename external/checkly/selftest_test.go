@@ -0,0 +1,88 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSendTestAlert(t *testing.T) {
+	var gotRequest *http.Request
+	var gotBody string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotRequest, gotBody = r, string(body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	ac := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		Spec: checklyv1alpha1.AlertChannelSpec{
+			Webhook: checklyv1alpha1.AlertChannelWebhook{
+				URL:             server.URL + "/alerts",
+				Method:          http.MethodPut,
+				Template:        `{"check": "{{CHECK_NAME}}"}`,
+				Headers:         []checklyv1alpha1.AlertChannelKeyValue{{Key: "X-Team", Value: "sre"}},
+				QueryParameters: []checklyv1alpha1.AlertChannelKeyValue{{Key: "source", Value: "checkly"}},
+			},
+		},
+	}
+
+	err := SendTestAlert(context.Background(), ac)
+	if err != nil {
+		t.Fatalf("Expected no error, got %e", err)
+	}
+	if gotRequest.Method != http.MethodPut || gotRequest.URL.Path != "/alerts" || gotRequest.URL.Query().Get("source") != "checkly" {
+		t.Errorf("Expected the method, path and query parameters of the webhook, got %s %s", gotRequest.Method, gotRequest.URL)
+	}
+	if gotRequest.Header.Get("X-Team") != "sre" {
+		t.Errorf("Expected the headers of the webhook, got %v", gotRequest.Header)
+	}
+	if gotBody != `{"check": "Sample check"}` {
+		t.Errorf("Expected the template rendered against the sample alert, got %s", gotBody)
+	}
+
+	ac.Spec.Webhook.Template = ""
+	err = SendTestAlert(context.Background(), ac)
+	if err != nil || !strings.Contains(gotBody, "Sample check has failed") {
+		t.Errorf("Expected the default test alert, got %s, %v", gotBody, err)
+	}
+
+	status = http.StatusBadGateway
+	err = SendTestAlert(context.Background(), ac)
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("Expected error for a failed delivery, got %v", err)
+	}
+
+	email := &checklyv1alpha1.AlertChannel{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	email.Spec.Email.Address = "foo@bar.baz"
+	err = SendTestAlert(context.Background(), email)
+	if !errors.Is(err, ErrTestAlertUnsupported) {
+		t.Errorf("Expected ErrTestAlertUnsupported, got %v", err)
+	}
+}
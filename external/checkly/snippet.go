@@ -0,0 +1,115 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/checkly/checkly-go-sdk"
+)
+
+// Snippet is a struct for the internal packages to help put together the checkly snippet
+type Snippet struct {
+	Name   string
+	ID     int64
+	Script string
+}
+
+func checklySnippet(snippet Snippet) (s checkly.Snippet, err error) {
+	if strings.TrimSpace(snippet.Script) == "" {
+		err = fmt.Errorf("snippet %s has an empty script", snippet.Name)
+		return
+	}
+
+	s = checkly.Snippet{
+		Name:   snippet.Name,
+		Script: snippet.Script,
+	}
+
+	return
+}
+
+// CreateSnippet creates a new checklyhq.com snippet
+func CreateSnippet(snippet Snippet, client checkly.Client) (ID int64, err error) {
+	s, err := checklySnippet(snippet)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	gotSnippet, err := client.CreateSnippet(ctx, s)
+	if err != nil {
+		return
+	}
+
+	return gotSnippet.ID, nil
+}
+
+// UpdateSnippet updates an existing checklyhq.com snippet
+func UpdateSnippet(snippet Snippet, client checkly.Client) (err error) {
+	s, err := checklySnippet(snippet)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	_, err = client.UpdateSnippet(ctx, snippet.ID, s)
+
+	return
+}
+
+// DeleteSnippet deletes an existing checklyhq.com snippet
+func DeleteSnippet(ID int64, client checkly.Client) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	err = client.DeleteSnippet(ctx, ID)
+
+	return
+}
+
+// SnippetDrift reads the snippet from checklyhq.com and returns the attributes which differ from the desired state
+func SnippetDrift(snippet Snippet, client checkly.Client) (fields []string, err error) {
+	want, err := checklySnippet(snippet)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	got, err := client.GetSnippet(ctx, snippet.ID)
+	if err != nil {
+		return
+	}
+
+	if want.Name != got.Name {
+		fields = append(fields, "name")
+	}
+	if want.Script != got.Script {
+		fields = append(fields, "script")
+	}
+
+	return
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/checkly/checkly-go-sdk"
+)
+
+func TestSnippetActions(t *testing.T) {
+	var deleted bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		response := checkly.Snippet{ID: 4, Name: "login", Script: "await login()"}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/snippets":
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && r.URL.Path == "/v1/snippets/4":
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/snippets/4":
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/snippets/4":
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+	client := checkly.NewClient(server.URL, "key", server.Client(), nil)
+
+	snippet := Snippet{Name: "login", Script: "await login()"}
+	ID, err := CreateSnippet(snippet, client)
+	if err != nil || ID != 4 {
+		t.Errorf("Expected the ID, got %d, %v", ID, err)
+	}
+
+	snippet.ID = ID
+	drift, err := SnippetDrift(snippet, client)
+	if err != nil || len(drift) != 0 {
+		t.Errorf("Expected no drift, got %v, %v", drift, err)
+	}
+
+	snippet.Script = "await logout()"
+	drift, _ = SnippetDrift(snippet, client)
+	if len(drift) != 1 || drift[0] != "script" {
+		t.Errorf("Expected the script to drift, got %v", drift)
+	}
+
+	if err = UpdateSnippet(snippet, client); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	// Empty scripts never reach the API
+	snippet.Script = " "
+	if _, err = CreateSnippet(snippet, client); err == nil {
+		t.Error("Expected an error for the empty script")
+	}
+
+	err = DeleteSnippet(ID, client)
+	if err != nil || !deleted {
+		t.Errorf("Expected the snippet to be deleted, got %v", err)
+	}
+}
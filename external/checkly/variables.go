@@ -0,0 +1,151 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/checkly/checkly-go-sdk"
+)
+
+// variableKey matches the keys checklyhq.com accepts for environment variables
+var variableKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Variable is a struct for the internal packages to help put together the checkly environment variable, the value is
+// resolved from its secret already
+type Variable struct {
+	Key    string
+	Value  string
+	Locked bool
+}
+
+// ValidateVariables makes sure the keys of the variables are valid and unique and every variable has a value
+func ValidateVariables(variables []Variable) error {
+	keys := map[string]bool{}
+	for _, variable := range variables {
+		if !variableKey.MatchString(variable.Key) {
+			return fmt.Errorf("variable key %q has to start with a letter or underscore and only hold letters, digits and underscores", variable.Key)
+		}
+		if keys[variable.Key] {
+			return fmt.Errorf("variable key %q is set more than once", variable.Key)
+		}
+		keys[variable.Key] = true
+
+		if variable.Value == "" {
+			return fmt.Errorf("variable %s has no value", variable.Key)
+		}
+	}
+	return nil
+}
+
+// VariablesHash returns a hash of the variables, it changes when the value of a variable changes, ex. because its
+// secret was rotated. It's an HMAC keyed with a key held by the operator, readers of the status can't guess the values
+// by hashing candidates.
+func VariablesHash(key []byte, variables []Variable) (string, error) {
+	payload, err := json.Marshal(variables)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// notFound determines if the SDK error is the checklyhq.com API reporting the resource doesn't exist
+func notFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	match := responseStatus.FindStringSubmatch(err.Error())
+	if match == nil {
+		return false
+	}
+	status, _ := strconv.Atoi(match[1])
+	return status == http.StatusNotFound
+}
+
+// SetVariable updates the checklyhq.com environment variable, or creates it if it doesn't exist
+func SetVariable(variable Variable, client checkly.Client) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	ev := checkly.EnvironmentVariable{
+		Key:    variable.Key,
+		Value:  variable.Value,
+		Locked: variable.Locked,
+	}
+
+	_, err = client.UpdateEnvironmentVariable(ctx, variable.Key, ev)
+	if notFound(err) {
+		_, err = client.CreateEnvironmentVariable(ctx, ev)
+	}
+
+	return
+}
+
+// VariablesDrift reads the variables from checklyhq.com and returns the keys of those which differ from the desired
+// state or are missing. The values of locked variables are masked by the API, only their locked flag is compared.
+func VariablesDrift(variables []Variable, client checkly.Client) (keys []string, err error) {
+	for _, variable := range variables {
+		got, err := getVariable(variable.Key, client)
+		if notFound(err) {
+			keys = append(keys, variable.Key)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if got.Locked != variable.Locked || (!variable.Locked && got.Value != variable.Value) {
+			keys = append(keys, variable.Key)
+		}
+	}
+
+	return
+}
+
+// getVariable reads the checklyhq.com environment variable
+func getVariable(key string, client checkly.Client) (*checkly.EnvironmentVariable, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	return client.GetEnvironmentVariable(ctx, key)
+}
+
+// DeleteVariable deletes the checklyhq.com environment variable, a variable which doesn't exist anymore is considered
+// deleted
+func DeleteVariable(key string, client checkly.Client) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	err = client.DeleteEnvironmentVariable(ctx, key)
+	if notFound(err) {
+		return nil
+	}
+
+	return
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/checkly/checkly-go-sdk"
+)
+
+func TestValidateVariables(t *testing.T) {
+	err := ValidateVariables([]Variable{{Key: "BASE_URL", Value: "https://foo.bar"}, {Key: "_TOKEN", Value: "t", Locked: true}})
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	for _, invalid := range [][]Variable{
+		{{Key: "1ST", Value: "a"}},
+		{{Key: "BASE-URL", Value: "a"}},
+		{{Key: "TOKEN", Value: ""}},
+		{{Key: "TOKEN", Value: "a"}, {Key: "TOKEN", Value: "b"}},
+	} {
+		if ValidateVariables(invalid) == nil {
+			t.Errorf("Expected an error for %+v", invalid)
+		}
+	}
+}
+
+func TestVariablesHash(t *testing.T) {
+	hash, _ := VariablesHash([]byte("key"), []Variable{{Key: "TOKEN", Value: "old"}})
+	rotated, _ := VariablesHash([]byte("key"), []Variable{{Key: "TOKEN", Value: "new"}})
+	if hash == rotated {
+		t.Error("Expected the hash to change with the value")
+	}
+
+	// Without the key the values can't be guessed from the hash
+	unkeyed, _ := VariablesHash([]byte("other"), []Variable{{Key: "TOKEN", Value: "old"}})
+	if hash == unkeyed {
+		t.Error("Expected the hash to depend on the key")
+	}
+}
+
+func TestVariableActions(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/v1/variables/NEW":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/variables":
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/variables/GONE":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`{"key": "TOKEN", "value": "t"}`))
+	}))
+	defer server.Close()
+	client := checkly.NewClient(server.URL, "key", server.Client(), nil)
+
+	err := SetVariable(Variable{Key: "TOKEN", Value: "t"}, client)
+	if err != nil || len(calls) != 1 {
+		t.Errorf("Expected the variable to be updated, got %v, %v", calls, err)
+	}
+
+	// Missing variables are created
+	calls = nil
+	err = SetVariable(Variable{Key: "NEW", Value: "t"}, client)
+	if err != nil || len(calls) != 2 || calls[1] != "POST /v1/variables" {
+		t.Errorf("Expected the variable to be created, got %v, %v", calls, err)
+	}
+
+	if err = DeleteVariable("TOKEN", client); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err = DeleteVariable("GONE", client); err != nil {
+		t.Errorf("Expected a missing variable to be considered deleted, got %v", err)
+	}
+}
+
+func TestVariablesDrift(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/variables/HOST":
+			w.Write([]byte(`{"key": "HOST", "value": "edited.example.com", "locked": false}`))
+		case "/v1/variables/TOKEN":
+			w.Write([]byte(`{"key": "TOKEN", "value": "****", "locked": true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := checkly.NewClient(server.URL, "key", server.Client(), nil)
+
+	// The masked value of the locked variable isn't compared
+	variables := []Variable{{Key: "HOST", Value: "api.example.com"}, {Key: "TOKEN", Value: "secret", Locked: true}, {Key: "REGION", Value: "eu"}}
+	drift, err := VariablesDrift(variables, client)
+	if err != nil || len(drift) != 2 || drift[0] != "HOST" || drift[1] != "REGION" {
+		t.Errorf("Expected the edited and the deleted variable to drift, got %v, %v", drift, err)
+	}
+}
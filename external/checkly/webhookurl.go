@@ -0,0 +1,118 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// WebhookURLPolicy rejects webhook URLs resolving to private, loopback, link-local or otherwise internal addresses, so
+// alert channels can't be pointed at cluster internal services or the cloud metadata endpoint. A nil policy accepts
+// every URL.
+type WebhookURLPolicy struct {
+	allowlist []*net.IPNet
+	lookupIP  func(ctx context.Context, host string) ([]net.IP, error)
+}
+
+// NewWebhookURLPolicy returns a WebhookURLPolicy allowing the comma separated CIDRs or IPs of the allowlist, ex.
+// 10.20.0.0/16,192.168.1.10
+func NewWebhookURLPolicy(allowlist string) (*WebhookURLPolicy, error) {
+	policy := &WebhookURLPolicy{
+		lookupIP: func(ctx context.Context, host string) ([]net.IP, error) {
+			return net.DefaultResolver.LookupIP(ctx, "ip", host)
+		},
+	}
+
+	for _, entry := range strings.Split(allowlist, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("webhook URL allowlist entry %q is neither an IP nor a CIDR", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			policy.allowlist = append(policy.allowlist, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("webhook URL allowlist entry %q is neither an IP nor a CIDR", entry)
+		}
+		policy.allowlist = append(policy.allowlist, network)
+	}
+
+	return policy, nil
+}
+
+// Validate resolves the host of the webhook URL and rejects it if any of its addresses is internal and not allowlisted.
+// Every address is checked, a host resolving to a public and an internal address is rejected.
+func (p *WebhookURLPolicy) Validate(ctx context.Context, rawURL string) error {
+	if p == nil {
+		return nil
+	}
+
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return fmt.Errorf("webhook URL %q is invalid: %w", rawURL, err)
+	}
+	host := parsed.Hostname()
+	if host == "" {
+		return fmt.Errorf("webhook URL %q has no host", rawURL)
+	}
+
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		ips, err = p.lookupIP(ctx, host)
+		if err != nil {
+			return fmt.Errorf("webhook URL host %s can't be resolved: %w", host, err)
+		}
+	}
+
+	for _, ip := range ips {
+		if internalIP(ip) && !p.allowed(ip) {
+			return fmt.Errorf("webhook URL host %s resolves to the internal address %s, which isn't allowlisted", host, ip)
+		}
+	}
+	return nil
+}
+
+// allowed determines if the address is part of the allowlist
+func (p *WebhookURLPolicy) allowed(ip net.IP) bool {
+	for _, network := range p.allowlist {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// internalIP determines if the address isn't reachable from the internet, ex. 10.0.0.1 or 169.254.169.254
+func internalIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified()
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestWebhookURLPolicy(t *testing.T) {
+	policy, err := NewWebhookURLPolicy("10.20.0.0/16, 192.168.1.10")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	policy.lookupIP = func(ctx context.Context, host string) ([]net.IP, error) {
+		switch host {
+		case "hooks.foo.bar":
+			return []net.IP{net.ParseIP("203.0.113.10")}, nil
+		case "metadata.foo.bar":
+			return []net.IP{net.ParseIP("203.0.113.10"), net.ParseIP("169.254.169.254")}, nil
+		case "internal.foo.bar":
+			return []net.IP{net.ParseIP("10.20.1.5")}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	for _, allowed := range []string{
+		"https://hooks.foo.bar/alert",
+		"https://internal.foo.bar/alert",
+		"http://192.168.1.10:8080/alert",
+		"https://203.0.113.10/alert",
+	} {
+		if err := policy.Validate(context.TODO(), allowed); err != nil {
+			t.Errorf("Expected %s to be allowed, got %v", allowed, err)
+		}
+	}
+
+	for _, rejected := range []string{
+		"http://169.254.169.254/latest/meta-data",
+		"https://metadata.foo.bar/alert",
+		"http://127.0.0.1/alert",
+		"http://[::1]/alert",
+		"http://192.168.1.11/alert",
+		"https://unknown.foo.bar/alert",
+	} {
+		if err := policy.Validate(context.TODO(), rejected); err == nil {
+			t.Errorf("Expected %s to be rejected", rejected)
+		}
+	}
+
+	// Without a policy every URL is accepted
+	var disabled *WebhookURLPolicy
+	if err := disabled.Validate(context.TODO(), "http://169.254.169.254/latest/meta-data"); err != nil {
+		t.Errorf("Expected no error without a policy, got %v", err)
+	}
+
+	_, err = NewWebhookURLPolicy("10.20.0.0/33")
+	if err == nil {
+		t.Error("Expected an error for an invalid allowlist entry")
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"io"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+const (
+	Create = "create"
+	Update = "update"
+	Delete = "delete"
+)
+
+// Record describes a single change made to a checklyhq.com resource
+type Record struct {
+	// Operation is one of Create, Update or Delete
+	Operation string
+	// Kind of the kubernetes resource the change was made for
+	Kind string
+	// Object is the kubernetes resource the change was made for
+	Object client.Object
+	// ChecklyID holds the ID of the checklyhq.com resource
+	ChecklyID interface{}
+	// Spec holds the desired state of the resource, it must not hold secret values
+	Spec interface{}
+	// Before holds what's known of the resource before an update or a delete, it's nil for creates
+	Before *State
+	// Err holds the error returned by the checklyhq.com API, if any
+	Err error
+}
+
+// State describes a checklyhq.com resource before a change, as far as the operator knows it
+type State struct {
+	// Generation of the kubernetes resource last synced to checklyhq.com, 0 if it's unknown
+	Generation int64 `json:"generation,omitempty"`
+	// AppliedHash identifies the payload last applied, for the kinds keeping track of it
+	AppliedHash string `json:"appliedHash,omitempty"`
+	// Differed holds the fields which differed in checklyhq.com from the desired state, if they were read
+	Differed []string `json:"differed,omitempty"`
+	// Values holds the values of the differing fields read from checklyhq.com, if they're known, secrets are masked
+	Values map[string]interface{} `json:"values,omitempty"`
+}
+
+// Logger writes audit records as JSON lines, a nil Logger discards them
+type Logger struct {
+	log logr.Logger
+}
+
+// New returns a Logger which writes to the supplied writer, separate from the operator logs
+func New(w io.Writer) *Logger {
+	return &Logger{
+		log: zap.New(zap.WriteTo(w), zap.JSONEncoder()).WithName("audit"),
+	}
+}
+
+// Log writes the record to the audit log
+func (l *Logger) Log(record Record) {
+	if l == nil {
+		return
+	}
+
+	result := "success"
+	if record.Err != nil {
+		result = "error"
+	}
+
+	// The last manager of the object is the best guess we have on who triggered the change
+	var manager string
+	managedFields := record.Object.GetManagedFields()
+	if len(managedFields) != 0 {
+		manager = managedFields[len(managedFields)-1].Manager
+	}
+
+	keysAndValues := []interface{}{
+		"operation", record.Operation,
+		"kind", record.Kind,
+		"name", record.Object.GetName(),
+		"namespace", record.Object.GetNamespace(),
+		"uid", record.Object.GetUID(),
+		"generation", record.Object.GetGeneration(),
+		"manager", manager,
+		"checklyID", record.ChecklyID,
+		"spec", record.Spec,
+		"result", result,
+	}
+	if record.Before != nil {
+		keysAndValues = append(keysAndValues, "before", record.Before)
+	}
+	if record.Err != nil {
+		keysAndValues = append(keysAndValues, "error", record.Err.Error())
+	}
+
+	l.log.Info("checklyhq.com resource changed", keysAndValues...)
+}
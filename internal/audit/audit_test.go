@@ -0,0 +1,74 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(buf)
+
+	group := &checklyv1alpha1.Group{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "foo",
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "kubectl"},
+				{Manager: "argocd"},
+			},
+		},
+	}
+
+	before := &State{Generation: 2, Differed: []string{"name"}, Values: map[string]interface{}{"name": "bar"}}
+	logger.Log(Record{Operation: Update, Kind: "Group", Object: group, ChecklyID: int64(1), Spec: group.Spec, Before: before, Err: errors.New("bar")})
+
+	record := make(map[string]interface{})
+	err := json.Unmarshal(buf.Bytes(), &record)
+	if err != nil {
+		t.Errorf("Expected no error, got %e", err)
+	}
+
+	expected := map[string]interface{}{
+		"operation": "update",
+		"kind":      "Group",
+		"name":      "foo",
+		"manager":   "argocd",
+		"result":    "error",
+		"error":     "bar",
+	}
+	for k, v := range expected {
+		if record[k] != v {
+			t.Errorf("Expected %s for %s, got %v", v, k, record[k])
+		}
+	}
+
+	got, _ := record["before"].(map[string]interface{})
+	if got["generation"] != float64(2) || got["values"].(map[string]interface{})["name"] != "bar" {
+		t.Errorf("Expected the state before the change, got %v", record["before"])
+	}
+
+	// A nil logger should discard records
+	var nilLogger *Logger
+	nilLogger.Log(Record{Operation: Update, Kind: "Group", Object: group})
+}
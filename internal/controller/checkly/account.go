@@ -0,0 +1,76 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/checkly/checkly-go-sdk"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	external "github.com/checkly/checkly-operator/external/checkly"
+)
+
+// Keys of the credentials secret of an account
+const (
+	credentialsAPIKey    = "apikey"
+	credentialsAccountID = "accountid"
+)
+
+// accountClient returns the checkly client of the account the credentials secret is for and its account ID. Without a
+// secret the operator client and an empty account ID are returned.
+func accountClient(ctx context.Context, c client.Reader, accounts *external.Accounts, operatorClient checkly.Client, secret corev1.SecretReference) (checkly.Client, string, error) {
+	if secret.Name == "" {
+		return operatorClient, "", nil
+	}
+	if accounts == nil {
+		return nil, "", fmt.Errorf("credentials secret %s/%s is set but per-resource credentials are disabled", secret.Namespace, secret.Name)
+	}
+	if secret.Namespace == "" {
+		return nil, "", fmt.Errorf("credentials secret %s has no namespace", secret.Name)
+	}
+
+	apiKey, err := GetSecretValue(ctx, c, corev1.ObjectReference{Namespace: secret.Namespace, Name: secret.Name, FieldPath: credentialsAPIKey})
+	if err != nil {
+		return nil, "", err
+	}
+	accountID, err := GetSecretValue(ctx, c, corev1.ObjectReference{Namespace: secret.Namespace, Name: secret.Name, FieldPath: credentialsAccountID})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return accounts.Client(apiKey, accountID), accountID, nil
+}
+
+// checkAccount refuses to sync a resource with the credentials of another account than the one it was created in, the
+// resource would otherwise be updated or deleted in the wrong account
+func checkAccount(kind string, synced bool, createdIn string, accountID string) error {
+	if !synced || createdIn == accountID {
+		return nil
+	}
+
+	return fmt.Errorf("%s was created in checklyhq.com account %s but its credentials are for account %s, restore the credentials it was created with", kind, accountName(createdIn), accountName(accountID))
+}
+
+func accountName(accountID string) string {
+	if accountID == "" {
+		return "of the operator"
+	}
+	return fmt.Sprintf("%q", accountID)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"testing"
+
+	"github.com/checkly/checkly-go-sdk"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	external "github.com/checkly/checkly-operator/external/checkly"
+)
+
+func TestAccountClient(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "acme", Namespace: "checkly"},
+			Data:       map[string][]byte{"apikey": []byte("key"), "accountid": []byte("acme")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "keyless", Namespace: "checkly"},
+			Data:       map[string][]byte{"accountid": []byte("acme")},
+		},
+	).Build()
+
+	operatorClient := checkly.NewClient("http://localhost", "operator", nil, nil)
+	accounts := external.NewAccounts("http://localhost", nil, nil)
+
+	// Without credentials the operator account is used
+	apiClient, accountID, err := accountClient(context.TODO(), c, accounts, operatorClient, corev1.SecretReference{})
+	if err != nil || apiClient != operatorClient || accountID != "" {
+		t.Errorf("Expected the operator client, got %v, %q, %v", apiClient, accountID, err)
+	}
+
+	apiClient, accountID, err = accountClient(context.TODO(), c, accounts, operatorClient, corev1.SecretReference{Namespace: "checkly", Name: "acme"})
+	if err != nil || apiClient != accounts.Client("key", "acme") || accountID != "acme" {
+		t.Errorf("Expected the client of the account, got %v, %q, %v", apiClient, accountID, err)
+	}
+
+	for _, secret := range []corev1.SecretReference{
+		{Namespace: "checkly", Name: "keyless"},
+		{Namespace: "checkly", Name: "missing"},
+		{Name: "acme"},
+	} {
+		_, _, err = accountClient(context.TODO(), c, accounts, operatorClient, secret)
+		if err == nil {
+			t.Errorf("Expected an error for the credentials %v", secret)
+		}
+	}
+
+	// Credentials are refused unless per-resource credentials are enabled
+	_, _, err = accountClient(context.TODO(), c, nil, operatorClient, corev1.SecretReference{Namespace: "checkly", Name: "acme"})
+	if err == nil {
+		t.Error("Expected an error with per-resource credentials disabled")
+	}
+}
+
+func TestCheckAccount(t *testing.T) {
+	testData := []struct {
+		synced    bool
+		createdIn string
+		accountID string
+		fails     bool
+	}{
+		{false, "", "acme", false},
+		{true, "", "", false},
+		{true, "acme", "acme", false},
+		{true, "", "acme", true},
+		{true, "acme", "", true},
+		{true, "acme", "other", true},
+	}
+
+	for _, tt := range testData {
+		err := checkAccount("Group", tt.synced, tt.createdIn, tt.accountID)
+		if (err != nil) != tt.fails {
+			t.Errorf("Expected failure %t for %+v, got %v", tt.fails, tt, err)
+		}
+	}
+}
@@ -20,19 +20,37 @@ import (
 	"context"
 	errs "errors"
 	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/checkly/checkly-go-sdk"
 	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
 	external "github.com/checkly/checkly-operator/external/checkly"
+	"github.com/checkly/checkly-operator/internal/audit"
+	"github.com/checkly/checkly-operator/internal/mapping"
+	"github.com/checkly/checkly-operator/internal/metrics"
+	"github.com/checkly/checkly-operator/internal/notify"
+	"github.com/checkly/checkly-operator/internal/policy"
 )
 
 // AlertChannelReconciler reconciles a AlertChannel object
@@ -41,43 +59,206 @@ type AlertChannelReconciler struct {
 	Scheme           *runtime.Scheme
 	ApiClient        checkly.Client
 	ControllerDomain string
+	CreateOnly       bool
+	SkipFinalizer    bool
+	ReconcileSummary bool
+	PolicyConfigMap  types.NamespacedName
+	QuotaConfigMap   types.NamespacedName
+	QuotaLabel       string
+	Audit            *audit.Logger
+	Mapping          *mapping.ConfigMap
+	Notifier         *notify.Notifier
+	FinalizerTimeout time.Duration
+	DriftInterval    time.Duration
+	RetryLimiter     workqueue.RateLimiter
+	Workers          int
+	RequeueIntervals map[string]time.Duration
+	MaxPendingAge    time.Duration
+	DetectDuplicates bool
+	ValidateTemplate bool
+	VerifyWrites     bool
+	VerifiedReady    bool
+	ChangeEvents     bool
+	EscalationTiers  EscalationTiers
+	UnknownFields    string
+	ConfirmChanges   bool
+	ParityLabel      string
+	SecretCache      *SecretCache
+	ValidateOpsGenie bool
+	WebhookURLPolicy *external.WebhookURLPolicy
+	RolloutLabel     string
+	CanarySoak       time.Duration
+	Breaker          *external.CircuitBreaker
+	Deprecations     *external.DeprecationTracker
+	Accounts         *external.Accounts
+	NameCollision    string
+	NameCharset      string
+	Validation       string
+	Directory        *external.AlertChannelDirectory
+	RecreateTypes    map[string]bool
+	HashKey          []byte
+	Recorder         record.EventRecorder
+
+	quota *quotaReservations
 }
 
 //+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=alertchannels,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=alertchannels/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=alertchannels/finalizers,verbs=update
-//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=alertpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=groups,verbs=get;list;watch;update
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=apichecks,verbs=get;list;watch;update
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// groupAlertChannelsIndex is the field index of the alert channels referenced by groups
+const groupAlertChannelsIndex = "spec.alertchannel"
+
+// apiCheckAlertChannelsIndex is the field index of the alert channels API checks subscribe to
+const apiCheckAlertChannelsIndex = "spec.alertchannelsubscriptions"
+
+// alertChannelParentIndex is the field index of the parent referenced by alert channels
+const alertChannelParentIndex = "spec.parentref"
+
+// alertChannelPolicyIndex is the field index of the AlertPolicy referenced by alert channels
+const alertChannelPolicyIndex = "spec.policyref"
+
+// alertChannelGroupsIndex is the field index of the groups alert channels are attached to in bulk
+const alertChannelGroupsIndex = "spec.groups"
+
+// alertChannelConfigHashIndex is the field index of the configuration hash of alert channels
+const alertChannelConfigHashIndex = "status.confighash"
+
+// rolloutPollInterval is how often AlertChannels check on canaries which haven't synced yet
+const rolloutPollInterval = 30 * time.Second
+
+// Values of the priority annotation, each maps to a requeue interval configured operator-wide
+const (
+	PriorityHigh   = "high"
+	PriorityMedium = "medium"
+	PriorityLow    = "low"
+)
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.12.1/pkg/reconcile
-func (r *AlertChannelReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *AlertChannelReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.Result, err error) {
 	logger := log.FromContext(ctx)
+	defer metrics.WorkerStarted("alertchannel")()
 
 	logger.V(1).Info("Reconciler started")
 
 	acFinalizer := fmt.Sprintf("%s/finalizer", r.ControllerDomain)
 
 	ac := &checklyv1alpha1.AlertChannel{}
+	start := time.Now()
+	operation := metrics.OperationNone
+	defer func() {
+		metrics.ObserveReconcile("AlertChannel", req.Namespace, ac.Labels, err)
+		metrics.ObserveReconcileDuration("AlertChannel", operation, time.Since(start))
+		// Deleted AlertChannels no longer count as paused
+		if ac.ResourceVersion == "" {
+			metrics.SetResourcePaused("AlertChannel", req.String(), false)
+		}
+		if r.ReconcileSummary {
+			logSummary(logger, operation, ac.Status.ID, time.Since(start), err)
+		}
+
+		// Requests failing during an outage are retried once the circuit breaker lets a probe through, instead of
+		// with the exponential back-off of every AlertChannel
+		if err != nil && r.Breaker.Tripped() {
+			res, err = unavailableResult(ctx, r.Client, r.Breaker, "AlertChannel", ac), nil
+		} else if err == nil && !r.Breaker.Tripped() {
+			availableErr := clearUnavailable(ctx, r.Client, ac)
+			if availableErr != nil {
+				logger.Error(availableErr, "Failed to update AlertChannel status")
+			}
+		}
+
+		resultErr := r.recordResult(ctx, ac, err)
+		if resultErr != nil {
+			logger.Error(resultErr, "Failed to update AlertChannel ready status")
+		}
+
+		pendingErr := r.checkPending(ctx, ac)
+		if pendingErr != nil {
+			logger.Error(pendingErr, "Failed to update AlertChannel pending status")
+		}
+
+		reportDeprecations(logger, r.Recorder, r.Deprecations, ac)
 
-	err := r.Get(ctx, req.NamespacedName, ac)
+		// Rate limited requests are retried when the checklyhq.com API asks for it, instead of with the error back-off
+		if retryAfter, ok := external.RetryAfter(err); ok {
+			logger.V(1).Info("checklyhq.com API rate limit hit, backing off", "retry after", retryAfter)
+			res, err = ctrl.Result{RequeueAfter: retryAfter}, nil
+		}
+	}()
+
+	err = r.Get(ctx, req.NamespacedName, ac)
 
 	// ////////////////////////////////
 	// Delete Logic
 	// ///////////////////////////////
 	if err != nil {
 		if errors.IsNotFound(err) {
-			// The resource has been deleted
-			logger.V(1).Info("Deleted", "checkly AlertChannel ID", ac.Status.ID)
+			// The resource has been deleted, the checkly AlertChannel ID is logged by the finalizer which still had it
+			logger.V(1).Info("AlertChannel removed")
 			return ctrl.Result{}, nil
 		}
 		// Error reading the object
 		logger.Error(err, "can't read the object")
+		return ctrl.Result{}, err
+	}
+
+	// Paused resources are left as they are, in the cluster and in checklyhq.com, until the annotation is removed
+	if paused(ctx, r.Client, r.ControllerDomain, r.Recorder, "AlertChannel", ac) {
+		logger.V(1).Info("AlertChannel reconciliation paused")
 		return ctrl.Result{}, nil
 	}
 
+	// Without the finalizer deletions don't wait for checklyhq.com, the finalizer added before it was disabled is removed
+	if r.SkipFinalizer {
+		var removed bool
+		removed, err = dropFinalizer(ctx, r.Client, ac, acFinalizer)
+		if err != nil {
+			logger.Error(err, "Failed to remove disabled AlertChannel finalizer")
+			return ctrl.Result{}, err
+		}
+		if removed {
+			logger.V(1).Info("Removed disabled finalizer")
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if r.Breaker.Open() {
+		return unavailableResult(ctx, r.Client, r.Breaker, "AlertChannel", ac), nil
+	}
+
+	// ////////////////////////////////
+	// Account Logic
+	// ///////////////////////////////
+	apiClient, accountID, err := accountClient(ctx, r.Client, r.Accounts, r.ApiClient, ac.Spec.Credentials)
+	if err != nil {
+		if ac.GetDeletionTimestamp() != nil && finalizerTimedOut(ac, r.ControllerDomain, r.FinalizerTimeout) {
+			logger.Error(err, "Failed to read the AlertChannel credentials past the finalizer timeout, leaving the checkly AlertChannel in place", "ID", ac.Status.ID)
+			_, err = dropFinalizer(ctx, r.Client, ac, acFinalizer)
+			return ctrl.Result{}, err
+		}
+		logger.Error(err, "Failed to read the AlertChannel credentials")
+		return ctrl.Result{}, err
+	}
+	err = checkAccount("AlertChannel", ac.Status.ID != 0, ac.Status.AccountID, accountID)
+	if err != nil {
+		logger.Error(err, "Refusing to sync AlertChannel")
+		return ctrl.Result{}, err
+	}
+	// The AlertChannel is created, adopted or pinned in the account of its credentials
+	ac.Status.AccountID = accountID
+	r = r.withApiClient(apiClient)
+
 	// ////////////////////////////////
 	// Remove Finalizer Logic
 	// ///////////////////////////////
@@ -85,13 +266,71 @@ func (r *AlertChannelReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	if ac.GetDeletionTimestamp() != nil {
 		if controllerutil.ContainsFinalizer(ac, acFinalizer) {
 			logger.V(1).Info("Finalizer is present, trying to delete Checkly AlertChannel", "ID", ac.Status.ID)
-			err := external.DeleteAlertChannel(ac, r.ApiClient)
+			forceDeleteAnnotation := fmt.Sprintf("%s/force-delete", r.ControllerDomain)
+			if ac.GetAnnotations()[forceDeleteAnnotation] != "true" {
+				groups, err := r.alertingGroups(ctx, ac)
+				if err != nil && !finalizerTimedOut(ac, r.ControllerDomain, r.FinalizerTimeout) {
+					logger.Error(err, "Failed to determine if AlertChannel is in use")
+					return ctrl.Result{}, err
+				}
+				if err != nil {
+					logger.Error(err, "Failed to determine if AlertChannel is in use past the finalizer timeout, deleting it anyway")
+				}
+				if len(groups) != 0 {
+					err = fmt.Errorf("AlertChannel is still in use by groups %s, set the %s annotation to \"true\" to delete it anyway", strings.Join(groups, ", "), forceDeleteAnnotation)
+					logger.Error(err, "Refusing to delete AlertChannel")
+					return ctrl.Result{}, err
+				}
+			}
+
+			// The references are only removed once the in-use check passed, detaching first would get the AlertChannel
+			// past the check by silently stopping the groups alerting to it
+			err := r.detachFromGroups(ctx, ac)
+			if err != nil {
+				logger.Error(err, "Failed to remove AlertChannel from groups")
+				return ctrl.Result{}, err
+			}
+
+			err = r.detachFromChecks(ctx, ac)
 			if err != nil {
-				logger.Error(err, "Failed to delete checkly AlertChannel")
+				logger.Error(err, "Failed to remove AlertChannel from checks")
 				return ctrl.Result{}, err
 			}
 
-			logger.V(1).Info("Successfully deleted checkly AlertChannel", "ID", ac.Status.ID)
+			if r.CreateOnly {
+				logger.Info("Create only mode, leaving checkly AlertChannel in place", "ID", ac.Status.ID)
+			} else if r.dryRun(ac) {
+				logger.Info("Dry run, leaving checkly AlertChannel in place", "ID", ac.Status.ID)
+			} else {
+				err = r.unsubscribe(ctx, ac)
+				if err != nil && !finalizerTimedOut(ac, r.ControllerDomain, r.FinalizerTimeout) {
+					logger.Error(err, "Failed to remove checkly AlertChannel subscriptions")
+					return ctrl.Result{}, err
+				}
+
+				operation = metrics.OperationDelete
+				err = external.DeleteAlertChannel(ac, r.ApiClient)
+				change := audit.Record{Operation: audit.Delete, Kind: "AlertChannel", Object: ac, ChecklyID: ac.Status.ID, Spec: ac.Spec, Before: r.stateBefore(ac, nil), Err: err}
+				r.Audit.Log(change)
+				r.Notifier.Notify(ctx, change)
+				if err != nil && !finalizerTimedOut(ac, r.ControllerDomain, r.FinalizerTimeout) {
+					logger.Error(err, "Failed to delete checkly AlertChannel")
+					return ctrl.Result{}, err
+				}
+
+				if err != nil {
+					logger.Error(err, "Failed to delete checkly AlertChannel past the finalizer timeout, removing the finalizer anyway", "ID", ac.Status.ID)
+					r.Recorder.Eventf(ac, corev1.EventTypeWarning, "FinalizerTimeout", "Failed to delete checkly AlertChannel %v past the finalizer timeout, it has to be deleted manually: %s", ac.Status.ID, err)
+				} else {
+					logger.V(1).Info("Successfully deleted checkly AlertChannel", "ID", ac.Status.ID)
+				}
+			}
+
+			err = r.Mapping.Remove(ctx, "AlertChannel", ac.Status.ID)
+			if err != nil {
+				logger.Error(err, "Failed to remove AlertChannel from the ID mapping")
+				return ctrl.Result{}, err
+			}
 
 			controllerutil.RemoveFinalizer(ac, acFinalizer)
 			err = r.Update(ctx, ac)
@@ -104,12 +343,37 @@ func (r *AlertChannelReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, nil
 	}
 
+	// /////////////////////////////
+	// Unknown fields logic
+	// ////////////////////////////
+	if r.UnknownFields == UnknownFieldsWarn || r.UnknownFields == UnknownFieldsReject {
+		var fields []string
+		fields, err = r.unknownSpecFields(ctx, ac)
+		if err != nil {
+			logger.Error(err, "Failed to look up unknown AlertChannel fields")
+			return ctrl.Result{}, err
+		}
+
+		err = r.setUnknownFieldsCondition(ctx, ac, fields)
+		if err != nil {
+			logger.Error(err, "Failed to update AlertChannel status")
+			return ctrl.Result{}, err
+		}
+
+		if len(fields) != 0 && r.UnknownFields == UnknownFieldsReject {
+			logger.Info("AlertChannel sets fields unknown to the operator, not syncing it", "fields", fields)
+			return ctrl.Result{}, nil
+		}
+	}
+
 	// /////////////////////////////
 	// Add Finalizer logic
 	// ////////////////////////////
-	if !controllerutil.ContainsFinalizer(ac, acFinalizer) {
+	if !r.SkipFinalizer && !controllerutil.ContainsFinalizer(ac, acFinalizer) {
+		// Patch rather than update, an update would drop the spec fields unknown to the operator
+		patch := client.MergeFromWithOptions(ac.DeepCopy(), client.MergeFromWithOptimisticLock{})
 		controllerutil.AddFinalizer(ac, acFinalizer)
-		err = r.Update(ctx, ac)
+		err = r.Patch(ctx, ac, patch)
 		if err != nil {
 			logger.Error(err, "Failed to update AlertChannel status")
 			return ctrl.Result{}, err
@@ -119,78 +383,1728 @@ func (r *AlertChannelReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	}
 
 	// /////////////////////////////
-	// OpsGenie logic + secret retrieval
+	// Inheritance logic
 	// ////////////////////////////
-	opsGenieConfig := checkly.AlertChannelOpsgenie{}
-	if ac.Spec.OpsGenie.APISecret != (corev1.ObjectReference{}) {
-		secret := &corev1.Secret{}
-		err := r.Get(ctx,
-			types.NamespacedName{
-				Name:      ac.Spec.OpsGenie.APISecret.Name,
-				Namespace: ac.Spec.OpsGenie.APISecret.Namespace},
-			secret)
+	resolved := ac.DeepCopy()
+	resolved.Spec, err = r.resolveSpec(ctx, ac)
+	if err != nil {
+		logger.Error(err, "Failed to inherit from parent AlertChannel or AlertPolicy", "parent", ac.Spec.ParentRef, "policy", ac.Spec.PolicyRef)
+		return ctrl.Result{}, err
+	}
+
+	err = external.ValidateAlertChannelType(resolved.Spec)
+	if err != nil {
+		logger.Error(err, "Invalid AlertChannel")
+		return ctrl.Result{}, err
+	}
+
+	err = external.ValidateAlertChannelNames(resolved, r.NameCharset)
+	if err != nil {
+		logger.Error(err, "Invalid AlertChannel name")
+		return ctrl.Result{}, err
+	}
+
+	err = r.EscalationTiers.Validate(resolved.Spec.Tier)
+	if err != nil {
+		logger.Error(err, "Invalid escalation tier")
+		return ctrl.Result{}, err
+	}
+
+	var validationWarnings []string
+	if r.Validation == ValidationBestEffort {
+		validationWarnings = external.SanitizeAlertChannelSpec(&resolved.Spec)
+	}
+
+	if resolved.Spec.RawConfig != "" {
+		logger.Info("AlertChannel sets rawconfig, its contents are not validated by the operator")
+		if ac.Status.SyncedGeneration != ac.Generation {
+			r.Recorder.Event(ac, corev1.EventTypeWarning, "RawConfigUnvalidated", "spec.rawconfig is not validated by the operator, mistakes only surface as checklyhq.com API errors")
+		}
+	}
+
+	if r.ParityLabel != "" {
+		err = r.checkParity(ctx, ac)
 		if err != nil {
-			logger.Error(err, "Unable to read secret for API Key")
+			logger.Error(err, "Failed to check AlertChannel parity")
 			return ctrl.Result{}, err
 		}
+	}
+
+	// /////////////////////////////
+	// Policy validation
+	// ////////////////////////////
+	if r.PolicyConfigMap.Name != "" {
+		err = r.validatePolicies(ctx, resolved)
+		if err != nil {
+			logger.Error(err, "AlertChannel rejected by policies")
+			return ctrl.Result{}, err
+		}
+	}
 
-		secretValue := string(secret.Data[ac.Spec.OpsGenie.APISecret.FieldPath])
-		if secretValue == "" {
-			secretErr := errs.New("secret value is empty")
-			logger.Error(secretErr, "Please add Opsgenie secret")
+	// /////////////////////////////
+	// OpsGenie logic + secret retrieval
+	// ////////////////////////////
+	opsGenieConfig := checkly.AlertChannelOpsgenie{}
+	if resolved.Spec.OpsGenie != (checklyv1alpha1.AlertChannelOpsGenie{}) {
+		var secretValue string
+		err = errs.New("OpsGenie API secret is required")
+		if resolved.Spec.OpsGenie.APISecret != (corev1.ObjectReference{}) {
+			secretValue, err = r.secretValue(ctx, resolved.Spec.OpsGenie.APISecret)
+		}
+		if err == nil {
+			err = external.ValidateSecretValue("OPSGENIE", secretValue)
+		}
+
+		conditionErr := r.setSecretCondition(ctx, ac, err)
+		if err != nil {
+			logger.Error(err, "Invalid secret for API Key")
 			return ctrl.Result{}, err
 		}
+		if conditionErr != nil {
+			logger.Error(conditionErr, "Failed to update AlertChannel status")
+			return ctrl.Result{}, conditionErr
+		}
 
 		opsGenieConfig = checkly.AlertChannelOpsgenie{
-			Name:     ac.Name,
+			Name:     external.AlertChannelName(ac),
 			APIKey:   secretValue,
-			Region:   ac.Spec.OpsGenie.Region,
-			Priority: ac.Spec.OpsGenie.Priority,
+			Region:   resolved.Spec.OpsGenie.Region,
+			Priority: resolved.Spec.OpsGenie.Priority,
 		}
 
+		if r.ValidateOpsGenie {
+			credentialErr := external.ValidateOpsGenieAPIKey(ctx, resolved.Spec.OpsGenie.Region, secretValue)
+			err = r.setCredentialCondition(ctx, ac, credentialErr)
+			if errs.Is(credentialErr, external.ErrOpsGenieKeyRejected) {
+				logger.Error(credentialErr, "Invalid OpsGenie API key")
+				return ctrl.Result{}, credentialErr
+			}
+			if credentialErr != nil {
+				logger.Error(credentialErr, "Failed to validate OpsGenie API key, syncing it anyway")
+			}
+			if err != nil {
+				logger.Error(err, "Failed to update AlertChannel status")
+				return ctrl.Result{}, err
+			}
+		}
 	}
 
 	// /////////////////////////////
-	// Update logic
+	// Slack logic + secret retrieval
 	// ////////////////////////////
+	if resolved.Spec.Slack.URLSecret != (corev1.ObjectReference{}) {
+		var secretValue string
+		secretValue, err = r.secretValue(ctx, resolved.Spec.Slack.URLSecret)
+		if err == nil {
+			err = external.ValidateSecretValue("SLACK", secretValue)
+		}
 
-	// Determine if it's a new object or if it's an update to an existing object
-	if ac.Status.ID != 0 {
-		// Existing object, we need to update it
-		logger.V(1).Info("Existing object, with ID", "checkly AlertChannel ID", ac.Status.ID)
-		err := external.UpdateAlertChannel(ac, opsGenieConfig, r.ApiClient)
+		conditionErr := r.setSecretCondition(ctx, ac, err)
 		if err != nil {
-			logger.Error(err, "Failed to update checkly AlertChannel")
+			logger.Error(err, "Invalid secret for Slack webhook URL")
 			return ctrl.Result{}, err
 		}
-		logger.V(1).Info("Updated checkly AlertChannel", "ID", ac.Status.ID)
-		return ctrl.Result{}, nil
+		if conditionErr != nil {
+			logger.Error(conditionErr, "Failed to update AlertChannel status")
+			return ctrl.Result{}, conditionErr
+		}
+
+		// Only the resolved copy synced to checklyhq.com holds the URL, it's left out of the config hash
+		resolved.Spec.Slack.URL = secretValue
 	}
 
 	// /////////////////////////////
-	// Create logic
+	// PagerDuty logic + secret retrieval
+	// ////////////////////////////
+	if resolved.Spec.PagerDuty.ServiceKeySecret != (corev1.ObjectReference{}) {
+		var secretValue string
+		secretValue, err = r.secretValue(ctx, resolved.Spec.PagerDuty.ServiceKeySecret)
+		if err == nil {
+			err = external.ValidateSecretValue("PAGERDUTY", secretValue)
+		}
+
+		// A missing key requeues instead of creating a PagerDuty alert channel which can't deliver alerts
+		conditionErr := r.setSecretCondition(ctx, ac, err)
+		if err != nil {
+			logger.Error(err, "Invalid secret for PagerDuty service key")
+			return ctrl.Result{}, err
+		}
+		if conditionErr != nil {
+			logger.Error(conditionErr, "Failed to update AlertChannel status")
+			return ctrl.Result{}, conditionErr
+		}
+
+		// Only the resolved copy synced to checklyhq.com holds the service key, it's left out of the config hash
+		resolved.Spec.PagerDuty.ServiceKey = secretValue
+	}
+
+	// /////////////////////////////
+	// Webhook URL validation
+	// ////////////////////////////
+	// Internal destinations are never synced, not even on a best-effort basis
+	if r.WebhookURLPolicy != nil && !resolved.Spec.Webhook.IsZero() {
+		urlErr := r.WebhookURLPolicy.Validate(ctx, resolved.Spec.Webhook.URL)
+		err = r.setWebhookURLCondition(ctx, ac, urlErr)
+		if urlErr != nil {
+			logger.Error(urlErr, "Webhook URL not allowed")
+			r.Recorder.Event(ac, corev1.EventTypeWarning, ReasonURLBlocked, urlErr.Error())
+			return ctrl.Result{}, urlErr
+		}
+		if err != nil {
+			logger.Error(err, "Failed to update AlertChannel status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// /////////////////////////////
+	// Webhook template validation
+	// ////////////////////////////
+	if r.ValidateTemplate && !resolved.Spec.Webhook.IsZero() {
+		templateErr := external.ValidateWebhookTemplate(resolved.Spec.Webhook)
+		err = r.setTemplateCondition(ctx, ac, templateErr)
+		if templateErr != nil && r.Validation == ValidationBestEffort {
+			validationWarnings = append(validationWarnings, templateErr.Error())
+			templateErr = nil
+		}
+		if templateErr != nil {
+			logger.Error(templateErr, "Invalid webhook template")
+			return ctrl.Result{}, templateErr
+		}
+		if err != nil {
+			logger.Error(err, "Failed to update AlertChannel status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// /////////////////////////////
+	// Webhook client certificate validation
+	// ////////////////////////////
+	if resolved.Spec.Webhook.ClientCertSecret != (corev1.ObjectReference{}) {
+		certErr := r.validateClientCert(ctx, resolved.Spec.Webhook.ClientCertSecret)
+		err = r.setClientCertCondition(ctx, ac, certErr)
+		if certErr != nil && r.Validation == ValidationBestEffort {
+			validationWarnings = append(validationWarnings, certErr.Error())
+			certErr = nil
+		}
+		if certErr != nil {
+			logger.Error(certErr, "Invalid webhook client certificate")
+			return ctrl.Result{}, certErr
+		}
+		if err != nil {
+			logger.Error(err, "Failed to update AlertChannel status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if r.Validation == ValidationBestEffort {
+		err = r.setSanitizedCondition(ctx, ac, validationWarnings)
+		if err != nil {
+			logger.Error(err, "Failed to update AlertChannel status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// /////////////////////////////
+	// Duplicate detection
+	// ////////////////////////////
+	configHash := external.AlertChannelConfigHash(resolved.Spec)
+	if r.DetectDuplicates {
+		err = r.warnDuplicates(ctx, ac, configHash)
+		if err != nil {
+			logger.Error(err, "Failed to look up duplicate AlertChannels")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// /////////////////////////////
+	// Dry run logic
+	// ////////////////////////////
+	if r.dryRun(ac) {
+		payload, err := external.AlertChannelPayload(resolved, opsGenieConfig)
+		if err != nil {
+			logger.Error(err, "Failed to build checkly AlertChannel payload")
+			return ctrl.Result{}, err
+		}
+		logger.Info("Dry run, skipping checkly AlertChannel sync", "checkly AlertChannel ID", ac.Status.ID, "payload", payload)
+
+		err = r.setCondition(ctx, ac, metav1.Condition{
+			Type:               ConditionDryRun,
+			Status:             metav1.ConditionTrue,
+			Reason:             ReasonDryRun,
+			Message:            "Dry run annotation is set, the AlertChannel is not synced to checklyhq.com",
+			ObservedGeneration: ac.Generation,
+		})
+		if err != nil {
+			logger.Error(err, "Failed to update AlertChannel status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	if meta.FindStatusCondition(ac.Status.Conditions, ConditionDryRun) != nil {
+		meta.RemoveStatusCondition(&ac.Status.Conditions, ConditionDryRun)
+		err = r.Status().Update(ctx, ac)
+		if err != nil {
+			logger.Error(err, "Failed to update AlertChannel status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// /////////////////////////////
+	// Canary rollout
+	// ////////////////////////////
+	if r.RolloutLabel != "" && !r.canary(ac) && ac.Status.SyncedGeneration != ac.Generation {
+		retryAfter, err := r.awaitCanaries(ctx, ac)
+		if err != nil {
+			logger.Error(err, "Failed to check the canaries of the AlertChannel rollout cohort")
+			return ctrl.Result{}, err
+		}
+		if retryAfter != 0 {
+			logger.V(1).Info("Waiting for the canaries of the rollout cohort", "retry after", retryAfter)
+			return ctrl.Result{RequeueAfter: retryAfter}, nil
+		}
+	}
+
+	// /////////////////////////////
+	// Adopted ID logic
 	// ////////////////////////////
-	acID, err := external.CreateAlertChannel(ac, opsGenieConfig, r.ApiClient)
+	err = r.bindAdoptedID(ctx, ac)
 	if err != nil {
-		logger.Error(err, "Failed to create checkly AlertChannel")
+		logger.Error(err, "Failed to adopt the checkly AlertChannel")
 		return ctrl.Result{}, err
 	}
 
-	// Update the custom resource Status with the returned ID
-	ac.Status.ID = acID
-	err = r.Status().Update(ctx, ac)
+	// /////////////////////////////
+	// Pinned ID logic
+	// ////////////////////////////
+	err = r.bindPinnedID(ctx, ac)
 	if err != nil {
-		logger.Error(err, "Failed to update AlertChannel status", "ID", ac.Status.ID)
+		logger.Error(err, "Failed to bind to the pinned checkly AlertChannel")
 		return ctrl.Result{}, err
 	}
-	logger.V(1).Info("New checkly AlertChannel created", "ID", ac.Status.ID)
 
-	return ctrl.Result{}, nil
-}
+	// /////////////////////////////
+	// Update logic
+	// ////////////////////////////
 
-// SetupWithManager sets up the controller with the Manager.
-func (r *AlertChannelReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&checklyv1alpha1.AlertChannel{}).
-		Complete(r)
+	// Determine if it's a new object or if it's an update to an existing object
+	if ac.Status.ID != 0 {
+		err = r.Mapping.Set(ctx, "AlertChannel", ac.Status.ID, ac)
+		if err != nil {
+			logger.Error(err, "Failed to add AlertChannel to the ID mapping")
+			return ctrl.Result{}, err
+		}
+
+		if r.CreateOnly {
+			logger.V(1).Info("Create only mode, skipping update", "checkly AlertChannel ID", ac.Status.ID)
+			return ctrl.Result{}, nil
+		}
+
+		// Existing object, we need to update it
+		logger.V(1).Info("Existing object, with ID", "checkly AlertChannel ID", ac.Status.ID)
+		appliedHash, err := external.AlertChannelAppliedHash(r.HashKey, resolved, opsGenieConfig, ac.Status.ID)
+		if err != nil {
+			logger.Error(err, "Failed to render checkly AlertChannel", "ID", ac.Status.ID)
+			return ctrl.Result{}, err
+		}
+
+		// An AlertChannel rendering the payload last applied isn't read or written until its next drift check is due
+		if ac.GetAnnotations()[r.lastAppliedHashAnnotation()] == appliedHash && !r.driftCheckDue(ac) {
+			logger.V(1).Info("Unchanged AlertChannel, skipping update", "ID", ac.Status.ID)
+		} else {
+			// The AlertChannel is only written if it differs in checklyhq.com, the changes also feed the change log
+			changes, err := external.AlertChannelChanges(resolved, opsGenieConfig, r.ApiClient)
+			changesRead := err == nil
+			if err != nil {
+				logger.Error(err, "Failed to read checkly AlertChannel changes", "ID", ac.Status.ID)
+				// A failed read falls back to writing the AlertChannel anyway, it only blocks the update if the changes
+				// have to be checked for destructive ones
+				if r.ConfirmChanges {
+					return ctrl.Result{}, err
+				}
+			}
+
+			// Drift is only reported for AlertChannels observing it, as long as the AlertChannel itself is unchanged
+			observeOnly := changesRead && r.observesDrift(ac) && ac.GetAnnotations()[r.lastAppliedHashAnnotation()] == appliedHash
+			if observeOnly {
+				err = r.reportDrift(ctx, ac, changes)
+				if err != nil {
+					logger.Error(err, "Failed to update AlertChannel status")
+					return ctrl.Result{}, err
+				}
+				if len(changes) != 0 {
+					logger.Info("checkly AlertChannel drifted, not reverting it", "ID", ac.Status.ID, "changes", formatChanges(changes))
+				}
+			}
+
+			var confirmed bool
+			if r.ConfirmChanges && !observeOnly {
+				var held bool
+				held, confirmed, err = r.holdDestructiveChanges(ctx, ac, changes)
+				if err != nil {
+					logger.Error(err, "Failed to update AlertChannel status")
+					return ctrl.Result{}, err
+				}
+				if held {
+					logger.Info("Destructive change awaiting confirmation", "ID", ac.Status.ID, "changes", formatChanges(changes))
+					return ctrl.Result{}, nil
+				}
+			}
+
+			// The AlertChannel read from checklyhq.com already matches, there's nothing to write
+			if changesRead && len(changes) == 0 {
+				logger.V(1).Info("Unchanged checkly AlertChannel, skipping update", "ID", ac.Status.ID)
+			} else if !observeOnly {
+				operation = metrics.OperationUpdate
+				// Recreated AlertChannels are replaced by a new checklyhq.com alert channel, with a new ID
+				recreated := r.recreates(ac, resolved)
+				var err error
+				if recreated {
+					err = r.recreate(ctx, ac, resolved, opsGenieConfig)
+				} else {
+					err = external.UpdateAlertChannel(resolved, opsGenieConfig, r.ApiClient)
+				}
+				change := audit.Record{Operation: audit.Update, Kind: "AlertChannel", Object: ac, ChecklyID: ac.Status.ID, Spec: external.RedactedAlertChannelSpec(resolved.Spec), Before: r.stateBefore(ac, changes), Err: err}
+				r.Audit.Log(change)
+				r.Notifier.Notify(ctx, change)
+				if statusErr := r.setCondition(ctx, ac, syncedCondition(ac.Generation, err)); statusErr != nil {
+					logger.Error(statusErr, "Failed to update AlertChannel status", "ID", ac.Status.ID)
+					if err == nil {
+						return ctrl.Result{}, statusErr
+					}
+				}
+				if err != nil {
+					logger.Error(err, "Failed to update checkly AlertChannel")
+					return ctrl.Result{}, err
+				}
+				logger.V(1).Info("Updated checkly AlertChannel", "ID", ac.Status.ID)
+				if recreated {
+					// The applied payload includes the ID, which changed
+					appliedHash, err = external.AlertChannelAppliedHash(r.HashKey, resolved, opsGenieConfig, ac.Status.ID)
+					if err != nil {
+						logger.Error(err, "Failed to render checkly AlertChannel", "ID", ac.Status.ID)
+						return ctrl.Result{}, err
+					}
+				}
+				// The update reverted any drift reported before
+				if meta.FindStatusCondition(ac.Status.Conditions, ConditionDriftDetected) != nil {
+					err = r.reportDrift(ctx, ac, nil)
+					if err != nil {
+						logger.Error(err, "Failed to update AlertChannel status")
+						return ctrl.Result{}, err
+					}
+				}
+				if confirmed {
+					// A confirmation is only good for a single change
+					err = r.removeConfirmation(ctx, ac)
+					if err != nil {
+						logger.Error(err, "Failed to remove the confirm-destructive annotation")
+						return ctrl.Result{}, err
+					}
+				}
+				if r.ChangeEvents && len(changes) != 0 {
+					r.Recorder.Eventf(ac, corev1.EventTypeNormal, "Updated", "Updated checkly AlertChannel %d: %s", ac.Status.ID, formatChanges(changes))
+				}
+			}
+
+			err = r.recordApplied(ctx, ac, appliedHash)
+			if err != nil {
+				logger.Error(err, "Failed to record the applied AlertChannel", "ID", ac.Status.ID)
+				return ctrl.Result{}, err
+			}
+		}
+
+		if r.VerifyWrites {
+			err = r.verify(ctx, ac, resolved, opsGenieConfig)
+			if err != nil {
+				logger.Error(err, "Failed to verify checkly AlertChannel", "ID", ac.Status.ID)
+				return ctrl.Result{}, err
+			}
+		}
+
+		err = r.selfTest(ctx, ac, resolved)
+		if err != nil {
+			logger.Error(err, "Failed to update AlertChannel status")
+			return ctrl.Result{}, err
+		}
+
+		if ac.Status.ConfigHash != configHash || ac.Status.SyncedGeneration != ac.Generation {
+			ac.Status.ConfigHash = configHash
+			markSynced(ac)
+			err = r.Status().Update(ctx, ac)
+			if err != nil {
+				logger.Error(err, "Failed to update AlertChannel status", "ID", ac.Status.ID)
+				return ctrl.Result{}, err
+			}
+		}
+
+		// /////////////////////////////
+		// Group attachment
+		// ////////////////////////////
+		pending, err := r.attachToGroups(ctx, ac)
+		if err != nil {
+			logger.Error(err, "Failed to attach AlertChannel to groups")
+			return ctrl.Result{}, err
+		}
+		if pending {
+			logger.V(1).Info("Waiting for groups to be synced before attaching", "groups", ac.Spec.Groups)
+			return ctrl.Result{Requeue: true}, nil
+		}
+
+		return r.successResult(ac), nil
+	}
+
+	// /////////////////////////////
+	// Create logic
+	// ////////////////////////////
+	// The directory lists the alert channels of the operator account only
+	if r.NameCollision != "" && r.NameCollision != NameCollisionIgnore && accountID == "" {
+		adopted, err := r.resolveNameCollision(ctx, ac, resolved, &opsGenieConfig)
+		if err != nil {
+			logger.Error(err, "Failed to resolve checkly AlertChannel name collision")
+			return ctrl.Result{}, err
+		}
+		if adopted {
+			// The adopted alert channel is overwritten with the AlertChannel configuration by the update logic
+			return ctrl.Result{Requeue: true}, nil
+		}
+	}
+
+	if r.QuotaConfigMap.Name != "" {
+		err = r.checkQuota(ctx, ac)
+		if err != nil {
+			logger.Error(err, "AlertChannel not created")
+			return ctrl.Result{}, err
+		}
+	}
+
+	operation = metrics.OperationCreate
+	acID, err := external.CreateAlertChannel(resolved, opsGenieConfig, r.ApiClient)
+	change := audit.Record{Operation: audit.Create, Kind: "AlertChannel", Object: ac, ChecklyID: acID, Spec: external.RedactedAlertChannelSpec(resolved.Spec), Err: err}
+	r.Audit.Log(change)
+	r.Notifier.Notify(ctx, change)
+	if err != nil {
+		logger.Error(err, "Failed to create checkly AlertChannel")
+		r.releaseQuota(ac)
+		if statusErr := r.setCondition(ctx, ac, syncedCondition(ac.Generation, err)); statusErr != nil {
+			logger.Error(statusErr, "Failed to update AlertChannel status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	// Update the custom resource Status with the returned ID
+	ac.Status.ID = acID
+	ac.Status.ConfigHash = configHash
+	markSynced(ac)
+	meta.SetStatusCondition(&ac.Status.Conditions, syncedCondition(ac.Generation, nil))
+	err = r.Status().Update(ctx, ac)
+	if err != nil {
+		logger.Error(err, "Failed to update AlertChannel status", "ID", ac.Status.ID)
+		return ctrl.Result{}, err
+	}
+
+	err = r.Mapping.Set(ctx, "AlertChannel", ac.Status.ID, ac)
+	if err != nil {
+		logger.Error(err, "Failed to add AlertChannel to the ID mapping")
+		return ctrl.Result{}, err
+	}
+	logger.V(1).Info("New checkly AlertChannel created", "ID", ac.Status.ID)
+
+	appliedHash, err := external.AlertChannelAppliedHash(r.HashKey, resolved, opsGenieConfig, ac.Status.ID)
+	if err == nil {
+		err = r.recordApplied(ctx, ac, appliedHash)
+	}
+	if err != nil {
+		logger.Error(err, "Failed to record the applied AlertChannel", "ID", ac.Status.ID)
+		return ctrl.Result{}, err
+	}
+
+	if r.VerifyWrites {
+		err = r.verify(ctx, ac, resolved, opsGenieConfig)
+		if err != nil {
+			logger.Error(err, "Failed to verify checkly AlertChannel", "ID", ac.Status.ID)
+			return ctrl.Result{}, err
+		}
+	}
+
+	err = r.selfTest(ctx, ac, resolved)
+	if err != nil {
+		logger.Error(err, "Failed to update AlertChannel status")
+		return ctrl.Result{}, err
+	}
+
+	// /////////////////////////////
+	// Group attachment
+	// ////////////////////////////
+	pending, err := r.attachToGroups(ctx, ac)
+	if err != nil {
+		logger.Error(err, "Failed to attach AlertChannel to groups")
+		return ctrl.Result{}, err
+	}
+	if pending {
+		logger.V(1).Info("Waiting for groups to be synced before attaching", "groups", ac.Spec.Groups)
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	return r.successResult(ac), nil
+}
+
+// bindAdoptedID binds the AlertChannel which wasn't synced yet to the checklyhq.com alert channel of the adopt-id annotation, once it's
+// confirmed to exist, the configuration is then synced onto it by the update logic instead of creating a duplicate
+func (r *AlertChannelReconciler) bindAdoptedID(ctx context.Context, ac *checklyv1alpha1.AlertChannel) error {
+	if ac.Status.ID != 0 {
+		return nil
+	}
+	adopted, err := adoptedID(r.ControllerDomain, ac)
+	if err != nil || adopted == 0 {
+		return err
+	}
+
+	err = external.AlertChannelExists(adopted, r.ApiClient)
+	if err != nil {
+		return fmt.Errorf("adopted checkly AlertChannel %d: %w", adopted, err)
+	}
+
+	r.Recorder.Eventf(ac, corev1.EventTypeNormal, "Adopted", "Adopted the checkly AlertChannel %d of the adopt-id annotation", adopted)
+	ac.Status.ID = adopted
+	return r.Status().Update(ctx, ac)
+}
+
+// bindPinnedID binds the AlertChannel to the checklyhq.com alert channel of the pin-id annotation, once it's confirmed
+// to exist, the configuration is then synced onto it by the update logic
+func (r *AlertChannelReconciler) bindPinnedID(ctx context.Context, ac *checklyv1alpha1.AlertChannel) error {
+	pinned, err := pinnedID(r.ControllerDomain, ac)
+	if err != nil || pinned == 0 || pinned == ac.Status.ID {
+		return err
+	}
+
+	err = external.AlertChannelExists(pinned, r.ApiClient)
+	if err != nil {
+		return fmt.Errorf("pinned checkly AlertChannel %d: %w", pinned, err)
+	}
+
+	r.Recorder.Eventf(ac, corev1.EventTypeNormal, "Pinned", "Bound to the checkly AlertChannel %d of the pin-id annotation, previously %d", pinned, ac.Status.ID)
+	ac.Status.ID = pinned
+	return r.Status().Update(ctx, ac)
+}
+
+// withApiClient returns a copy of the reconciler using the client, so a reconciliation talks to the account of the
+// AlertChannel throughout
+func (r *AlertChannelReconciler) withApiClient(apiClient checkly.Client) *AlertChannelReconciler {
+	if apiClient == r.ApiClient {
+		return r
+	}
+	copied := *r
+	copied.ApiClient = apiClient
+	return &copied
+}
+
+// dryRun determines if the dry run annotation is set on the AlertChannel, in which case checklyhq.com is left untouched
+func (r *AlertChannelReconciler) dryRun(ac *checklyv1alpha1.AlertChannel) bool {
+	return ac.GetAnnotations()[fmt.Sprintf("%s/dry-run", r.ControllerDomain)] == "true"
+}
+
+// successResult requeues the AlertChannel after its resync interval
+func (r *AlertChannelReconciler) successResult(ac *checklyv1alpha1.AlertChannel) ctrl.Result {
+	return ctrl.Result{RequeueAfter: r.resyncInterval(ac)}
+}
+
+// resyncInterval returns the interval of the priority annotation of the AlertChannel, so drift is corrected sooner
+// for critical channels, without the annotation it's the drift interval. 0 disables drift checks.
+func (r *AlertChannelReconciler) resyncInterval(ac *checklyv1alpha1.AlertChannel) time.Duration {
+	priority := ac.GetAnnotations()[fmt.Sprintf("%s/priority", r.ControllerDomain)]
+	interval, ok := r.RequeueIntervals[priority]
+	if !ok {
+		interval = r.DriftInterval
+	}
+	return interval
+}
+
+// resolveNameCollision looks up alert channels in checklyhq.com with the name the AlertChannel is about to be created
+// with, including ones created outside the operator, and applies the name collision policy. adopted reports the
+// AlertChannel took over the existing alert channel, with the suffix policy the name of the AlertChannel is changed.
+func (r *AlertChannelReconciler) resolveNameCollision(ctx context.Context, ac *checklyv1alpha1.AlertChannel, resolved *checklyv1alpha1.AlertChannel, opsGenieConfig *checkly.AlertChannelOpsgenie) (adopted bool, err error) {
+	names, err := r.Directory.AlertChannelNames(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	name := external.AlertChannelName(ac)
+	ids := names[name]
+	if len(ids) == 0 {
+		return false, nil
+	}
+
+	switch r.NameCollision {
+	case NameCollisionAdopt:
+		if len(ids) > 1 {
+			return false, fmt.Errorf("can't adopt checkly AlertChannel %q, %d alert channels share the name", name, len(ids))
+		}
+		ac.Status.ID = ids[0]
+		err = r.Status().Update(ctx, ac)
+		if err != nil {
+			return false, err
+		}
+		r.Recorder.Eventf(ac, corev1.EventTypeNormal, "Adopted", "Adopted the existing checkly AlertChannel %d named %s", ids[0], name)
+		return true, nil
+
+	case NameCollisionSuffix:
+		ac.Status.ChecklyName = suffixedName(name, names)
+		resolved.Status.ChecklyName = ac.Status.ChecklyName
+		if opsGenieConfig.Name != "" {
+			opsGenieConfig.Name = ac.Status.ChecklyName
+		}
+		r.Recorder.Eventf(ac, corev1.EventTypeNormal, "Renamed", "checkly AlertChannel %s already exists, creating it as %s", name, ac.Status.ChecklyName)
+		return false, nil
+
+	default:
+		r.Recorder.Eventf(ac, corev1.EventTypeWarning, "NameCollision", "checkly AlertChannel %s already exists with ID %d", name, ids[0])
+		return false, fmt.Errorf("checkly AlertChannel %q already exists with ID %d", name, ids[0])
+	}
+}
+
+// canary determines if the canary label is set on the AlertChannel, canaries are synced ahead of their rollout cohort
+func (r *AlertChannelReconciler) canary(ac *checklyv1alpha1.AlertChannel) bool {
+	return ac.GetLabels()[fmt.Sprintf("%s/canary", r.ControllerDomain)] == "true"
+}
+
+// markSynced records the generation of the AlertChannel as synced to checklyhq.com
+func markSynced(ac *checklyv1alpha1.AlertChannel) {
+	if ac.Status.SyncedGeneration == ac.Generation && ac.Status.SyncedAt != nil {
+		return
+	}
+	now := metav1.Now()
+	ac.Status.SyncedGeneration = ac.Generation
+	ac.Status.SyncedAt = &now
+}
+
+// awaitCanaries checks the canaries sharing the rollout label value of the AlertChannel and reports them in the
+// RolloutPending condition, retryAfter is non-zero while any of them isn't healthy yet
+func (r *AlertChannelReconciler) awaitCanaries(ctx context.Context, ac *checklyv1alpha1.AlertChannel) (retryAfter time.Duration, err error) {
+	cohort, ok := ac.GetLabels()[r.RolloutLabel]
+	if !ok {
+		return 0, nil
+	}
+
+	canaries := &checklyv1alpha1.AlertChannelList{}
+	err = r.List(ctx, canaries, client.MatchingLabels{
+		r.RolloutLabel: cohort,
+		fmt.Sprintf("%s/canary", r.ControllerDomain): "true",
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(canaries.Items) == 0 {
+		return 0, nil
+	}
+
+	var pending []string
+	now := time.Now()
+	for _, canary := range canaries.Items {
+		reason, soakLeft := canaryPending(canary, r.CanarySoak, now)
+		if reason == "" {
+			continue
+		}
+		pending = append(pending, reason)
+		// Canaries which haven't synced are polled, the cohort is also enqueued once they change
+		if soakLeft == 0 {
+			soakLeft = rolloutPollInterval
+		}
+		if retryAfter == 0 || soakLeft < retryAfter {
+			retryAfter = soakLeft
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:               ConditionRolloutPending,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonCanaryHealthy,
+		Message:            fmt.Sprintf("The canaries labeled %s=%s are healthy", r.RolloutLabel, cohort),
+		ObservedGeneration: ac.Generation,
+	}
+	if len(pending) != 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = ReasonAwaitingCanary
+		condition.Message = strings.Join(pending, "; ")
+	}
+
+	return retryAfter, r.setCondition(ctx, ac, condition)
+}
+
+// rolloutCohort returns the AlertChannels sharing the rollout label value of the supplied canary, so they continue
+// the rollout once it's healthy
+func (r *AlertChannelReconciler) rolloutCohort(ctx context.Context, o client.Object) (requests []reconcile.Request) {
+	if r.RolloutLabel == "" || o.GetLabels()[fmt.Sprintf("%s/canary", r.ControllerDomain)] != "true" {
+		return
+	}
+	cohort, ok := o.GetLabels()[r.RolloutLabel]
+	if !ok {
+		return
+	}
+
+	members := &checklyv1alpha1.AlertChannelList{}
+	err := r.List(ctx, members, client.MatchingLabels{r.RolloutLabel: cohort})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list AlertChannel rollout cohort", "label", r.RolloutLabel, "value", cohort)
+		return
+	}
+
+	for _, member := range members.Items {
+		if member.Name != o.GetName() && !r.canary(&member) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: member.Name}})
+		}
+	}
+	return
+}
+
+// recordResult reports the outcome of the reconciliation in the Ready condition, successful reconciliations also
+// record the generation they observed, so GitOps tools can tell when the AlertChannel is in sync
+func (r *AlertChannelReconciler) recordResult(ctx context.Context, ac *checklyv1alpha1.AlertChannel, reconcileErr error) error {
+	if ac.CreationTimestamp.IsZero() || ac.GetDeletionTimestamp() != nil {
+		return nil
+	}
+
+	condition := metav1.Condition{
+		Type:               ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonSynced,
+		Message:            "The AlertChannel is synced to checklyhq.com",
+		ObservedGeneration: ac.Generation,
+	}
+	// Create only mode leaves changes to existing AlertChannels out on purpose
+	createdOnly := r.CreateOnly && ac.Status.ID != 0
+	switch {
+	case reconcileErr != nil:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonSyncFailed
+		condition.Message = reconcileErr.Error()
+	case ac.Status.SyncedGeneration != ac.Generation && !createdOnly:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonSyncPending
+		condition.Message = fmt.Sprintf("Generation %d is not synced to checklyhq.com yet, the other conditions tell why", ac.Generation)
+	// A successful write isn't enough, the AlertChannel read back from checklyhq.com has to match the generation
+	case r.VerifiedReady && !verifiedGeneration(ac) && !createdOnly:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonUnverified
+		condition.Message = fmt.Sprintf("Generation %d is synced to checklyhq.com but not verified yet, see the Verified condition", ac.Generation)
+	}
+
+	changed := meta.SetStatusCondition(&ac.Status.Conditions, condition)
+	if reconcileErr == nil && ac.Status.ObservedGeneration != ac.Generation {
+		ac.Status.ObservedGeneration = ac.Generation
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	return r.Status().Update(ctx, ac)
+}
+
+// verifiedGeneration reports if the AlertChannel read back from checklyhq.com matched the current generation
+func verifiedGeneration(ac *checklyv1alpha1.AlertChannel) bool {
+	verified := meta.FindStatusCondition(ac.Status.Conditions, ConditionVerified)
+	return verified != nil && verified.Status == metav1.ConditionTrue && verified.ObservedGeneration == ac.Generation
+}
+
+// checkPending flags the AlertChannel with the StalePending condition and a warning event if it hasn't been synced
+// to checklyhq.com within MaxPendingAge of its creation, the condition is removed once it's synced
+func (r *AlertChannelReconciler) checkPending(ctx context.Context, ac *checklyv1alpha1.AlertChannel) error {
+	if r.MaxPendingAge == 0 || ac.CreationTimestamp.IsZero() || ac.GetDeletionTimestamp() != nil || r.dryRun(ac) {
+		return nil
+	}
+
+	if ac.Status.ID != 0 {
+		if meta.FindStatusCondition(ac.Status.Conditions, ConditionStalePending) == nil {
+			return nil
+		}
+		meta.RemoveStatusCondition(&ac.Status.Conditions, ConditionStalePending)
+		return r.Status().Update(ctx, ac)
+	}
+
+	if time.Since(ac.CreationTimestamp.Time) < r.MaxPendingAge {
+		return nil
+	}
+
+	message := fmt.Sprintf("AlertChannel has not been synced to checklyhq.com within %s of its creation", r.MaxPendingAge)
+	changed := meta.SetStatusCondition(&ac.Status.Conditions, metav1.Condition{
+		Type:               ConditionStalePending,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonNotSynced,
+		Message:            message,
+		ObservedGeneration: ac.Generation,
+	})
+	if !changed {
+		return nil
+	}
+
+	r.Recorder.Event(ac, corev1.EventTypeWarning, ConditionStalePending, message)
+	return r.Status().Update(ctx, ac)
+}
+
+// setSecretCondition records the outcome of the secret validation on the AlertChannel status
+func (r *AlertChannelReconciler) setSecretCondition(ctx context.Context, ac *checklyv1alpha1.AlertChannel, secretErr error) error {
+	condition := metav1.Condition{
+		Type:               ConditionSecretValid,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonSecretValid,
+		Message:            "Secret holds a well-formed value",
+		ObservedGeneration: ac.Generation,
+	}
+	if secretErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonSecretInvalid
+		condition.Message = secretErr.Error()
+	}
+
+	return r.setCondition(ctx, ac, condition)
+}
+
+// setCredentialCondition records the outcome of the OpsGenie API key validation on the AlertChannel status, keys which
+// couldn't be checked are reported with an unknown status
+func (r *AlertChannelReconciler) setCredentialCondition(ctx context.Context, ac *checklyv1alpha1.AlertChannel, credentialErr error) error {
+	condition := metav1.Condition{
+		Type:               ConditionCredentialValid,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonKeyAccepted,
+		Message:            "OpsGenie accepts the API key",
+		ObservedGeneration: ac.Generation,
+	}
+	switch {
+	case errs.Is(credentialErr, external.ErrOpsGenieKeyRejected):
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonKeyRejected
+		condition.Message = credentialErr.Error()
+	case credentialErr != nil:
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = ReasonKeyUnchecked
+		condition.Message = credentialErr.Error()
+	}
+
+	return r.setCondition(ctx, ac, condition)
+}
+
+// validateClientCert reads the certificate and key of the referenced kubernetes.io/tls secret and makes sure they
+// form a valid pair
+func (r *AlertChannelReconciler) validateClientCert(ctx context.Context, ref corev1.ObjectReference) error {
+	ref.FieldPath = corev1.TLSCertKey
+	cert, err := r.secretValue(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	ref.FieldPath = corev1.TLSPrivateKeyKey
+	key, err := r.secretValue(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	return external.ValidateClientCertificate(cert, key, time.Now())
+}
+
+// setClientCertCondition records the outcome of the webhook client certificate validation on the AlertChannel status
+func (r *AlertChannelReconciler) setClientCertCondition(ctx context.Context, ac *checklyv1alpha1.AlertChannel, certErr error) error {
+	condition := metav1.Condition{
+		Type:               ConditionClientCertValid,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonCertValid,
+		Message:            "Secret holds a valid client certificate and key",
+		ObservedGeneration: ac.Generation,
+	}
+	if certErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonCertInvalid
+		condition.Message = certErr.Error()
+	}
+
+	return r.setCondition(ctx, ac, condition)
+}
+
+// setTemplateCondition records the outcome of the webhook template validation on the AlertChannel status
+func (r *AlertChannelReconciler) setTemplateCondition(ctx context.Context, ac *checklyv1alpha1.AlertChannel, templateErr error) error {
+	condition := metav1.Condition{
+		Type:               ConditionTemplateValid,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonTemplateValid,
+		Message:            "Webhook template renders to valid JSON",
+		ObservedGeneration: ac.Generation,
+	}
+	if templateErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonTemplateInvalid
+		condition.Message = templateErr.Error()
+	}
+
+	return r.setCondition(ctx, ac, condition)
+}
+
+// setWebhookURLCondition sets the WebhookURLValid condition of the AlertChannel to the outcome of the webhook URL
+// validation
+func (r *AlertChannelReconciler) setWebhookURLCondition(ctx context.Context, ac *checklyv1alpha1.AlertChannel, urlErr error) error {
+	condition := metav1.Condition{
+		Type:               ConditionWebhookURLValid,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonURLAllowed,
+		Message:            "Webhook URL resolves to public or allowlisted addresses",
+		ObservedGeneration: ac.Generation,
+	}
+	if urlErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonURLBlocked
+		condition.Message = urlErr.Error()
+	}
+
+	return r.setCondition(ctx, ac, condition)
+}
+
+// stateBefore describes the AlertChannel last applied to checklyhq.com for the audit log, with the changes read from it
+func (r *AlertChannelReconciler) stateBefore(ac *checklyv1alpha1.AlertChannel, changes []external.AlertChannelChange) *audit.State {
+	state := &audit.State{
+		Generation:  ac.Status.SyncedGeneration,
+		AppliedHash: ac.GetAnnotations()[r.lastAppliedHashAnnotation()],
+	}
+	for _, change := range changes {
+		if state.Values == nil {
+			state.Values = make(map[string]interface{}, len(changes))
+		}
+		state.Differed = append(state.Differed, change.Field)
+		state.Values[change.Field] = change.From
+	}
+	return state
+}
+
+// formatChanges lists the changes in a single line for events
+func formatChanges(changes []external.AlertChannelChange) string {
+	lines := make([]string, len(changes))
+	for i, change := range changes {
+		lines[i] = change.String()
+	}
+	return strings.Join(lines, ", ")
+}
+
+// holdDestructiveChanges determines if the update has to be held because it contains destructive changes which aren't
+// confirmed with the confirm-destructive annotation, the outcome is recorded in the ConfirmationRequired condition
+func (r *AlertChannelReconciler) holdDestructiveChanges(ctx context.Context, ac *checklyv1alpha1.AlertChannel, changes []external.AlertChannelChange) (held bool, confirmed bool, err error) {
+	var destructive []external.AlertChannelChange
+	for _, change := range changes {
+		if change.Destructive() {
+			destructive = append(destructive, change)
+		}
+	}
+
+	confirmAnnotation := fmt.Sprintf("%s/confirm-destructive", r.ControllerDomain)
+	condition := metav1.Condition{
+		Type:               ConditionConfirmationRequired,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonNotDestructive,
+		Message:            "No destructive changes pending",
+		ObservedGeneration: ac.Generation,
+	}
+	switch {
+	case len(destructive) == 0:
+	case ac.GetAnnotations()[confirmAnnotation] == "true":
+		confirmed = true
+		condition.Reason = ReasonConfirmed
+		condition.Message = fmt.Sprintf("Destructive changes confirmed: %s", formatChanges(destructive))
+	default:
+		held = true
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = ReasonUnconfirmed
+		condition.Message = fmt.Sprintf("Destructive changes have to be confirmed with the %s: \"true\" annotation: %s", confirmAnnotation, formatChanges(destructive))
+	}
+
+	err = r.setCondition(ctx, ac, condition)
+	return
+}
+
+// removeConfirmation removes the confirm-destructive annotation once the confirmed change was made
+func (r *AlertChannelReconciler) removeConfirmation(ctx context.Context, ac *checklyv1alpha1.AlertChannel) error {
+	patch := client.MergeFromWithOptions(ac.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	annotations := ac.GetAnnotations()
+	delete(annotations, fmt.Sprintf("%s/confirm-destructive", r.ControllerDomain))
+	ac.SetAnnotations(annotations)
+
+	return r.Patch(ctx, ac, patch)
+}
+
+// unknownSpecFields returns the spec fields of the AlertChannel stored in the cluster which the operator doesn't know
+// about, the typed object already lost them while being decoded
+func (r *AlertChannelReconciler) unknownSpecFields(ctx context.Context, ac *checklyv1alpha1.AlertChannel) ([]string, error) {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(checklyv1alpha1.GroupVersion.WithKind("AlertChannel"))
+	err := r.Get(ctx, client.ObjectKeyFromObject(ac), u)
+	if err != nil {
+		return nil, err
+	}
+
+	return unknownFields(u.Object["spec"], reflect.TypeOf(ac.Spec), "spec"), nil
+}
+
+// setUnknownFieldsCondition records the spec fields unknown to the operator on the AlertChannel status
+func (r *AlertChannelReconciler) setUnknownFieldsCondition(ctx context.Context, ac *checklyv1alpha1.AlertChannel, fields []string) error {
+	condition := metav1.Condition{
+		Type:               ConditionUnknownFields,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonNoUnknownFields,
+		Message:            "All spec fields are known to the operator",
+		ObservedGeneration: ac.Generation,
+	}
+	if len(fields) != 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = ReasonFieldsIgnored
+		condition.Message = fmt.Sprintf("Fields unknown to the operator are not synced: %s", strings.Join(fields, ", "))
+		if r.UnknownFields == UnknownFieldsReject {
+			condition.Reason = ReasonFieldsRejected
+			condition.Message = fmt.Sprintf("AlertChannel is not synced, it sets fields unknown to the operator: %s", strings.Join(fields, ", "))
+		}
+	}
+
+	return r.setCondition(ctx, ac, condition)
+}
+
+// setSanitizedCondition records the fixes made to sync the AlertChannel on a best-effort basis, new fixes are also
+// reported with a warning event
+func (r *AlertChannelReconciler) setSanitizedCondition(ctx context.Context, ac *checklyv1alpha1.AlertChannel, warnings []string) error {
+	condition := metav1.Condition{
+		Type:               ConditionSanitized,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonValid,
+		Message:            "AlertChannel passed validation and is synced as is",
+		ObservedGeneration: ac.Generation,
+	}
+	if len(warnings) != 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = ReasonBestEffort
+		condition.Message = fmt.Sprintf("AlertChannel failed validation and is synced on a best-effort basis: %s", strings.Join(warnings, "; "))
+
+		previous := meta.FindStatusCondition(ac.Status.Conditions, ConditionSanitized)
+		if previous == nil || previous.Message != condition.Message {
+			r.Recorder.Event(ac, corev1.EventTypeWarning, ConditionSanitized, condition.Message)
+		}
+	}
+
+	return r.setCondition(ctx, ac, condition)
+}
+
+// selfTest sends a test alert to AlertChannels with the self-test annotation once per generation and records whether
+// it was delivered in the SelfTest condition. Failed deliveries don't fail the reconciliation, retrying them would only
+// send more notifications.
+func (r *AlertChannelReconciler) selfTest(ctx context.Context, ac *checklyv1alpha1.AlertChannel, resolved *checklyv1alpha1.AlertChannel) error {
+	if ac.GetAnnotations()[fmt.Sprintf("%s/self-test", r.ControllerDomain)] != "true" {
+		return nil
+	}
+
+	previous := meta.FindStatusCondition(ac.Status.Conditions, ConditionSelfTest)
+	if previous != nil && previous.ObservedGeneration == ac.Generation {
+		return nil
+	}
+
+	testErr := external.SendTestAlert(ctx, resolved)
+	condition := metav1.Condition{
+		Type:               ConditionSelfTest,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonDelivered,
+		Message:            "Test alert was delivered",
+		ObservedGeneration: ac.Generation,
+	}
+	switch {
+	case errs.Is(testErr, external.ErrTestAlertUnsupported):
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = ReasonUnsupported
+		condition.Message = testErr.Error()
+	case testErr != nil:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonUndelivered
+		condition.Message = testErr.Error()
+		r.Recorder.Eventf(ac, corev1.EventTypeWarning, "SelfTestFailed", "Test alert was not delivered: %s", testErr)
+	default:
+		r.Recorder.Event(ac, corev1.EventTypeNormal, "SelfTest", "Test alert was delivered")
+	}
+
+	return r.setCondition(ctx, ac, condition)
+}
+
+// verify reads the AlertChannel back from checklyhq.com and records the outcome in the Verified condition, a mismatch
+// fails the reconciliation so the write is retried
+func (r *AlertChannelReconciler) verify(ctx context.Context, ac *checklyv1alpha1.AlertChannel, resolved *checklyv1alpha1.AlertChannel, opsGenieConfig checkly.AlertChannelOpsgenie) error {
+	resolved.Status.ID = ac.Status.ID
+	verifyErr := external.VerifyAlertChannel(resolved, opsGenieConfig, r.ApiClient)
+
+	condition := metav1.Condition{
+		Type:               ConditionVerified,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonVerified,
+		Message:            "checkly AlertChannel matches the desired state",
+		ObservedGeneration: ac.Generation,
+	}
+	if verifyErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonNotVerified
+		condition.Message = verifyErr.Error()
+	}
+
+	err := r.setCondition(ctx, ac, condition)
+	if err != nil {
+		return err
+	}
+
+	return verifyErr
+}
+
+// setCondition updates the AlertChannel status if the condition changed
+func (r *AlertChannelReconciler) setCondition(ctx context.Context, ac *checklyv1alpha1.AlertChannel, condition metav1.Condition) error {
+	if !meta.SetStatusCondition(&ac.Status.Conditions, condition) {
+		return nil
+	}
+
+	return r.Status().Update(ctx, ac)
+}
+
+// warnDuplicates emits an advisory event if other AlertChannels were synced with the same configuration
+func (r *AlertChannelReconciler) warnDuplicates(ctx context.Context, ac *checklyv1alpha1.AlertChannel, configHash string) error {
+	alertChannels := &checklyv1alpha1.AlertChannelList{}
+	err := r.List(ctx, alertChannels, client.MatchingFields{alertChannelConfigHashIndex: configHash})
+	if err != nil {
+		return err
+	}
+
+	var duplicates []string
+	for _, alertChannel := range alertChannels.Items {
+		if alertChannel.Name != ac.Name {
+			duplicates = append(duplicates, alertChannel.Name)
+		}
+	}
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	r.Recorder.Eventf(ac, corev1.EventTypeWarning, "DuplicateConfig", "AlertChannel has the same configuration as %s, consider consolidating them", strings.Join(duplicates, ", "))
+	return nil
+}
+
+// resolveSpec returns the spec of the AlertChannel with the unset fields inherited from its chain of parents
+func (r *AlertChannelReconciler) resolveSpec(ctx context.Context, ac *checklyv1alpha1.AlertChannel) (spec checklyv1alpha1.AlertChannelSpec, err error) {
+	spec = ac.Spec
+
+	// The chain of parents is followed until it ends, a parent showing up twice would be followed forever
+	chain := []string{ac.Name}
+	for parentName := ac.Spec.ParentRef; parentName != ""; {
+		if cycle := parentRefCycle(chain, parentName); cycle != "" {
+			err = fmt.Errorf("AlertChannel parent references form a cycle: %s", cycle)
+			return
+		}
+		chain = append(chain, parentName)
+
+		parent := &checklyv1alpha1.AlertChannel{}
+		err = r.Get(ctx, types.NamespacedName{Name: parentName}, parent)
+		if err != nil {
+			return
+		}
+
+		spec = external.InheritAlertChannelSpec(parent.Spec, spec)
+		parentName = parent.Spec.ParentRef
+	}
+
+	// The policy fills the fields neither the AlertChannel nor its parents set, its reference is inherited too
+	if spec.PolicyRef != "" {
+		alertPolicy := &checklyv1alpha1.AlertPolicy{}
+		err = r.Get(ctx, types.NamespacedName{Name: spec.PolicyRef}, alertPolicy)
+		if err != nil {
+			return
+		}
+
+		spec, err = external.ApplyAlertPolicy(alertPolicy.Spec, spec)
+		if err != nil {
+			err = fmt.Errorf("AlertPolicy %s: %w", alertPolicy.Name, err)
+		}
+	}
+
+	return
+}
+
+// parentRefCycle returns the cycle the parent closes in the chain of AlertChannels, ex. foo -> bar -> foo, empty if it
+// doesn't close one
+func parentRefCycle(chain []string, parentName string) string {
+	index := slices.Index(chain, parentName)
+	if index == -1 {
+		return ""
+	}
+	return strings.Join(append(slices.Clone(chain[index:]), parentName), " -> ")
+}
+
+// policyMembers returns reconcile requests for every AlertChannel referencing the supplied AlertPolicy, directly or
+// through its parents
+func (r *AlertChannelReconciler) policyMembers(ctx context.Context, o client.Object) (requests []reconcile.Request) {
+	members := &checklyv1alpha1.AlertChannelList{}
+	err := r.List(ctx, members, client.MatchingFields{alertChannelPolicyIndex: o.GetName()})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list AlertChannels referencing AlertPolicy", "policy", o.GetName())
+		return
+	}
+
+	visited := map[string]bool{}
+	for i := range members.Items {
+		member := &members.Items[i]
+		inheriting := append(r.childrenOf(ctx, member), reconcile.Request{NamespacedName: types.NamespacedName{Name: member.Name}})
+		for _, request := range inheriting {
+			if !visited[request.Name] {
+				visited[request.Name] = true
+				requests = append(requests, request)
+			}
+		}
+	}
+	return
+}
+
+// childrenOf returns reconcile requests for every AlertChannel inheriting from the supplied one, directly or through other parents
+func (r *AlertChannelReconciler) childrenOf(ctx context.Context, o client.Object) (requests []reconcile.Request) {
+	logger := log.FromContext(ctx)
+
+	visited := map[string]bool{o.GetName(): true}
+	parents := []string{o.GetName()}
+	for len(parents) != 0 {
+		children := &checklyv1alpha1.AlertChannelList{}
+		err := r.List(ctx, children, client.MatchingFields{alertChannelParentIndex: parents[0]})
+		if err != nil {
+			logger.Error(err, "Failed to list children of AlertChannel", "parent", parents[0])
+			return
+		}
+		parents = parents[1:]
+
+		for _, child := range children.Items {
+			if visited[child.Name] {
+				continue
+			}
+			visited[child.Name] = true
+			parents = append(parents, child.Name)
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: child.Name}})
+		}
+	}
+
+	return
+}
+
+// secretValue returns the value of the referenced secret field, from the secret cache if it's enabled
+func (r *AlertChannelReconciler) secretValue(ctx context.Context, ref corev1.ObjectReference) (string, error) {
+	if r.SecretCache != nil {
+		return r.SecretCache.Get(ctx, ref)
+	}
+
+	return GetSecretValue(ctx, r.Client, ref)
+}
+
+// secretChanged drops the cached values of the changed secret and returns the AlertChannels using it, so the new
+// value is synced right away
+func (r *AlertChannelReconciler) secretChanged(ctx context.Context, o client.Object) (requests []reconcile.Request) {
+	r.SecretCache.Invalidate(o.GetNamespace(), o.GetName())
+
+	alertChannels := &checklyv1alpha1.AlertChannelList{}
+	err := r.List(ctx, alertChannels)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list AlertChannels using secret", "secret", client.ObjectKeyFromObject(o))
+		return
+	}
+
+	for _, ac := range alertChannels.Items {
+		for _, secret := range []corev1.ObjectReference{ac.Spec.OpsGenie.APISecret, ac.Spec.Webhook.ClientCertSecret, ac.Spec.Slack.URLSecret, ac.Spec.PagerDuty.ServiceKeySecret} {
+			if secret.Namespace == o.GetNamespace() && secret.Name == o.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: ac.Name}})
+				break
+			}
+		}
+	}
+	return
+}
+
+// checkParity compares the fields set on the AlertChannel with the AlertChannels sharing its parity label value, ex.
+// the same alert channel in other environments, and reports the divergence in the ParityDrift condition
+func (r *AlertChannelReconciler) checkParity(ctx context.Context, ac *checklyv1alpha1.AlertChannel) error {
+	identity, ok := ac.GetLabels()[r.ParityLabel]
+	if !ok {
+		return nil
+	}
+
+	peers := &checklyv1alpha1.AlertChannelList{}
+	err := r.List(ctx, peers, client.MatchingLabels{r.ParityLabel: identity})
+	if err != nil {
+		return err
+	}
+
+	paths, err := fieldPaths(ac.Spec, "spec")
+	if err != nil {
+		return err
+	}
+
+	var drift []string
+	for _, peer := range peers.Items {
+		if peer.Name == ac.Name {
+			continue
+		}
+
+		peerPaths, err := fieldPaths(peer.Spec, "spec")
+		if err != nil {
+			return err
+		}
+		if peerDrift := parityDrift(paths, peer.Name, peerPaths); peerDrift != "" {
+			drift = append(drift, peerDrift)
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:               ConditionParityDrift,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonInParity,
+		Message:            fmt.Sprintf("Same fields set as the other AlertChannels labeled %s=%s", r.ParityLabel, identity),
+		ObservedGeneration: ac.Generation,
+	}
+	if len(drift) != 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = ReasonDrifted
+		condition.Message = strings.Join(drift, "; ")
+	}
+
+	return r.setCondition(ctx, ac, condition)
+}
+
+// parityPeers returns the AlertChannels sharing the parity label value of the supplied AlertChannel, so they're
+// checked again when it changes
+func (r *AlertChannelReconciler) parityPeers(ctx context.Context, o client.Object) (requests []reconcile.Request) {
+	if r.ParityLabel == "" {
+		return
+	}
+	identity, ok := o.GetLabels()[r.ParityLabel]
+	if !ok {
+		return
+	}
+
+	peers := &checklyv1alpha1.AlertChannelList{}
+	err := r.List(ctx, peers, client.MatchingLabels{r.ParityLabel: identity})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list AlertChannel parity peers", "label", r.ParityLabel, "value", identity)
+		return
+	}
+
+	for _, peer := range peers.Items {
+		if peer.Name != o.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: peer.Name}})
+		}
+	}
+	return
+}
+
+// validatePolicies evaluates the CEL policies held in the policy ConfigMap against the AlertChannel
+func (r *AlertChannelReconciler) validatePolicies(ctx context.Context, ac *checklyv1alpha1.AlertChannel) error {
+	cm := &corev1.ConfigMap{}
+	err := r.Get(ctx, r.PolicyConfigMap, cm)
+	if err != nil {
+		return fmt.Errorf("unable to read policy ConfigMap: %w", err)
+	}
+
+	policies, err := policy.Compile(cm.Data)
+	if err != nil {
+		return err
+	}
+
+	return policies.Evaluate(ac.ObjectMeta, ac.Spec)
+}
+
+// alertingGroups returns the names of the groups referencing the AlertChannel which still alert to it in checklyhq.com
+func (r *AlertChannelReconciler) alertingGroups(ctx context.Context, ac *checklyv1alpha1.AlertChannel) (names []string, err error) {
+	if ac.Status.ID == 0 {
+		return
+	}
+
+	groups := &checklyv1alpha1.GroupList{}
+	err = r.List(ctx, groups, client.MatchingFields{groupAlertChannelsIndex: ac.Name})
+	if err != nil {
+		return
+	}
+
+	for _, group := range groups.Items {
+		if group.Status.ID == 0 {
+			continue
+		}
+
+		var alerts bool
+		alerts, err = external.GroupAlertsTo(group.Status.ID, ac.Status.ID, r.ApiClient)
+		if err != nil {
+			return
+		}
+		if alerts {
+			names = append(names, group.Name)
+		}
+	}
+
+	return
+}
+
+// detachFromGroups removes the AlertChannel from every group referencing it, so they're not left with a dangling reference
+func (r *AlertChannelReconciler) detachFromGroups(ctx context.Context, ac *checklyv1alpha1.AlertChannel) error {
+	logger := log.FromContext(ctx)
+
+	groups := &checklyv1alpha1.GroupList{}
+	err := r.List(ctx, groups, client.MatchingFields{groupAlertChannelsIndex: ac.Name})
+	if err != nil {
+		return err
+	}
+
+	for i := range groups.Items {
+		group := &groups.Items[i]
+
+		var alertChannels []string
+		for _, alertChannel := range group.Spec.AlertChannels {
+			if alertChannel != ac.Name {
+				alertChannels = append(alertChannels, alertChannel)
+			}
+		}
+		group.Spec.AlertChannels = alertChannels
+		group.Spec.AlertChannelSubscriptions = withoutAlertChannel(group.Spec.AlertChannelSubscriptions, ac.Name)
+
+		err = r.Update(ctx, group)
+		if err != nil {
+			return err
+		}
+		logger.V(1).Info("Removed AlertChannel from group", "group", group.Name)
+	}
+
+	return nil
+}
+
+// detachFromChecks removes the subscriptions to the AlertChannel from every API check, so they're not left with a
+// dangling reference
+func (r *AlertChannelReconciler) detachFromChecks(ctx context.Context, ac *checklyv1alpha1.AlertChannel) error {
+	logger := log.FromContext(ctx)
+
+	checks := &checklyv1alpha1.ApiCheckList{}
+	err := r.List(ctx, checks, client.MatchingFields{apiCheckAlertChannelsIndex: ac.Name})
+	if err != nil {
+		return err
+	}
+
+	for i := range checks.Items {
+		check := &checks.Items[i]
+		check.Spec.AlertChannelSubscriptions = withoutAlertChannel(check.Spec.AlertChannelSubscriptions, ac.Name)
+
+		err = r.Update(ctx, check)
+		if err != nil {
+			return err
+		}
+		logger.V(1).Info("Removed AlertChannel from API check", "check", client.ObjectKeyFromObject(check))
+	}
+
+	return nil
+}
+
+// attachToGroups adds the AlertChannel to the groups it's attached to in bulk, groups which don't exist or aren't
+// synced to checklyhq.com yet are left out and reported in the GroupsPending condition, pending reports there are any
+func (r *AlertChannelReconciler) attachToGroups(ctx context.Context, ac *checklyv1alpha1.AlertChannel) (pending bool, err error) {
+	logger := log.FromContext(ctx)
+
+	if len(ac.Spec.Groups) == 0 {
+		if meta.FindStatusCondition(ac.Status.Conditions, ConditionGroupsPending) == nil {
+			return false, nil
+		}
+		meta.RemoveStatusCondition(&ac.Status.Conditions, ConditionGroupsPending)
+		return false, r.Status().Update(ctx, ac)
+	}
+
+	var missing []string
+	for _, name := range ac.Spec.Groups {
+		group := &checklyv1alpha1.Group{}
+		err = r.Get(ctx, types.NamespacedName{Name: name}, group)
+		if errors.IsNotFound(err) {
+			missing = append(missing, fmt.Sprintf("%s (not found)", name))
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if group.Status.ID == 0 || group.Status.ObservedGeneration != group.Generation {
+			missing = append(missing, fmt.Sprintf("%s (not synced)", name))
+			continue
+		}
+		if slices.Contains(group.Spec.AlertChannels, ac.Name) || slices.Contains(subscribedAlertChannels(group.Spec.AlertChannelSubscriptions), ac.Name) {
+			continue
+		}
+		if group.Status.AccountID != ac.Status.AccountID {
+			return false, fmt.Errorf("group %s is managed in checklyhq.com account %s, the AlertChannel in account %s", group.Name, accountName(group.Status.AccountID), accountName(ac.Status.AccountID))
+		}
+
+		group.Spec.AlertChannels = append(group.Spec.AlertChannels, ac.Name)
+		err = r.Update(ctx, group)
+		if err != nil {
+			return false, err
+		}
+		logger.V(1).Info("Attached AlertChannel to group", "group", group.Name)
+	}
+
+	condition := metav1.Condition{
+		Type:               ConditionGroupsPending,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonGroupsAttached,
+		Message:            fmt.Sprintf("Attached to the groups %s", strings.Join(ac.Spec.Groups, ", ")),
+		ObservedGeneration: ac.Generation,
+	}
+	if len(missing) != 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = ReasonGroupsMissing
+		condition.Message = fmt.Sprintf("Waiting for the groups %s", strings.Join(missing, ", "))
+	}
+
+	return len(missing) != 0, r.setCondition(ctx, ac, condition)
+}
+
+// attachedAlertChannels returns the AlertChannels attached to the supplied group in bulk, so they're attached once it's
+// synced
+func (r *AlertChannelReconciler) attachedAlertChannels(ctx context.Context, o client.Object) (requests []reconcile.Request) {
+	alertChannels := &checklyv1alpha1.AlertChannelList{}
+	err := r.List(ctx, alertChannels, client.MatchingFields{alertChannelGroupsIndex: o.GetName()})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list AlertChannels attached to group", "group", o.GetName())
+		return
+	}
+
+	for _, ac := range alertChannels.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: ac.Name}})
+	}
+	return
+}
+
+// unsubscribe removes the subscriptions to the AlertChannel from the checks and groups managed by the operator in
+// checklyhq.com, so they're not left with dangling subscriptions
+func (r *AlertChannelReconciler) unsubscribe(ctx context.Context, ac *checklyv1alpha1.AlertChannel) error {
+	logger := log.FromContext(ctx)
+
+	if ac.Status.ID == 0 {
+		return nil
+	}
+
+	checks := &checklyv1alpha1.ApiCheckList{}
+	err := r.List(ctx, checks)
+	if err != nil {
+		return err
+	}
+
+	for _, check := range checks.Items {
+		if check.Status.ID == "" {
+			continue
+		}
+
+		unsubscribed, err := external.Unsubscribe(check.Status.ID, ac.Status.ID, r.ApiClient)
+		if err != nil {
+			return err
+		}
+		if unsubscribed {
+			logger.V(1).Info("Removed AlertChannel subscription from check", "check", check.Name, "checkly ID", check.Status.ID)
+		}
+	}
+
+	groups := &checklyv1alpha1.GroupList{}
+	err = r.List(ctx, groups)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups.Items {
+		if group.Status.ID == 0 {
+			continue
+		}
+
+		unsubscribed, err := external.GroupUnsubscribe(group.Status.ID, ac.Status.ID, r.ApiClient)
+		if err != nil {
+			return err
+		}
+		if unsubscribed {
+			logger.V(1).Info("Removed AlertChannel subscription from group", "group", group.Name, "checkly group ID", group.Status.ID)
+		}
+	}
+
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *AlertChannelReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Index groups by the alert channels they reference, this is used to detach deleted alert channels
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), &checklyv1alpha1.Group{}, groupAlertChannelsIndex, func(o client.Object) []string {
+		group := o.(*checklyv1alpha1.Group)
+		return append(subscribedAlertChannels(group.Spec.AlertChannelSubscriptions), group.Spec.AlertChannels...)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Index API checks by the alert channels they subscribe to, this is used to detach deleted alert channels
+	err = mgr.GetFieldIndexer().IndexField(context.Background(), &checklyv1alpha1.ApiCheck{}, apiCheckAlertChannelsIndex, func(o client.Object) []string {
+		check := o.(*checklyv1alpha1.ApiCheck)
+		return subscribedAlertChannels(check.Spec.AlertChannelSubscriptions)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Index alert channels by their parent, this is used to re-reconcile children when the parent changes
+	err = mgr.GetFieldIndexer().IndexField(context.Background(), &checklyv1alpha1.AlertChannel{}, alertChannelParentIndex, func(o client.Object) []string {
+		ac := o.(*checklyv1alpha1.AlertChannel)
+		if ac.Spec.ParentRef == "" {
+			return nil
+		}
+		return []string{ac.Spec.ParentRef}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Index alert channels by their policy, this is used to re-reconcile them when the policy changes
+	err = mgr.GetFieldIndexer().IndexField(context.Background(), &checklyv1alpha1.AlertChannel{}, alertChannelPolicyIndex, func(o client.Object) []string {
+		ac := o.(*checklyv1alpha1.AlertChannel)
+		if ac.Spec.PolicyRef == "" {
+			return nil
+		}
+		return []string{ac.Spec.PolicyRef}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Index alert channels by the groups they're attached to in bulk, this is used to attach them once a group is synced
+	err = mgr.GetFieldIndexer().IndexField(context.Background(), &checklyv1alpha1.AlertChannel{}, alertChannelGroupsIndex, func(o client.Object) []string {
+		return o.(*checklyv1alpha1.AlertChannel).Spec.Groups
+	})
+	if err != nil {
+		return err
+	}
+
+	// Index alert channels by their configuration hash, this is used to detect duplicates
+	err = mgr.GetFieldIndexer().IndexField(context.Background(), &checklyv1alpha1.AlertChannel{}, alertChannelConfigHashIndex, func(o client.Object) []string {
+		ac := o.(*checklyv1alpha1.AlertChannel)
+		if ac.Status.ConfigHash == "" {
+			return nil
+		}
+		return []string{ac.Status.ConfigHash}
+	})
+	if err != nil {
+		return err
+	}
+
+	r.quota = newQuotaReservations()
+
+	// The controller is named explicitly, it's the controller and name label of the controller-runtime metrics
+	workers := max(r.Workers, 1)
+	metrics.SetWorkers("alertchannel", workers)
+
+	b := ctrl.NewControllerManagedBy(mgr).
+		Named("alertchannel").
+		For(&checklyv1alpha1.AlertChannel{}).
+		WithOptions(controller.Options{RateLimiter: r.RetryLimiter, MaxConcurrentReconciles: workers}).
+		Watches(&checklyv1alpha1.AlertChannel{}, handler.EnqueueRequestsFromMapFunc(r.childrenOf)).
+		Watches(&checklyv1alpha1.AlertChannel{}, handler.EnqueueRequestsFromMapFunc(r.parityPeers)).
+		Watches(&checklyv1alpha1.AlertChannel{}, handler.EnqueueRequestsFromMapFunc(r.rolloutCohort)).
+		Watches(&checklyv1alpha1.AlertPolicy{}, handler.EnqueueRequestsFromMapFunc(r.policyMembers)).
+		Watches(&checklyv1alpha1.Group{}, handler.EnqueueRequestsFromMapFunc(r.attachedAlertChannels))
+
+	// Only the metadata of secrets is watched to invalidate the cache, the values are read on demand
+	if r.SecretCache != nil {
+		b = b.Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.secretChanged), builder.OnlyMetadata)
+	}
+
+	return b.Complete(r)
 }
@@ -71,7 +71,7 @@ var _ = Describe("ApiCheck Controller", func() {
 			}
 
 			secretData := map[string][]byte{
-				"TEST": []byte("test"),
+				"TEST": []byte("01234567-89ab-cdef-0123-456789abcdef"),
 			}
 
 			alertChannel := &checklyv1alpha1.AlertChannel{
@@ -195,5 +195,77 @@ var _ = Describe("ApiCheck Controller", func() {
 			}, timeout, interval).Should(Succeed())
 		})
 		// return
+
+		It("Detaches from groups on delete", func() {
+
+			acKey := types.NamespacedName{
+				Name: "test-alert-channel",
+			}
+
+			groupKey := types.NamespacedName{
+				Name: "test-alert-channel-group",
+			}
+
+			alertChannel := &checklyv1alpha1.AlertChannel{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: acKey.Name,
+					Annotations: map[string]string{
+						"testing.domain.tld/force-delete": "true",
+					},
+				},
+				Spec: checklyv1alpha1.AlertChannelSpec{
+					Email: checkly.AlertChannelEmail{
+						Address: "foo@bar.baz",
+					},
+				},
+			}
+
+			group := &checklyv1alpha1.Group{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: groupKey.Name,
+				},
+				Spec: checklyv1alpha1.GroupSpec{
+					Locations:     []string{"eu-west-1"},
+					AlertChannels: []string{acKey.Name},
+				},
+			}
+
+			Expect(k8sClient.Create(context.Background(), alertChannel)).Should(Succeed())
+			Expect(k8sClient.Create(context.Background(), group)).Should(Succeed())
+
+			By("Expecting AlertChannel ID")
+			Eventually(func() bool {
+				f := &checklyv1alpha1.AlertChannel{}
+				err := k8sClient.Get(context.Background(), acKey, f)
+				return err == nil && f.Status.ID == 3
+			}, timeout, interval).Should(BeTrue())
+
+			By("Expecting to delete alertchannel successfully")
+			Eventually(func() error {
+				f := &checklyv1alpha1.AlertChannel{}
+				k8sClient.Get(context.Background(), acKey, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+
+			By("Expecting the group to drop the reference")
+			Eventually(func() bool {
+				f := &checklyv1alpha1.Group{}
+				err := k8sClient.Get(context.Background(), groupKey, f)
+				return err == nil && len(f.Spec.AlertChannels) == 0
+			}, timeout, interval).Should(BeTrue())
+
+			By("Expecting delete to finish")
+			Eventually(func() error {
+				f := &checklyv1alpha1.AlertChannel{}
+				return k8sClient.Get(context.Background(), acKey, f)
+			}, timeout, interval).ShouldNot(Succeed())
+
+			By("Expecting to delete group successfully")
+			Eventually(func() error {
+				f := &checklyv1alpha1.Group{}
+				k8sClient.Get(context.Background(), groupKey, f)
+				return k8sClient.Delete(context.Background(), f)
+			}, timeout, interval).Should(Succeed())
+		})
 	})
 })
@@ -19,32 +19,71 @@ package checkly
 import (
 	"context"
 	"fmt"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/checkly/checkly-go-sdk"
 	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
 	external "github.com/checkly/checkly-operator/external/checkly"
+	"github.com/checkly/checkly-operator/internal/audit"
+	"github.com/checkly/checkly-operator/internal/mapping"
+	"github.com/checkly/checkly-operator/internal/metrics"
+	"github.com/checkly/checkly-operator/internal/notify"
 )
 
+// apiCheckGroupIndex is the field index of the group of API checks
+const apiCheckGroupIndex = "spec.group"
+
+// apiCheckSnippetIndex is the field index of the setup and teardown snippets of API checks
+const apiCheckSnippetIndex = "spec.snippets"
+
 // ApiCheckReconciler reconciles a ApiCheck object
 type ApiCheckReconciler struct {
 	client.Client
 	Scheme           *runtime.Scheme
 	ApiClient        checkly.Client
 	ControllerDomain string
+	CreateOnly       bool
+	SkipFinalizer    bool
+	ReconcileSummary bool
+	Audit            *audit.Logger
+	Mapping          *mapping.ConfigMap
+	Notifier         *notify.Notifier
+	FinalizerTimeout time.Duration
+	DriftInterval    time.Duration
+	RetryLimiter     workqueue.RateLimiter
+	Workers          int
+	Breaker          *external.CircuitBreaker
+	Deprecations     *external.DeprecationTracker
+	Accounts         *external.Accounts
+	TagMapping       TagMapping
+	FallbackChannel  string
+	Recorder         record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=apichecks,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=apichecks/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=apichecks/finalizers,verbs=update
 //+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=groups,verbs=get;list
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=snippets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -55,18 +94,37 @@ type ApiCheckReconciler struct {
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.11.0/pkg/reconcile
-func (r *ApiCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *ApiCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.Result, err error) {
 	logger := log.FromContext(ctx)
+	defer metrics.WorkerStarted("apicheck")()
 
 	apiCheckFinalizer := fmt.Sprintf("%s/finalizer", r.ControllerDomain)
 	logger.V(1).Info("Reconciler started")
 
 	apiCheck := &checklyv1alpha1.ApiCheck{}
+	start := time.Now()
+	operation := metrics.OperationNone
+	defer func() {
+		res, err = finishReconcile(ctx, reconcileRun{
+			kind:         "ApiCheck",
+			namespace:    req.Namespace,
+			name:         req.Name,
+			object:       apiCheck,
+			checklyID:    apiCheck.Status.ID,
+			operation:    operation,
+			start:        start,
+			client:       r.Client,
+			summary:      r.ReconcileSummary,
+			breaker:      r.Breaker,
+			deprecations: r.Deprecations,
+			recorder:     r.Recorder,
+		}, res, err)
+	}()
 
 	// ////////////////////////////////
 	// Delete Logic
 	// ///////////////////////////////
-	err := r.Get(ctx, req.NamespacedName, apiCheck)
+	err = r.Get(ctx, req.NamespacedName, apiCheck)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// The resource has been deleted
@@ -75,22 +133,89 @@ func (r *ApiCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		}
 		// Error reading the object
 		logger.Error(err, "can't read the object")
+		return ctrl.Result{}, err
+	}
+
+	// Paused resources are left as they are, in the cluster and in checklyhq.com, until the annotation is removed
+	if paused(ctx, r.Client, r.ControllerDomain, r.Recorder, "ApiCheck", apiCheck) {
+		logger.V(1).Info("ApiCheck reconciliation paused")
 		return ctrl.Result{}, nil
 	}
 
+	// Without the finalizer deletions don't wait for checklyhq.com, the finalizer added before it was disabled is removed
+	if r.SkipFinalizer {
+		var removed bool
+		removed, err = dropFinalizer(ctx, r.Client, apiCheck, apiCheckFinalizer)
+		if err != nil {
+			logger.Error(err, "Failed to remove disabled ApiCheck finalizer")
+			return ctrl.Result{}, err
+		}
+		if removed {
+			logger.V(1).Info("Removed disabled finalizer")
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if r.Breaker.Open() {
+		return unavailableResult(ctx, r.Client, r.Breaker, "ApiCheck", apiCheck), nil
+	}
+
+	// ////////////////////////////////
+	// Account Logic
+	// ///////////////////////////////
+	// Checks only read the credentials of their own namespace
+	credentials := corev1.SecretReference{Namespace: apiCheck.Namespace, Name: apiCheck.Spec.Credentials.Name}
+	apiClient, accountID, err := accountClient(ctx, r.Client, r.Accounts, r.ApiClient, credentials)
+	if err != nil {
+		if apiCheck.GetDeletionTimestamp() != nil && finalizerTimedOut(apiCheck, r.ControllerDomain, r.FinalizerTimeout) {
+			logger.Error(err, "Failed to read the ApiCheck credentials past the finalizer timeout, leaving the checkly API check in place", "checkly ID", apiCheck.Status.ID)
+			_, err = dropFinalizer(ctx, r.Client, apiCheck, apiCheckFinalizer)
+			return ctrl.Result{}, err
+		}
+		logger.Error(err, "Failed to read the ApiCheck credentials")
+		return ctrl.Result{}, err
+	}
+	err = checkAccount("ApiCheck", apiCheck.Status.ID != "", apiCheck.Status.AccountID, accountID)
+	if err != nil {
+		logger.Error(err, "Refusing to sync ApiCheck")
+		return ctrl.Result{}, err
+	}
+	// The check is created or pinned in the account of its credentials
+	apiCheck.Status.AccountID = accountID
+	r = r.withApiClient(apiClient)
+
 	if apiCheck.GetDeletionTimestamp() != nil {
 		if controllerutil.ContainsFinalizer(apiCheck, apiCheckFinalizer) {
 			logger.V(1).Info("Finalizer is present, trying to delete Checkly check", "checkly ID", apiCheck.Status.ID)
-			err := external.Delete(apiCheck.Status.ID, r.ApiClient)
+			if r.CreateOnly {
+				logger.Info("Create only mode, leaving checkly API check in place", "checkly ID", apiCheck.Status.ID)
+			} else {
+				operation = metrics.OperationDelete
+				err := external.Delete(apiCheck.Status.ID, r.ApiClient)
+				change := audit.Record{Operation: audit.Delete, Kind: "ApiCheck", Object: apiCheck, ChecklyID: apiCheck.Status.ID, Spec: apiCheck.Spec, Before: &audit.State{Generation: apiCheck.Status.ObservedGeneration}, Err: err}
+				r.Audit.Log(change)
+				r.Notifier.Notify(ctx, change)
+				if err != nil && !finalizerTimedOut(apiCheck, r.ControllerDomain, r.FinalizerTimeout) {
+					logger.Error(err, "Failed to delete checkly API check")
+					return ctrl.Result{}, err
+				}
+
+				if err != nil {
+					logger.Error(err, "Failed to delete checkly API check past the finalizer timeout, removing the finalizer anyway", "checkly ID", apiCheck.Status.ID)
+					r.Recorder.Eventf(apiCheck, corev1.EventTypeWarning, "FinalizerTimeout", "Failed to delete checkly API check %v past the finalizer timeout, it has to be deleted manually: %s", apiCheck.Status.ID, err)
+				} else {
+					logger.Info("Successfully deleted checkly API check", "checkly ID", apiCheck.Status.ID)
+				}
+			}
+
+			err = r.Mapping.Remove(ctx, "ApiCheck", apiCheck.Status.ID)
 			if err != nil {
-				logger.Error(err, "Failed to delete checkly API check")
+				logger.Error(err, "Failed to remove ApiCheck from the ID mapping")
 				return ctrl.Result{}, err
 			}
 
-			logger.Info("Successfully deleted checkly API check", "checkly ID", apiCheck.Status.ID)
-
 			controllerutil.RemoveFinalizer(apiCheck, apiCheckFinalizer)
-			err = r.Update(ctx, apiCheck)
+			err := r.Update(ctx, apiCheck)
 			if err != nil {
 				logger.Error(err, "Failed to delete finalizer")
 				return ctrl.Result{}, err
@@ -106,7 +231,7 @@ func (r *ApiCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	// /////////////////////////////
 	// Finalizer logic
 	// ////////////////////////////
-	if !controllerutil.ContainsFinalizer(apiCheck, apiCheckFinalizer) {
+	if !r.SkipFinalizer && !controllerutil.ContainsFinalizer(apiCheck, apiCheckFinalizer) {
 		controllerutil.AddFinalizer(apiCheck, apiCheckFinalizer)
 		err = r.Update(ctx, apiCheck)
 		if err != nil {
@@ -138,18 +263,110 @@ func (r *ApiCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{Requeue: true}, nil
 	}
 
+	// /////////////////////////////
+	// AlertChannelsSubscription logic
+	// ////////////////////////////
+	if group.Status.AccountID != accountID {
+		err = fmt.Errorf("group %s is managed in checklyhq.com account %s, the check in account %s", group.Name, accountName(group.Status.AccountID), accountName(accountID))
+		logger.Error(err, "Check and group accounts differ", "group name", apiCheck.Spec.Group)
+		return ctrl.Result{}, err
+	}
+
+	err = validateRoutes(group.Spec.Routes)
+	if err != nil {
+		logger.Error(err, "Invalid routes of the group", "group name", apiCheck.Spec.Group)
+		return ctrl.Result{}, err
+	}
+
+	// /////////////////////////////
+	// Lookup snippet IDs
+	// ////////////////////////////
+	setupSnippetID, pendingSetup, err := snippetID(ctx, r.Client, accountID, apiCheck.Spec.SetupSnippet)
+	if err != nil {
+		logger.Error(err, "Failed to resolve the setup snippet", "snippet name", apiCheck.Spec.SetupSnippet)
+		return ctrl.Result{}, err
+	}
+	teardownSnippetID, pendingTeardown, err := snippetID(ctx, r.Client, accountID, apiCheck.Spec.TeardownSnippet)
+	if err != nil {
+		logger.Error(err, "Failed to resolve the teardown snippet", "snippet name", apiCheck.Spec.TeardownSnippet)
+		return ctrl.Result{}, err
+	}
+	if pendingSetup || pendingTeardown {
+		logger.V(1).Info("Snippet ID has not been populated, we're too quick, requeining for retry", "setup snippet", apiCheck.Spec.SetupSnippet, "teardown snippet", apiCheck.Spec.TeardownSnippet)
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	subscriptions, unavailable, err := withFallback(ctx, r.Client, checkSubscriptions(apiCheck, group), r.FallbackChannel)
+	if err != nil {
+		logger.Error(err, "Could not determine the availability of the alert channels")
+		return ctrl.Result{}, err
+	}
+	if len(unavailable) != 0 {
+		logger.Info("AlertChannels unavailable, alerting through the fallback AlertChannel", "unavailable", unavailable, "fallback", r.FallbackChannel)
+	}
+
+	alertChannels, _, pending, err := resolveSubscriptions(ctx, r.Client, accountID, subscriptions)
+	if err != nil {
+		logger.Error(err, "Could not find alertChannel resource")
+		return ctrl.Result{}, err
+	}
+	if pending != "" {
+		logger.V(1).Info("AlertChannel ID has not been populated, we're too quick, requeining for retry", "name", pending)
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	err = r.recordFallback(ctx, apiCheck, unavailable)
+	if err != nil {
+		logger.Error(err, "Failed to update ApiCheck status")
+		return ctrl.Result{}, err
+	}
+
+	// Checks alerting through the fallback AlertChannel drop it soon after the unavailable ones recover
+	requeueAfter := r.DriftInterval
+	if len(unavailable) != 0 {
+		requeueAfter = fallbackPollInterval
+	}
+
+	// /////////////////////////////
+	// Adopted ID logic
+	// ////////////////////////////
+	err = r.bindAdoptedID(ctx, apiCheck)
+	if err != nil {
+		logger.Error(err, "Failed to adopt the checkly API check")
+		return ctrl.Result{}, err
+	}
+
+	// /////////////////////////////
+	// Pinned ID logic
+	// ////////////////////////////
+	err = r.bindPinnedID(ctx, apiCheck)
+	if err != nil {
+		logger.Error(err, "Failed to bind to the pinned checkly API check")
+		return ctrl.Result{}, err
+	}
+
 	// Create internal Check type
+	tags, err := r.TagMapping.Tags(apiCheck)
+	if err != nil {
+		logger.Error(err, "Failed to map fields to tags")
+		return ctrl.Result{}, err
+	}
+
 	internalCheck := external.Check{
-		Name:            apiCheck.Name,
-		Namespace:       apiCheck.Namespace,
-		Frequency:       apiCheck.Spec.Frequency,
-		MaxResponseTime: apiCheck.Spec.MaxResponseTime,
-		Endpoint:        apiCheck.Spec.Endpoint,
-		SuccessCode:     apiCheck.Spec.Success,
-		ID:              apiCheck.Status.ID,
-		GroupID:         group.Status.ID,
-		Muted:           apiCheck.Spec.Muted,
-		Labels:          apiCheck.Labels,
+		Name:              apiCheck.Name,
+		Namespace:         apiCheck.Namespace,
+		Frequency:         apiCheck.Spec.Frequency,
+		MaxResponseTime:   apiCheck.Spec.MaxResponseTime,
+		Endpoint:          apiCheck.Spec.Endpoint,
+		SuccessCode:       apiCheck.Spec.Success,
+		ID:                apiCheck.Status.ID,
+		GroupID:           group.Status.ID,
+		SetupSnippetID:    setupSnippetID,
+		TeardownSnippetID: teardownSnippetID,
+		Muted:             apiCheck.Spec.Muted,
+		Labels:            apiCheck.Labels,
+		Tags:              tags,
+		AlertChannels:     alertChannels,
 	}
 
 	// /////////////////////////////
@@ -158,25 +375,67 @@ func (r *ApiCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 
 	// Determine if it's a new object or if it's an update to an existing object
 	if apiCheck.Status.ID != "" {
+		err = r.Mapping.Set(ctx, "ApiCheck", apiCheck.Status.ID, apiCheck)
+		if err != nil {
+			logger.Error(err, "Failed to add ApiCheck to the ID mapping")
+			return ctrl.Result{}, err
+		}
+
+		if r.CreateOnly {
+			logger.V(1).Info("Create only mode, skipping update", "checkly ID", apiCheck.Status.ID)
+			return ctrl.Result{}, r.observeGeneration(ctx, apiCheck)
+		}
+
 		// Existing object, we need to update it
 		logger.V(1).Info("Existing object, with ID", "checkly ID", apiCheck.Status.ID, "endpoint", apiCheck.Spec.Endpoint)
-		err := external.Update(internalCheck, r.ApiClient)
-		// err :=
+
+		// The check is only written if it differs in checklyhq.com, a failed read falls back to writing it anyway
+		drift, err := external.CheckDrift(internalCheck, r.ApiClient)
+		if err != nil {
+			logger.Error(err, "Failed to read the checkly check", "checkly ID", apiCheck.Status.ID)
+		} else if len(drift) == 0 {
+			logger.V(1).Info("Unchanged checkly check, skipping update", "checkly ID", apiCheck.Status.ID)
+			return ctrl.Result{RequeueAfter: requeueAfter}, recordSync(ctx, r.Client, apiCheck, nil)
+		} else {
+			logger.V(1).Info("Checkly check differs from the desired state", "checkly ID", apiCheck.Status.ID, "fields", drift)
+		}
+
+		operation = metrics.OperationUpdate
+		err = external.Update(internalCheck, r.ApiClient)
+		change := audit.Record{Operation: audit.Update, Kind: "ApiCheck", Object: apiCheck, ChecklyID: apiCheck.Status.ID, Spec: apiCheck.Spec, Before: &audit.State{Generation: apiCheck.Status.ObservedGeneration, Differed: drift}, Err: err}
+		r.Audit.Log(change)
+		r.Notifier.Notify(ctx, change)
 		if err != nil {
 			logger.Error(err, "Failed to update the checkly check")
+			if statusErr := recordSync(ctx, r.Client, apiCheck, err); statusErr != nil {
+				logger.Error(statusErr, "Failed to update ApiCheck status")
+			}
 			return ctrl.Result{}, err
 		}
 		logger.Info("Updated checkly check", "checkly ID", apiCheck.Status.ID)
-		return ctrl.Result{}, nil
+
+		err = recordSync(ctx, r.Client, apiCheck, nil)
+		if err != nil {
+			logger.Error(err, "Failed to update ApiCheck status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
 	}
 
 	// /////////////////////////////
 	// Create logic
 	// ////////////////////////////
 
+	operation = metrics.OperationCreate
 	checklyID, err := external.Create(internalCheck, r.ApiClient)
+	change := audit.Record{Operation: audit.Create, Kind: "ApiCheck", Object: apiCheck, ChecklyID: checklyID, Spec: apiCheck.Spec, Err: err}
+	r.Audit.Log(change)
+	r.Notifier.Notify(ctx, change)
 	if err != nil {
 		logger.Error(err, "Failed to create checkly alert")
+		if statusErr := recordSync(ctx, r.Client, apiCheck, err); statusErr != nil {
+			logger.Error(statusErr, "Failed to update ApiCheck status")
+		}
 		return ctrl.Result{}, err
 	}
 
@@ -184,19 +443,161 @@ func (r *ApiCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 
 	apiCheck.Status.ID = checklyID
 	apiCheck.Status.GroupID = group.Status.ID
+	apiCheck.Status.ObservedGeneration = apiCheck.Generation
+	meta.SetStatusCondition(&apiCheck.Status.Conditions, syncedCondition(apiCheck.Generation, nil))
 	err = r.Status().Update(ctx, apiCheck)
 	if err != nil {
 		logger.Error(err, "Failed to update ApiCheck status")
 		return ctrl.Result{}, err
 	}
+
+	err = r.Mapping.Set(ctx, "ApiCheck", apiCheck.Status.ID, apiCheck)
+	if err != nil {
+		logger.Error(err, "Failed to add ApiCheck to the ID mapping")
+		return ctrl.Result{}, err
+	}
 	logger.V(1).Info("New checkly check created with", "checkly ID", apiCheck.Status.ID, "spec", apiCheck.Spec)
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// withApiClient returns a copy of the reconciler using the client, so a reconciliation talks to the account of the
+// check throughout
+func (r *ApiCheckReconciler) withApiClient(apiClient checkly.Client) *ApiCheckReconciler {
+	if apiClient == r.ApiClient {
+		return r
+	}
+	copied := *r
+	copied.ApiClient = apiClient
+	return &copied
+}
+
+// bindAdoptedID binds the ApiCheck which wasn't synced yet to the checkly API check of the adopt-id annotation, once it's
+// confirmed to exist, the configuration is then synced onto it by the update logic instead of creating a duplicate
+func (r *ApiCheckReconciler) bindAdoptedID(ctx context.Context, apiCheck *checklyv1alpha1.ApiCheck) error {
+	if apiCheck.Status.ID != "" {
+		return nil
+	}
+	adopted, err := adoptedCheckID(r.ControllerDomain, apiCheck)
+	if err != nil || adopted == "" {
+		return err
+	}
+
+	err = external.Exists(adopted, r.ApiClient)
+	if err != nil {
+		return fmt.Errorf("adopted checkly API check %s: %w", adopted, err)
+	}
+
+	r.Recorder.Eventf(apiCheck, corev1.EventTypeNormal, "Adopted", "Adopted the checkly API check %s of the adopt-id annotation", adopted)
+	apiCheck.Status.ID = adopted
+	return r.Status().Update(ctx, apiCheck)
+}
+
+// bindPinnedID binds the ApiCheck to the checkly API check of the pin-id annotation, once it's confirmed to exist, the
+// configuration is then synced onto it by the update logic
+func (r *ApiCheckReconciler) bindPinnedID(ctx context.Context, apiCheck *checklyv1alpha1.ApiCheck) error {
+	pinned, err := pinnedCheckID(r.ControllerDomain, apiCheck)
+	if err != nil || pinned == "" || pinned == apiCheck.Status.ID {
+		return err
+	}
+
+	err = external.Exists(pinned, r.ApiClient)
+	if err != nil {
+		return fmt.Errorf("pinned checkly API check %s: %w", pinned, err)
+	}
+
+	r.Recorder.Eventf(apiCheck, corev1.EventTypeNormal, "Pinned", "Bound to the checkly API check %s of the pin-id annotation, previously %q", pinned, apiCheck.Status.ID)
+	apiCheck.Status.ID = pinned
+	return r.Status().Update(ctx, apiCheck)
+}
+
+// observeGeneration records the generation of the ApiCheck as reconciled successfully, so GitOps tools can tell when
+// it's in sync
+func (r *ApiCheckReconciler) observeGeneration(ctx context.Context, apiCheck *checklyv1alpha1.ApiCheck) error {
+	if apiCheck.Status.ObservedGeneration == apiCheck.Generation {
+		return nil
+	}
+
+	apiCheck.Status.ObservedGeneration = apiCheck.Generation
+	return r.Status().Update(ctx, apiCheck)
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ApiCheckReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// The controller is named explicitly, it's the controller and name label of the controller-runtime metrics
+	workers := max(r.Workers, 1)
+	metrics.SetWorkers("apicheck", workers)
+
+	// Index API checks by their group, this is used to re-route them when the routes of the group change
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), &checklyv1alpha1.ApiCheck{}, apiCheckGroupIndex, func(o client.Object) []string {
+		return []string{o.(*checklyv1alpha1.ApiCheck).Spec.Group}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Index API checks by their snippets, checks waiting for a snippet to be created are reconciled once it is
+	err = mgr.GetFieldIndexer().IndexField(context.Background(), &checklyv1alpha1.ApiCheck{}, apiCheckSnippetIndex, func(o client.Object) (snippets []string) {
+		apiCheck := o.(*checklyv1alpha1.ApiCheck)
+		for _, name := range []string{apiCheck.Spec.SetupSnippet, apiCheck.Spec.TeardownSnippet} {
+			if name != "" {
+				snippets = append(snippets, name)
+			}
+		}
+		return
+	})
+	if err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
+		Named("apicheck").
 		For(&checklyv1alpha1.ApiCheck{}).
+		Watches(&checklyv1alpha1.Group{}, handler.EnqueueRequestsFromMapFunc(r.groupChecks), builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, groupIDChanged))).
+		Watches(&checklyv1alpha1.Snippet{}, handler.EnqueueRequestsFromMapFunc(r.snippetChecks)).
+		WithOptions(controller.Options{RateLimiter: r.RetryLimiter, MaxConcurrentReconciles: workers}).
 		Complete(r)
 }
+
+// groupIDChanged passes updates assigning the group its checklyhq.com ID, checks referencing the group by name wait
+// for it and are created right away instead of on their next retry
+var groupIDChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldGroup, ok := e.ObjectOld.(*checklyv1alpha1.Group)
+		if !ok {
+			return false
+		}
+		newGroup, ok := e.ObjectNew.(*checklyv1alpha1.Group)
+		return ok && oldGroup.Status.ID != newGroup.Status.ID
+	},
+}
+
+// groupChecks returns reconcile requests for the API checks of the group
+func (r *ApiCheckReconciler) groupChecks(ctx context.Context, o client.Object) (requests []reconcile.Request) {
+	checks := &checklyv1alpha1.ApiCheckList{}
+	err := r.List(ctx, checks, client.MatchingFields{apiCheckGroupIndex: o.GetName()})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list API checks of group", "group", o.GetName())
+		return
+	}
+
+	for _, check := range checks.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: check.Namespace, Name: check.Name}})
+	}
+	return
+}
+
+// snippetChecks returns reconcile requests for the API checks using the snippet
+func (r *ApiCheckReconciler) snippetChecks(ctx context.Context, o client.Object) (requests []reconcile.Request) {
+	checks := &checklyv1alpha1.ApiCheckList{}
+	err := r.List(ctx, checks, client.MatchingFields{apiCheckSnippetIndex: o.GetName()})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list API checks of snippet", "snippet", o.GetName())
+		return
+	}
+
+	for _, check := range checks.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: check.Namespace, Name: check.Name}})
+	}
+	return
+}
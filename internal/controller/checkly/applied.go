@@ -0,0 +1,105 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	external "github.com/checkly/checkly-operator/external/checkly"
+)
+
+// DriftModeObserve is the value of the drift-mode annotation which reports drift of the AlertChannel instead of
+// reverting it
+const DriftModeObserve = "observe"
+
+// lastAppliedHashAnnotation is the key of the annotation holding the hash of the payload last applied to checklyhq.com
+func (r *AlertChannelReconciler) lastAppliedHashAnnotation() string {
+	return fmt.Sprintf("%s/last-applied-hash", r.ControllerDomain)
+}
+
+// driftCheckDue determines if the AlertChannel has to be compared to checklyhq.com again, because it wasn't for
+// longer than its resync interval
+func (r *AlertChannelReconciler) driftCheckDue(ac *checklyv1alpha1.AlertChannel) bool {
+	interval := r.resyncInterval(ac)
+	if interval == 0 {
+		return false
+	}
+	return ac.Status.DriftCheckedAt == nil || time.Since(ac.Status.DriftCheckedAt.Time) >= interval
+}
+
+// recordApplied stores the hash of the payload applied to checklyhq.com in the last-applied-hash annotation and the
+// time of the drift check, so unchanged AlertChannels are skipped until the next one is due
+func (r *AlertChannelReconciler) recordApplied(ctx context.Context, ac *checklyv1alpha1.AlertChannel, appliedHash string) error {
+	if ac.GetAnnotations()[r.lastAppliedHashAnnotation()] != appliedHash {
+		patch := client.MergeFrom(ac.DeepCopy())
+		if ac.Annotations == nil {
+			ac.Annotations = map[string]string{}
+		}
+		ac.Annotations[r.lastAppliedHashAnnotation()] = appliedHash
+		err := r.Patch(ctx, ac, patch)
+		if err != nil {
+			return err
+		}
+	}
+
+	now := metav1.Now()
+	ac.Status.DriftCheckedAt = &now
+	return r.Status().Update(ctx, ac)
+}
+
+// observesDrift determines if changes made to the AlertChannel in checklyhq.com are only reported, because of the
+// drift-mode annotation, changes to the AlertChannel resource are still applied
+func (r *AlertChannelReconciler) observesDrift(ac *checklyv1alpha1.AlertChannel) bool {
+	return ac.GetAnnotations()[fmt.Sprintf("%s/drift-mode", r.ControllerDomain)] == DriftModeObserve
+}
+
+// reportDrift records the changes made in checklyhq.com in the DriftDetected condition, a warning event is emitted
+// when drift is first detected or changes
+func (r *AlertChannelReconciler) reportDrift(ctx context.Context, ac *checklyv1alpha1.AlertChannel, changes []external.AlertChannelChange) error {
+	// A masked secret always differs from the desired one, it would report every AlertChannel holding one as drifted
+	changes = slices.DeleteFunc(slices.Clone(changes), external.AlertChannelChange.Masked)
+
+	condition := metav1.Condition{
+		Type:               ConditionDriftDetected,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonNoDrift,
+		Message:            "checklyhq.com matches the AlertChannel last applied",
+		ObservedGeneration: ac.Generation,
+	}
+	if len(changes) != 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = ReasonDrifted
+		condition.Message = fmt.Sprintf("Changed in checklyhq.com, not reverted: %s", formatChanges(changes))
+	}
+
+	if !meta.SetStatusCondition(&ac.Status.Conditions, condition) {
+		return nil
+	}
+	if len(changes) != 0 {
+		r.Recorder.Eventf(ac, corev1.EventTypeWarning, "DriftDetected", "checkly AlertChannel %d was changed in checklyhq.com, the changes are not reverted: %s", ac.Status.ID, formatChanges(changes))
+	}
+	return r.Status().Update(ctx, ac)
+}
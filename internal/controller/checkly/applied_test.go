@@ -0,0 +1,128 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	external "github.com/checkly/checkly-operator/external/checkly"
+)
+
+func TestDriftCheckDue(t *testing.T) {
+	r := &AlertChannelReconciler{ControllerDomain: "k8s.checklyhq.com", DriftInterval: 10 * time.Minute}
+	ac := &checklyv1alpha1.AlertChannel{}
+	if !r.driftCheckDue(ac) {
+		t.Error("Expected a drift check for an AlertChannel which was never checked")
+	}
+
+	checked := metav1.NewTime(time.Now().Add(-5 * time.Minute))
+	ac.Status.DriftCheckedAt = &checked
+	if r.driftCheckDue(ac) {
+		t.Error("Expected no drift check within the drift interval")
+	}
+
+	checked = metav1.NewTime(time.Now().Add(-11 * time.Minute))
+	if !r.driftCheckDue(ac) {
+		t.Error("Expected a drift check after the drift interval")
+	}
+
+	// The interval of the priority annotation takes precedence
+	r.RequeueIntervals = map[string]time.Duration{PriorityLow: time.Hour}
+	ac.Annotations = map[string]string{"k8s.checklyhq.com/priority": PriorityLow}
+	if r.driftCheckDue(ac) {
+		t.Error("Expected no drift check within the interval of the priority")
+	}
+
+	r.RequeueIntervals = nil
+	r.DriftInterval = 0
+	if r.driftCheckDue(ac) {
+		t.Error("Expected no drift check with drift checks disabled")
+	}
+}
+
+func TestRecordApplied(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	ac := &checklyv1alpha1.AlertChannel{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ac).WithStatusSubresource(ac).Build()
+	r := &AlertChannelReconciler{Client: c, ControllerDomain: "k8s.checklyhq.com"}
+	ctx := context.Background()
+
+	err := r.recordApplied(ctx, ac, "abc")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	stored := &checklyv1alpha1.AlertChannel{}
+	_ = c.Get(ctx, client.ObjectKeyFromObject(ac), stored)
+	if stored.Annotations["k8s.checklyhq.com/last-applied-hash"] != "abc" {
+		t.Errorf("Expected the applied hash annotation, got %v", stored.Annotations)
+	}
+	if stored.Status.DriftCheckedAt == nil {
+		t.Error("Expected the time of the drift check")
+	}
+}
+
+func TestReportDrift(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	ac := &checklyv1alpha1.AlertChannel{ObjectMeta: metav1.ObjectMeta{
+		Name:        "foo",
+		Annotations: map[string]string{"k8s.checklyhq.com/drift-mode": DriftModeObserve},
+	}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ac).WithStatusSubresource(ac).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &AlertChannelReconciler{Client: c, ControllerDomain: "k8s.checklyhq.com", Recorder: recorder}
+	ctx := context.Background()
+
+	if !r.observesDrift(ac) {
+		t.Error("Expected the AlertChannel to observe drift")
+	}
+
+	changes := []external.AlertChannelChange{{Field: "config.address", From: "foo@bar.baz", To: "oncall@bar.baz"}}
+	for i := 0; i < 2; i++ {
+		err := r.reportDrift(ctx, ac, changes)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	condition := meta.FindStatusCondition(ac.Status.Conditions, ConditionDriftDetected)
+	if condition == nil || condition.Status != metav1.ConditionTrue || condition.Reason != ReasonDrifted {
+		t.Errorf("Expected the DriftDetected condition to be set, got %+v", condition)
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("Expected a single DriftDetected event, got %d", len(recorder.Events))
+	}
+
+	err := r.reportDrift(ctx, ac, nil)
+	condition = meta.FindStatusCondition(ac.Status.Conditions, ConditionDriftDetected)
+	if err != nil || condition == nil || condition.Status != metav1.ConditionFalse {
+		t.Errorf("Expected the DriftDetected condition to be cleared, got %+v, %v", condition, err)
+	}
+}
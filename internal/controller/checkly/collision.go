@@ -0,0 +1,42 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import "fmt"
+
+// Handling of AlertChannels whose name is already taken by an alert channel in checklyhq.com, ex. one created in the
+// checklyhq.com UI
+const (
+	// NameCollisionIgnore creates the AlertChannel regardless, checklyhq.com allows duplicate names
+	NameCollisionIgnore = "ignore"
+	// NameCollisionAdopt takes over the existing alert channel and overwrites it with the AlertChannel configuration
+	NameCollisionAdopt = "adopt"
+	// NameCollisionReject doesn't sync the AlertChannel until the name is free
+	NameCollisionReject = "reject"
+	// NameCollisionSuffix creates the AlertChannel under the first free name with a numeric suffix, ex. foo-2
+	NameCollisionSuffix = "suffix"
+)
+
+// suffixedName returns the name with the lowest numeric suffix that's not taken yet
+func suffixedName(name string, taken map[string][]int64) string {
+	for i := 2; ; i++ {
+		suffixed := fmt.Sprintf("%s-%d", name, i)
+		if len(taken[suffixed]) == 0 {
+			return suffixed
+		}
+	}
+}
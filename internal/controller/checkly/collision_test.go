@@ -0,0 +1,35 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import "testing"
+
+func TestSuffixedName(t *testing.T) {
+	taken := map[string][]int64{
+		"foo":   {1},
+		"foo-2": {2},
+		"foo-4": {4},
+	}
+
+	if name := suffixedName("foo", taken); name != "foo-3" {
+		t.Errorf("Expected foo-3, got %s", name)
+	}
+
+	if name := suffixedName("bar", taken); name != "bar-2" {
+		t.Errorf("Expected bar-2, got %s", name)
+	}
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+// Condition types set on the status of the checkly resources
+const (
+	// ConditionSecretValid reports if the referenced secret holds a value in the expected format
+	ConditionSecretValid = "SecretValid"
+
+	// ConditionStalePending reports the resource has not been synced to checklyhq.com long after its creation
+	ConditionStalePending = "StalePending"
+
+	// ConditionTemplateValid reports if the webhook template renders to valid JSON against a sample alert
+	ConditionTemplateValid = "TemplateValid"
+
+	// ConditionWebhookURLValid reports if the webhook URL resolves to public or allowlisted addresses only
+	ConditionWebhookURLValid = "WebhookURLValid"
+
+	// ConditionClientCertValid reports if the webhook client certificate secret holds a valid certificate and key
+	ConditionClientCertValid = "ClientCertValid"
+
+	// ConditionSanitized reports the AlertChannel failed validation and a sanitized spec is synced on a best-effort basis
+	ConditionSanitized = "Sanitized"
+
+	// ConditionSelfTest reports if the test alert sent because of the self-test annotation was delivered
+	ConditionSelfTest = "SelfTest"
+
+	// ConditionDryRun reports the resource is not synced to checklyhq.com because of the dry run annotation
+	ConditionDryRun = "DryRun"
+
+	// ConditionVerified reports if the resource read back from checklyhq.com matches the desired state
+	ConditionVerified = "Verified"
+
+	// ConditionUnknownFields reports the resource sets spec fields the operator doesn't know about
+	ConditionUnknownFields = "UnknownFields"
+
+	// ConditionConfirmationRequired reports a destructive change is held until it's confirmed with an annotation
+	ConditionConfirmationRequired = "ConfirmationRequired"
+
+	// ConditionParityDrift reports the resource sets different fields than its counterparts in other environments
+	ConditionParityDrift = "ParityDrift"
+
+	// ConditionCredentialValid reports if the third party accepts the credential held in the referenced secret
+	ConditionCredentialValid = "CredentialValid"
+
+	// ConditionRolloutPending reports the resource waits for the canaries of its rollout cohort to be healthy
+	ConditionRolloutPending = "RolloutPending"
+
+	// ConditionChecklyUnavailable reports the resource isn't synced because the checklyhq.com API keeps failing
+	ConditionChecklyUnavailable = "ChecklyUnavailable"
+
+	// ConditionGroupsPending reports the AlertChannel waits for the groups it's attached to in bulk to be synced
+	ConditionGroupsPending = "GroupsPending"
+
+	// ConditionDriftDetected reports the AlertChannel observing drift was changed in checklyhq.com
+	ConditionDriftDetected = "DriftDetected"
+
+	// ConditionQuotaExceeded reports the AlertChannel isn't created because its quota of alert channels is exhausted
+	ConditionQuotaExceeded = "QuotaExceeded"
+
+	// ConditionFallbackAlerting reports the check alerts through the fallback AlertChannel as a subscribed one is
+	// unavailable
+	ConditionFallbackAlerting = "FallbackAlerting"
+
+	// ConditionReady reports if the latest generation of the resource is synced to checklyhq.com
+	ConditionReady = "Ready"
+
+	// ConditionSynced reports if the last create or update call to checklyhq.com succeeded
+	ConditionSynced = "Synced"
+
+	// ConditionPaused reports the reconciliation of the resource is frozen by the paused annotation
+	ConditionPaused = "Paused"
+)
+
+// Condition reasons set on the status of the checkly resources
+const (
+	ReasonSecretValid     = "SecretValid"
+	ReasonSecretInvalid   = "SecretInvalid"
+	ReasonNotSynced       = "NotSynced"
+	ReasonTemplateValid   = "TemplateValid"
+	ReasonTemplateInvalid = "TemplateInvalid"
+	ReasonCertValid       = "CertValid"
+	ReasonCertInvalid     = "CertInvalid"
+	ReasonBestEffort      = "BestEffort"
+	ReasonValid           = "Valid"
+	ReasonDelivered       = "Delivered"
+	ReasonUndelivered     = "Undelivered"
+	ReasonUnsupported     = "Unsupported"
+	ReasonDryRun          = "DryRun"
+	ReasonVerified        = "Verified"
+	ReasonNotVerified     = "NotVerified"
+	ReasonNoUnknownFields = "NoUnknownFields"
+	ReasonFieldsIgnored   = "FieldsIgnored"
+	ReasonFieldsRejected  = "FieldsRejected"
+	ReasonUnconfirmed     = "Unconfirmed"
+	ReasonConfirmed       = "Confirmed"
+	ReasonNotDestructive  = "NotDestructive"
+	ReasonInParity        = "InParity"
+	ReasonDrifted         = "Drifted"
+	ReasonKeyAccepted     = "KeyAccepted"
+	ReasonKeyRejected     = "KeyRejected"
+	ReasonKeyUnchecked    = "KeyUnchecked"
+	ReasonAwaitingCanary  = "AwaitingCanary"
+	ReasonCanaryHealthy   = "CanaryHealthy"
+	ReasonCircuitOpen     = "CircuitOpen"
+	ReasonCircuitClosed   = "CircuitClosed"
+	ReasonSynced          = "Synced"
+	ReasonSyncFailed      = "SyncFailed"
+	ReasonSyncPending     = "SyncPending"
+	ReasonAPIError        = "APIError"
+	ReasonGroupsMissing   = "GroupsMissing"
+	ReasonGroupsAttached  = "GroupsAttached"
+	ReasonOverQuota       = "OverQuota"
+	ReasonWithinQuota     = "WithinQuota"
+	ReasonNoDrift         = "NoDrift"
+	ReasonUnavailable     = "Unavailable"
+	ReasonAvailable       = "Available"
+	ReasonInvalid         = "Invalid"
+	ReasonURLAllowed      = "URLAllowed"
+	ReasonURLBlocked      = "URLBlocked"
+	ReasonUnverified      = "Unverified"
+	ReasonPaused          = "PausedByAnnotation"
+	ReasonResumed         = "Resumed"
+)
+
+// syncedCondition returns the Synced condition for the outcome of a create or update call to checklyhq.com, failed
+// calls carry the error of the API
+func syncedCondition(generation int64, err error) metav1.Condition {
+	if err != nil {
+		return metav1.Condition{
+			Type:               ConditionSynced,
+			Status:             metav1.ConditionFalse,
+			Reason:             ReasonAPIError,
+			Message:            err.Error(),
+			ObservedGeneration: generation,
+		}
+	}
+
+	return metav1.Condition{
+		Type:               ConditionSynced,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonSynced,
+		Message:            "Synced to checklyhq.com",
+		ObservedGeneration: generation,
+	}
+}
+
+// statusConditions returns the conditions and the observed generation of the status of a checkly resource
+func statusConditions(obj client.Object) (conditions *[]metav1.Condition, observedGeneration *int64, err error) {
+	switch o := obj.(type) {
+	case *checklyv1alpha1.AlertChannel:
+		return &o.Status.Conditions, &o.Status.ObservedGeneration, nil
+	case *checklyv1alpha1.ApiCheck:
+		return &o.Status.Conditions, &o.Status.ObservedGeneration, nil
+	case *checklyv1alpha1.Group:
+		return &o.Status.Conditions, &o.Status.ObservedGeneration, nil
+	case *checklyv1alpha1.HeartbeatCheck:
+		return &o.Status.Conditions, &o.Status.ObservedGeneration, nil
+	case *checklyv1alpha1.MaintenanceWindow:
+		return &o.Status.Conditions, &o.Status.ObservedGeneration, nil
+	case *checklyv1alpha1.Snippet:
+		return &o.Status.Conditions, &o.Status.ObservedGeneration, nil
+	case *checklyv1alpha1.VariableGroup:
+		return &o.Status.Conditions, &o.Status.ObservedGeneration, nil
+	case *checklyv1alpha1.Dashboard:
+		return &o.Status.Conditions, &o.Status.ObservedGeneration, nil
+	case *checklyv1alpha1.PrivateLocation:
+		return &o.Status.Conditions, &o.Status.ObservedGeneration, nil
+	default:
+		return nil, nil, fmt.Errorf("%T has no status conditions", obj)
+	}
+}
+
+// recordSync sets the Synced condition of the resource to the outcome of the last call to checklyhq.com, a successful
+// call also observes the generation
+func recordSync(ctx context.Context, c client.Client, obj client.Object, syncErr error) error {
+	conditions, observedGeneration, err := statusConditions(obj)
+	if err != nil {
+		return err
+	}
+
+	changed := meta.SetStatusCondition(conditions, syncedCondition(obj.GetGeneration(), syncErr))
+	if syncErr == nil && *observedGeneration != obj.GetGeneration() {
+		*observedGeneration = obj.GetGeneration()
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	return c.Status().Update(ctx, obj)
+}
+
+// setStatusCondition sets the condition on the status of the resource, the status is only written if it changed
+func setStatusCondition(ctx context.Context, c client.Client, obj client.Object, condition metav1.Condition) error {
+	conditions, _, err := statusConditions(obj)
+	if err != nil {
+		return err
+	}
+
+	if !meta.SetStatusCondition(conditions, condition) {
+		return nil
+	}
+	return c.Status().Update(ctx, obj)
+}
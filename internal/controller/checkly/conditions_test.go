@@ -0,0 +1,116 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSyncedCondition(t *testing.T) {
+	condition := syncedCondition(3, nil)
+	if condition.Type != ConditionSynced || condition.Status != metav1.ConditionTrue || condition.Reason != ReasonSynced || condition.ObservedGeneration != 3 {
+		t.Errorf("Expected a True Synced condition for generation 3, got %+v", condition)
+	}
+
+	condition = syncedCondition(4, errors.New("502 Bad Gateway"))
+	if condition.Status != metav1.ConditionFalse || condition.Reason != ReasonAPIError || condition.Message != "502 Bad Gateway" {
+		t.Errorf("Expected a False Synced condition with the API error, got %+v", condition)
+	}
+}
+
+func TestRecordSync(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	group := &checklyv1alpha1.Group{ObjectMeta: metav1.ObjectMeta{Name: "foo", Generation: 2}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(group).WithStatusSubresource(group).Build()
+	ctx := context.Background()
+
+	err := recordSync(ctx, c, group, errors.New("502 Bad Gateway"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	stored := &checklyv1alpha1.Group{}
+	_ = c.Get(ctx, client.ObjectKeyFromObject(group), stored)
+	if !meta.IsStatusConditionFalse(stored.Status.Conditions, ConditionSynced) || stored.Status.ObservedGeneration != 0 {
+		t.Errorf("Expected a False Synced condition without observed generation, got %+v", stored.Status)
+	}
+
+	err = recordSync(ctx, c, stored, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_ = c.Get(ctx, client.ObjectKeyFromObject(group), stored)
+	if !meta.IsStatusConditionTrue(stored.Status.Conditions, ConditionSynced) || stored.Status.ObservedGeneration != 2 {
+		t.Errorf("Expected a True Synced condition for generation 2, got %+v", stored.Status)
+	}
+}
+
+func TestAlertChannelRecordResultVerifiedReady(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	ac := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Generation: 2, CreationTimestamp: metav1.Now()},
+		Status:     checklyv1alpha1.AlertChannelStatus{ID: 7, SyncedGeneration: 2},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ac).WithStatusSubresource(ac).Build()
+	r := &AlertChannelReconciler{Client: c, VerifiedReady: true}
+	ctx := context.Background()
+
+	// A synced generation which wasn't read back isn't Ready yet
+	err := r.recordResult(ctx, ac, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	ready := meta.FindStatusCondition(ac.Status.Conditions, ConditionReady)
+	if ready == nil || ready.Status != metav1.ConditionFalse || ready.Reason != ReasonUnverified {
+		t.Errorf("Expected an Unverified Ready condition, got %+v", ready)
+	}
+
+	// Verification of a previous generation doesn't count
+	meta.SetStatusCondition(&ac.Status.Conditions, metav1.Condition{Type: ConditionVerified, Status: metav1.ConditionTrue, Reason: ReasonVerified, ObservedGeneration: 1})
+	_ = r.recordResult(ctx, ac, nil)
+	if meta.IsStatusConditionTrue(ac.Status.Conditions, ConditionReady) {
+		t.Error("Expected the AlertChannel verified for generation 1 not to be Ready")
+	}
+
+	meta.SetStatusCondition(&ac.Status.Conditions, metav1.Condition{Type: ConditionVerified, Status: metav1.ConditionTrue, Reason: ReasonVerified, ObservedGeneration: 2})
+	_ = r.recordResult(ctx, ac, nil)
+	if !meta.IsStatusConditionTrue(ac.Status.Conditions, ConditionReady) {
+		t.Errorf("Expected the verified AlertChannel to be Ready, got %+v", meta.FindStatusCondition(ac.Status.Conditions, ConditionReady))
+	}
+
+	// Without the gate a synced generation is Ready right away
+	r.VerifiedReady = false
+	meta.RemoveStatusCondition(&ac.Status.Conditions, ConditionVerified)
+	_ = r.recordResult(ctx, ac, nil)
+	if !meta.IsStatusConditionTrue(ac.Status.Conditions, ConditionReady) {
+		t.Error("Expected the synced AlertChannel to be Ready")
+	}
+}
@@ -0,0 +1,334 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/checkly/checkly-go-sdk"
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	external "github.com/checkly/checkly-operator/external/checkly"
+	"github.com/checkly/checkly-operator/internal/audit"
+	"github.com/checkly/checkly-operator/internal/metrics"
+	"github.com/checkly/checkly-operator/internal/notify"
+)
+
+// DashboardReconciler reconciles a Dashboard object
+type DashboardReconciler struct {
+	client.Client
+	Scheme           *runtime.Scheme
+	ApiClient        checkly.Client
+	ControllerDomain string
+	CreateOnly       bool
+	SkipFinalizer    bool
+	ReconcileSummary bool
+	Audit            *audit.Logger
+	Notifier         *notify.Notifier
+	FinalizerTimeout time.Duration
+	DriftInterval    time.Duration
+	RetryLimiter     workqueue.RateLimiter
+	Workers          int
+	Breaker          *external.CircuitBreaker
+	Deprecations     *external.DeprecationTracker
+	Recorder         record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=dashboards,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=dashboards/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=dashboards/finalizers,verbs=update
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.12.1/pkg/reconcile
+func (r *DashboardReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.Result, err error) {
+	logger := log.FromContext(ctx)
+	defer metrics.WorkerStarted("dashboard")()
+
+	dashboardFinalizer := fmt.Sprintf("%s/finalizer", r.ControllerDomain)
+
+	dashboard := &checklyv1alpha1.Dashboard{}
+	start := time.Now()
+	operation := metrics.OperationNone
+	defer func() {
+		res, err = finishReconcile(ctx, reconcileRun{
+			kind:         "Dashboard",
+			namespace:    req.Namespace,
+			name:         req.Name,
+			object:       dashboard,
+			checklyID:    dashboard.Status.ID,
+			operation:    operation,
+			start:        start,
+			client:       r.Client,
+			summary:      r.ReconcileSummary,
+			breaker:      r.Breaker,
+			deprecations: r.Deprecations,
+			recorder:     r.Recorder,
+		}, res, err)
+	}()
+
+	// ////////////////////////////////
+	// Delete Logic
+	// ///////////////////////////////
+	err = r.Get(ctx, req.NamespacedName, dashboard)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// The resource has been deleted
+			logger.V(1).Info("Dashboard removed")
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object
+		logger.Error(err, "can't read the Dashboard object")
+		return ctrl.Result{}, err
+	}
+
+	// Paused resources are left as they are, in the cluster and in checklyhq.com, until the annotation is removed
+	if paused(ctx, r.Client, r.ControllerDomain, r.Recorder, "Dashboard", dashboard) {
+		logger.V(1).Info("Dashboard reconciliation paused")
+		return ctrl.Result{}, nil
+	}
+
+	// Without the finalizer deletions don't wait for checklyhq.com, the finalizer added before it was disabled is removed
+	if r.SkipFinalizer {
+		var removed bool
+		removed, err = dropFinalizer(ctx, r.Client, dashboard, dashboardFinalizer)
+		if err != nil {
+			logger.Error(err, "Failed to remove disabled Dashboard finalizer")
+			return ctrl.Result{}, err
+		}
+		if removed {
+			logger.V(1).Info("Removed disabled finalizer")
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if r.Breaker.Open() {
+		return unavailableResult(ctx, r.Client, r.Breaker, "Dashboard", dashboard), nil
+	}
+
+	if dashboard.GetDeletionTimestamp() != nil {
+		if controllerutil.ContainsFinalizer(dashboard, dashboardFinalizer) {
+			logger.V(1).Info("Finalizer is present, trying to delete Checkly dashboard", "checkly ID", dashboard.Status.ID)
+			if r.CreateOnly {
+				logger.Info("Create only mode, leaving checkly dashboard in place", "checkly ID", dashboard.Status.ID)
+			} else if dashboard.Status.ID != "" {
+				operation = metrics.OperationDelete
+				err := external.DeleteDashboard(dashboard.Status.ID, r.ApiClient)
+				change := audit.Record{Operation: audit.Delete, Kind: "Dashboard", Object: dashboard, ChecklyID: dashboard.Status.ID, Spec: dashboard.Spec, Before: &audit.State{Generation: dashboard.Status.ObservedGeneration}, Err: err}
+				r.Audit.Log(change)
+				r.Notifier.Notify(ctx, change)
+				if err != nil && !finalizerTimedOut(dashboard, r.ControllerDomain, r.FinalizerTimeout) {
+					logger.Error(err, "Failed to delete checkly dashboard")
+					return ctrl.Result{}, err
+				}
+
+				if err != nil {
+					logger.Error(err, "Failed to delete checkly dashboard past the finalizer timeout, removing the finalizer anyway", "checkly ID", dashboard.Status.ID)
+					r.Recorder.Eventf(dashboard, corev1.EventTypeWarning, "FinalizerTimeout", "Failed to delete checkly dashboard %v past the finalizer timeout, it has to be deleted manually: %s", dashboard.Status.ID, err)
+				} else {
+					logger.Info("Successfully deleted checkly dashboard", "checkly ID", dashboard.Status.ID)
+				}
+			}
+
+			controllerutil.RemoveFinalizer(dashboard, dashboardFinalizer)
+			err := r.Update(ctx, dashboard)
+			if err != nil {
+				logger.Error(err, "Failed to delete finalizer")
+				return ctrl.Result{}, err
+			}
+			logger.V(1).Info("Successfully deleted finalizer")
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// /////////////////////////////
+	// Finalizer logic
+	// ////////////////////////////
+	if !r.SkipFinalizer && !controllerutil.ContainsFinalizer(dashboard, dashboardFinalizer) {
+		controllerutil.AddFinalizer(dashboard, dashboardFinalizer)
+		err = r.Update(ctx, dashboard)
+		if err != nil {
+			logger.Error(err, "Failed to add Dashboard finalizer")
+			return ctrl.Result{}, err
+		}
+		logger.V(1).Info("Added finalizer", "checkly ID", dashboard.Status.ID)
+		return ctrl.Result{}, nil
+	}
+
+	// /////////////////////////////
+	// Validation logic
+	// ////////////////////////////
+
+	// An invalid dashboard isn't sent to checklyhq.com, it's retried once the spec changes
+	internalDashboard := external.Dashboard{
+		Name:           dashboard.Name,
+		ID:             dashboard.Status.ID,
+		CustomURL:      dashboard.Spec.CustomURL,
+		CustomDomain:   dashboard.Spec.CustomDomain,
+		Tags:           dashboard.Spec.Tags,
+		Width:          dashboard.Spec.Width,
+		RefreshRate:    dashboard.Spec.RefreshRate,
+		Paginate:       dashboard.Spec.Paginate,
+		PaginationRate: dashboard.Spec.PaginationRate,
+		HideTags:       dashboard.Spec.HideTags,
+	}
+	err = external.ValidateDashboard(internalDashboard)
+	if err == nil {
+		err = r.claimedURL(ctx, dashboard)
+	}
+	if err != nil {
+		logger.Error(err, "Invalid Dashboard")
+		return ctrl.Result{}, r.recordInvalid(ctx, dashboard, err)
+	}
+
+	// /////////////////////////////
+	// Update logic
+	// ////////////////////////////
+	if dashboard.Status.ID != "" {
+		synced := meta.IsStatusConditionTrue(dashboard.Status.Conditions, ConditionSynced)
+		if dashboard.Status.ObservedGeneration == dashboard.Generation && synced && r.CreateOnly {
+			logger.V(1).Info("Unchanged Dashboard, skipping update", "checkly ID", dashboard.Status.ID)
+			return ctrl.Result{}, nil
+		}
+		// An unchanged dashboard is only written if it was changed in checklyhq.com, a failed read falls back to writing it
+		if dashboard.Status.ObservedGeneration == dashboard.Generation && synced {
+			drift, err := external.DashboardDrift(internalDashboard, r.ApiClient)
+			if err != nil {
+				logger.Error(err, "Failed to read the checkly dashboard", "checkly ID", dashboard.Status.ID)
+			} else if len(drift) == 0 {
+				logger.V(1).Info("Unchanged Dashboard, skipping update", "checkly ID", dashboard.Status.ID)
+				return ctrl.Result{RequeueAfter: r.DriftInterval}, nil
+			} else {
+				logger.V(1).Info("Checkly dashboard differs from the desired state", "checkly ID", dashboard.Status.ID, "fields", drift)
+			}
+		}
+		if r.CreateOnly {
+			logger.V(1).Info("Create only mode, skipping update", "checkly ID", dashboard.Status.ID)
+			return ctrl.Result{}, recordSync(ctx, r.Client, dashboard, nil)
+		}
+
+		operation = metrics.OperationUpdate
+		err = external.UpdateDashboard(internalDashboard, r.ApiClient)
+		change := audit.Record{Operation: audit.Update, Kind: "Dashboard", Object: dashboard, ChecklyID: dashboard.Status.ID, Spec: dashboard.Spec, Before: &audit.State{Generation: dashboard.Status.ObservedGeneration}, Err: err}
+		r.Audit.Log(change)
+		r.Notifier.Notify(ctx, change)
+		if err != nil {
+			logger.Error(err, "Failed to update the checkly dashboard")
+			if statusErr := recordSync(ctx, r.Client, dashboard, err); statusErr != nil {
+				logger.Error(statusErr, "Failed to update Dashboard status")
+			}
+			return ctrl.Result{}, err
+		}
+		logger.Info("Updated checkly dashboard", "checkly ID", dashboard.Status.ID)
+
+		err = recordSync(ctx, r.Client, dashboard, nil)
+		if err != nil {
+			logger.Error(err, "Failed to update Dashboard status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: r.DriftInterval}, nil
+	}
+
+	// /////////////////////////////
+	// Create logic
+	// ////////////////////////////
+	operation = metrics.OperationCreate
+	checklyID, err := external.CreateDashboard(internalDashboard, r.ApiClient)
+	change := audit.Record{Operation: audit.Create, Kind: "Dashboard", Object: dashboard, ChecklyID: checklyID, Spec: dashboard.Spec, Err: err}
+	r.Audit.Log(change)
+	r.Notifier.Notify(ctx, change)
+	if err != nil {
+		logger.Error(err, "Failed to create checkly dashboard")
+		if statusErr := recordSync(ctx, r.Client, dashboard, err); statusErr != nil {
+			logger.Error(statusErr, "Failed to update Dashboard status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	dashboard.Status.ID = checklyID
+	err = recordSync(ctx, r.Client, dashboard, nil)
+	if err != nil {
+		logger.Error(err, "Failed to update Dashboard status", "ID", dashboard.Status.ID)
+		return ctrl.Result{}, err
+	}
+	logger.Info("New checkly dashboard created", "ID", dashboard.Status.ID)
+
+	return ctrl.Result{RequeueAfter: r.DriftInterval}, nil
+}
+
+// claimedURL makes sure the custom URL of the Dashboard isn't used by another Dashboard already, checklyhq.com only
+// reports the conflict as a generic error on creation
+func (r *DashboardReconciler) claimedURL(ctx context.Context, dashboard *checklyv1alpha1.Dashboard) error {
+	dashboards := &checklyv1alpha1.DashboardList{}
+	err := r.List(ctx, dashboards)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range dashboards.Items {
+		if other.Name != dashboard.Name && other.Spec.CustomURL == dashboard.Spec.CustomURL {
+			return fmt.Errorf("custom URL %q is used by Dashboard %s", dashboard.Spec.CustomURL, other.Name)
+		}
+	}
+	return nil
+}
+
+// recordInvalid sets the Synced condition of the Dashboard to the validation error of its spec
+func (r *DashboardReconciler) recordInvalid(ctx context.Context, dashboard *checklyv1alpha1.Dashboard, invalid error) error {
+	condition := metav1.Condition{
+		Type:               ConditionSynced,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonInvalid,
+		Message:            invalid.Error(),
+		ObservedGeneration: dashboard.Generation,
+	}
+	if !meta.SetStatusCondition(&dashboard.Status.Conditions, condition) {
+		return nil
+	}
+
+	r.Recorder.Eventf(dashboard, corev1.EventTypeWarning, ReasonInvalid, "Dashboard isn't synced to checklyhq.com: %s", invalid)
+	return r.Status().Update(ctx, dashboard)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DashboardReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// The controller is named explicitly, it's the controller and name label of the controller-runtime metrics
+	workers := max(r.Workers, 1)
+	metrics.SetWorkers("dashboard", workers)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("dashboard").
+		For(&checklyv1alpha1.Dashboard{}).
+		WithOptions(controller.Options{RateLimiter: r.RetryLimiter, MaxConcurrentReconciles: workers}).
+		Complete(r)
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	external "github.com/checkly/checkly-operator/external/checkly"
+)
+
+// reportDeprecations surfaces the checklyhq.com API deprecation warnings recorded since the last reconciliation as
+// warning events of the reconciled object, warnings are kept for the next reconciliation if the object is gone
+func reportDeprecations(logger logr.Logger, recorder record.EventRecorder, deprecations *external.DeprecationTracker, obj client.Object) {
+	if obj.GetName() == "" {
+		return
+	}
+
+	for _, warning := range deprecations.Drain() {
+		logger.Info("checklyhq.com API deprecation", "warning", warning)
+		recorder.Event(obj, corev1.EventTypeWarning, "APIDeprecation", warning)
+	}
+}
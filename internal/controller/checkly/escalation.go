@@ -0,0 +1,104 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EscalationTiers maps the escalation tiers AlertChannels can be assigned to onto the delay after which they alert
+type EscalationTiers map[string]time.Duration
+
+// escalationDelays holds the time based escalation thresholds checklyhq.com accepts, in minutes
+var escalationDelays = map[int]bool{0: true, 5: true, 10: true, 15: true, 30: true}
+
+// ParseEscalationTiers parses a comma separated list of tier=delay pairs, ex. "tier1=0m,tier2=15m"
+func ParseEscalationTiers(value string) (tiers EscalationTiers, err error) {
+	tiers = EscalationTiers{}
+	if value == "" {
+		return
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		name, delayValue, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("escalation tier %q has to be in the tier=delay format", pair)
+		}
+
+		delay, err := time.ParseDuration(delayValue)
+		if err != nil {
+			return nil, fmt.Errorf("escalation tier %q has an invalid delay: %w", name, err)
+		}
+		if delay%time.Minute != 0 || !escalationDelays[int(delay.Minutes())] {
+			return nil, fmt.Errorf("escalation tier %q has to have a delay of 0, 5, 10, 15 or 30 minutes", name)
+		}
+
+		tiers[name] = delay
+	}
+
+	return
+}
+
+// Validate returns an error if the tier is not one of the configured tiers, AlertChannels without a tier are valid
+func (t EscalationTiers) Validate(tier string) error {
+	if tier == "" {
+		return nil
+	}
+
+	if len(t) == 0 {
+		return fmt.Errorf("escalation tier %q is set, but no escalation tiers are configured", tier)
+	}
+
+	if _, ok := t[tier]; !ok {
+		return fmt.Errorf("unknown escalation tier %q, valid tiers are: %s", tier, strings.Join(t.names(), ", "))
+	}
+
+	return nil
+}
+
+// Delay returns the escalation delay of a group alerting to AlertChannels of the supplied tiers. checklyhq.com
+// escalates per group rather than per alert channel, so a group mixing tiers alerts all of them after the shortest
+// delay, mixed reports if that's the case.
+func (t EscalationTiers) Delay(tiers []string) (delay time.Duration, mixed bool) {
+	for i, tier := range tiers {
+		tierDelay := t[tier]
+		if i == 0 {
+			delay = tierDelay
+			continue
+		}
+		if tierDelay != delay {
+			mixed = true
+		}
+		if tierDelay < delay {
+			delay = tierDelay
+		}
+	}
+
+	return
+}
+
+func (t EscalationTiers) names() []string {
+	names := make([]string, 0, len(t))
+	for name := range t {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseEscalationTiers(t *testing.T) {
+	tiers, err := ParseEscalationTiers("tier1=0m, tier2=15m")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if len(tiers) != 2 || tiers["tier1"] != 0 || tiers["tier2"] != 15*time.Minute {
+		t.Errorf("Expected tier1 and tier2, got %v", tiers)
+	}
+
+	tiers, err = ParseEscalationTiers("")
+	if err != nil || len(tiers) != 0 {
+		t.Errorf("Expected no tiers, got %v and %v", tiers, err)
+	}
+
+	for _, value := range []string{"tier1", "=5m", "tier1=foo", "tier1=7m", "tier1=90s"} {
+		_, err = ParseEscalationTiers(value)
+		if err == nil {
+			t.Errorf("Expected %q to be invalid", value)
+		}
+	}
+}
+
+func TestEscalationTiers(t *testing.T) {
+	tiers := EscalationTiers{"tier1": 0, "tier2": 15 * time.Minute}
+
+	if err := tiers.Validate("tier2"); err != nil {
+		t.Errorf("Expected tier2 to be valid, got %s", err)
+	}
+	if err := tiers.Validate(""); err != nil {
+		t.Errorf("Expected no tier to be valid, got %s", err)
+	}
+	if err := tiers.Validate("tier3"); err == nil {
+		t.Error("Expected tier3 to be invalid")
+	}
+
+	testData := []struct {
+		tiers []string
+		delay time.Duration
+		mixed bool
+	}{
+		{[]string{"tier2", "tier2"}, 15 * time.Minute, false},
+		{[]string{"tier2", "tier1"}, 0, true},
+		{[]string{"tier2", ""}, 0, true},
+		{nil, 0, false},
+	}
+
+	for _, tt := range testData {
+		delay, mixed := tiers.Delay(tt.tiers)
+		if delay != tt.delay || mixed != tt.mixed {
+			t.Errorf("Expected %s and %t for %v, got %s and %t", tt.delay, tt.mixed, tt.tiers, delay, mixed)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+// fallbackPollInterval is how often checks alerting through the fallback AlertChannel check on their primary ones
+const fallbackPollInterval = 30 * time.Second
+
+// withFallback subscribes the check to the fallback AlertChannel while an AlertChannel of its activated subscriptions
+// is unavailable, so it doesn't go without alerting. Subscriptions to AlertChannels which are missing or not synced to
+// checklyhq.com yet are dropped, ones whose last sync failed are kept next to the fallback. unavailable holds the names
+// of the unavailable AlertChannels. Without a fallback the subscriptions are returned unchanged.
+func withFallback(ctx context.Context, c client.Reader, subscriptions []checklyv1alpha1.AlertChannelSubscription, fallback string) (result []checklyv1alpha1.AlertChannelSubscription, unavailable []string, err error) {
+	if fallback == "" {
+		return subscriptions, nil, nil
+	}
+
+	for _, subscription := range subscriptions {
+		ac := &checklyv1alpha1.AlertChannel{}
+		err = c.Get(ctx, types.NamespacedName{Name: subscription.Name}, ac)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, nil, err
+		}
+		synced := err == nil && ac.Status.ID != 0
+		failing := synced && meta.IsStatusConditionFalse(ac.Status.Conditions, ConditionSynced)
+
+		if synced {
+			result = append(result, subscription)
+		}
+		if subscription.Activated && (!synced || failing) {
+			unavailable = append(unavailable, subscription.Name)
+		}
+	}
+
+	if len(unavailable) != 0 && !slices.ContainsFunc(result, func(subscription checklyv1alpha1.AlertChannelSubscription) bool {
+		return subscription.Name == fallback && subscription.Activated
+	}) {
+		result = append(result, checklyv1alpha1.AlertChannelSubscription{Name: fallback, Activated: true})
+	}
+	return result, unavailable, nil
+}
+
+// recordFallback sets the FallbackAlerting condition of the ApiCheck, a warning event is emitted when the check starts
+// alerting through the fallback AlertChannel
+func (r *ApiCheckReconciler) recordFallback(ctx context.Context, apiCheck *checklyv1alpha1.ApiCheck, unavailable []string) error {
+	condition := metav1.Condition{
+		Type:               ConditionFallbackAlerting,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonAvailable,
+		Message:            "The subscribed AlertChannels are available",
+		ObservedGeneration: apiCheck.Generation,
+	}
+	if len(unavailable) != 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = ReasonUnavailable
+		condition.Message = fmt.Sprintf("Alerting through %s, AlertChannels %s are unavailable", r.FallbackChannel, strings.Join(unavailable, ", "))
+	} else if meta.FindStatusCondition(apiCheck.Status.Conditions, ConditionFallbackAlerting) == nil {
+		return nil
+	}
+
+	previous := meta.FindStatusCondition(apiCheck.Status.Conditions, ConditionFallbackAlerting)
+	if previous != nil && previous.Status == condition.Status && previous.Message == condition.Message {
+		return nil
+	}
+	if condition.Status == metav1.ConditionTrue {
+		r.Recorder.Event(apiCheck, corev1.EventTypeWarning, ReasonUnavailable, condition.Message)
+	}
+
+	meta.SetStatusCondition(&apiCheck.Status.Conditions, condition)
+	return r.Status().Update(ctx, apiCheck)
+}
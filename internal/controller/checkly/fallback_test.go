@@ -0,0 +1,161 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/checkly/checkly-go-sdk"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+func TestWithFallback(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+	failed := []metav1.Condition{{Type: ConditionSynced, Status: metav1.ConditionFalse, Reason: ReasonAPIError}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&checklyv1alpha1.AlertChannel{ObjectMeta: metav1.ObjectMeta{Name: "oncall"}, Status: checklyv1alpha1.AlertChannelStatus{ID: 1}},
+		&checklyv1alpha1.AlertChannel{ObjectMeta: metav1.ObjectMeta{Name: "new"}},
+		&checklyv1alpha1.AlertChannel{ObjectMeta: metav1.ObjectMeta{Name: "flaky"}, Status: checklyv1alpha1.AlertChannelStatus{ID: 3, Conditions: failed}},
+	).Build()
+
+	testData := []struct {
+		subscriptions []checklyv1alpha1.AlertChannelSubscription
+		fallback      string
+		result        []checklyv1alpha1.AlertChannelSubscription
+		unavailable   []string
+	}{
+		// Available AlertChannels are kept as they are
+		{
+			[]checklyv1alpha1.AlertChannelSubscription{{Name: "oncall", Activated: true}},
+			"backup",
+			[]checklyv1alpha1.AlertChannelSubscription{{Name: "oncall", Activated: true}},
+			nil,
+		},
+		// Missing and not synced AlertChannels are replaced
+		{
+			[]checklyv1alpha1.AlertChannelSubscription{{Name: "oncall", Activated: true}, {Name: "new", Activated: true}, {Name: "missing", Activated: true}},
+			"backup",
+			[]checklyv1alpha1.AlertChannelSubscription{{Name: "oncall", Activated: true}, {Name: "backup", Activated: true}},
+			[]string{"new", "missing"},
+		},
+		// AlertChannels failing to sync are kept next to the fallback
+		{
+			[]checklyv1alpha1.AlertChannelSubscription{{Name: "flaky", Activated: true}},
+			"backup",
+			[]checklyv1alpha1.AlertChannelSubscription{{Name: "flaky", Activated: true}, {Name: "backup", Activated: true}},
+			[]string{"flaky"},
+		},
+		// Deactivated subscriptions don't alert, they don't need a fallback
+		{
+			[]checklyv1alpha1.AlertChannelSubscription{{Name: "new", Activated: false}},
+			"backup",
+			nil,
+			nil,
+		},
+		// Without a fallback the subscriptions are unchanged
+		{
+			[]checklyv1alpha1.AlertChannelSubscription{{Name: "new", Activated: true}},
+			"",
+			[]checklyv1alpha1.AlertChannelSubscription{{Name: "new", Activated: true}},
+			nil,
+		},
+	}
+
+	for _, tt := range testData {
+		result, unavailable, err := withFallback(context.TODO(), c, tt.subscriptions, tt.fallback)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !reflect.DeepEqual(result, tt.result) || !reflect.DeepEqual(unavailable, tt.unavailable) {
+			t.Errorf("Expected %v and %v for %v, got %v and %v", tt.result, tt.unavailable, tt.subscriptions, result, unavailable)
+		}
+	}
+}
+
+func TestReconcileFallback(t *testing.T) {
+	var created checkly.Check
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &created)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "7"}`))
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	apiCheck := &checklyv1alpha1.ApiCheck{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments", Namespace: "default", Finalizers: []string{"k8s.checklyhq.com/finalizer"}},
+		Spec: checklyv1alpha1.ApiCheckSpec{
+			Endpoint:                  "https://foo.bar/baz",
+			Success:                   "200",
+			Group:                     "team",
+			AlertChannelSubscriptions: []checklyv1alpha1.AlertChannelSubscription{{Name: "broken", Activated: true}},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		apiCheck,
+		&checklyv1alpha1.Group{ObjectMeta: metav1.ObjectMeta{Name: "team"}, Status: checklyv1alpha1.GroupStatus{ID: 1}},
+		&checklyv1alpha1.AlertChannel{ObjectMeta: metav1.ObjectMeta{Name: "broken"}},
+		&checklyv1alpha1.AlertChannel{ObjectMeta: metav1.ObjectMeta{Name: "backup"}, Status: checklyv1alpha1.AlertChannelStatus{ID: 9}},
+	).WithStatusSubresource(apiCheck).Build()
+	r := &ApiCheckReconciler{
+		Client:           c,
+		Scheme:           scheme,
+		ApiClient:        checkly.NewClient(server.URL, "key", server.Client(), nil),
+		ControllerDomain: "k8s.checklyhq.com",
+		FallbackChannel:  "backup",
+		Recorder:         record.NewFakeRecorder(10),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "payments"}}
+	res, err := r.Reconcile(context.TODO(), req)
+	if err != nil || res.RequeueAfter != fallbackPollInterval {
+		t.Fatalf("Expected a requeue after the fallback poll interval, got %+v, %v", res, err)
+	}
+
+	// The check with a broken primary AlertChannel still alerts
+	expected := []checkly.AlertChannelSubscription{{ChannelID: 9, Activated: true}}
+	if !reflect.DeepEqual(created.AlertChannelSubscriptions, expected) {
+		t.Errorf("Expected the check to alert through the fallback, got %v", created.AlertChannelSubscriptions)
+	}
+
+	err = c.Get(context.TODO(), req.NamespacedName, apiCheck)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !meta.IsStatusConditionTrue(apiCheck.Status.Conditions, ConditionFallbackAlerting) {
+		t.Errorf("Expected the FallbackAlerting condition, got %v", apiCheck.Status.Conditions)
+	}
+}
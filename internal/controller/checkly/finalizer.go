@@ -0,0 +1,140 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+// finalizerKinds holds the kinds the finalizer can be disabled for, named like the kind label of the metrics
+var finalizerKinds = []string{"alertchannel", "apicheck", "group", "heartbeatcheck", "maintenancewindow", "snippet", "variablegroup", "dashboard", "privatelocation"}
+
+// ParseSkipFinalizer parses the comma separated list of kinds the finalizer is disabled for, ex. apicheck,group
+func ParseSkipFinalizer(value string) (kinds map[string]bool, err error) {
+	kinds = map[string]bool{}
+	for _, kind := range strings.Split(value, ",") {
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if kind == "" {
+			continue
+		}
+
+		known := false
+		for _, finalizerKind := range finalizerKinds {
+			known = known || kind == finalizerKind
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown kind %q, valid options are %s", kind, strings.Join(finalizerKinds, ", "))
+		}
+		kinds[kind] = true
+	}
+	return
+}
+
+// dropFinalizer removes the finalizer from objects of a kind it's disabled for, they're deleted right away without
+// deleting them from checklyhq.com. removed reports the object was patched.
+func dropFinalizer(ctx context.Context, c client.Client, obj client.Object, finalizer string) (removed bool, err error) {
+	if !controllerutil.ContainsFinalizer(obj, finalizer) {
+		return false, nil
+	}
+
+	// Patch rather than update, an update would drop the spec fields unknown to the operator
+	patch := client.MergeFromWithOptions(obj.DeepCopyObject().(client.Object), client.MergeFromWithOptimisticLock{})
+	controllerutil.RemoveFinalizer(obj, finalizer)
+	return true, c.Patch(ctx, obj, patch)
+}
+
+// finalizerTimedOut determines if the object has been pending deletion for longer than its finalizer timeout. The
+// finalizer-timeout annotation takes precedence over the operator-wide timeout, without a timeout it never times out.
+func finalizerTimedOut(obj client.Object, controllerDomain string, timeout time.Duration) bool {
+	if obj.GetDeletionTimestamp() == nil {
+		return false
+	}
+
+	annotation := obj.GetAnnotations()[fmt.Sprintf("%s/finalizer-timeout", controllerDomain)]
+	if annotation != "" {
+		annotationTimeout, err := time.ParseDuration(annotation)
+		if err == nil {
+			timeout = annotationTimeout
+		}
+	}
+
+	if timeout <= 0 {
+		return false
+	}
+
+	return time.Since(obj.GetDeletionTimestamp().Time) > timeout
+}
+
+// CleanupFinalizers removes the finalizer of the operator from every resource it manages, so they can be deleted once
+// the operator is uninstalled. checklyhq.com isn't called, resources are left in place there. It returns the number
+// of resources the finalizer was removed from.
+func CleanupFinalizers(ctx context.Context, c client.Client, controllerDomain string) (removed int, err error) {
+	logger := log.FromContext(ctx)
+	finalizer := fmt.Sprintf("%s/finalizer", controllerDomain)
+
+	lists := []struct {
+		kind string
+		list client.ObjectList
+	}{
+		{"AlertChannel", &checklyv1alpha1.AlertChannelList{}},
+		{"ApiCheck", &checklyv1alpha1.ApiCheckList{}},
+		{"Group", &checklyv1alpha1.GroupList{}},
+		{"HeartbeatCheck", &checklyv1alpha1.HeartbeatCheckList{}},
+		{"MaintenanceWindow", &checklyv1alpha1.MaintenanceWindowList{}},
+		{"Snippet", &checklyv1alpha1.SnippetList{}},
+		{"VariableGroup", &checklyv1alpha1.VariableGroupList{}},
+		{"Dashboard", &checklyv1alpha1.DashboardList{}},
+		{"PrivateLocation", &checklyv1alpha1.PrivateLocationList{}},
+	}
+	for _, kind := range lists {
+		err = c.List(ctx, kind.list)
+		if err != nil {
+			return removed, fmt.Errorf("listing %s resources: %w", kind.kind, err)
+		}
+
+		var items []runtime.Object
+		items, err = meta.ExtractList(kind.list)
+		if err != nil {
+			return
+		}
+		for _, item := range items {
+			obj := item.(client.Object)
+			var dropped bool
+			dropped, err = dropFinalizer(ctx, c, obj, finalizer)
+			if err != nil {
+				return removed, fmt.Errorf("removing the finalizer of %s %s: %w", kind.kind, client.ObjectKeyFromObject(obj), err)
+			}
+			if dropped {
+				logger.Info("Removed finalizer", "kind", kind.kind, "name", client.ObjectKeyFromObject(obj))
+				removed++
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestFinalizerTimedOut(t *testing.T) {
+	deleted := metav1.NewTime(time.Now().Add(-time.Hour))
+
+	testData := []struct {
+		name              string
+		deletionTimestamp *metav1.Time
+		annotation        string
+		timeout           time.Duration
+		timedOut          bool
+	}{
+		{"not deleted", nil, "", time.Minute, false},
+		{"disabled", &deleted, "", 0, false},
+		{"within timeout", &deleted, "", 2 * time.Hour, false},
+		{"past timeout", &deleted, "", time.Minute, true},
+		{"annotation enables", &deleted, "30m", 0, true},
+		{"annotation extends", &deleted, "2h", time.Minute, false},
+		{"invalid annotation", &deleted, "foo", time.Minute, true},
+	}
+
+	for _, tt := range testData {
+		ac := &checklyv1alpha1.AlertChannel{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "foo",
+				DeletionTimestamp: tt.deletionTimestamp,
+			},
+		}
+		if tt.annotation != "" {
+			ac.Annotations = map[string]string{"testing.domain.tld/finalizer-timeout": tt.annotation}
+		}
+
+		timedOut := finalizerTimedOut(ac, "testing.domain.tld", tt.timeout)
+		if timedOut != tt.timedOut {
+			t.Errorf("%s: expected %t, got %t", tt.name, tt.timedOut, timedOut)
+		}
+	}
+}
+
+func TestParseSkipFinalizer(t *testing.T) {
+	kinds, err := ParseSkipFinalizer(" ApiCheck, group,Dashboard")
+	if err != nil {
+		t.Fatalf("Expected no error, got %e", err)
+	}
+	if len(kinds) != 3 || !kinds["apicheck"] || !kinds["group"] || !kinds["dashboard"] {
+		t.Errorf("Expected apicheck, group and dashboard, got %v", kinds)
+	}
+
+	kinds, err = ParseSkipFinalizer("")
+	if err != nil || len(kinds) != 0 {
+		t.Errorf("Expected no kinds, got %v, %v", kinds, err)
+	}
+
+	_, err = ParseSkipFinalizer("service")
+	if err == nil {
+		t.Error("Expected error for an unknown kind")
+	}
+}
+
+func TestDropFinalizer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	group := &checklyv1alpha1.Group{ObjectMeta: metav1.ObjectMeta{Name: "foo", Finalizers: []string{"k8s.checklyhq.com/finalizer"}}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(group).Build()
+	ctx := context.Background()
+
+	removed, err := dropFinalizer(ctx, c, group, "k8s.checklyhq.com/finalizer")
+	if err != nil || !removed {
+		t.Fatalf("Expected the finalizer to be removed, got %t, %v", removed, err)
+	}
+
+	stored := &checklyv1alpha1.Group{}
+	_ = c.Get(ctx, client.ObjectKeyFromObject(group), stored)
+	if len(stored.Finalizers) != 0 {
+		t.Errorf("Expected the stored group without finalizer, got %v", stored.Finalizers)
+	}
+
+	removed, err = dropFinalizer(ctx, c, stored, "k8s.checklyhq.com/finalizer")
+	if err != nil || removed {
+		t.Errorf("Expected nothing to remove, got %t, %v", removed, err)
+	}
+}
+
+func TestCleanupFinalizers(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	deleted := metav1.Now()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&checklyv1alpha1.AlertChannel{ObjectMeta: metav1.ObjectMeta{Name: "email", Finalizers: []string{"testing.domain.tld/finalizer"}}},
+		&checklyv1alpha1.ApiCheck{ObjectMeta: metav1.ObjectMeta{Name: "payments", Namespace: "default", DeletionTimestamp: &deleted, Finalizers: []string{"testing.domain.tld/finalizer"}}},
+		&checklyv1alpha1.Group{ObjectMeta: metav1.ObjectMeta{Name: "team", Finalizers: []string{"testing.domain.tld/finalizer", "other.domain.tld/finalizer"}}},
+		&checklyv1alpha1.Snippet{ObjectMeta: metav1.ObjectMeta{Name: "login"}},
+	).Build()
+
+	removed, err := CleanupFinalizers(context.TODO(), c, "testing.domain.tld")
+	if err != nil || removed != 3 {
+		t.Fatalf("Expected the finalizer to be removed from 3 resources, got %d, %v", removed, err)
+	}
+
+	// The resource pending deletion is gone, finalizers of others are kept
+	err = c.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "payments"}, &checklyv1alpha1.ApiCheck{})
+	if err == nil {
+		t.Error("Expected the ApiCheck pending deletion to be deleted")
+	}
+	group := &checklyv1alpha1.Group{}
+	_ = c.Get(context.TODO(), client.ObjectKey{Name: "team"}, group)
+	if len(group.Finalizers) != 1 || group.Finalizers[0] != "other.domain.tld/finalizer" {
+		t.Errorf("Expected the other finalizer to be kept, got %v", group.Finalizers)
+	}
+}
@@ -19,18 +19,27 @@ package checkly
 import (
 	"context"
 	"fmt"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/checkly/checkly-go-sdk"
 	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
 	external "github.com/checkly/checkly-operator/external/checkly"
+	"github.com/checkly/checkly-operator/internal/audit"
+	"github.com/checkly/checkly-operator/internal/mapping"
+	"github.com/checkly/checkly-operator/internal/metrics"
+	"github.com/checkly/checkly-operator/internal/notify"
 )
 
 // GroupReconciler reconciles a Group object
@@ -39,31 +48,67 @@ type GroupReconciler struct {
 	Scheme           *runtime.Scheme
 	ApiClient        checkly.Client
 	ControllerDomain string
+	CreateOnly       bool
+	SkipFinalizer    bool
+	ReconcileSummary bool
+	Audit            *audit.Logger
+	Mapping          *mapping.ConfigMap
+	Notifier         *notify.Notifier
+	FinalizerTimeout time.Duration
+	DriftInterval    time.Duration
+	RetryLimiter     workqueue.RateLimiter
+	Workers          int
+	Breaker          *external.CircuitBreaker
+	Deprecations     *external.DeprecationTracker
+	Accounts         *external.Accounts
+	TagMapping       TagMapping
+	EscalationTiers  EscalationTiers
+	Recorder         record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=groups,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=groups/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=groups/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.11.0/pkg/reconcile
-func (r *GroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *GroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.Result, err error) {
 	logger := log.FromContext(ctx)
+	defer metrics.WorkerStarted("group")()
 
 	logger.V(1).Info("Reconciler started")
 
 	groupFinalizer := fmt.Sprintf("%s/finalizer", r.ControllerDomain)
 
 	group := &checklyv1alpha1.Group{}
+	start := time.Now()
+	operation := metrics.OperationNone
+	defer func() {
+		res, err = finishReconcile(ctx, reconcileRun{
+			kind:         "Group",
+			namespace:    req.Namespace,
+			name:         req.Name,
+			object:       group,
+			checklyID:    group.Status.ID,
+			operation:    operation,
+			start:        start,
+			client:       r.Client,
+			summary:      r.ReconcileSummary,
+			breaker:      r.Breaker,
+			deprecations: r.Deprecations,
+			recorder:     r.Recorder,
+		}, res, err)
+	}()
 
 	// ////////////////////////////////
 	// Delete Logic
 	// TODO: Add logic to determine if there are any checks that are part of the group. If yes, throw error and do not delete the group until the checks have been deleted first.
 	// ///////////////////////////////
-	err := r.Get(ctx, req.NamespacedName, group)
+	err = r.Get(ctx, req.NamespacedName, group)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// The resource has been deleted
@@ -72,23 +117,88 @@ func (r *GroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		}
 		// Error reading the object
 		logger.Error(err, "can't read the Group object")
+		return ctrl.Result{}, err
+	}
+
+	// Paused resources are left as they are, in the cluster and in checklyhq.com, until the annotation is removed
+	if paused(ctx, r.Client, r.ControllerDomain, r.Recorder, "Group", group) {
+		logger.V(1).Info("Group reconciliation paused")
 		return ctrl.Result{}, nil
 	}
 
+	// Without the finalizer deletions don't wait for checklyhq.com, the finalizer added before it was disabled is removed
+	if r.SkipFinalizer {
+		var removed bool
+		removed, err = dropFinalizer(ctx, r.Client, group, groupFinalizer)
+		if err != nil {
+			logger.Error(err, "Failed to remove disabled Group finalizer")
+			return ctrl.Result{}, err
+		}
+		if removed {
+			logger.V(1).Info("Removed disabled finalizer")
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if r.Breaker.Open() {
+		return unavailableResult(ctx, r.Client, r.Breaker, "Group", group), nil
+	}
+
+	// ////////////////////////////////
+	// Account Logic
+	// ///////////////////////////////
+	apiClient, accountID, err := accountClient(ctx, r.Client, r.Accounts, r.ApiClient, group.Spec.Credentials)
+	if err != nil {
+		if group.GetDeletionTimestamp() != nil && finalizerTimedOut(group, r.ControllerDomain, r.FinalizerTimeout) {
+			logger.Error(err, "Failed to read the Group credentials past the finalizer timeout, leaving the checkly group in place", "checkly group ID", group.Status.ID)
+			_, err = dropFinalizer(ctx, r.Client, group, groupFinalizer)
+			return ctrl.Result{}, err
+		}
+		logger.Error(err, "Failed to read the Group credentials")
+		return ctrl.Result{}, err
+	}
+	err = checkAccount("Group", group.Status.ID != 0, group.Status.AccountID, accountID)
+	if err != nil {
+		logger.Error(err, "Refusing to sync Group")
+		return ctrl.Result{}, err
+	}
+	// The group is created or pinned in the account of its credentials
+	group.Status.AccountID = accountID
+	r = r.withApiClient(apiClient)
+
 	// If DeletionTimestamp is present, the object is marked for deletion, we need to remove the finalizer
 	if group.GetDeletionTimestamp() != nil {
 		if controllerutil.ContainsFinalizer(group, groupFinalizer) {
 			logger.V(1).Info("Finalizer is present, trying to delete Checkly group", "checkly group ID", group.Status.ID)
-			err := external.GroupDelete(group.Status.ID, r.ApiClient)
+			if r.CreateOnly {
+				logger.Info("Create only mode, leaving checkly group in place", "checkly group ID", group.Status.ID)
+			} else {
+				operation = metrics.OperationDelete
+				err := external.GroupDelete(group.Status.ID, r.ApiClient)
+				change := audit.Record{Operation: audit.Delete, Kind: "Group", Object: group, ChecklyID: group.Status.ID, Spec: group.Spec, Before: &audit.State{Generation: group.Status.ObservedGeneration}, Err: err}
+				r.Audit.Log(change)
+				r.Notifier.Notify(ctx, change)
+				if err != nil && !finalizerTimedOut(group, r.ControllerDomain, r.FinalizerTimeout) {
+					logger.Error(err, "Failed to delete checkly group")
+					return ctrl.Result{}, err
+				}
+
+				if err != nil {
+					logger.Error(err, "Failed to delete checkly group past the finalizer timeout, removing the finalizer anyway", "checkly group ID", group.Status.ID)
+					r.Recorder.Eventf(group, corev1.EventTypeWarning, "FinalizerTimeout", "Failed to delete checkly group %v past the finalizer timeout, it has to be deleted manually: %s", group.Status.ID, err)
+				} else {
+					logger.Info("Successfully deleted checkly group", "checkly group ID", group.Status.ID)
+				}
+			}
+
+			err = r.Mapping.Remove(ctx, "Group", group.Status.ID)
 			if err != nil {
-				logger.Error(err, "Failed to delete checkly group")
+				logger.Error(err, "Failed to remove Group from the ID mapping")
 				return ctrl.Result{}, err
 			}
 
-			logger.Info("Successfully deleted checkly group", "checkly group ID", group.Status.ID)
-
 			controllerutil.RemoveFinalizer(group, groupFinalizer)
-			err = r.Update(ctx, group)
+			err := r.Update(ctx, group)
 			if err != nil {
 				logger.Error(err, "Failed to delete finalizer")
 				return ctrl.Result{}, err
@@ -104,7 +214,7 @@ func (r *GroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	// /////////////////////////////
 	// Finalizer logic
 	// ////////////////////////////
-	if !controllerutil.ContainsFinalizer(group, groupFinalizer) {
+	if !r.SkipFinalizer && !controllerutil.ContainsFinalizer(group, groupFinalizer) {
 		controllerutil.AddFinalizer(group, groupFinalizer)
 		err = r.Update(ctx, group)
 		if err != nil {
@@ -118,35 +228,66 @@ func (r *GroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	// /////////////////////////////
 	// AlertChannelsSubscription logic
 	// ////////////////////////////
-	var alertChannels []checkly.AlertChannelSubscription
+	err = validateRoutes(group.Spec.Routes)
+	if err != nil {
+		logger.Error(err, "Invalid routes")
+		return ctrl.Result{}, err
+	}
 
-	if len(group.Spec.AlertChannels) != 0 {
-		for _, alertChannel := range group.Spec.AlertChannels {
-			ac := &checklyv1alpha1.AlertChannel{}
-			err := r.Get(ctx, types.NamespacedName{Name: alertChannel}, ac)
-			if err != nil {
-				logger.Error(err, "Could not find alertChannel resource", "name", alertChannel)
-				return ctrl.Result{}, err
-			}
-			if ac.Status.ID == 0 {
-				logger.Info("AlertChannel ID not yet populated, we'll retry")
-				return ctrl.Result{Requeue: true}, nil
-			}
-			alertChannels = append(alertChannels, checkly.AlertChannelSubscription{
-				ChannelID: ac.Status.ID,
-				Activated: true,
-			})
+	alertChannels, tiers, pending, err := resolveSubscriptions(ctx, r.Client, accountID, groupSubscriptions(group))
+	if err != nil {
+		logger.Error(err, "Could not find alertChannel resource")
+		return ctrl.Result{}, err
+	}
+	if pending != "" {
+		logger.Info("AlertChannel ID not yet populated, we'll retry", "name", pending)
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// checklyhq.com escalates per group, the group alerts after the delay of the escalation tier of its alert channels
+	var escalationDelay time.Duration
+	if len(r.EscalationTiers) != 0 {
+		var mixed bool
+		escalationDelay, mixed = r.EscalationTiers.Delay(tiers)
+		if mixed {
+			r.Recorder.Eventf(group, corev1.EventTypeWarning, "MixedEscalationTiers", "Group alerts to AlertChannels of different escalation tiers, all of them alert after %s, split the group to honour the tiers", escalationDelay)
 		}
 	}
 
+	// /////////////////////////////
+	// Adopted ID logic
+	// ////////////////////////////
+	err = r.bindAdoptedID(ctx, group)
+	if err != nil {
+		logger.Error(err, "Failed to adopt the checkly group")
+		return ctrl.Result{}, err
+	}
+
+	// /////////////////////////////
+	// Pinned ID logic
+	// ////////////////////////////
+	err = r.bindPinnedID(ctx, group)
+	if err != nil {
+		logger.Error(err, "Failed to bind to the pinned checkly group")
+		return ctrl.Result{}, err
+	}
+
 	// Create internal Check type
+	tags, err := r.TagMapping.Tags(group)
+	if err != nil {
+		logger.Error(err, "Failed to map fields to tags")
+		return ctrl.Result{}, err
+	}
+
 	internalCheck := external.Group{
-		Name:          group.Name,
-		Activated:     group.Spec.Activated,
-		Locations:     group.Spec.Locations,
-		AlertChannels: alertChannels,
-		ID:            group.Status.ID,
-		Labels:        group.Labels,
+		Name:            group.Name,
+		Activated:       group.Spec.Activated,
+		Locations:       group.Spec.Locations,
+		AlertChannels:   alertChannels,
+		ID:              group.Status.ID,
+		Labels:          group.Labels,
+		EscalationDelay: escalationDelay,
+		Tags:            tags,
 	}
 
 	// /////////////////////////////
@@ -155,41 +296,159 @@ func (r *GroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 
 	// Determine if it's a new object or if it's an update to an existing object
 	if group.Status.ID != 0 {
+		err = r.Mapping.Set(ctx, "Group", group.Status.ID, group)
+		if err != nil {
+			logger.Error(err, "Failed to add Group to the ID mapping")
+			return ctrl.Result{}, err
+		}
+
+		if r.CreateOnly {
+			logger.V(1).Info("Create only mode, skipping update", "checkly group ID", group.Status.ID)
+			return ctrl.Result{}, r.observeGeneration(ctx, group)
+		}
+
 		// Existing object, we need to update it
 		logger.V(1).Info("Existing object, with ID", "checkly group ID", group.Status.ID)
-		err := external.GroupUpdate(internalCheck, r.ApiClient)
+
+		// The group is only written if it differs in checklyhq.com, a failed read falls back to writing it anyway
+		drift, err := external.GroupDrift(internalCheck, r.ApiClient)
+		if err != nil {
+			logger.Error(err, "Failed to read the checkly group", "checkly group ID", group.Status.ID)
+		} else if len(drift) == 0 {
+			logger.V(1).Info("Unchanged checkly group, skipping update", "checkly group ID", group.Status.ID)
+			return ctrl.Result{RequeueAfter: r.DriftInterval}, recordSync(ctx, r.Client, group, nil)
+		} else {
+			logger.V(1).Info("Checkly group differs from the desired state", "checkly group ID", group.Status.ID, "fields", drift)
+		}
+
+		operation = metrics.OperationUpdate
+		err = external.GroupUpdate(internalCheck, r.ApiClient)
+		change := audit.Record{Operation: audit.Update, Kind: "Group", Object: group, ChecklyID: group.Status.ID, Spec: group.Spec, Before: &audit.State{Generation: group.Status.ObservedGeneration}, Err: err}
+		r.Audit.Log(change)
+		r.Notifier.Notify(ctx, change)
 		if err != nil {
 			logger.Error(err, "Failed to update the checkly group")
+			if statusErr := recordSync(ctx, r.Client, group, err); statusErr != nil {
+				logger.Error(statusErr, "Failed to update group status", "ID", group.Status.ID)
+			}
 			return ctrl.Result{}, err
 		}
 		logger.V(1).Info("Updated checkly check", "checkly group ID", group.Status.ID)
-		return ctrl.Result{}, nil
+
+		err = recordSync(ctx, r.Client, group, nil)
+		if err != nil {
+			logger.Error(err, "Failed to update group status", "ID", group.Status.ID)
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: r.DriftInterval}, nil
 	}
 
 	// /////////////////////////////
 	// Create logic
 	// ////////////////////////////
+	operation = metrics.OperationCreate
 	checklyID, err := external.GroupCreate(internalCheck, r.ApiClient)
+	change := audit.Record{Operation: audit.Create, Kind: "Group", Object: group, ChecklyID: checklyID, Spec: group.Spec, Err: err}
+	r.Audit.Log(change)
+	r.Notifier.Notify(ctx, change)
 	if err != nil {
 		logger.Error(err, "Failed to create checkly group")
+		if statusErr := recordSync(ctx, r.Client, group, err); statusErr != nil {
+			logger.Error(statusErr, "Failed to update group status")
+		}
 		return ctrl.Result{}, err
 	}
 
 	// Update the custom resource Status with the returned ID
 	group.Status.ID = checklyID
+	group.Status.ObservedGeneration = group.Generation
+	meta.SetStatusCondition(&group.Status.Conditions, syncedCondition(group.Generation, nil))
 	err = r.Status().Update(ctx, group)
 	if err != nil {
 		logger.Error(err, "Failed to update group status", "ID", group.Status.ID)
 		return ctrl.Result{}, err
 	}
+
+	err = r.Mapping.Set(ctx, "Group", group.Status.ID, group)
+	if err != nil {
+		logger.Error(err, "Failed to add Group to the ID mapping")
+		return ctrl.Result{}, err
+	}
 	logger.Info("New checkly group created", "ID", group.Status.ID)
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: r.DriftInterval}, nil
+}
+
+// withApiClient returns a copy of the reconciler using the client, so a reconciliation talks to the account of the
+// group throughout
+func (r *GroupReconciler) withApiClient(apiClient checkly.Client) *GroupReconciler {
+	if apiClient == r.ApiClient {
+		return r
+	}
+	copied := *r
+	copied.ApiClient = apiClient
+	return &copied
+}
+
+// bindAdoptedID binds the Group which wasn't synced yet to the checkly group of the adopt-id annotation, once it's
+// confirmed to exist, the configuration is then synced onto it by the update logic instead of creating a duplicate
+func (r *GroupReconciler) bindAdoptedID(ctx context.Context, group *checklyv1alpha1.Group) error {
+	if group.Status.ID != 0 {
+		return nil
+	}
+	adopted, err := adoptedID(r.ControllerDomain, group)
+	if err != nil || adopted == 0 {
+		return err
+	}
+
+	err = external.GroupExists(adopted, r.ApiClient)
+	if err != nil {
+		return fmt.Errorf("adopted checkly group %d: %w", adopted, err)
+	}
+
+	r.Recorder.Eventf(group, corev1.EventTypeNormal, "Adopted", "Adopted the checkly group %d of the adopt-id annotation", adopted)
+	group.Status.ID = adopted
+	return r.Status().Update(ctx, group)
+}
+
+// bindPinnedID binds the Group to the checkly group of the pin-id annotation, once it's confirmed to exist, the
+// configuration is then synced onto it by the update logic
+func (r *GroupReconciler) bindPinnedID(ctx context.Context, group *checklyv1alpha1.Group) error {
+	pinned, err := pinnedID(r.ControllerDomain, group)
+	if err != nil || pinned == 0 || pinned == group.Status.ID {
+		return err
+	}
+
+	err = external.GroupExists(pinned, r.ApiClient)
+	if err != nil {
+		return fmt.Errorf("pinned checkly group %d: %w", pinned, err)
+	}
+
+	r.Recorder.Eventf(group, corev1.EventTypeNormal, "Pinned", "Bound to the checkly group %d of the pin-id annotation, previously %d", pinned, group.Status.ID)
+	group.Status.ID = pinned
+	return r.Status().Update(ctx, group)
+}
+
+// observeGeneration records the generation of the Group as reconciled successfully, so GitOps tools can tell when
+// it's in sync
+func (r *GroupReconciler) observeGeneration(ctx context.Context, group *checklyv1alpha1.Group) error {
+	if group.Status.ObservedGeneration == group.Generation {
+		return nil
+	}
+
+	group.Status.ObservedGeneration = group.Generation
+	return r.Status().Update(ctx, group)
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *GroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// The controller is named explicitly, it's the controller and name label of the controller-runtime metrics
+	workers := max(r.Workers, 1)
+	metrics.SetWorkers("group", workers)
+
 	return ctrl.NewControllerManagedBy(mgr).
+		Named("group").
 		For(&checklyv1alpha1.Group{}).
+		WithOptions(controller.Options{RateLimiter: r.RetryLimiter, MaxConcurrentReconciles: workers}).
 		Complete(r)
 }
@@ -0,0 +1,350 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/checkly/checkly-go-sdk"
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	external "github.com/checkly/checkly-operator/external/checkly"
+	"github.com/checkly/checkly-operator/internal/audit"
+	"github.com/checkly/checkly-operator/internal/metrics"
+	"github.com/checkly/checkly-operator/internal/notify"
+)
+
+// HeartbeatCheckReconciler reconciles a HeartbeatCheck object
+type HeartbeatCheckReconciler struct {
+	client.Client
+	Scheme           *runtime.Scheme
+	ApiClient        checkly.Client
+	ControllerDomain string
+	CreateOnly       bool
+	SkipFinalizer    bool
+	ReconcileSummary bool
+	Audit            *audit.Logger
+	Notifier         *notify.Notifier
+	FinalizerTimeout time.Duration
+	DriftInterval    time.Duration
+	RetryLimiter     workqueue.RateLimiter
+	Workers          int
+	Breaker          *external.CircuitBreaker
+	Deprecations     *external.DeprecationTracker
+	Recorder         record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=heartbeatchecks,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=heartbeatchecks/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=heartbeatchecks/finalizers,verbs=update
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=alertchannels,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update
+
+// Keys of the ping token secret of a HeartbeatCheck
+const (
+	pingTokenKey = "pingtoken"
+	pingURLKey   = "url"
+)
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.12.1/pkg/reconcile
+func (r *HeartbeatCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.Result, err error) {
+	logger := log.FromContext(ctx)
+	defer metrics.WorkerStarted("heartbeatcheck")()
+
+	heartbeatFinalizer := fmt.Sprintf("%s/finalizer", r.ControllerDomain)
+
+	heartbeatCheck := &checklyv1alpha1.HeartbeatCheck{}
+	start := time.Now()
+	operation := metrics.OperationNone
+	defer func() {
+		res, err = finishReconcile(ctx, reconcileRun{
+			kind:         "HeartbeatCheck",
+			namespace:    req.Namespace,
+			name:         req.Name,
+			object:       heartbeatCheck,
+			checklyID:    heartbeatCheck.Status.ID,
+			operation:    operation,
+			start:        start,
+			client:       r.Client,
+			summary:      r.ReconcileSummary,
+			breaker:      r.Breaker,
+			deprecations: r.Deprecations,
+			recorder:     r.Recorder,
+		}, res, err)
+	}()
+
+	// ////////////////////////////////
+	// Delete Logic
+	// ///////////////////////////////
+	err = r.Get(ctx, req.NamespacedName, heartbeatCheck)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// The resource has been deleted, the ping token secret is garbage collected with it
+			logger.V(1).Info("HeartbeatCheck removed")
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object
+		logger.Error(err, "can't read the object")
+		return ctrl.Result{}, err
+	}
+
+	// Paused resources are left as they are, in the cluster and in checklyhq.com, until the annotation is removed
+	if paused(ctx, r.Client, r.ControllerDomain, r.Recorder, "HeartbeatCheck", heartbeatCheck) {
+		logger.V(1).Info("HeartbeatCheck reconciliation paused")
+		return ctrl.Result{}, nil
+	}
+
+	// Without the finalizer deletions don't wait for checklyhq.com, the finalizer added before it was disabled is removed
+	if r.SkipFinalizer {
+		var removed bool
+		removed, err = dropFinalizer(ctx, r.Client, heartbeatCheck, heartbeatFinalizer)
+		if err != nil {
+			logger.Error(err, "Failed to remove disabled HeartbeatCheck finalizer")
+			return ctrl.Result{}, err
+		}
+		if removed {
+			logger.V(1).Info("Removed disabled finalizer")
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if r.Breaker.Open() {
+		return unavailableResult(ctx, r.Client, r.Breaker, "HeartbeatCheck", heartbeatCheck), nil
+	}
+
+	if heartbeatCheck.GetDeletionTimestamp() != nil {
+		if controllerutil.ContainsFinalizer(heartbeatCheck, heartbeatFinalizer) {
+			logger.V(1).Info("Finalizer is present, trying to delete Checkly heartbeat check", "checkly ID", heartbeatCheck.Status.ID)
+			if r.CreateOnly {
+				logger.Info("Create only mode, leaving checkly heartbeat check in place", "checkly ID", heartbeatCheck.Status.ID)
+			} else if heartbeatCheck.Status.ID != "" {
+				operation = metrics.OperationDelete
+				err := external.DeleteHeartbeat(heartbeatCheck.Status.ID, r.ApiClient)
+				change := audit.Record{Operation: audit.Delete, Kind: "HeartbeatCheck", Object: heartbeatCheck, ChecklyID: heartbeatCheck.Status.ID, Spec: heartbeatCheck.Spec, Before: &audit.State{Generation: heartbeatCheck.Status.ObservedGeneration}, Err: err}
+				r.Audit.Log(change)
+				r.Notifier.Notify(ctx, change)
+				if err != nil && !finalizerTimedOut(heartbeatCheck, r.ControllerDomain, r.FinalizerTimeout) {
+					logger.Error(err, "Failed to delete checkly heartbeat check")
+					return ctrl.Result{}, err
+				}
+
+				if err != nil {
+					logger.Error(err, "Failed to delete checkly heartbeat check past the finalizer timeout, removing the finalizer anyway", "checkly ID", heartbeatCheck.Status.ID)
+					r.Recorder.Eventf(heartbeatCheck, corev1.EventTypeWarning, "FinalizerTimeout", "Failed to delete checkly heartbeat check %v past the finalizer timeout, it has to be deleted manually: %s", heartbeatCheck.Status.ID, err)
+				} else {
+					logger.Info("Successfully deleted checkly heartbeat check", "checkly ID", heartbeatCheck.Status.ID)
+				}
+			}
+
+			controllerutil.RemoveFinalizer(heartbeatCheck, heartbeatFinalizer)
+			err := r.Update(ctx, heartbeatCheck)
+			if err != nil {
+				logger.Error(err, "Failed to delete finalizer")
+				return ctrl.Result{}, err
+			}
+			logger.V(1).Info("Successfully deleted finalizer")
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// /////////////////////////////
+	// Finalizer logic
+	// ////////////////////////////
+	if !r.SkipFinalizer && !controllerutil.ContainsFinalizer(heartbeatCheck, heartbeatFinalizer) {
+		controllerutil.AddFinalizer(heartbeatCheck, heartbeatFinalizer)
+		err = r.Update(ctx, heartbeatCheck)
+		if err != nil {
+			logger.Error(err, "Failed to add HeartbeatCheck finalizer")
+			return ctrl.Result{}, err
+		}
+		logger.V(1).Info("Added finalizer", "checkly ID", heartbeatCheck.Status.ID)
+		return ctrl.Result{}, nil
+	}
+
+	// /////////////////////////////
+	// AlertChannelsSubscription logic
+	// ////////////////////////////
+	alertChannels, _, pending, err := resolveSubscriptions(ctx, r.Client, "", heartbeatCheck.Spec.AlertChannelSubscriptions)
+	if err != nil {
+		logger.Error(err, "Failed to resolve the alert channel subscriptions")
+		return ctrl.Result{}, err
+	}
+	if pending != "" {
+		logger.V(1).Info("AlertChannel has not been synced yet, requeuing", "alert channel", pending)
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	internalHeartbeat := external.Heartbeat{
+		Name:          heartbeatCheck.Name,
+		Namespace:     heartbeatCheck.Namespace,
+		Period:        heartbeatCheck.Spec.Period,
+		PeriodUnit:    heartbeatCheck.Spec.PeriodUnit,
+		Grace:         heartbeatCheck.Spec.Grace,
+		GraceUnit:     heartbeatCheck.Spec.GraceUnit,
+		ID:            heartbeatCheck.Status.ID,
+		Muted:         heartbeatCheck.Spec.Muted,
+		Labels:        heartbeatCheck.Labels,
+		AlertChannels: alertChannels,
+	}
+
+	// /////////////////////////////
+	// Update logic
+	// ////////////////////////////
+	if heartbeatCheck.Status.ID != "" {
+		// The check is only written if it differs in checklyhq.com, the ping token is read along
+		drift, pingToken, err := external.HeartbeatDrift(internalHeartbeat, r.ApiClient)
+		if err != nil {
+			logger.Error(err, "Failed to read the checkly heartbeat check", "checkly ID", heartbeatCheck.Status.ID)
+			if statusErr := recordSync(ctx, r.Client, heartbeatCheck, err); statusErr != nil {
+				logger.Error(statusErr, "Failed to update HeartbeatCheck status")
+			}
+			return ctrl.Result{}, err
+		}
+
+		if len(drift) != 0 && !r.CreateOnly {
+			logger.V(1).Info("Checkly heartbeat check differs from the desired state", "checkly ID", heartbeatCheck.Status.ID, "fields", drift)
+
+			operation = metrics.OperationUpdate
+			pingToken, err = external.UpdateHeartbeat(internalHeartbeat, r.ApiClient)
+			change := audit.Record{Operation: audit.Update, Kind: "HeartbeatCheck", Object: heartbeatCheck, ChecklyID: heartbeatCheck.Status.ID, Spec: heartbeatCheck.Spec, Before: &audit.State{Generation: heartbeatCheck.Status.ObservedGeneration}, Err: err}
+			r.Audit.Log(change)
+			r.Notifier.Notify(ctx, change)
+			if err != nil {
+				logger.Error(err, "Failed to update the checkly heartbeat check")
+				if statusErr := recordSync(ctx, r.Client, heartbeatCheck, err); statusErr != nil {
+					logger.Error(statusErr, "Failed to update HeartbeatCheck status")
+				}
+				return ctrl.Result{}, err
+			}
+			logger.Info("Updated checkly heartbeat check", "checkly ID", heartbeatCheck.Status.ID)
+		}
+
+		err = r.writePingToken(ctx, heartbeatCheck, pingToken)
+		if err != nil {
+			logger.Error(err, "Failed to write the ping token secret")
+			return ctrl.Result{}, err
+		}
+
+		err = recordSync(ctx, r.Client, heartbeatCheck, nil)
+		if err != nil {
+			logger.Error(err, "Failed to update HeartbeatCheck status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: r.DriftInterval}, nil
+	}
+
+	// /////////////////////////////
+	// Create logic
+	// ////////////////////////////
+	operation = metrics.OperationCreate
+	checklyID, pingToken, err := external.CreateHeartbeat(internalHeartbeat, r.ApiClient)
+	change := audit.Record{Operation: audit.Create, Kind: "HeartbeatCheck", Object: heartbeatCheck, ChecklyID: checklyID, Spec: heartbeatCheck.Spec, Err: err}
+	r.Audit.Log(change)
+	r.Notifier.Notify(ctx, change)
+	if err != nil {
+		logger.Error(err, "Failed to create checkly heartbeat check")
+		if statusErr := recordSync(ctx, r.Client, heartbeatCheck, err); statusErr != nil {
+			logger.Error(statusErr, "Failed to update HeartbeatCheck status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	// The ID is recorded first, a failure to write the secret is retried by the update logic
+	heartbeatCheck.Status.ID = checklyID
+	err = r.Status().Update(ctx, heartbeatCheck)
+	if err != nil {
+		logger.Error(err, "Failed to update HeartbeatCheck status")
+		return ctrl.Result{}, err
+	}
+	logger.V(1).Info("New checkly heartbeat check created", "checkly ID", heartbeatCheck.Status.ID)
+
+	err = r.writePingToken(ctx, heartbeatCheck, pingToken)
+	if err != nil {
+		logger.Error(err, "Failed to write the ping token secret")
+		return ctrl.Result{}, err
+	}
+
+	err = recordSync(ctx, r.Client, heartbeatCheck, nil)
+	if err != nil {
+		logger.Error(err, "Failed to update HeartbeatCheck status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: r.DriftInterval}, nil
+}
+
+// pingTokenSecretName returns the name of the secret holding the ping token of the HeartbeatCheck
+func pingTokenSecretName(heartbeatCheck *checklyv1alpha1.HeartbeatCheck) string {
+	return fmt.Sprintf("%s-heartbeat", heartbeatCheck.Name)
+}
+
+// writePingToken keeps the ping token and URL of the HeartbeatCheck in a secret owned by it, so jobs can mount them
+// and they're garbage collected with the HeartbeatCheck. The token is never written to the status.
+func (r *HeartbeatCheckReconciler) writePingToken(ctx context.Context, heartbeatCheck *checklyv1alpha1.HeartbeatCheck, pingToken string) error {
+	if pingToken == "" {
+		return fmt.Errorf("checkly heartbeat check %s has no ping token", heartbeatCheck.Status.ID)
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: pingTokenSecretName(heartbeatCheck), Namespace: heartbeatCheck.Namespace}}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		secret.Data = map[string][]byte{
+			pingTokenKey: []byte(pingToken),
+			pingURLKey:   []byte(external.HeartbeatPingURL(pingToken)),
+		}
+		return controllerutil.SetControllerReference(heartbeatCheck, secret, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+
+	if heartbeatCheck.Status.PingTokenSecret == secret.Name {
+		return nil
+	}
+	heartbeatCheck.Status.PingTokenSecret = secret.Name
+	return r.Status().Update(ctx, heartbeatCheck)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HeartbeatCheckReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// The controller is named explicitly, it's the controller and name label of the controller-runtime metrics
+	workers := max(r.Workers, 1)
+	metrics.SetWorkers("heartbeatcheck", workers)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("heartbeatcheck").
+		For(&checklyv1alpha1.HeartbeatCheck{}).
+		Owns(&corev1.Secret{}).
+		WithOptions(controller.Options{RateLimiter: r.RetryLimiter, MaxConcurrentReconciles: workers}).
+		Complete(r)
+}
@@ -0,0 +1,350 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/checkly/checkly-go-sdk"
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	external "github.com/checkly/checkly-operator/external/checkly"
+	"github.com/checkly/checkly-operator/internal/audit"
+	"github.com/checkly/checkly-operator/internal/metrics"
+	"github.com/checkly/checkly-operator/internal/notify"
+)
+
+// MaintenanceWindowReconciler reconciles a MaintenanceWindow object
+type MaintenanceWindowReconciler struct {
+	client.Client
+	Scheme           *runtime.Scheme
+	ApiClient        checkly.Client
+	ControllerDomain string
+	CreateOnly       bool
+	SkipFinalizer    bool
+	ReconcileSummary bool
+	Audit            *audit.Logger
+	Notifier         *notify.Notifier
+	FinalizerTimeout time.Duration
+	DriftInterval    time.Duration
+	RetryLimiter     workqueue.RateLimiter
+	Workers          int
+	Breaker          *external.CircuitBreaker
+	Deprecations     *external.DeprecationTracker
+	Recorder         record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=maintenancewindows,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=maintenancewindows/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=maintenancewindows/finalizers,verbs=update
+
+// maintenanceWindowTimeLayouts are the accepted formats of the times of a MaintenanceWindow, the ones without an
+// offset are in the timezone of the window
+var maintenanceWindowTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04"}
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.12.1/pkg/reconcile
+func (r *MaintenanceWindowReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.Result, err error) {
+	logger := log.FromContext(ctx)
+	defer metrics.WorkerStarted("maintenancewindow")()
+
+	windowFinalizer := fmt.Sprintf("%s/finalizer", r.ControllerDomain)
+
+	window := &checklyv1alpha1.MaintenanceWindow{}
+	start := time.Now()
+	operation := metrics.OperationNone
+	defer func() {
+		res, err = finishReconcile(ctx, reconcileRun{
+			kind:         "MaintenanceWindow",
+			namespace:    req.Namespace,
+			name:         req.Name,
+			object:       window,
+			checklyID:    window.Status.ID,
+			operation:    operation,
+			start:        start,
+			client:       r.Client,
+			summary:      r.ReconcileSummary,
+			breaker:      r.Breaker,
+			deprecations: r.Deprecations,
+			recorder:     r.Recorder,
+		}, res, err)
+	}()
+
+	// ////////////////////////////////
+	// Delete Logic
+	// ///////////////////////////////
+	err = r.Get(ctx, req.NamespacedName, window)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// The resource has been deleted
+			logger.V(1).Info("MaintenanceWindow removed")
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object
+		logger.Error(err, "can't read the MaintenanceWindow object")
+		return ctrl.Result{}, err
+	}
+
+	// Paused resources are left as they are, in the cluster and in checklyhq.com, until the annotation is removed
+	if paused(ctx, r.Client, r.ControllerDomain, r.Recorder, "MaintenanceWindow", window) {
+		logger.V(1).Info("MaintenanceWindow reconciliation paused")
+		return ctrl.Result{}, nil
+	}
+
+	// Without the finalizer deletions don't wait for checklyhq.com, the finalizer added before it was disabled is removed
+	if r.SkipFinalizer {
+		var removed bool
+		removed, err = dropFinalizer(ctx, r.Client, window, windowFinalizer)
+		if err != nil {
+			logger.Error(err, "Failed to remove disabled MaintenanceWindow finalizer")
+			return ctrl.Result{}, err
+		}
+		if removed {
+			logger.V(1).Info("Removed disabled finalizer")
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if r.Breaker.Open() {
+		return unavailableResult(ctx, r.Client, r.Breaker, "MaintenanceWindow", window), nil
+	}
+
+	if window.GetDeletionTimestamp() != nil {
+		if controllerutil.ContainsFinalizer(window, windowFinalizer) {
+			logger.V(1).Info("Finalizer is present, trying to delete Checkly maintenance window", "checkly ID", window.Status.ID)
+			if r.CreateOnly {
+				logger.Info("Create only mode, leaving checkly maintenance window in place", "checkly ID", window.Status.ID)
+			} else if window.Status.ID != 0 {
+				operation = metrics.OperationDelete
+				err := external.DeleteMaintenanceWindow(window.Status.ID, r.ApiClient)
+				change := audit.Record{Operation: audit.Delete, Kind: "MaintenanceWindow", Object: window, ChecklyID: window.Status.ID, Spec: window.Spec, Before: &audit.State{Generation: window.Status.ObservedGeneration}, Err: err}
+				r.Audit.Log(change)
+				r.Notifier.Notify(ctx, change)
+				if err != nil && !finalizerTimedOut(window, r.ControllerDomain, r.FinalizerTimeout) {
+					logger.Error(err, "Failed to delete checkly maintenance window")
+					return ctrl.Result{}, err
+				}
+
+				if err != nil {
+					logger.Error(err, "Failed to delete checkly maintenance window past the finalizer timeout, removing the finalizer anyway", "checkly ID", window.Status.ID)
+					r.Recorder.Eventf(window, corev1.EventTypeWarning, "FinalizerTimeout", "Failed to delete checkly maintenance window %v past the finalizer timeout, it has to be deleted manually: %s", window.Status.ID, err)
+				} else {
+					logger.Info("Successfully deleted checkly maintenance window", "checkly ID", window.Status.ID)
+				}
+			}
+
+			controllerutil.RemoveFinalizer(window, windowFinalizer)
+			err := r.Update(ctx, window)
+			if err != nil {
+				logger.Error(err, "Failed to delete finalizer")
+				return ctrl.Result{}, err
+			}
+			logger.V(1).Info("Successfully deleted finalizer")
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// /////////////////////////////
+	// Finalizer logic
+	// ////////////////////////////
+	if !r.SkipFinalizer && !controllerutil.ContainsFinalizer(window, windowFinalizer) {
+		controllerutil.AddFinalizer(window, windowFinalizer)
+		err = r.Update(ctx, window)
+		if err != nil {
+			logger.Error(err, "Failed to add MaintenanceWindow finalizer")
+			return ctrl.Result{}, err
+		}
+		logger.V(1).Info("Added finalizer", "checkly ID", window.Status.ID)
+		return ctrl.Result{}, nil
+	}
+
+	// /////////////////////////////
+	// Validation logic
+	// ////////////////////////////
+
+	// An invalid window isn't sent to checklyhq.com, it's retried once the spec changes
+	internalWindow, err := maintenanceWindow(window)
+	if err == nil {
+		err = external.ValidateMaintenanceWindow(internalWindow)
+	}
+	if err != nil {
+		logger.Error(err, "Invalid MaintenanceWindow")
+		return ctrl.Result{}, r.recordInvalid(ctx, window, err)
+	}
+
+	// /////////////////////////////
+	// Update logic
+	// ////////////////////////////
+	if window.Status.ID != 0 {
+		synced := meta.IsStatusConditionTrue(window.Status.Conditions, ConditionSynced)
+		if window.Status.ObservedGeneration == window.Generation && synced && r.CreateOnly {
+			logger.V(1).Info("Unchanged MaintenanceWindow, skipping update", "checkly ID", window.Status.ID)
+			return ctrl.Result{}, nil
+		}
+		// An unchanged window is only written if it was changed in checklyhq.com, a failed read falls back to writing it
+		if window.Status.ObservedGeneration == window.Generation && synced {
+			drift, err := external.MaintenanceWindowDrift(internalWindow, r.ApiClient)
+			if err != nil {
+				logger.Error(err, "Failed to read the checkly maintenance window", "checkly ID", window.Status.ID)
+			} else if len(drift) == 0 {
+				logger.V(1).Info("Unchanged MaintenanceWindow, skipping update", "checkly ID", window.Status.ID)
+				return ctrl.Result{RequeueAfter: r.DriftInterval}, nil
+			} else {
+				logger.V(1).Info("Checkly maintenance window differs from the desired state", "checkly ID", window.Status.ID, "fields", drift)
+			}
+		}
+		if r.CreateOnly {
+			logger.V(1).Info("Create only mode, skipping update", "checkly ID", window.Status.ID)
+			return ctrl.Result{}, recordSync(ctx, r.Client, window, nil)
+		}
+
+		operation = metrics.OperationUpdate
+		err = external.UpdateMaintenanceWindow(internalWindow, r.ApiClient)
+		change := audit.Record{Operation: audit.Update, Kind: "MaintenanceWindow", Object: window, ChecklyID: window.Status.ID, Spec: window.Spec, Before: &audit.State{Generation: window.Status.ObservedGeneration}, Err: err}
+		r.Audit.Log(change)
+		r.Notifier.Notify(ctx, change)
+		if err != nil {
+			logger.Error(err, "Failed to update the checkly maintenance window")
+			if statusErr := recordSync(ctx, r.Client, window, err); statusErr != nil {
+				logger.Error(statusErr, "Failed to update MaintenanceWindow status")
+			}
+			return ctrl.Result{}, err
+		}
+		logger.Info("Updated checkly maintenance window", "checkly ID", window.Status.ID)
+
+		err = recordSync(ctx, r.Client, window, nil)
+		if err != nil {
+			logger.Error(err, "Failed to update MaintenanceWindow status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: r.DriftInterval}, nil
+	}
+
+	// /////////////////////////////
+	// Create logic
+	// ////////////////////////////
+	operation = metrics.OperationCreate
+	checklyID, err := external.CreateMaintenanceWindow(internalWindow, r.ApiClient)
+	change := audit.Record{Operation: audit.Create, Kind: "MaintenanceWindow", Object: window, ChecklyID: checklyID, Spec: window.Spec, Err: err}
+	r.Audit.Log(change)
+	r.Notifier.Notify(ctx, change)
+	if err != nil {
+		logger.Error(err, "Failed to create checkly maintenance window")
+		if statusErr := recordSync(ctx, r.Client, window, err); statusErr != nil {
+			logger.Error(statusErr, "Failed to update MaintenanceWindow status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	window.Status.ID = checklyID
+	err = recordSync(ctx, r.Client, window, nil)
+	if err != nil {
+		logger.Error(err, "Failed to update MaintenanceWindow status", "ID", window.Status.ID)
+		return ctrl.Result{}, err
+	}
+	logger.Info("New checkly maintenance window created", "ID", window.Status.ID)
+
+	return ctrl.Result{RequeueAfter: r.DriftInterval}, nil
+}
+
+// maintenanceWindow puts together the external maintenance window of the MaintenanceWindow, the times without an
+// offset are read in its timezone
+func maintenanceWindow(window *checklyv1alpha1.MaintenanceWindow) (external.MaintenanceWindow, error) {
+	internalWindow := external.MaintenanceWindow{
+		Name:           window.Name,
+		ID:             window.Status.ID,
+		RepeatInterval: window.Spec.RepeatInterval,
+		RepeatUnit:     window.Spec.RepeatUnit,
+		Tags:           window.Spec.Tags,
+	}
+
+	location, err := resolveTimezone(window.Spec.Timezone)
+	if err != nil {
+		return internalWindow, err
+	}
+
+	internalWindow.StartsAt, err = parseWindowTime("startsat", window.Spec.StartsAt, location)
+	if err != nil {
+		return internalWindow, err
+	}
+	internalWindow.EndsAt, err = parseWindowTime("endsat", window.Spec.EndsAt, location)
+	if err != nil {
+		return internalWindow, err
+	}
+	if window.Spec.RepeatEndsAt != "" {
+		internalWindow.RepeatEndsAt, err = parseWindowTime("repeatendsat", window.Spec.RepeatEndsAt, location)
+	}
+	return internalWindow, err
+}
+
+// parseWindowTime parses a time of a MaintenanceWindow in one of the accepted layouts
+func parseWindowTime(field, value string, location *time.Location) (time.Time, error) {
+	for _, layout := range maintenanceWindowTimeLayouts {
+		parsed, err := time.ParseInLocation(layout, value, location)
+		if err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s %q has to be a time like 2024-01-31T22:00 or 2024-01-31T22:00:00Z", field, value)
+}
+
+// recordInvalid sets the Synced condition of the MaintenanceWindow to the validation error of its spec
+func (r *MaintenanceWindowReconciler) recordInvalid(ctx context.Context, window *checklyv1alpha1.MaintenanceWindow, invalid error) error {
+	condition := metav1.Condition{
+		Type:               ConditionSynced,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonInvalid,
+		Message:            invalid.Error(),
+		ObservedGeneration: window.Generation,
+	}
+	if !meta.SetStatusCondition(&window.Status.Conditions, condition) {
+		return nil
+	}
+
+	r.Recorder.Eventf(window, corev1.EventTypeWarning, ReasonInvalid, "MaintenanceWindow isn't synced to checklyhq.com: %s", invalid)
+	return r.Status().Update(ctx, window)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *MaintenanceWindowReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// The controller is named explicitly, it's the controller and name label of the controller-runtime metrics
+	workers := max(r.Workers, 1)
+	metrics.SetWorkers("maintenancewindow", workers)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("maintenancewindow").
+		For(&checklyv1alpha1.MaintenanceWindow{}).
+		WithOptions(controller.Options{RateLimiter: r.RetryLimiter, MaxConcurrentReconciles: workers}).
+		Complete(r)
+}
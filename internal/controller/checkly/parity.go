@@ -0,0 +1,95 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// fieldPaths returns the paths of the fields set in the object, ex. spec.webhook.url, values are ignored so objects
+// can be compared structurally
+func fieldPaths(object interface{}, path string) (paths []string, err error) {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return
+	}
+
+	var value interface{}
+	err = json.Unmarshal(data, &value)
+	if err != nil {
+		return
+	}
+
+	paths = leafPaths(value, path)
+	sort.Strings(paths)
+	return
+}
+
+func leafPaths(value interface{}, path string) (paths []string) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			paths = append(paths, leafPaths(child, path+"."+key)...)
+		}
+	case []interface{}:
+		if len(value) != 0 {
+			paths = append(paths, path)
+		}
+	// Not every field is omitempty, zero values count as unset
+	case bool, float64, string:
+		if value != false && value != 0.0 && value != "" {
+			paths = append(paths, path)
+		}
+	}
+	return
+}
+
+// parityDrift describes the fields set in only one of the two sets of paths, it's empty if they're in parity
+func parityDrift(paths []string, peerName string, peerPaths []string) string {
+	own := map[string]bool{}
+	for _, path := range paths {
+		own[path] = true
+	}
+	peer := map[string]bool{}
+	for _, path := range peerPaths {
+		peer[path] = true
+	}
+
+	var missing, extra []string
+	for _, path := range peerPaths {
+		if !own[path] {
+			missing = append(missing, path)
+		}
+	}
+	for _, path := range paths {
+		if !peer[path] {
+			extra = append(extra, path)
+		}
+	}
+
+	var drift []string
+	if len(missing) != 0 {
+		drift = append(drift, fmt.Sprintf("set in %s but not here: %s", peerName, strings.Join(missing, ", ")))
+	}
+	if len(extra) != 0 {
+		drift = append(drift, fmt.Sprintf("set here but not in %s: %s", peerName, strings.Join(extra, ", ")))
+	}
+	return strings.Join(drift, "; ")
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"reflect"
+	"testing"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+func TestParityDrift(t *testing.T) {
+	prod := checklyv1alpha1.AlertChannelSpec{
+		SendFailure: true,
+		Webhook: checklyv1alpha1.AlertChannelWebhook{
+			URL:      "https://prod.foo.bar/alerts",
+			DedupKey: "{{CHECK_ID}}",
+		},
+	}
+	staging := checklyv1alpha1.AlertChannelSpec{
+		SendFailure: true,
+		Webhook: checklyv1alpha1.AlertChannelWebhook{
+			URL: "https://staging.foo.bar/alerts",
+		},
+	}
+
+	prodPaths, err := fieldPaths(prod, "spec")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	expected := []string{"spec.sendfailure", "spec.webhook.dedupkey", "spec.webhook.url"}
+	if !reflect.DeepEqual(prodPaths, expected) {
+		t.Errorf("Expected %v, got %v", expected, prodPaths)
+	}
+
+	stagingPaths, err := fieldPaths(staging, "spec")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	drift := parityDrift(stagingPaths, "prod", prodPaths)
+	if drift != "set in prod but not here: spec.webhook.dedupkey" {
+		t.Errorf("Expected the dedup key to be missing, got %q", drift)
+	}
+
+	drift = parityDrift(prodPaths, "staging", stagingPaths)
+	if drift != "set here but not in staging: spec.webhook.dedupkey" {
+		t.Errorf("Expected the dedup key to be extra, got %q", drift)
+	}
+
+	staging.Webhook.DedupKey = "{{ALERT_TYPE}}"
+	stagingPaths, _ = fieldPaths(staging, "spec")
+	if drift = parityDrift(stagingPaths, "prod", prodPaths); drift != "" {
+		t.Errorf("Expected no drift for differing values, got %q", drift)
+	}
+}
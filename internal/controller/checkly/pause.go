@@ -0,0 +1,72 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/checkly/checkly-operator/internal/metrics"
+)
+
+// pausedAnnotation is the key of the annotation freezing the reconciliation of a resource, ex. during incident response
+func pausedAnnotation(domain string) string {
+	return fmt.Sprintf("%s/paused", domain)
+}
+
+// paused determines if the reconciliation of the resource is paused by the paused annotation, paused resources are
+// neither synced to nor deleted from checklyhq.com and their finalizer is kept. A Paused event and condition make them
+// discoverable, the paused resources metric tells monitoring the operator leaves them alone on purpose.
+func paused(ctx context.Context, c client.Client, domain string, recorder record.EventRecorder, kind string, o client.Object) bool {
+	isPaused := o.GetAnnotations()[pausedAnnotation(domain)] == "true"
+	metrics.SetResourcePaused(kind, client.ObjectKeyFromObject(o).String(), isPaused)
+
+	condition := metav1.Condition{
+		Type:               ConditionPaused,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonPaused,
+		Message:            fmt.Sprintf("Reconciliation is paused by the %s annotation", pausedAnnotation(domain)),
+		ObservedGeneration: o.GetGeneration(),
+	}
+	if !isPaused {
+		conditions, _, err := statusConditions(o)
+		if err != nil || !meta.IsStatusConditionTrue(*conditions, ConditionPaused) {
+			return false
+		}
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonResumed
+		condition.Message = "Reconciliation resumed"
+	}
+
+	err := setStatusCondition(ctx, c, o, condition)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to update status", "kind", kind)
+	}
+	if !isPaused {
+		return false
+	}
+
+	recorder.Eventf(o, corev1.EventTypeNormal, "Paused", "Reconciliation is paused by the %s annotation, remove it to resume", pausedAnnotation(domain))
+	return true
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// pinIDAnnotation is the key of the annotation binding a resource to an existing checklyhq.com ID, ex. during
+// migrations, regardless of the ID in its status
+func pinIDAnnotation(domain string) string {
+	return fmt.Sprintf("%s/pin-id", domain)
+}
+
+// adoptIDAnnotation is the key of the annotation binding a resource which wasn't synced yet to an existing
+// checklyhq.com ID, ex. when moving hand-made resources under the operator, instead of creating a duplicate
+func adoptIDAnnotation(domain string) string {
+	return fmt.Sprintf("%s/adopt-id", domain)
+}
+
+// pinnedID returns the numeric checklyhq.com ID of the pin-id annotation of alert channels and groups, 0 if it's not set
+func pinnedID(domain string, o client.Object) (int64, error) {
+	return annotatedID(pinIDAnnotation(domain), o)
+}
+
+// pinnedCheckID returns the checklyhq.com ID of the pin-id annotation of checks, which are UUIDs, empty if it's not set
+func pinnedCheckID(domain string, o client.Object) (string, error) {
+	return annotatedCheckID(pinIDAnnotation(domain), o)
+}
+
+// adoptedID returns the numeric checklyhq.com ID of the adopt-id annotation of alert channels and groups, 0 if it's
+// not set
+func adoptedID(domain string, o client.Object) (int64, error) {
+	return annotatedID(adoptIDAnnotation(domain), o)
+}
+
+// adoptedCheckID returns the checklyhq.com ID of the adopt-id annotation of checks, empty if it's not set
+func adoptedCheckID(domain string, o client.Object) (string, error) {
+	return annotatedCheckID(adoptIDAnnotation(domain), o)
+}
+
+func annotatedID(annotation string, o client.Object) (int64, error) {
+	value, ok := o.GetAnnotations()[annotation]
+	if !ok {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%s annotation %q is not a checklyhq.com ID", annotation, value)
+	}
+	return id, nil
+}
+
+func annotatedCheckID(annotation string, o client.Object) (string, error) {
+	value, ok := o.GetAnnotations()[annotation]
+	if !ok {
+		return "", nil
+	}
+
+	id := strings.TrimSpace(value)
+	if id == "" {
+		return "", fmt.Errorf("%s annotation is empty", annotation)
+	}
+	return id, nil
+}
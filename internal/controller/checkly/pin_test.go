@@ -0,0 +1,144 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/checkly/checkly-go-sdk"
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPinnedID(t *testing.T) {
+	ac := &checklyv1alpha1.AlertChannel{}
+	id, err := pinnedID("k8s.checklyhq.com", ac)
+	if err != nil || id != 0 {
+		t.Errorf("Expected no pinned ID, got %d, %v", id, err)
+	}
+
+	ac.ObjectMeta = metav1.ObjectMeta{Annotations: map[string]string{"k8s.checklyhq.com/pin-id": " 42 "}}
+	id, err = pinnedID("k8s.checklyhq.com", ac)
+	if err != nil || id != 42 {
+		t.Errorf("Expected pinned ID 42, got %d, %v", id, err)
+	}
+
+	for _, value := range []string{"", "foo", "-1", "0"} {
+		ac.Annotations["k8s.checklyhq.com/pin-id"] = value
+		_, err = pinnedID("k8s.checklyhq.com", ac)
+		if err == nil {
+			t.Errorf("Expected error for %q, got none", value)
+		}
+	}
+
+	apiCheck := &checklyv1alpha1.ApiCheck{}
+	checkID, err := pinnedCheckID("k8s.checklyhq.com", apiCheck)
+	if err != nil || checkID != "" {
+		t.Errorf("Expected no pinned ID, got %s, %v", checkID, err)
+	}
+
+	apiCheck.ObjectMeta = metav1.ObjectMeta{Annotations: map[string]string{"k8s.checklyhq.com/pin-id": "00000000-0000-0000-0000-000000000001"}}
+	checkID, err = pinnedCheckID("k8s.checklyhq.com", apiCheck)
+	if err != nil || checkID != "00000000-0000-0000-0000-000000000001" {
+		t.Errorf("Expected the pinned check ID, got %s, %v", checkID, err)
+	}
+
+	apiCheck.Annotations["k8s.checklyhq.com/pin-id"] = " "
+	_, err = pinnedCheckID("k8s.checklyhq.com", apiCheck)
+	if err == nil {
+		t.Error("Expected error for an empty annotation, got none")
+	}
+}
+
+func TestAdoptedID(t *testing.T) {
+	ac := &checklyv1alpha1.AlertChannel{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"k8s.checklyhq.com/pin-id": "7"}}}
+	id, err := adoptedID("k8s.checklyhq.com", ac)
+	if err != nil || id != 0 {
+		t.Errorf("Expected no adopted ID, got %d, %v", id, err)
+	}
+
+	ac.Annotations["k8s.checklyhq.com/adopt-id"] = "12345"
+	id, err = adoptedID("k8s.checklyhq.com", ac)
+	if err != nil || id != 12345 {
+		t.Errorf("Expected adopted ID 12345, got %d, %v", id, err)
+	}
+
+	ac.Annotations["k8s.checklyhq.com/adopt-id"] = "foo"
+	_, err = adoptedID("k8s.checklyhq.com", ac)
+	if err == nil {
+		t.Error("Expected error for an invalid ID, got none")
+	}
+}
+
+func TestAlertChannelBindAdoptedID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/alert-channels/12345" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"id":12345,"type":"EMAIL","config":{"address":"foo@bar.baz"}}`))
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	ac := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Annotations: map[string]string{"k8s.checklyhq.com/adopt-id": "12345"}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ac).WithStatusSubresource(ac).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &AlertChannelReconciler{
+		Client:           c,
+		ApiClient:        checkly.NewClient(server.URL, "key", server.Client(), nil),
+		ControllerDomain: "k8s.checklyhq.com",
+		Recorder:         recorder,
+	}
+	ctx := context.Background()
+
+	err := r.bindAdoptedID(ctx, ac)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ac.Status.ID != 12345 {
+		t.Errorf("Expected the AlertChannel to adopt ID 12345, got %d", ac.Status.ID)
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("Expected an Adopted event, got %d events", len(recorder.Events))
+	}
+
+	// The annotation is ignored once the AlertChannel is synced
+	ac.Annotations["k8s.checklyhq.com/adopt-id"] = "1"
+	err = r.bindAdoptedID(ctx, ac)
+	if err != nil || ac.Status.ID != 12345 {
+		t.Errorf("Expected the synced AlertChannel to keep its ID, got %d, %v", ac.Status.ID, err)
+	}
+
+	// IDs which don't exist in checklyhq.com aren't adopted
+	ac.Status.ID = 0
+	err = r.bindAdoptedID(ctx, ac)
+	if err == nil || ac.Status.ID != 0 {
+		t.Errorf("Expected an error adopting a missing ID, got %d, %v", ac.Status.ID, err)
+	}
+}
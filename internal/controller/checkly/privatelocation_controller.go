@@ -0,0 +1,401 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/checkly/checkly-go-sdk"
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	external "github.com/checkly/checkly-operator/external/checkly"
+	"github.com/checkly/checkly-operator/internal/audit"
+	"github.com/checkly/checkly-operator/internal/metrics"
+	"github.com/checkly/checkly-operator/internal/notify"
+)
+
+// PrivateLocationReconciler reconciles a PrivateLocation object
+type PrivateLocationReconciler struct {
+	client.Client
+	Scheme           *runtime.Scheme
+	ApiClient        checkly.Client
+	ControllerDomain string
+	CreateOnly       bool
+	SkipFinalizer    bool
+	ReconcileSummary bool
+	Audit            *audit.Logger
+	Notifier         *notify.Notifier
+	FinalizerTimeout time.Duration
+	DriftInterval    time.Duration
+	RetryLimiter     workqueue.RateLimiter
+	Workers          int
+	Breaker          *external.CircuitBreaker
+	Deprecations     *external.DeprecationTracker
+	Recorder         record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=privatelocations,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=privatelocations/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=privatelocations/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update
+
+// agentKeyKey is the key of the agent key secret of a PrivateLocation holding the newest agent API key, the key
+// checkly agents read from the API_KEY environment variable
+const agentKeyKey = "API_KEY"
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.12.1/pkg/reconcile
+func (r *PrivateLocationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.Result, err error) {
+	logger := log.FromContext(ctx)
+	defer metrics.WorkerStarted("privatelocation")()
+
+	privateLocationFinalizer := fmt.Sprintf("%s/finalizer", r.ControllerDomain)
+
+	privateLocation := &checklyv1alpha1.PrivateLocation{}
+	start := time.Now()
+	operation := metrics.OperationNone
+	defer func() {
+		res, err = finishReconcile(ctx, reconcileRun{
+			kind:         "PrivateLocation",
+			namespace:    req.Namespace,
+			name:         req.Name,
+			object:       privateLocation,
+			checklyID:    privateLocation.Status.ID,
+			operation:    operation,
+			start:        start,
+			client:       r.Client,
+			summary:      r.ReconcileSummary,
+			breaker:      r.Breaker,
+			deprecations: r.Deprecations,
+			recorder:     r.Recorder,
+		}, res, err)
+	}()
+
+	// ////////////////////////////////
+	// Delete Logic
+	// ///////////////////////////////
+	err = r.Get(ctx, req.NamespacedName, privateLocation)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// The resource has been deleted, the agent key secret is garbage collected with it
+			logger.V(1).Info("PrivateLocation removed")
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object
+		logger.Error(err, "can't read the PrivateLocation object")
+		return ctrl.Result{}, err
+	}
+
+	// Paused resources are left as they are, in the cluster and in checklyhq.com, until the annotation is removed
+	if paused(ctx, r.Client, r.ControllerDomain, r.Recorder, "PrivateLocation", privateLocation) {
+		logger.V(1).Info("PrivateLocation reconciliation paused")
+		return ctrl.Result{}, nil
+	}
+
+	// Without the finalizer deletions don't wait for checklyhq.com, the finalizer added before it was disabled is removed
+	if r.SkipFinalizer {
+		var removed bool
+		removed, err = dropFinalizer(ctx, r.Client, privateLocation, privateLocationFinalizer)
+		if err != nil {
+			logger.Error(err, "Failed to remove disabled PrivateLocation finalizer")
+			return ctrl.Result{}, err
+		}
+		if removed {
+			logger.V(1).Info("Removed disabled finalizer")
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if r.Breaker.Open() {
+		return unavailableResult(ctx, r.Client, r.Breaker, "PrivateLocation", privateLocation), nil
+	}
+
+	if privateLocation.GetDeletionTimestamp() != nil {
+		if controllerutil.ContainsFinalizer(privateLocation, privateLocationFinalizer) {
+			logger.V(1).Info("Finalizer is present, trying to delete Checkly private location", "checkly ID", privateLocation.Status.ID)
+			if r.CreateOnly {
+				logger.Info("Create only mode, leaving checkly private location in place", "checkly ID", privateLocation.Status.ID)
+			} else if privateLocation.Status.ID != "" {
+				operation = metrics.OperationDelete
+				err := external.DeletePrivateLocation(privateLocation.Status.ID, r.ApiClient)
+				change := audit.Record{Operation: audit.Delete, Kind: "PrivateLocation", Object: privateLocation, ChecklyID: privateLocation.Status.ID, Spec: privateLocation.Spec, Before: &audit.State{Generation: privateLocation.Status.ObservedGeneration}, Err: err}
+				r.Audit.Log(change)
+				r.Notifier.Notify(ctx, change)
+				if err != nil && !finalizerTimedOut(privateLocation, r.ControllerDomain, r.FinalizerTimeout) {
+					logger.Error(err, "Failed to delete checkly private location")
+					return ctrl.Result{}, err
+				}
+
+				if err != nil {
+					logger.Error(err, "Failed to delete checkly private location past the finalizer timeout, removing the finalizer anyway", "checkly ID", privateLocation.Status.ID)
+					r.Recorder.Eventf(privateLocation, corev1.EventTypeWarning, "FinalizerTimeout", "Failed to delete checkly private location %v past the finalizer timeout, it has to be deleted manually: %s", privateLocation.Status.ID, err)
+				} else {
+					logger.Info("Successfully deleted checkly private location", "checkly ID", privateLocation.Status.ID)
+				}
+			}
+
+			controllerutil.RemoveFinalizer(privateLocation, privateLocationFinalizer)
+			err := r.Update(ctx, privateLocation)
+			if err != nil {
+				logger.Error(err, "Failed to delete finalizer")
+				return ctrl.Result{}, err
+			}
+			logger.V(1).Info("Successfully deleted finalizer")
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// /////////////////////////////
+	// Finalizer logic
+	// ////////////////////////////
+	if !r.SkipFinalizer && !controllerutil.ContainsFinalizer(privateLocation, privateLocationFinalizer) {
+		controllerutil.AddFinalizer(privateLocation, privateLocationFinalizer)
+		err = r.Update(ctx, privateLocation)
+		if err != nil {
+			logger.Error(err, "Failed to add PrivateLocation finalizer")
+			return ctrl.Result{}, err
+		}
+		logger.V(1).Info("Added finalizer", "checkly ID", privateLocation.Status.ID)
+		return ctrl.Result{}, nil
+	}
+
+	// /////////////////////////////
+	// Validation logic
+	// ////////////////////////////
+
+	// An invalid private location isn't sent to checklyhq.com, it's retried once the spec changes
+	internalPrivateLocation := external.PrivateLocation{
+		Name:     privateLocation.Name,
+		ID:       privateLocation.Status.ID,
+		SlugName: privateLocation.Spec.SlugName,
+		Icon:     privateLocation.Spec.Icon,
+	}
+	err = external.ValidatePrivateLocation(internalPrivateLocation)
+	if err != nil {
+		logger.Error(err, "Invalid PrivateLocation")
+		return ctrl.Result{}, r.recordInvalid(ctx, privateLocation, err)
+	}
+
+	// /////////////////////////////
+	// Update logic
+	// ////////////////////////////
+	if privateLocation.Status.ID != "" {
+		synced := meta.IsStatusConditionTrue(privateLocation.Status.Conditions, ConditionSynced)
+		if (privateLocation.Status.ObservedGeneration != privateLocation.Generation || !synced) && !r.CreateOnly {
+			operation = metrics.OperationUpdate
+			err = external.UpdatePrivateLocation(internalPrivateLocation, r.ApiClient)
+			change := audit.Record{Operation: audit.Update, Kind: "PrivateLocation", Object: privateLocation, ChecklyID: privateLocation.Status.ID, Spec: privateLocation.Spec, Before: &audit.State{Generation: privateLocation.Status.ObservedGeneration}, Err: err}
+			r.Audit.Log(change)
+			r.Notifier.Notify(ctx, change)
+			if err != nil {
+				logger.Error(err, "Failed to update the checkly private location")
+				if statusErr := recordSync(ctx, r.Client, privateLocation, err); statusErr != nil {
+					logger.Error(statusErr, "Failed to update PrivateLocation status")
+				}
+				return ctrl.Result{}, err
+			}
+			logger.Info("Updated checkly private location", "checkly ID", privateLocation.Status.ID)
+		}
+
+		// The keys are read on every reconciliation, keys rotated in checklyhq.com end up in the secret
+		keys, err := external.PrivateLocationKeys(privateLocation.Status.ID, r.ApiClient)
+		if err != nil {
+			logger.Error(err, "Failed to read the checkly private location keys", "checkly ID", privateLocation.Status.ID)
+			return ctrl.Result{}, err
+		}
+
+		err = r.writeAgentKeys(ctx, privateLocation, keys)
+		if err != nil {
+			logger.Error(err, "Failed to write the agent key secret")
+			return ctrl.Result{}, err
+		}
+
+		err = recordSync(ctx, r.Client, privateLocation, nil)
+		if err != nil {
+			logger.Error(err, "Failed to update PrivateLocation status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: r.DriftInterval}, nil
+	}
+
+	// /////////////////////////////
+	// Create logic
+	// ////////////////////////////
+	operation = metrics.OperationCreate
+	checklyID, keys, err := external.CreatePrivateLocation(internalPrivateLocation, r.ApiClient)
+	change := audit.Record{Operation: audit.Create, Kind: "PrivateLocation", Object: privateLocation, ChecklyID: checklyID, Spec: privateLocation.Spec, Err: err}
+	r.Audit.Log(change)
+	r.Notifier.Notify(ctx, change)
+	if err != nil {
+		logger.Error(err, "Failed to create checkly private location")
+		if statusErr := recordSync(ctx, r.Client, privateLocation, err); statusErr != nil {
+			logger.Error(statusErr, "Failed to update PrivateLocation status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	// The keys are only returned in full on creation, a retry can't recover them. They're written before the ID is
+	// recorded, if that fails the private location is deleted again and the retry creates a new one with new keys.
+	err = r.writeAgentKeys(ctx, privateLocation, keys)
+	if err != nil {
+		logger.Error(err, "Failed to write the agent key secret, deleting the checkly private location", "checkly ID", checklyID)
+		deleteErr := external.DeletePrivateLocation(checklyID, r.ApiClient)
+		change := audit.Record{Operation: audit.Delete, Kind: "PrivateLocation", Object: privateLocation, ChecklyID: checklyID, Spec: privateLocation.Spec, Err: deleteErr}
+		r.Audit.Log(change)
+		r.Notifier.Notify(ctx, change)
+		if deleteErr != nil {
+			logger.Error(deleteErr, "Failed to delete checkly private location, it has to be deleted manually", "checkly ID", checklyID)
+			r.Recorder.Eventf(privateLocation, corev1.EventTypeWarning, "KeyUnavailable", "Failed to write the agent API keys and to delete checkly private location %s, it has to be deleted manually: %s", checklyID, deleteErr)
+		}
+		return ctrl.Result{}, err
+	}
+
+	privateLocation.Status.ID = checklyID
+	err = r.Status().Update(ctx, privateLocation)
+	if err != nil {
+		logger.Error(err, "Failed to update PrivateLocation status")
+		return ctrl.Result{}, err
+	}
+	logger.Info("New checkly private location created", "checkly ID", privateLocation.Status.ID)
+
+	err = recordSync(ctx, r.Client, privateLocation, nil)
+	if err != nil {
+		logger.Error(err, "Failed to update PrivateLocation status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: r.DriftInterval}, nil
+}
+
+// agentKeySecretName returns the name of the secret holding the agent API keys of the PrivateLocation
+func agentKeySecretName(privateLocation *checklyv1alpha1.PrivateLocation) string {
+	return fmt.Sprintf("%s-agent-keys", privateLocation.Name)
+}
+
+// agentKeyData puts together the data of the agent key secret, holding every key of the private location under its
+// ID and the newest one under API_KEY. checklyhq.com only returns keys in full on creation, keys it masks are taken
+// from the current data of the secret, the IDs of keys missing from both are returned. Revoked keys are dropped.
+func agentKeyData(keys []checkly.PrivateLocationKey, current map[string][]byte) (data map[string][]byte, missing []string) {
+	// The API doesn't order the keys, the newest one is found by its creation time
+	keys = slices.Clone(keys)
+	slices.SortStableFunc(keys, func(a, b checkly.PrivateLocationKey) int {
+		return cmp.Or(keyCreated(a).Compare(keyCreated(b)), cmp.Compare(a.Id, b.Id))
+	})
+
+	data = map[string][]byte{}
+	for _, key := range keys {
+		rawKey := []byte(key.RawKey)
+		if key.RawKey == "" {
+			rawKey = current[key.Id]
+		}
+		if len(rawKey) == 0 {
+			missing = append(missing, key.Id)
+			continue
+		}
+
+		data[key.Id] = rawKey
+		data[agentKeyKey] = rawKey
+	}
+
+	return
+}
+
+// keyCreated returns the creation time of the agent API key, keys without a valid one sort first
+func keyCreated(key checkly.PrivateLocationKey) time.Time {
+	created, err := time.Parse(time.RFC3339, key.CreatedAt)
+	if err != nil {
+		return time.Time{}
+	}
+	return created
+}
+
+// writeAgentKeys keeps the agent API keys of the PrivateLocation in a secret owned by it, so agents can mount them and
+// they're garbage collected with the PrivateLocation. The keys are never written to the status.
+func (r *PrivateLocationReconciler) writeAgentKeys(ctx context.Context, privateLocation *checklyv1alpha1.PrivateLocation, keys []checkly.PrivateLocationKey) error {
+	var missing []string
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: agentKeySecretName(privateLocation), Namespace: privateLocation.Namespace}}
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		secret.Data, missing = agentKeyData(keys, secret.Data)
+		return controllerutil.SetControllerReference(privateLocation, secret, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(missing) != 0 {
+		r.Recorder.Eventf(privateLocation, corev1.EventTypeWarning, "KeyUnavailable", "checklyhq.com only returns agent API keys in full on creation, keys %v are missing from secret %s", missing, secret.Name)
+	}
+	if result == controllerutil.OperationResultUpdated {
+		r.Recorder.Eventf(privateLocation, corev1.EventTypeNormal, "KeysRotated", "Updated the agent API keys in secret %s", secret.Name)
+	}
+
+	if privateLocation.Status.KeySecret == secret.Name {
+		return nil
+	}
+	privateLocation.Status.KeySecret = secret.Name
+	return r.Status().Update(ctx, privateLocation)
+}
+
+// recordInvalid sets the Synced condition of the PrivateLocation to the validation error of its spec
+func (r *PrivateLocationReconciler) recordInvalid(ctx context.Context, privateLocation *checklyv1alpha1.PrivateLocation, invalid error) error {
+	condition := metav1.Condition{
+		Type:               ConditionSynced,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonInvalid,
+		Message:            invalid.Error(),
+		ObservedGeneration: privateLocation.Generation,
+	}
+	if !meta.SetStatusCondition(&privateLocation.Status.Conditions, condition) {
+		return nil
+	}
+
+	r.Recorder.Eventf(privateLocation, corev1.EventTypeWarning, ReasonInvalid, "PrivateLocation isn't synced to checklyhq.com: %s", invalid)
+	return r.Status().Update(ctx, privateLocation)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PrivateLocationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// The controller is named explicitly, it's the controller and name label of the controller-runtime metrics
+	workers := max(r.Workers, 1)
+	metrics.SetWorkers("privatelocation", workers)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("privatelocation").
+		For(&checklyv1alpha1.PrivateLocation{}).
+		Owns(&corev1.Secret{}).
+		WithOptions(controller.Options{RateLimiter: r.RetryLimiter, MaxConcurrentReconciles: workers}).
+		Complete(r)
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+// quotaReservations holds the AlertChannels admitted by checkQuota, per quota label value, until their checklyhq.com ID
+// shows up in the cache. Workers reconciling AlertChannels of the same label value concurrently would otherwise all
+// count the same stale cache and together exceed the quota.
+type quotaReservations struct {
+	mu       sync.Mutex
+	reserved map[string]map[string]bool
+}
+
+// newQuotaReservations returns empty quotaReservations, they're shared by all workers of the AlertChannel controller
+func newQuotaReservations() *quotaReservations {
+	return &quotaReservations{reserved: map[string]map[string]bool{}}
+}
+
+// checkQuota refuses the creation of the AlertChannel once the AlertChannels sharing its quota label value, which
+// were created in checklyhq.com already or are being created, reach the quota held in the quota ConfigMap for that
+// value. AlertChannels are cluster scoped, the quota applies to the label value rather than a namespace.
+// AlertChannels whose label value has no quota are not limited. An admitted AlertChannel is reserved against the quota
+// until it's observed with its ID, releaseQuota drops the reservation if the creation fails. The QuotaExceeded
+// condition reports the outcome.
+func (r *AlertChannelReconciler) checkQuota(ctx context.Context, ac *checklyv1alpha1.AlertChannel) error {
+	labelValue := ac.GetLabels()[r.QuotaLabel]
+
+	cm := &corev1.ConfigMap{}
+	err := r.Get(ctx, r.QuotaConfigMap, cm)
+	if err != nil {
+		return fmt.Errorf("unable to read quota ConfigMap: %w", err)
+	}
+
+	value, ok := cm.Data[labelValue]
+	if !ok {
+		return nil
+	}
+	quota, err := strconv.Atoi(value)
+	if err != nil || quota < 0 {
+		return fmt.Errorf("invalid quota %q for %s %q in the quota ConfigMap", value, r.QuotaLabel, labelValue)
+	}
+
+	used, err := r.reserveQuota(ctx, ac, labelValue, quota)
+	if err != nil {
+		return err
+	}
+
+	if used >= quota {
+		err = fmt.Errorf("quota of %d alert channels for %s %q exhausted", quota, r.QuotaLabel, labelValue)
+		condErr := r.setCondition(ctx, ac, metav1.Condition{
+			Type:               ConditionQuotaExceeded,
+			Status:             metav1.ConditionTrue,
+			Reason:             ReasonOverQuota,
+			Message:            err.Error(),
+			ObservedGeneration: ac.Generation,
+		})
+		if condErr != nil {
+			return condErr
+		}
+		return err
+	}
+
+	return r.setCondition(ctx, ac, metav1.Condition{
+		Type:               ConditionQuotaExceeded,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonWithinQuota,
+		Message:            fmt.Sprintf("%d of %d alert channels for %s %q in use", used, quota, r.QuotaLabel, labelValue),
+		ObservedGeneration: ac.Generation,
+	})
+}
+
+// reserveQuota counts the other AlertChannels of the label value which exist in checklyhq.com or are reserved, and
+// reserves the AlertChannel if it's within the quota. Counting and reserving happen under one lock, so two workers
+// can't both take the last free slot. Reservations of AlertChannels which now have an ID, or are gone from the label
+// value, are dropped since the cache accounts for them.
+func (r *AlertChannelReconciler) reserveQuota(ctx context.Context, ac *checklyv1alpha1.AlertChannel, labelValue string, quota int) (used int, err error) {
+	r.quota.mu.Lock()
+	defer r.quota.mu.Unlock()
+
+	alertChannels := &checklyv1alpha1.AlertChannelList{}
+	err = r.List(ctx, alertChannels, client.MatchingLabels{r.QuotaLabel: labelValue})
+	if err != nil {
+		return 0, err
+	}
+
+	reserved := r.quota.reserved[labelValue]
+	pending := map[string]bool{}
+	for _, alertChannel := range alertChannels.Items {
+		if alertChannel.Status.ID == 0 && reserved[alertChannel.Name] {
+			pending[alertChannel.Name] = true
+		}
+		if alertChannel.Name != ac.Name && (alertChannel.Status.ID != 0 || reserved[alertChannel.Name]) {
+			used++
+		}
+	}
+
+	if used < quota {
+		pending[ac.Name] = true
+	}
+	r.quota.reserved[labelValue] = pending
+
+	return used, nil
+}
+
+// releaseQuota drops the reservation of the AlertChannel, it's called when its creation in checklyhq.com failed
+func (r *AlertChannelReconciler) releaseQuota(ac *checklyv1alpha1.AlertChannel) {
+	if r.quota == nil {
+		return
+	}
+	r.quota.mu.Lock()
+	defer r.quota.mu.Unlock()
+
+	delete(r.quota.reserved[ac.GetLabels()[r.QuotaLabel]], ac.Name)
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+func TestCheckQuota(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "quotas", Namespace: "checkly"},
+		Data:       map[string]string{"payments": "2", "search": "many"},
+	}
+	synced := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "synced", Labels: map[string]string{"team": "payments"}},
+		Status:     checklyv1alpha1.AlertChannelStatus{ID: 1},
+	}
+	pending := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "pending", Labels: map[string]string{"team": "payments"}},
+	}
+	other := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Labels: map[string]string{"team": "search"}},
+	}
+	unlimited := &checklyv1alpha1.AlertChannel{ObjectMeta: metav1.ObjectMeta{Name: "unlimited"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm, synced, pending, other, unlimited).
+		WithStatusSubresource(synced, pending, other, unlimited).Build()
+	r := &AlertChannelReconciler{Client: c, QuotaConfigMap: types.NamespacedName{Namespace: "checkly", Name: "quotas"}, QuotaLabel: "team", quota: newQuotaReservations()}
+	ctx := context.Background()
+
+	// Only alert channels created in checklyhq.com count towards the quota
+	err := r.checkQuota(ctx, pending)
+	if err != nil {
+		t.Fatalf("Expected the AlertChannel to be within its quota, got %v", err)
+	}
+	condition := meta.FindStatusCondition(pending.Status.Conditions, ConditionQuotaExceeded)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Message != `1 of 2 alert channels for team "payments" in use` {
+		t.Errorf("Expected the QuotaExceeded condition to report the usage, got %+v", condition)
+	}
+
+	synced2 := synced.DeepCopy()
+	synced2.ObjectMeta = metav1.ObjectMeta{Name: "synced-2", Labels: map[string]string{"team": "payments"}}
+	_ = c.Create(ctx, synced2)
+	synced2.Status.ID = 2
+	_ = c.Status().Update(ctx, synced2)
+
+	err = r.checkQuota(ctx, pending)
+	if err == nil {
+		t.Fatal("Expected the exhausted quota to be reported")
+	}
+	condition = meta.FindStatusCondition(pending.Status.Conditions, ConditionQuotaExceeded)
+	if condition == nil || condition.Status != metav1.ConditionTrue || condition.Reason != ReasonOverQuota {
+		t.Errorf("Expected the QuotaExceeded condition to be set, got %+v", condition)
+	}
+
+	if err = r.checkQuota(ctx, unlimited); err != nil {
+		t.Errorf("Expected no quota for an AlertChannel without a team, got %v", err)
+	}
+	if err = r.checkQuota(ctx, other); err == nil {
+		t.Error("Expected an error for an invalid quota")
+	}
+}
+
+func TestCheckQuotaConcurrent(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "quotas", Namespace: "checkly"},
+		Data:       map[string]string{"payments": "2"},
+	}
+	synced := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "synced", Labels: map[string]string{"team": "payments"}},
+		Status:     checklyv1alpha1.AlertChannelStatus{ID: 1},
+	}
+	objects := []client.Object{cm, synced}
+	var pending []*checklyv1alpha1.AlertChannel
+	for i := 0; i < 2; i++ {
+		ac := &checklyv1alpha1.AlertChannel{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pending-%d", i), Labels: map[string]string{"team": "payments"}},
+		}
+		pending = append(pending, ac)
+		objects = append(objects, ac)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).
+		WithStatusSubresource(&checklyv1alpha1.AlertChannel{}).Build()
+	r := &AlertChannelReconciler{Client: c, QuotaConfigMap: types.NamespacedName{Namespace: "checkly", Name: "quotas"}, QuotaLabel: "team", quota: newQuotaReservations()}
+	ctx := context.Background()
+
+	// Two workers creating alert channels with one slot left, neither ID is in the cache yet
+	errs := make([]error, len(pending))
+	var wg sync.WaitGroup
+	for i, ac := range pending {
+		wg.Add(1)
+		go func(i int, ac *checklyv1alpha1.AlertChannel) {
+			defer wg.Done()
+			errs[i] = r.checkQuota(ctx, ac)
+		}(i, ac)
+	}
+	wg.Wait()
+
+	admitted := -1
+	for i, err := range errs {
+		if err == nil {
+			if admitted != -1 {
+				t.Fatal("Expected only one AlertChannel to be admitted with one slot left")
+			}
+			admitted = i
+		}
+	}
+	if admitted == -1 {
+		t.Fatalf("Expected one AlertChannel to be admitted, got %v", errs)
+	}
+	refused := pending[1-admitted]
+
+	// The reservation holds until the admitted alert channel is observed with its ID
+	if err := r.checkQuota(ctx, refused); err == nil {
+		t.Error("Expected the reservation to count towards the quota")
+	}
+	_ = c.Get(ctx, client.ObjectKeyFromObject(pending[admitted]), pending[admitted])
+	pending[admitted].Status.ID = 2
+	_ = c.Status().Update(ctx, pending[admitted])
+	if err := r.checkQuota(ctx, refused); err == nil {
+		t.Error("Expected the created AlertChannel to count towards the quota")
+	}
+
+	// A deleted AlertChannel frees its slot and a failed creation releases the reservation
+	_ = c.Delete(ctx, pending[admitted])
+	if err := r.checkQuota(ctx, refused); err != nil {
+		t.Fatalf("Expected the freed slot to be admitted, got %v", err)
+	}
+	r.releaseQuota(refused)
+	other := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Labels: map[string]string{"team": "payments"}},
+	}
+	_ = c.Create(ctx, other)
+	if err := r.checkQuota(ctx, other); err != nil {
+		t.Errorf("Expected the released slot to be admitted, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	external "github.com/checkly/checkly-operator/external/checkly"
+	"github.com/checkly/checkly-operator/internal/metrics"
+)
+
+// reconcileRun describes a finished reconciliation of a resource for finishReconcile
+type reconcileRun struct {
+	kind      string
+	namespace string
+	name      string
+	object    client.Object
+	checklyID interface{}
+	operation string
+	start     time.Time
+
+	client       client.Client
+	summary      bool
+	breaker      *external.CircuitBreaker
+	deprecations *external.DeprecationTracker
+	recorder     record.EventRecorder
+}
+
+// finishReconcile is deferred by the reconcilers, it records the metrics and the summary of the reconciliation and
+// reports the API deprecations. Failures caused by an unavailable or rate limiting checklyhq.com API are requeued for
+// when the API can be called again, instead of with the error back-off.
+func finishReconcile(ctx context.Context, run reconcileRun, res ctrl.Result, err error) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	metrics.ObserveReconcile(run.kind, run.namespace, run.object.GetLabels(), err)
+	metrics.ObserveReconcileDuration(run.kind, run.operation, time.Since(run.start))
+	// Deleted resources no longer count as paused
+	if run.object.GetResourceVersion() == "" {
+		metrics.SetResourcePaused(run.kind, types.NamespacedName{Namespace: run.namespace, Name: run.name}.String(), false)
+	}
+	if run.summary {
+		logSummary(logger, run.operation, run.checklyID, time.Since(run.start), err)
+	}
+
+	// Requests failing during an outage are retried once the circuit breaker lets a probe through, instead of with the
+	// exponential back-off of every resource
+	if err != nil && run.breaker.Tripped() {
+		res, err = unavailableResult(ctx, run.client, run.breaker, run.kind, run.object), nil
+	} else if err == nil && !run.breaker.Tripped() {
+		availableErr := clearUnavailable(ctx, run.client, run.object)
+		if availableErr != nil {
+			logger.Error(availableErr, "Failed to update status", "kind", run.kind)
+		}
+	}
+
+	reportDeprecations(logger, run.recorder, run.deprecations, run.object)
+
+	// Rate limited requests are retried when the checklyhq.com API asks for it, instead of with the error back-off
+	if retryAfter, ok := external.RetryAfter(err); ok {
+		logger.V(1).Info("checklyhq.com API rate limit hit, backing off", "retry after", retryAfter)
+		res, err = ctrl.Result{RequeueAfter: retryAfter}, nil
+	}
+
+	return res, err
+}
+
+// unavailableResult flags the resource with the ChecklyUnavailable condition and requeues it once the circuit breaker
+// lets a probe through to checklyhq.com
+func unavailableResult(ctx context.Context, c client.Client, breaker *external.CircuitBreaker, kind string, obj client.Object) ctrl.Result {
+	retryAfter := breaker.RetryAfter()
+	log.FromContext(ctx).V(1).Info("checklyhq.com API unavailable, backing off", "retry after", retryAfter)
+
+	if created := obj.GetCreationTimestamp(); !created.IsZero() {
+		err := setStatusCondition(ctx, c, obj, metav1.Condition{
+			Type:               ConditionChecklyUnavailable,
+			Status:             metav1.ConditionTrue,
+			Reason:             ReasonCircuitOpen,
+			Message:            fmt.Sprintf("The checklyhq.com API keeps failing, the %s is synced once it recovers", kind),
+			ObservedGeneration: obj.GetGeneration(),
+		})
+		if err != nil {
+			log.FromContext(ctx).Error(err, "Failed to update status", "kind", kind)
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: retryAfter}
+}
+
+// clearUnavailable marks the ChecklyUnavailable condition as resolved once the resource synced again
+func clearUnavailable(ctx context.Context, c client.Client, obj client.Object) error {
+	conditions, _, err := statusConditions(obj)
+	if err != nil || obj.GetDeletionTimestamp() != nil || !meta.IsStatusConditionTrue(*conditions, ConditionChecklyUnavailable) {
+		return nil
+	}
+
+	return setStatusCondition(ctx, c, obj, metav1.Condition{
+		Type:               ConditionChecklyUnavailable,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonCircuitClosed,
+		Message:            "The checklyhq.com API is available",
+		ObservedGeneration: obj.GetGeneration(),
+	})
+}
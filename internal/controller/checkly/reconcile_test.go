@@ -0,0 +1,1260 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/checkly/checkly-go-sdk"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	external "github.com/checkly/checkly-operator/external/checkly"
+	"github.com/checkly/checkly-operator/internal/audit"
+	"github.com/checkly/checkly-operator/internal/notify"
+)
+
+func TestReconcileReadError(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	readErr := errors.New("etcdserver: request timed out")
+	c := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			return readErr
+		},
+	}).Build()
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "test"}}
+	reconcilers := map[string]interface {
+		Reconcile(context.Context, ctrl.Request) (ctrl.Result, error)
+	}{
+		"AlertChannel": &AlertChannelReconciler{Client: c, Scheme: scheme, ControllerDomain: "k8s.checklyhq.com"},
+		"ApiCheck":     &ApiCheckReconciler{Client: c, Scheme: scheme, ControllerDomain: "k8s.checklyhq.com"},
+		"Group":        &GroupReconciler{Client: c, Scheme: scheme, ControllerDomain: "k8s.checklyhq.com"},
+	}
+
+	for kind, r := range reconcilers {
+		_, err := r.Reconcile(context.TODO(), req)
+		if !errors.Is(err, readErr) {
+			t.Errorf("Expected the %s reconciler to return the read error, got %v", kind, err)
+		}
+	}
+}
+
+func TestReconcileDeleteAlertChannel(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deleted = append(deleted, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	apiClient := checkly.NewClient(server.URL, "key", server.Client(), nil)
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	now := metav1.Now()
+	ac := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "oncall",
+			DeletionTimestamp: &now,
+			Finalizers:        []string{"k8s.checklyhq.com/finalizer"},
+		},
+		Status: checklyv1alpha1.AlertChannelStatus{ID: 42},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ac).WithStatusSubresource(ac).
+		WithIndex(&checklyv1alpha1.Group{}, groupAlertChannelsIndex, func(o client.Object) []string {
+			return o.(*checklyv1alpha1.Group).Spec.AlertChannels
+		}).
+		WithIndex(&checklyv1alpha1.ApiCheck{}, apiCheckAlertChannelsIndex, func(o client.Object) []string {
+			return subscribedAlertChannels(o.(*checklyv1alpha1.ApiCheck).Spec.AlertChannelSubscriptions)
+		}).
+		Build()
+	r := &AlertChannelReconciler{Client: c, Scheme: scheme, ApiClient: apiClient, ControllerDomain: "k8s.checklyhq.com"}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "oncall"}}
+	_, err := r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "/v1/alert-channels/42" {
+		t.Errorf("Expected the checkly AlertChannel 42 to be deleted, got %v", deleted)
+	}
+
+	// The finalizer was removed, so the AlertChannel is gone and the next reconciliation doesn't delete anything
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil || len(deleted) != 1 {
+		t.Errorf("Expected the removed AlertChannel to be left alone, got %v after %v", err, deleted)
+	}
+}
+
+func TestReconcileRateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	httpClient := server.Client()
+	httpClient.Transport = external.RetryAfterTransport(httpClient.Transport)
+	apiClient := external.NewRetryingClient(checkly.NewClient(server.URL, "key", httpClient, nil), 3)
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	group := &checklyv1alpha1.Group{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments", Finalizers: []string{"k8s.checklyhq.com/finalizer"}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(group).WithStatusSubresource(group).Build()
+	r := &GroupReconciler{Client: c, Scheme: scheme, ApiClient: apiClient, ControllerDomain: "k8s.checklyhq.com"}
+
+	res, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "payments"}})
+	if err != nil || res.RequeueAfter != 30*time.Second {
+		t.Errorf("Expected a requeue after 30s, got %+v, %v", res, err)
+	}
+}
+
+func TestReconcileChecklyUnavailable(t *testing.T) {
+	available := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 5, "name": "login", "script": "await login()"}`))
+	}))
+	defer server.Close()
+	breaker := external.NewCircuitBreaker(1, 10*time.Millisecond)
+	httpClient := server.Client()
+	httpClient.Transport = breaker.Transport(httpClient.Transport)
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	snippet := &checklyv1alpha1.Snippet{
+		ObjectMeta: metav1.ObjectMeta{Name: "login", CreationTimestamp: metav1.Now(), Finalizers: []string{"k8s.checklyhq.com/finalizer"}},
+		Spec:       checklyv1alpha1.SnippetSpec{Script: "await login()"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(snippet).WithStatusSubresource(snippet).Build()
+	r := &SnippetReconciler{
+		Client:           c,
+		Scheme:           scheme,
+		ApiClient:        checkly.NewClient(server.URL, "key", httpClient, nil),
+		ControllerDomain: "k8s.checklyhq.com",
+		Breaker:          breaker,
+		Recorder:         record.NewFakeRecorder(10),
+	}
+
+	// Every kind is flagged while the checklyhq.com API is unavailable, not only AlertChannels
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "login"}}
+	res, err := r.Reconcile(context.TODO(), req)
+	if err != nil || res.RequeueAfter == 0 {
+		t.Errorf("Expected a requeue once the circuit breaker lets a probe through, got %+v, %v", res, err)
+	}
+	_ = c.Get(context.TODO(), req.NamespacedName, snippet)
+	if !meta.IsStatusConditionTrue(snippet.Status.Conditions, ConditionChecklyUnavailable) {
+		t.Errorf("Expected the Snippet to be flagged as unavailable, got %+v", snippet.Status.Conditions)
+	}
+
+	available = true
+	time.Sleep(20 * time.Millisecond)
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_ = c.Get(context.TODO(), req.NamespacedName, snippet)
+	if snippet.Status.ID != 5 || !meta.IsStatusConditionFalse(snippet.Status.Conditions, ConditionChecklyUnavailable) {
+		t.Errorf("Expected the Snippet to be created and the condition to be resolved, got %+v", snippet.Status)
+	}
+}
+
+func TestReconcileObserveDrift(t *testing.T) {
+	var updates int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			updates++
+		}
+		// The address was changed in the checklyhq.com UI
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 5, "type": "EMAIL", "config": {"address": "ui@bar.baz"}, "sendRecovery": true, "sendFailure": true, "sendDegraded": false, "sslExpiry": false, "sslExpiryThreshold": 30}`))
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	ac := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "oncall",
+			Finalizers:  []string{"k8s.checklyhq.com/finalizer"},
+			Annotations: map[string]string{"k8s.checklyhq.com/drift-mode": DriftModeObserve},
+		},
+		Spec:   checklyv1alpha1.AlertChannelSpec{Email: checkly.AlertChannelEmail{Address: "oncall@bar.baz"}},
+		Status: checklyv1alpha1.AlertChannelStatus{ID: 5},
+	}
+	appliedHash, err := external.AlertChannelAppliedHash(nil, ac, checkly.AlertChannelOpsgenie{}, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	ac.Annotations["k8s.checklyhq.com/last-applied-hash"] = appliedHash
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ac).WithStatusSubresource(ac).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &AlertChannelReconciler{
+		Client:           c,
+		Scheme:           scheme,
+		ApiClient:        checkly.NewClient(server.URL, "key", server.Client(), nil),
+		ControllerDomain: "k8s.checklyhq.com",
+		DriftInterval:    10 * time.Minute,
+		Recorder:         recorder,
+	}
+
+	_, err = r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "oncall"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if updates != 0 {
+		t.Errorf("Expected the drift not to be reverted, got %d updates", updates)
+	}
+
+	stored := &checklyv1alpha1.AlertChannel{}
+	_ = c.Get(context.TODO(), client.ObjectKeyFromObject(ac), stored)
+	condition := meta.FindStatusCondition(stored.Status.Conditions, ConditionDriftDetected)
+	if condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Errorf("Expected the drift to be reported, got %+v", condition)
+	}
+}
+
+func TestReconcileObserveDriftMaskedSecret(t *testing.T) {
+	const apiKey = "01234567-89ab-cdef-0123-456789abcdef"
+
+	// checklyhq.com masks the API key, the rest of the alert channel is unchanged
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 5, "type": "OPSGENIE", "config": {"name": "oncall", "apiKey": "*****", "region": "EU", "priority": "P3"}, "sendRecovery": true, "sendFailure": true, "sendDegraded": false, "sslExpiry": false, "sslExpiryThreshold": 30}`))
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "opsgenie", Namespace: "checkly"},
+		Data:       map[string][]byte{"API_KEY": []byte(apiKey)},
+	}
+	ac := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "oncall",
+			Finalizers:  []string{"k8s.checklyhq.com/finalizer"},
+			Annotations: map[string]string{"k8s.checklyhq.com/drift-mode": DriftModeObserve},
+		},
+		Spec: checklyv1alpha1.AlertChannelSpec{
+			SendRecovery: true,
+			SendFailure:  true,
+			OpsGenie: checklyv1alpha1.AlertChannelOpsGenie{
+				APISecret: corev1.ObjectReference{Namespace: "checkly", Name: "opsgenie", FieldPath: "API_KEY"},
+				Region:    "EU",
+				Priority:  "P3",
+			},
+		},
+		Status: checklyv1alpha1.AlertChannelStatus{ID: 5},
+	}
+	opsGenieConfig := checkly.AlertChannelOpsgenie{Name: "oncall", APIKey: apiKey, Region: "EU", Priority: "P3"}
+	appliedHash, err := external.AlertChannelAppliedHash(nil, ac, opsGenieConfig, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	ac.Annotations["k8s.checklyhq.com/last-applied-hash"] = appliedHash
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret, ac).WithStatusSubresource(ac).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &AlertChannelReconciler{
+		Client:           c,
+		Scheme:           scheme,
+		ApiClient:        checkly.NewClient(server.URL, "key", server.Client(), nil),
+		ControllerDomain: "k8s.checklyhq.com",
+		DriftInterval:    10 * time.Minute,
+		Recorder:         recorder,
+	}
+
+	_, err = r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "oncall"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	stored := &checklyv1alpha1.AlertChannel{}
+	_ = c.Get(context.TODO(), client.ObjectKeyFromObject(ac), stored)
+	condition := meta.FindStatusCondition(stored.Status.Conditions, ConditionDriftDetected)
+	if condition == nil || condition.Status != metav1.ConditionFalse {
+		t.Errorf("Expected the masked API key not to be reported as drift, got %+v", condition)
+	}
+	for len(recorder.Events) != 0 {
+		if event := <-recorder.Events; strings.Contains(event, "DriftDetected") {
+			t.Errorf("Expected no drift event, got %s", event)
+		}
+	}
+
+	// The reported changes are filtered as well
+	err = r.reportDrift(context.TODO(), stored, []external.AlertChannelChange{{Field: "config.apiKey", From: "REDACTED", To: "REDACTED"}})
+	if err != nil || meta.IsStatusConditionTrue(stored.Status.Conditions, ConditionDriftDetected) {
+		t.Errorf("Expected a masked API key change not to be reported as drift, got %v", err)
+	}
+}
+
+func TestReportDeprecations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+	}))
+	defer server.Close()
+
+	deprecations := external.NewDeprecationTracker()
+	resp, err := (&http.Client{Transport: deprecations.Transport(http.DefaultTransport)}).Get(server.URL + "/v1/checks")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	recorder := record.NewFakeRecorder(10)
+	reportDeprecations(logr.Discard(), recorder, deprecations, &checklyv1alpha1.Group{})
+	if len(recorder.Events) != 0 {
+		t.Error("Expected the warnings to be kept for an object which is gone")
+	}
+
+	reportDeprecations(logr.Discard(), recorder, deprecations, &checklyv1alpha1.Group{ObjectMeta: metav1.ObjectMeta{Name: "payments"}})
+	if len(recorder.Events) != 1 {
+		t.Fatalf("Expected a single event, got %d", len(recorder.Events))
+	}
+	if event := <-recorder.Events; event != "Warning APIDeprecation checklyhq.com API GET /v1/checks is deprecated (Deprecation: true)" {
+		t.Errorf("Expected an APIDeprecation warning, got %s", event)
+	}
+}
+
+func TestReconcileAccountCredentials(t *testing.T) {
+	var accounts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accounts = append(accounts, r.Header.Get("x-checkly-account"))
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(`{"id": 7}`))
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "acme", Namespace: "checkly"},
+		Data:       map[string][]byte{"apikey": []byte("key"), "accountid": []byte("acme")},
+	}
+	group := &checklyv1alpha1.Group{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments", Finalizers: []string{"k8s.checklyhq.com/finalizer"}},
+		Spec:       checklyv1alpha1.GroupSpec{Credentials: corev1.SecretReference{Namespace: "checkly", Name: "acme"}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret, group).WithStatusSubresource(group).Build()
+	r := &GroupReconciler{
+		Client:           c,
+		Scheme:           scheme,
+		ApiClient:        checkly.NewClient(server.URL, "operator", server.Client(), nil),
+		Accounts:         external.NewAccounts(server.URL, server.Client(), nil),
+		ControllerDomain: "k8s.checklyhq.com",
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "payments"}}
+	_, err := r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(accounts) == 0 || accounts[0] != "acme" {
+		t.Errorf("Expected the group to be created in the account of its credentials, got %v", accounts)
+	}
+
+	err = c.Get(context.TODO(), req.NamespacedName, group)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if group.Status.ID != 7 || group.Status.AccountID != "acme" {
+		t.Errorf("Expected the account of the group to be recorded, got %+v", group.Status)
+	}
+
+	// Credentials of another account would update or delete the group in the wrong account
+	secret.Data["accountid"] = []byte("other")
+	err = c.Update(context.TODO(), secret)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	calls := len(accounts)
+	_, err = r.Reconcile(context.TODO(), req)
+	if err == nil || len(accounts) != calls {
+		t.Errorf("Expected the group to be left alone with credentials of another account, got %v after %d calls", err, len(accounts)-calls)
+	}
+}
+
+func TestReconcileHeartbeatCheck(t *testing.T) {
+	var updates int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodPut:
+			updates++
+		}
+		w.Write([]byte(`{"id": "7", "name": "backup", "activated": true, "tags": ["checkly-operator", "jobs"], "heartbeat": {"period": 1, "periodUnit": "days", "grace": 30, "graceUnit": "minutes", "pingToken": "token"}}`))
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	heartbeatCheck := &checklyv1alpha1.HeartbeatCheck{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "jobs", Finalizers: []string{"k8s.checklyhq.com/finalizer"}},
+		Spec:       checklyv1alpha1.HeartbeatCheckSpec{Period: 1, PeriodUnit: "days", Grace: 30, GraceUnit: "minutes"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(heartbeatCheck).WithStatusSubresource(heartbeatCheck).Build()
+	r := &HeartbeatCheckReconciler{
+		Client:           c,
+		Scheme:           scheme,
+		ApiClient:        checkly.NewClient(server.URL, "key", server.Client(), nil),
+		ControllerDomain: "k8s.checklyhq.com",
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "jobs", Name: "backup"}}
+	_, err := r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	err = c.Get(context.TODO(), req.NamespacedName, heartbeatCheck)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if heartbeatCheck.Status.ID != "7" || heartbeatCheck.Status.PingTokenSecret != "backup-heartbeat" {
+		t.Errorf("Expected the ID and ping token secret in the status, got %+v", heartbeatCheck.Status)
+	}
+	if !meta.IsStatusConditionTrue(heartbeatCheck.Status.Conditions, ConditionSynced) {
+		t.Errorf("Expected the HeartbeatCheck to be synced, got %v", heartbeatCheck.Status.Conditions)
+	}
+
+	// The ping token is kept in a secret owned by the HeartbeatCheck
+	secret := &corev1.Secret{}
+	err = c.Get(context.TODO(), types.NamespacedName{Namespace: "jobs", Name: "backup-heartbeat"}, secret)
+	if err != nil {
+		t.Fatalf("Expected the ping token secret, got %v", err)
+	}
+	if string(secret.Data["pingtoken"]) != "token" || string(secret.Data["url"]) != "https://ping.checklyhq.com/token" {
+		t.Errorf("Unexpected ping token secret %v", secret.Data)
+	}
+	if len(secret.OwnerReferences) != 1 || secret.OwnerReferences[0].Name != "backup" {
+		t.Errorf("Expected the secret to be owned by the HeartbeatCheck, got %v", secret.OwnerReferences)
+	}
+
+	// An unchanged heartbeat check isn't written again
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil || updates != 0 {
+		t.Errorf("Expected no update of the unchanged heartbeat check, got %d updates, %v", updates, err)
+	}
+}
+
+func TestReconcileMaintenanceWindow(t *testing.T) {
+	var requests, writes int
+	var stored checkly.MaintenanceWindow
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodGet {
+			writes++
+			json.NewDecoder(r.Body).Decode(&stored)
+			stored.ID = 3
+		}
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode(stored)
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	window := &checklyv1alpha1.MaintenanceWindow{
+		ObjectMeta: metav1.ObjectMeta{Name: "release", Generation: 1, Finalizers: []string{"k8s.checklyhq.com/finalizer"}},
+		Spec: checklyv1alpha1.MaintenanceWindowSpec{
+			StartsAt: "2024-01-31T22:00",
+			EndsAt:   "2024-01-31T23:30",
+			Timezone: "Europe/Amsterdam",
+			Tags:     []string{"payments"},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(window).WithStatusSubresource(window).Build()
+	r := &MaintenanceWindowReconciler{
+		Client:           c,
+		Scheme:           scheme,
+		ApiClient:        checkly.NewClient(server.URL, "key", server.Client(), nil),
+		ControllerDomain: "k8s.checklyhq.com",
+		Recorder:         record.NewFakeRecorder(10),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "release"}}
+	_, err := r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_ = c.Get(context.TODO(), req.NamespacedName, window)
+	if window.Status.ID != 3 || !meta.IsStatusConditionTrue(window.Status.Conditions, ConditionSynced) {
+		t.Errorf("Expected the window to be created, got %+v", window.Status)
+	}
+	if stored.StartsAt != "2024-01-31T21:00:00.000Z" {
+		t.Errorf("Expected the start in UTC, got %q", stored.StartsAt)
+	}
+
+	// An unchanged window isn't written again, it's re-synced after the drift interval
+	r.DriftInterval = time.Hour
+	res, err := r.Reconcile(context.TODO(), req)
+	if err != nil || writes != 1 || res.RequeueAfter != time.Hour {
+		t.Errorf("Expected no update of the unchanged window, got %d writes, %+v, %v", writes, res, err)
+	}
+
+	// A window changed in checklyhq.com is corrected
+	stored.Tags = []string{"checkout"}
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil || writes != 2 || stored.Tags[0] != "payments" {
+		t.Errorf("Expected the drifted window to be updated, got %d writes, %v, %v", writes, stored.Tags, err)
+	}
+
+	// A window ending before it starts never reaches the API
+	_ = c.Get(context.TODO(), req.NamespacedName, window)
+	window.Spec.EndsAt = "2024-01-31T21:00"
+	window.Generation = 2
+	_ = c.Update(context.TODO(), window)
+	requests = 0
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil || requests != 0 {
+		t.Errorf("Expected no request for the invalid window, got %d requests, %v", requests, err)
+	}
+
+	_ = c.Get(context.TODO(), req.NamespacedName, window)
+	synced := meta.FindStatusCondition(window.Status.Conditions, ConditionSynced)
+	if synced == nil || synced.Status != metav1.ConditionFalse || synced.Reason != ReasonInvalid {
+		t.Errorf("Expected the window to be invalid, got %v", synced)
+	}
+}
+
+func TestReconcileVariableGroup(t *testing.T) {
+	var requests []string
+	values := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		var variable checkly.EnvironmentVariable
+		json.NewDecoder(r.Body).Decode(&variable)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			key := strings.TrimPrefix(r.URL.Path, "/v1/variables/")
+			value, ok := values[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(checkly.EnvironmentVariable{Key: key, Value: value, Locked: key == "TOKEN"})
+			return
+		case http.MethodPut:
+			// Variables don't exist upstream until they're created
+			if _, ok := values[variable.Key]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		values[variable.Key] = variable.Value
+		json.NewEncoder(w).Encode(variable)
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments", Namespace: "default"},
+		Data:       map[string][]byte{"TOKEN": []byte("old")},
+	}
+	group := &checklyv1alpha1.VariableGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments", Generation: 1, Finalizers: []string{"k8s.checklyhq.com/finalizer"}},
+		Spec: checklyv1alpha1.VariableGroupSpec{Variables: []checklyv1alpha1.Variable{
+			{Key: "BASE_URL", Value: "https://foo.bar"},
+			{Key: "TOKEN", Locked: true, ValueSecret: corev1.ObjectReference{Name: "payments", Namespace: "default", FieldPath: "TOKEN"}},
+		}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(group, secret).WithStatusSubresource(group).Build()
+	r := &VariableGroupReconciler{
+		Client:           c,
+		Scheme:           scheme,
+		ApiClient:        checkly.NewClient(server.URL, "key", server.Client(), nil),
+		ControllerDomain: "k8s.checklyhq.com",
+		Recorder:         record.NewFakeRecorder(10),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "payments"}}
+	_, err := r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_ = c.Get(context.TODO(), req.NamespacedName, group)
+	if len(group.Status.Keys) != 2 || values["TOKEN"] != "old" || !meta.IsStatusConditionTrue(group.Status.Conditions, ConditionSynced) {
+		t.Errorf("Expected the variables to be created, got %+v, %v", group.Status, values)
+	}
+
+	// An unchanged group isn't written again, it's re-synced after the drift interval
+	requests = nil
+	r.DriftInterval = time.Hour
+	res, err := r.Reconcile(context.TODO(), req)
+	if err != nil || len(requests) != 2 || res.RequeueAfter != time.Hour {
+		t.Errorf("Expected the unchanged group to only be read, got %v, %+v, %v", requests, res, err)
+	}
+
+	// A value changed in checklyhq.com is corrected
+	values["BASE_URL"] = "https://edited.bar"
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil || values["BASE_URL"] != "https://foo.bar" {
+		t.Errorf("Expected the drifted variable to be updated, got %v, %v", values, err)
+	}
+
+	// Rotating the secret updates the variable without the group changing
+	secret.Data["TOKEN"] = []byte("new")
+	_ = c.Update(context.TODO(), secret)
+	if queued := r.secretChanged(context.TODO(), secret); len(queued) != 1 {
+		t.Errorf("Expected the group to be queued for the rotated secret, got %v", queued)
+	}
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil || values["TOKEN"] != "new" {
+		t.Errorf("Expected the rotated value to be synced, got %v, %v", values, err)
+	}
+
+	// Variables removed from the spec are deleted
+	_ = c.Get(context.TODO(), req.NamespacedName, group)
+	group.Spec.Variables = group.Spec.Variables[1:]
+	group.Generation = 2
+	_ = c.Update(context.TODO(), group)
+	requests = nil
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil || requests[len(requests)-1] != "DELETE /v1/variables/BASE_URL" {
+		t.Errorf("Expected the removed variable to be deleted, got %v, %v", requests, err)
+	}
+
+	_ = c.Get(context.TODO(), req.NamespacedName, group)
+	if len(group.Status.Keys) != 1 || group.Status.Keys[0] != "TOKEN" {
+		t.Errorf("Expected only the remaining key in the status, got %v", group.Status.Keys)
+	}
+}
+
+func TestReconcileVariableGroupPartialFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var variable checkly.EnvironmentVariable
+		json.NewDecoder(r.Body).Decode(&variable)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPut:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case variable.Key == "TOKEN":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(variable)
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	group := &checklyv1alpha1.VariableGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments", Generation: 1, Finalizers: []string{"k8s.checklyhq.com/finalizer"}},
+		Spec: checklyv1alpha1.VariableGroupSpec{Variables: []checklyv1alpha1.Variable{
+			{Key: "BASE_URL", Value: "https://foo.bar"},
+			{Key: "TOKEN", Value: "secret"},
+		}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(group).WithStatusSubresource(group).Build()
+	r := &VariableGroupReconciler{
+		Client:           c,
+		Scheme:           scheme,
+		ApiClient:        checkly.NewClient(server.URL, "key", server.Client(), nil),
+		ControllerDomain: "k8s.checklyhq.com",
+		Recorder:         record.NewFakeRecorder(10),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "payments"}}
+	_, err := r.Reconcile(context.TODO(), req)
+	if err == nil {
+		t.Fatal("Expected the failed variable to be returned as an error")
+	}
+
+	// The variable written before the failure is tracked, so it's deleted with the group
+	_ = c.Get(context.TODO(), req.NamespacedName, group)
+	if len(group.Status.Keys) != 1 || group.Status.Keys[0] != "BASE_URL" || group.Status.AppliedHash != "" {
+		t.Errorf("Expected only the written key in the status, got %+v", group.Status)
+	}
+	if meta.IsStatusConditionTrue(group.Status.Conditions, ConditionSynced) {
+		t.Errorf("Expected the group not to be synced, got %v", group.Status.Conditions)
+	}
+}
+
+func TestReconcileDashboard(t *testing.T) {
+	var requests, writes int
+	var stored checkly.Dashboard
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodGet {
+			writes++
+			json.NewDecoder(r.Body).Decode(&stored)
+			stored.ID, stored.DashboardID = 8, "a1b2"
+		}
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode(stored)
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	dashboard := &checklyv1alpha1.Dashboard{
+		ObjectMeta: metav1.ObjectMeta{Name: "status", Generation: 1, Finalizers: []string{"k8s.checklyhq.com/finalizer"}},
+		Spec:       checklyv1alpha1.DashboardSpec{CustomURL: "acme-status", Tags: []string{"public"}},
+	}
+	other := &checklyv1alpha1.Dashboard{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Generation: 1, Finalizers: []string{"k8s.checklyhq.com/finalizer"}},
+		Spec:       checklyv1alpha1.DashboardSpec{CustomURL: "other-status"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(dashboard, other).WithStatusSubresource(dashboard, other).Build()
+	r := &DashboardReconciler{
+		Client:           c,
+		Scheme:           scheme,
+		ApiClient:        checkly.NewClient(server.URL, "key", server.Client(), nil),
+		ControllerDomain: "k8s.checklyhq.com",
+		Recorder:         record.NewFakeRecorder(10),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "status"}}
+	_, err := r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_ = c.Get(context.TODO(), req.NamespacedName, dashboard)
+	if dashboard.Status.ID != "a1b2" || !meta.IsStatusConditionTrue(dashboard.Status.Conditions, ConditionSynced) {
+		t.Errorf("Expected the dashboard to be created, got %+v", dashboard.Status)
+	}
+
+	// An unchanged dashboard isn't written again, it's re-synced after the drift interval
+	r.DriftInterval = time.Hour
+	res, err := r.Reconcile(context.TODO(), req)
+	if err != nil || writes != 1 || res.RequeueAfter != time.Hour {
+		t.Errorf("Expected no update of the unchanged dashboard, got %d writes, %+v, %v", writes, res, err)
+	}
+
+	// A dashboard changed in checklyhq.com is corrected
+	stored.RefreshRate = 600
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil || writes != 2 || stored.RefreshRate != 60 {
+		t.Errorf("Expected the drifted dashboard to be updated, got %d writes, %d, %v", writes, stored.RefreshRate, err)
+	}
+
+	// A custom URL used by another dashboard never reaches the API
+	_ = c.Get(context.TODO(), types.NamespacedName{Name: "other"}, other)
+	other.Spec.CustomURL = "acme-status"
+	other.Generation = 2
+	_ = c.Update(context.TODO(), other)
+	requests = 0
+	_, err = r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "other"}})
+	if err != nil || requests != 0 {
+		t.Errorf("Expected no request for the taken custom URL, got %d requests, %v", requests, err)
+	}
+
+	_ = c.Get(context.TODO(), types.NamespacedName{Name: "other"}, other)
+	synced := meta.FindStatusCondition(other.Status.Conditions, ConditionSynced)
+	if synced == nil || synced.Status != metav1.ConditionFalse || synced.Reason != ReasonInvalid {
+		t.Errorf("Expected the dashboard to be invalid, got %v", synced)
+	}
+
+	// Without the finalizer the dashboard is deleted right away
+	r.SkipFinalizer = true
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_ = c.Get(context.TODO(), req.NamespacedName, dashboard)
+	if len(dashboard.Finalizers) != 0 {
+		t.Errorf("Expected the disabled finalizer to be removed, got %v", dashboard.Finalizers)
+	}
+}
+
+func TestReconcilePrivateLocation(t *testing.T) {
+	keys := `[{"id": "k1", "maskedKey": "pl_...abc", "rawKey": "pl_abc"}]`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(`{"id": "pl1", "name": "datacenter", "slugName": "eu-datacenter", "keys": ` + keys + `}`))
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	privateLocation := &checklyv1alpha1.PrivateLocation{
+		ObjectMeta: metav1.ObjectMeta{Name: "datacenter", Namespace: "agents", Generation: 1, Finalizers: []string{"k8s.checklyhq.com/finalizer"}},
+		Spec:       checklyv1alpha1.PrivateLocationSpec{SlugName: "eu-datacenter"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(privateLocation).WithStatusSubresource(privateLocation).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &PrivateLocationReconciler{
+		Client:           c,
+		Scheme:           scheme,
+		ApiClient:        checkly.NewClient(server.URL, "key", server.Client(), nil),
+		ControllerDomain: "k8s.checklyhq.com",
+		Recorder:         recorder,
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "agents", Name: "datacenter"}}
+	_, err := r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_ = c.Get(context.TODO(), req.NamespacedName, privateLocation)
+	if privateLocation.Status.ID != "pl1" || privateLocation.Status.KeySecret != "datacenter-agent-keys" {
+		t.Errorf("Expected the ID and agent key secret in the status, got %+v", privateLocation.Status)
+	}
+
+	// The agent keys are kept in a secret owned by the PrivateLocation
+	secret := &corev1.Secret{}
+	err = c.Get(context.TODO(), types.NamespacedName{Namespace: "agents", Name: "datacenter-agent-keys"}, secret)
+	if err != nil {
+		t.Fatalf("Expected the agent key secret, got %v", err)
+	}
+	if string(secret.Data["API_KEY"]) != "pl_abc" || string(secret.Data["k1"]) != "pl_abc" {
+		t.Errorf("Unexpected agent key secret %v", secret.Data)
+	}
+	if len(secret.OwnerReferences) != 1 || secret.OwnerReferences[0].Name != "datacenter" {
+		t.Errorf("Expected the secret to be owned by the PrivateLocation, got %v", secret.OwnerReferences)
+	}
+
+	// A rotated key replaces the revoked one, the masked key read back keeps its value from the secret
+	// The API doesn't order the keys, API_KEY holds the newest one
+	keys = `[{"id": "k2", "maskedKey": "pl_...def", "rawKey": "pl_def", "created_at": "2024-02-01T00:00:00.000Z"}, {"id": "k1", "maskedKey": "pl_...abc", "created_at": "2024-01-01T00:00:00.000Z"}]`
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_ = c.Get(context.TODO(), types.NamespacedName{Namespace: "agents", Name: "datacenter-agent-keys"}, secret)
+	if len(secret.Data) != 3 || string(secret.Data["API_KEY"]) != "pl_def" || string(secret.Data["k1"]) != "pl_abc" {
+		t.Errorf("Expected the newest key under API_KEY, got %v", secret.Data)
+	}
+
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	keys = `[{"id": "k2", "maskedKey": "pl_...def"}]`
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_ = c.Get(context.TODO(), types.NamespacedName{Namespace: "agents", Name: "datacenter-agent-keys"}, secret)
+	if len(secret.Data) != 2 || string(secret.Data["API_KEY"]) != "pl_def" || string(secret.Data["k2"]) != "pl_def" {
+		t.Errorf("Expected only the rotated key in the secret, got %v", secret.Data)
+	}
+	if len(recorder.Events) != 2 {
+		t.Errorf("Expected two KeysRotated events, got %d", len(recorder.Events))
+	}
+}
+
+func TestReconcilePrivateLocationKeySecretFailed(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(`{"id": "pl1", "name": "datacenter", "slugName": "eu-datacenter", "keys": [{"id": "k1", "rawKey": "pl_abc"}]}`))
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	privateLocation := &checklyv1alpha1.PrivateLocation{
+		ObjectMeta: metav1.ObjectMeta{Name: "datacenter", Namespace: "agents", Generation: 1, Finalizers: []string{"k8s.checklyhq.com/finalizer"}},
+		Spec:       checklyv1alpha1.PrivateLocationSpec{SlugName: "eu-datacenter"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(privateLocation).WithStatusSubresource(privateLocation).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				if _, ok := obj.(*corev1.Secret); ok {
+					return errors.New("secrets are forbidden")
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+		}).
+		Build()
+	r := &PrivateLocationReconciler{
+		Client:           c,
+		Scheme:           scheme,
+		ApiClient:        checkly.NewClient(server.URL, "key", server.Client(), nil),
+		ControllerDomain: "k8s.checklyhq.com",
+		Recorder:         record.NewFakeRecorder(10),
+	}
+
+	// The keys can't be read again, the private location is deleted so the retry creates one with new keys
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "agents", Name: "datacenter"}}
+	_, err := r.Reconcile(context.TODO(), req)
+	if err == nil {
+		t.Fatal("Expected the secret write to fail")
+	}
+	if len(requests) != 2 || requests[1] != "DELETE /v1/private-locations/pl1" {
+		t.Errorf("Expected the checkly private location to be deleted again, got %v", requests)
+	}
+	_ = c.Get(context.TODO(), req.NamespacedName, privateLocation)
+	if privateLocation.Status.ID != "" {
+		t.Errorf("Expected no ID in the status, got %q", privateLocation.Status.ID)
+	}
+}
+
+func TestReconcileApiCheckGroupReference(t *testing.T) {
+	var created checkly.Check
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			_ = json.NewDecoder(r.Body).Decode(&created)
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(`{"id": "c1"}`))
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	group := &checklyv1alpha1.Group{ObjectMeta: metav1.ObjectMeta{Name: "payments"}}
+	apiCheck := &checklyv1alpha1.ApiCheck{
+		ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: "shop", Generation: 1, Finalizers: []string{"k8s.checklyhq.com/finalizer"}},
+		Spec:       checklyv1alpha1.ApiCheckSpec{Endpoint: "https://foo.bar/checkout", Success: "200", Group: "payments"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(group, apiCheck).WithStatusSubresource(group, apiCheck).Build()
+	r := &ApiCheckReconciler{
+		Client:           c,
+		Scheme:           scheme,
+		ApiClient:        checkly.NewClient(server.URL, "key", server.Client(), nil),
+		ControllerDomain: "k8s.checklyhq.com",
+		Recorder:         record.NewFakeRecorder(10),
+	}
+
+	// The check waits for the group it references by name to be created in checklyhq.com
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "shop", Name: "checkout"}}
+	res, err := r.Reconcile(context.TODO(), req)
+	if err != nil || !res.Requeue || requests != 0 {
+		t.Fatalf("Expected a requeue without requests, got %+v, %d requests, %v", res, requests, err)
+	}
+
+	group.Status.ID = 7
+	err = c.Status().Update(context.TODO(), group)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if created.GroupID != 7 {
+		t.Errorf("Expected the check to be created in group 7, got %d", created.GroupID)
+	}
+
+	_ = c.Get(context.TODO(), req.NamespacedName, apiCheck)
+	if apiCheck.Status.ID != "c1" || apiCheck.Status.GroupID != 7 {
+		t.Errorf("Expected the check and group ID in the status, got %+v", apiCheck.Status)
+	}
+}
+
+func TestReconcilePaused(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	apiClient := checkly.NewClient(server.URL, "key", server.Client(), nil)
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	now := metav1.Now()
+	ac := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "oncall",
+			Annotations:       map[string]string{"k8s.checklyhq.com/paused": "true"},
+			DeletionTimestamp: &now,
+			Finalizers:        []string{"k8s.checklyhq.com/finalizer"},
+		},
+		Status: checklyv1alpha1.AlertChannelStatus{ID: 42},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ac).WithStatusSubresource(ac).
+		WithIndex(&checklyv1alpha1.Group{}, groupAlertChannelsIndex, func(o client.Object) []string {
+			return o.(*checklyv1alpha1.Group).Spec.AlertChannels
+		}).
+		WithIndex(&checklyv1alpha1.ApiCheck{}, apiCheckAlertChannelsIndex, func(o client.Object) []string {
+			return subscribedAlertChannels(o.(*checklyv1alpha1.ApiCheck).Spec.AlertChannelSubscriptions)
+		}).
+		Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &AlertChannelReconciler{Client: c, Scheme: scheme, ApiClient: apiClient, ControllerDomain: "k8s.checklyhq.com", SkipFinalizer: true, Recorder: recorder}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "oncall"}}
+	_, err := r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(requests) != 0 {
+		t.Errorf("Expected the paused AlertChannel not to call checklyhq.com, got %v", requests)
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("Expected a Paused event, got %d events", len(recorder.Events))
+	}
+
+	current := &checklyv1alpha1.AlertChannel{}
+	err = c.Get(context.TODO(), req.NamespacedName, current)
+	if err != nil || len(current.Finalizers) != 1 {
+		t.Fatalf("Expected the paused AlertChannel to keep its finalizer, got %v, %v", current.Finalizers, err)
+	}
+	if !meta.IsStatusConditionTrue(current.Status.Conditions, ConditionPaused) {
+		t.Errorf("Expected the AlertChannel to be flagged as paused, got %+v", current.Status.Conditions)
+	}
+
+	// Removing the annotation resumes the deletion
+	current.Annotations = nil
+	_ = c.Update(context.TODO(), current)
+	r.SkipFinalizer = false
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(requests) != 1 || requests[0] != "DELETE /v1/alert-channels/42" {
+		t.Errorf("Expected the checkly AlertChannel 42 to be deleted, got %v", requests)
+	}
+}
+
+func TestReconcilePausedResumed(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	snippet := &checklyv1alpha1.Snippet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "login",
+			Annotations: map[string]string{"k8s.checklyhq.com/paused": "true"},
+			Finalizers:  []string{"k8s.checklyhq.com/finalizer"},
+		},
+		Spec:   checklyv1alpha1.SnippetSpec{Script: "await login()"},
+		Status: checklyv1alpha1.SnippetStatus{ID: 5, ObservedGeneration: 1},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(snippet).WithStatusSubresource(snippet).Build()
+	r := &SnippetReconciler{Client: c, Scheme: scheme, ControllerDomain: "k8s.checklyhq.com", CreateOnly: true, Recorder: record.NewFakeRecorder(10)}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "login"}}
+	_, err := r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_ = c.Get(context.TODO(), req.NamespacedName, snippet)
+	if !meta.IsStatusConditionTrue(snippet.Status.Conditions, ConditionPaused) {
+		t.Errorf("Expected the Snippet to be flagged as paused, got %+v", snippet.Status.Conditions)
+	}
+
+	// Removing the annotation clears the condition
+	snippet.Annotations = nil
+	_ = c.Update(context.TODO(), snippet)
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_ = c.Get(context.TODO(), req.NamespacedName, snippet)
+	condition := meta.FindStatusCondition(snippet.Status.Conditions, ConditionPaused)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != ReasonResumed {
+		t.Errorf("Expected the Snippet to be resumed, got %+v", snippet.Status.Conditions)
+	}
+}
+
+func TestResolveSpecParentCycle(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	foo := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		Spec:       checklyv1alpha1.AlertChannelSpec{ParentRef: "bar"},
+	}
+	bar := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar"},
+		Spec:       checklyv1alpha1.AlertChannelSpec{ParentRef: "baz"},
+	}
+	baz := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "baz"},
+		Spec:       checklyv1alpha1.AlertChannelSpec{ParentRef: "bar"},
+	}
+	self := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "self"},
+		Spec:       checklyv1alpha1.AlertChannelSpec{ParentRef: "self"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(foo, bar, baz, self).Build()
+	r := &AlertChannelReconciler{Client: c, Scheme: scheme}
+
+	// The cycle is reported from where it starts, which isn't necessarily the AlertChannel itself
+	_, err := r.resolveSpec(context.TODO(), foo)
+	if err == nil || err.Error() != "AlertChannel parent references form a cycle: bar -> baz -> bar" {
+		t.Errorf("Expected the cycle of bar and baz, got %v", err)
+	}
+
+	_, err = r.resolveSpec(context.TODO(), self)
+	if err == nil || err.Error() != "AlertChannel parent references form a cycle: self -> self" {
+		t.Errorf("Expected the self reference, got %v", err)
+	}
+}
+
+func TestReconcileAlertChannelRedactsSecrets(t *testing.T) {
+	const webhookURL = "https://hooks.slack.com/services/T000/B000/s3cr3t"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(`{"id": 7, "type": "SLACK", "config": {"url": "https://hooks.slack.com/services/T000/B000/old"}}`))
+	}))
+	defer server.Close()
+
+	var notified []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := new(strings.Builder)
+		_, _ = io.Copy(body, r.Body)
+		notified = append(notified, body.String())
+	}))
+	defer receiver.Close()
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "checkly"},
+		Data:       map[string][]byte{"URL": []byte(webhookURL)},
+	}
+	ac := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "oncall", Finalizers: []string{"k8s.checklyhq.com/finalizer"}},
+		Spec: checklyv1alpha1.AlertChannelSpec{
+			SendFailure: true,
+			Slack:       checklyv1alpha1.AlertChannelSlack{URLSecret: corev1.ObjectReference{Namespace: "checkly", Name: "slack", FieldPath: "URL"}},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret, ac).WithStatusSubresource(ac).Build()
+	auditLog := &bytes.Buffer{}
+	r := &AlertChannelReconciler{
+		Client:           c,
+		Scheme:           scheme,
+		ApiClient:        checkly.NewClient(server.URL, "key", server.Client(), nil),
+		ControllerDomain: "k8s.checklyhq.com",
+		Audit:            audit.New(auditLog),
+		Notifier:         notify.New(receiver.URL),
+		Recorder:         record.NewFakeRecorder(10),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "oncall"}}
+	_, err := r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The update compares the alert channel read back, the old URL makes it write again
+	_ = c.Get(context.TODO(), req.NamespacedName, ac)
+	ac.Spec.SendRecovery = true
+	ac.Generation++
+	_ = c.Update(context.TODO(), ac)
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !strings.Contains(auditLog.String(), `"operation":"create"`) || !strings.Contains(auditLog.String(), `"operation":"update"`) {
+		t.Errorf("Expected the create and the update to be audited, got %s", auditLog.String())
+	}
+	if strings.Contains(auditLog.String(), "s3cr3t") {
+		t.Errorf("Expected the Slack webhook URL to be redacted in the audit log, got %s", auditLog.String())
+	}
+	if !strings.Contains(auditLog.String(), `"differed":["sendRecovery",`) || !strings.Contains(auditLog.String(), `"config.url":"REDACTED"`) {
+		t.Errorf("Expected the update to be audited with the state read before it, got %s", auditLog.String())
+	}
+	if len(notified) != 2 {
+		t.Errorf("Expected the create and the update to be notified, got %v", notified)
+	}
+	for _, body := range notified {
+		if strings.Contains(body, "s3cr3t") {
+			t.Errorf("Expected the Slack webhook URL to be redacted in the notification, got %s", body)
+		}
+	}
+}
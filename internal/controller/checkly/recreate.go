@@ -0,0 +1,172 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/checkly/checkly-go-sdk"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	external "github.com/checkly/checkly-operator/external/checkly"
+)
+
+// Values of the update-strategy annotation, they take precedence over the alert channel types recreated operator-wide
+const (
+	UpdateStrategyUpdate   = "update"
+	UpdateStrategyRecreate = "recreate"
+)
+
+// recreateTypes holds the alert channel types which can be recreated instead of updated, named like the spec fields
+var recreateTypes = []string{"email", "opsgenie", "webhook", "slack", "pagerduty", "sms", "phone"}
+
+// ParseRecreateTypes parses the comma separated list of alert channel types which are recreated instead of updated,
+// ex. webhook,opsgenie
+func ParseRecreateTypes(value string) (channelTypes map[string]bool, err error) {
+	channelTypes = map[string]bool{}
+	for _, channelType := range strings.Split(value, ",") {
+		channelType = strings.ToLower(strings.TrimSpace(channelType))
+		if channelType == "" {
+			continue
+		}
+
+		known := false
+		for _, recreateType := range recreateTypes {
+			known = known || channelType == recreateType
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown alert channel type %q, valid options are %s", channelType, strings.Join(recreateTypes, ", "))
+		}
+		channelTypes[channelType] = true
+	}
+	return
+}
+
+// recreates determines if changes to the AlertChannel are applied by replacing the checklyhq.com alert channel instead
+// of updating it, the update-strategy annotation takes precedence over the types recreated operator-wide
+func (r *AlertChannelReconciler) recreates(ac *checklyv1alpha1.AlertChannel, resolved *checklyv1alpha1.AlertChannel) bool {
+	switch ac.GetAnnotations()[fmt.Sprintf("%s/update-strategy", r.ControllerDomain)] {
+	case UpdateStrategyRecreate:
+		return true
+	case UpdateStrategyUpdate:
+		return false
+	}
+	return r.RecreateTypes[external.AlertChannelType(resolved.Spec)]
+}
+
+// recreate replaces the checklyhq.com alert channel with a new one holding the resolved configuration. The
+// subscriptions of the checks and groups managed by the operator are moved to the new alert channel before the old one
+// is deleted, so they keep alerting. The new ID is set on both the AlertChannel and resolved.
+func (r *AlertChannelReconciler) recreate(ctx context.Context, ac *checklyv1alpha1.AlertChannel, resolved *checklyv1alpha1.AlertChannel, opsGenieConfig checkly.AlertChannelOpsgenie) error {
+	logger := log.FromContext(ctx)
+	oldID := ac.Status.ID
+
+	newID, err := external.CreateAlertChannel(resolved, opsGenieConfig, r.ApiClient)
+	if err != nil {
+		return err
+	}
+
+	err = r.resubscribe(ctx, oldID, newID)
+	if err != nil {
+		// The old alert channel is kept, the new one would be a duplicate on the next attempt
+		replacement := resolved.DeepCopy()
+		replacement.Status.ID = newID
+		if deleteErr := external.DeleteAlertChannel(replacement, r.ApiClient); deleteErr != nil {
+			logger.Error(deleteErr, "Failed to delete the replacement checkly AlertChannel", "ID", newID)
+		}
+		return fmt.Errorf("moving subscriptions to the replacement of checkly AlertChannel %d: %w", oldID, err)
+	}
+
+	// The old alert channel doesn't have any subscriptions left, failing to delete it doesn't fail the recreation
+	err = external.DeleteAlertChannel(ac, r.ApiClient)
+	if err != nil {
+		logger.Error(err, "Failed to delete the replaced checkly AlertChannel", "ID", oldID)
+		r.Recorder.Eventf(ac, corev1.EventTypeWarning, "RecreateFailed", "Failed to delete the replaced checkly AlertChannel %d, it has to be deleted manually: %s", oldID, err)
+	}
+
+	ac.Status.ID = newID
+	resolved.Status.ID = newID
+	err = r.Status().Update(ctx, ac)
+	if err != nil {
+		return err
+	}
+
+	err = r.Mapping.Remove(ctx, "AlertChannel", oldID)
+	if err == nil {
+		err = r.Mapping.Set(ctx, "AlertChannel", newID, ac)
+	}
+	if err != nil {
+		return err
+	}
+
+	r.Recorder.Eventf(ac, corev1.EventTypeNormal, "Recreated", "Replaced checkly AlertChannel %d with %d", oldID, newID)
+	logger.Info("Recreated checkly AlertChannel", "old ID", oldID, "ID", newID)
+
+	return nil
+}
+
+// resubscribe moves the subscriptions of the checks and groups managed by the operator in checklyhq.com from one alert
+// channel to another
+func (r *AlertChannelReconciler) resubscribe(ctx context.Context, oldID int64, newID int64) error {
+	logger := log.FromContext(ctx)
+
+	checks := &checklyv1alpha1.ApiCheckList{}
+	err := r.List(ctx, checks)
+	if err != nil {
+		return err
+	}
+
+	for _, check := range checks.Items {
+		if check.Status.ID == "" {
+			continue
+		}
+
+		resubscribed, err := external.Resubscribe(check.Status.ID, oldID, newID, r.ApiClient)
+		if err != nil {
+			return err
+		}
+		if resubscribed {
+			logger.V(1).Info("Moved AlertChannel subscription of check", "check", check.Name, "checkly ID", check.Status.ID)
+		}
+	}
+
+	groups := &checklyv1alpha1.GroupList{}
+	err = r.List(ctx, groups)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups.Items {
+		if group.Status.ID == 0 {
+			continue
+		}
+
+		resubscribed, err := external.GroupResubscribe(group.Status.ID, oldID, newID, r.ApiClient)
+		if err != nil {
+			return err
+		}
+		if resubscribed {
+			logger.V(1).Info("Moved AlertChannel subscription of group", "group", group.Name, "checkly group ID", group.Status.ID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"testing"
+
+	"github.com/checkly/checkly-go-sdk"
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseRecreateTypes(t *testing.T) {
+	channelTypes, err := ParseRecreateTypes(" Webhook, opsgenie,")
+	if err != nil {
+		t.Fatalf("Expected no error, got %e", err)
+	}
+	if len(channelTypes) != 2 || !channelTypes["webhook"] || !channelTypes["opsgenie"] {
+		t.Errorf("Expected webhook and opsgenie, got %v", channelTypes)
+	}
+
+	channelTypes, err = ParseRecreateTypes("")
+	if err != nil || len(channelTypes) != 0 {
+		t.Errorf("Expected no types, got %v, %v", channelTypes, err)
+	}
+
+	_, err = ParseRecreateTypes("pigeon")
+	if err == nil {
+		t.Error("Expected error for an unknown type")
+	}
+}
+
+func TestRecreates(t *testing.T) {
+	r := &AlertChannelReconciler{ControllerDomain: "k8s.checklyhq.com", RecreateTypes: map[string]bool{"email": true}}
+	ac := &checklyv1alpha1.AlertChannel{
+		Spec: checklyv1alpha1.AlertChannelSpec{Email: checkly.AlertChannelEmail{Address: "foo@bar.baz"}},
+	}
+
+	if !r.recreates(ac, ac) {
+		t.Error("Expected the email AlertChannel to be recreated")
+	}
+
+	ac.ObjectMeta = metav1.ObjectMeta{Annotations: map[string]string{"k8s.checklyhq.com/update-strategy": UpdateStrategyUpdate}}
+	if r.recreates(ac, ac) {
+		t.Error("Expected the annotation to take precedence over the recreated types")
+	}
+
+	r.RecreateTypes = nil
+	ac.Annotations["k8s.checklyhq.com/update-strategy"] = UpdateStrategyRecreate
+	if !r.recreates(ac, ac) {
+		t.Error("Expected the annotated AlertChannel to be recreated")
+	}
+
+	delete(ac.Annotations, "k8s.checklyhq.com/update-strategy")
+	if r.recreates(ac, ac) {
+		t.Error("Expected the AlertChannel to be updated by default")
+	}
+}
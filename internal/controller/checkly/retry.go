@@ -0,0 +1,47 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// RetryRateLimiter returns the rate limiter failed reconciliations are retried with, an exponential back-off from
+// base to max per object, with the same overall limit as the controller-runtime default. It's independent of the
+// interval successfully synced resources are checked for drift after.
+func RetryRateLimiter(base time.Duration, max time.Duration) workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(base, max),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	)
+}
+
+// ValidateRetryDelays makes sure the back-off bounds can be honoured, a base above the max would retry every failure
+// after the max delay right away
+func ValidateRetryDelays(base time.Duration, max time.Duration) error {
+	if base <= 0 {
+		return fmt.Errorf("retry base delay has to be positive, got %s", base)
+	}
+	if max < base {
+		return fmt.Errorf("retry max delay %s has to be at least the base delay %s", max, base)
+	}
+	return nil
+}
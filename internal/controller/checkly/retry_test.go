@@ -0,0 +1,59 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryRateLimiter(t *testing.T) {
+	limiter := RetryRateLimiter(time.Second, 4*time.Second)
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second}
+	for i, delay := range expected {
+		if got := limiter.When("foo"); got != delay {
+			t.Errorf("Expected retry %d after %s, got %s", i, delay, got)
+		}
+	}
+
+	limiter.Forget("foo")
+	if got := limiter.When("foo"); got != time.Second {
+		t.Errorf("Expected the back-off to start over, got %s", got)
+	}
+}
+
+func TestValidateRetryDelays(t *testing.T) {
+	testData := []struct {
+		base  time.Duration
+		max   time.Duration
+		valid bool
+	}{
+		{5 * time.Millisecond, 1000 * time.Second, true},
+		{time.Second, time.Second, true},
+		{0, time.Second, false},
+		{-time.Second, time.Second, false},
+		{time.Minute, time.Second, false},
+	}
+
+	for _, tt := range testData {
+		err := ValidateRetryDelays(tt.base, tt.max)
+		if tt.valid != (err == nil) {
+			t.Errorf("Expected %s to %s to be valid: %t, got %v", tt.base, tt.max, tt.valid, err)
+		}
+	}
+}
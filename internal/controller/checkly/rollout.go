@@ -0,0 +1,44 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+// canaryPending describes why the canary isn't healthy yet, it's empty once the canary synced its latest generation
+// to checklyhq.com, passed verification and soaked for the soak period, retryAfter is the time left to soak
+func canaryPending(canary checklyv1alpha1.AlertChannel, soak time.Duration, now time.Time) (reason string, retryAfter time.Duration) {
+	if canary.Status.SyncedGeneration != canary.Generation || canary.Status.SyncedAt == nil {
+		return fmt.Sprintf("canary %s has not synced generation %d", canary.Name, canary.Generation), 0
+	}
+
+	if meta.IsStatusConditionFalse(canary.Status.Conditions, ConditionVerified) {
+		return fmt.Sprintf("canary %s failed verification", canary.Name), 0
+	}
+
+	if soaked := canary.Status.SyncedAt.Add(soak); now.Before(soaked) {
+		return fmt.Sprintf("canary %s is soaking until %s", canary.Name, soaked.UTC().Format(time.RFC3339)), soaked.Sub(now)
+	}
+
+	return "", 0
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+func TestCanaryPending(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	synced := func(generation int64, at time.Time, conditions ...metav1.Condition) checklyv1alpha1.AlertChannel {
+		return checklyv1alpha1.AlertChannel{
+			ObjectMeta: metav1.ObjectMeta{Name: "canary", Generation: 2},
+			Status: checklyv1alpha1.AlertChannelStatus{
+				SyncedGeneration: generation,
+				SyncedAt:         &metav1.Time{Time: at},
+				Conditions:       conditions,
+			},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		canary     checklyv1alpha1.AlertChannel
+		reason     string
+		retryAfter time.Duration
+	}{
+		{
+			name:   "never synced",
+			canary: checklyv1alpha1.AlertChannel{ObjectMeta: metav1.ObjectMeta{Name: "canary", Generation: 1}},
+			reason: "has not synced generation 1",
+		},
+		{
+			name:   "older generation synced",
+			canary: synced(1, now.Add(-time.Hour)),
+			reason: "has not synced generation 2",
+		},
+		{
+			name:   "failed verification",
+			canary: synced(2, now.Add(-time.Hour), metav1.Condition{Type: ConditionVerified, Status: metav1.ConditionFalse}),
+			reason: "failed verification",
+		},
+		{
+			name:       "soaking",
+			canary:     synced(2, now.Add(-4*time.Minute)),
+			reason:     "is soaking until 2024-01-01T12:01:00Z",
+			retryAfter: time.Minute,
+		},
+		{
+			name:   "healthy",
+			canary: synced(2, now.Add(-5*time.Minute), metav1.Condition{Type: ConditionVerified, Status: metav1.ConditionTrue}),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reason, retryAfter := canaryPending(test.canary, 5*time.Minute, now)
+			if test.reason == "" && reason != "" {
+				t.Errorf("Expected a healthy canary, got %q", reason)
+			}
+			if !strings.Contains(reason, test.reason) {
+				t.Errorf("Expected reason to contain %q, got %q", test.reason, reason)
+			}
+			if retryAfter != test.retryAfter {
+				t.Errorf("Expected retry after %s, got %s", test.retryAfter, retryAfter)
+			}
+		})
+	}
+}
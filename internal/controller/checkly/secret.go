@@ -0,0 +1,99 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GetSecretValue returns the value of the referenced secret field, the FieldPath of the reference holds the key
+func GetSecretValue(ctx context.Context, c client.Reader, ref corev1.ObjectReference) (value string, err error) {
+	secret := &corev1.Secret{}
+	err = c.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}, secret)
+	if err != nil {
+		return
+	}
+
+	value = string(secret.Data[ref.FieldPath])
+	if value == "" {
+		err = fmt.Errorf("secret %s/%s has no value for key %q", ref.Namespace, ref.Name, ref.FieldPath)
+	}
+
+	return
+}
+
+// SecretCache keeps resolved secret values for a while, so resyncs don't read unchanged secrets from the API server over
+// and over again. The values are only held in memory and never logged.
+type SecretCache struct {
+	reader  client.Reader
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[corev1.ObjectReference]secretCacheEntry
+}
+
+type secretCacheEntry struct {
+	value   string
+	expires time.Time
+}
+
+// NewSecretCache returns a SecretCache reading secrets with the supplied reader and holding their values for the TTL
+func NewSecretCache(reader client.Reader, ttl time.Duration) *SecretCache {
+	return &SecretCache{
+		reader:  reader,
+		ttl:     ttl,
+		entries: map[corev1.ObjectReference]secretCacheEntry{},
+	}
+}
+
+// Get returns the value of the referenced secret field from the cache, reading the secret if it's missing or expired
+func (s *SecretCache) Get(ctx context.Context, ref corev1.ObjectReference) (value string, err error) {
+	s.mu.Lock()
+	entry, ok := s.entries[ref]
+	s.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	value, err = GetSecretValue(ctx, s.reader, ref)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.entries[ref] = secretCacheEntry{value: value, expires: time.Now().Add(s.ttl)}
+	s.mu.Unlock()
+
+	return
+}
+
+// Invalidate drops the cached values of all fields of the secret, it's called when the secret changes
+func (s *SecretCache) Invalidate(namespace string, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ref := range s.entries {
+		if ref.Namespace == namespace && ref.Name == name {
+			delete(s.entries, ref)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// countingClient counts the reads made through it
+type countingClient struct {
+	client.Client
+	gets int
+}
+
+func (c *countingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	c.gets++
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func TestSecretCache(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "bar"},
+		Data:       map[string][]byte{"key": []byte("value")},
+	}
+	c := &countingClient{Client: fake.NewClientBuilder().WithObjects(secret).Build()}
+	ref := corev1.ObjectReference{Name: "foo", Namespace: "bar", FieldPath: "key"}
+	ctx := context.Background()
+
+	cache := NewSecretCache(c, time.Hour)
+	for i := 0; i < 3; i++ {
+		value, err := cache.Get(ctx, ref)
+		if err != nil || value != "value" {
+			t.Fatalf("Expected value, got %q and %v", value, err)
+		}
+	}
+	if c.gets != 1 {
+		t.Errorf("Expected a single read within the TTL, got %d", c.gets)
+	}
+
+	secret.Data["key"] = []byte("changed")
+	if err := c.Update(ctx, secret); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	cache.Invalidate("bar", "foo")
+	value, err := cache.Get(ctx, ref)
+	if err != nil || value != "changed" {
+		t.Errorf("Expected the changed value after invalidation, got %q and %v", value, err)
+	}
+
+	expired := NewSecretCache(c, 0)
+	_, _ = expired.Get(ctx, ref)
+	_, _ = expired.Get(ctx, ref)
+	if c.gets != 4 {
+		t.Errorf("Expected every read to go through without a TTL, got %d reads", c.gets)
+	}
+
+}
@@ -0,0 +1,293 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/checkly/checkly-go-sdk"
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	external "github.com/checkly/checkly-operator/external/checkly"
+	"github.com/checkly/checkly-operator/internal/audit"
+	"github.com/checkly/checkly-operator/internal/metrics"
+	"github.com/checkly/checkly-operator/internal/notify"
+)
+
+// SnippetReconciler reconciles a Snippet object
+type SnippetReconciler struct {
+	client.Client
+	Scheme           *runtime.Scheme
+	ApiClient        checkly.Client
+	ControllerDomain string
+	CreateOnly       bool
+	SkipFinalizer    bool
+	ReconcileSummary bool
+	Audit            *audit.Logger
+	Notifier         *notify.Notifier
+	FinalizerTimeout time.Duration
+	DriftInterval    time.Duration
+	RetryLimiter     workqueue.RateLimiter
+	Workers          int
+	Breaker          *external.CircuitBreaker
+	Deprecations     *external.DeprecationTracker
+	Recorder         record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=snippets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=snippets/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=snippets/finalizers,verbs=update
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.12.1/pkg/reconcile
+func (r *SnippetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.Result, err error) {
+	logger := log.FromContext(ctx)
+	defer metrics.WorkerStarted("snippet")()
+
+	snippetFinalizer := fmt.Sprintf("%s/finalizer", r.ControllerDomain)
+
+	snippet := &checklyv1alpha1.Snippet{}
+	start := time.Now()
+	operation := metrics.OperationNone
+	defer func() {
+		res, err = finishReconcile(ctx, reconcileRun{
+			kind:         "Snippet",
+			namespace:    req.Namespace,
+			name:         req.Name,
+			object:       snippet,
+			checklyID:    snippet.Status.ID,
+			operation:    operation,
+			start:        start,
+			client:       r.Client,
+			summary:      r.ReconcileSummary,
+			breaker:      r.Breaker,
+			deprecations: r.Deprecations,
+			recorder:     r.Recorder,
+		}, res, err)
+	}()
+
+	// ////////////////////////////////
+	// Delete Logic
+	// ///////////////////////////////
+	err = r.Get(ctx, req.NamespacedName, snippet)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// The resource has been deleted
+			logger.V(1).Info("Snippet removed")
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object
+		logger.Error(err, "can't read the Snippet object")
+		return ctrl.Result{}, err
+	}
+
+	// Paused resources are left as they are, in the cluster and in checklyhq.com, until the annotation is removed
+	if paused(ctx, r.Client, r.ControllerDomain, r.Recorder, "Snippet", snippet) {
+		logger.V(1).Info("Snippet reconciliation paused")
+		return ctrl.Result{}, nil
+	}
+
+	// Without the finalizer deletions don't wait for checklyhq.com, the finalizer added before it was disabled is removed
+	if r.SkipFinalizer {
+		var removed bool
+		removed, err = dropFinalizer(ctx, r.Client, snippet, snippetFinalizer)
+		if err != nil {
+			logger.Error(err, "Failed to remove disabled Snippet finalizer")
+			return ctrl.Result{}, err
+		}
+		if removed {
+			logger.V(1).Info("Removed disabled finalizer")
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if r.Breaker.Open() {
+		return unavailableResult(ctx, r.Client, r.Breaker, "Snippet", snippet), nil
+	}
+
+	if snippet.GetDeletionTimestamp() != nil {
+		if controllerutil.ContainsFinalizer(snippet, snippetFinalizer) {
+			logger.V(1).Info("Finalizer is present, trying to delete Checkly snippet", "checkly ID", snippet.Status.ID)
+			if r.CreateOnly {
+				logger.Info("Create only mode, leaving checkly snippet in place", "checkly ID", snippet.Status.ID)
+			} else if snippet.Status.ID != 0 {
+				operation = metrics.OperationDelete
+				err := external.DeleteSnippet(snippet.Status.ID, r.ApiClient)
+				change := audit.Record{Operation: audit.Delete, Kind: "Snippet", Object: snippet, ChecklyID: snippet.Status.ID, Spec: snippet.Spec, Before: &audit.State{Generation: snippet.Status.ObservedGeneration}, Err: err}
+				r.Audit.Log(change)
+				r.Notifier.Notify(ctx, change)
+				if err != nil && !finalizerTimedOut(snippet, r.ControllerDomain, r.FinalizerTimeout) {
+					logger.Error(err, "Failed to delete checkly snippet")
+					return ctrl.Result{}, err
+				}
+
+				if err != nil {
+					logger.Error(err, "Failed to delete checkly snippet past the finalizer timeout, removing the finalizer anyway", "checkly ID", snippet.Status.ID)
+					r.Recorder.Eventf(snippet, corev1.EventTypeWarning, "FinalizerTimeout", "Failed to delete checkly snippet %v past the finalizer timeout, it has to be deleted manually: %s", snippet.Status.ID, err)
+				} else {
+					logger.Info("Successfully deleted checkly snippet", "checkly ID", snippet.Status.ID)
+				}
+			}
+
+			controllerutil.RemoveFinalizer(snippet, snippetFinalizer)
+			err := r.Update(ctx, snippet)
+			if err != nil {
+				logger.Error(err, "Failed to delete finalizer")
+				return ctrl.Result{}, err
+			}
+			logger.V(1).Info("Successfully deleted finalizer")
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// /////////////////////////////
+	// Finalizer logic
+	// ////////////////////////////
+	if !r.SkipFinalizer && !controllerutil.ContainsFinalizer(snippet, snippetFinalizer) {
+		controllerutil.AddFinalizer(snippet, snippetFinalizer)
+		err = r.Update(ctx, snippet)
+		if err != nil {
+			logger.Error(err, "Failed to add Snippet finalizer")
+			return ctrl.Result{}, err
+		}
+		logger.V(1).Info("Added finalizer", "checkly ID", snippet.Status.ID)
+		return ctrl.Result{}, nil
+	}
+
+	internalSnippet := external.Snippet{
+		Name:   snippet.Name,
+		ID:     snippet.Status.ID,
+		Script: snippet.Spec.Script,
+	}
+
+	// /////////////////////////////
+	// Update logic
+	// ////////////////////////////
+	if snippet.Status.ID != 0 {
+		if r.CreateOnly {
+			logger.V(1).Info("Create only mode, skipping update", "checkly ID", snippet.Status.ID)
+			return ctrl.Result{}, recordSync(ctx, r.Client, snippet, nil)
+		}
+
+		// The snippet is only written if it differs in checklyhq.com, a failed read falls back to writing it anyway
+		drift, err := external.SnippetDrift(internalSnippet, r.ApiClient)
+		if err != nil {
+			logger.Error(err, "Failed to read the checkly snippet", "checkly ID", snippet.Status.ID)
+		} else if len(drift) == 0 {
+			logger.V(1).Info("Unchanged checkly snippet, skipping update", "checkly ID", snippet.Status.ID)
+			return ctrl.Result{RequeueAfter: r.DriftInterval}, recordSync(ctx, r.Client, snippet, nil)
+		} else {
+			logger.V(1).Info("Checkly snippet differs from the desired state", "checkly ID", snippet.Status.ID, "fields", drift)
+		}
+
+		operation = metrics.OperationUpdate
+		err = external.UpdateSnippet(internalSnippet, r.ApiClient)
+		change := audit.Record{Operation: audit.Update, Kind: "Snippet", Object: snippet, ChecklyID: snippet.Status.ID, Spec: snippet.Spec, Before: &audit.State{Generation: snippet.Status.ObservedGeneration}, Err: err}
+		r.Audit.Log(change)
+		r.Notifier.Notify(ctx, change)
+		if err != nil {
+			logger.Error(err, "Failed to update the checkly snippet")
+			if statusErr := recordSync(ctx, r.Client, snippet, err); statusErr != nil {
+				logger.Error(statusErr, "Failed to update Snippet status")
+			}
+			return ctrl.Result{}, err
+		}
+		logger.Info("Updated checkly snippet", "checkly ID", snippet.Status.ID)
+
+		err = recordSync(ctx, r.Client, snippet, nil)
+		if err != nil {
+			logger.Error(err, "Failed to update Snippet status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: r.DriftInterval}, nil
+	}
+
+	// /////////////////////////////
+	// Create logic
+	// ////////////////////////////
+	operation = metrics.OperationCreate
+	checklyID, err := external.CreateSnippet(internalSnippet, r.ApiClient)
+	change := audit.Record{Operation: audit.Create, Kind: "Snippet", Object: snippet, ChecklyID: checklyID, Spec: snippet.Spec, Err: err}
+	r.Audit.Log(change)
+	r.Notifier.Notify(ctx, change)
+	if err != nil {
+		logger.Error(err, "Failed to create checkly snippet")
+		if statusErr := recordSync(ctx, r.Client, snippet, err); statusErr != nil {
+			logger.Error(statusErr, "Failed to update Snippet status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	snippet.Status.ID = checklyID
+	err = recordSync(ctx, r.Client, snippet, nil)
+	if err != nil {
+		logger.Error(err, "Failed to update Snippet status", "ID", snippet.Status.ID)
+		return ctrl.Result{}, err
+	}
+	logger.Info("New checkly snippet created", "ID", snippet.Status.ID)
+
+	return ctrl.Result{RequeueAfter: r.DriftInterval}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SnippetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// The controller is named explicitly, it's the controller and name label of the controller-runtime metrics
+	workers := max(r.Workers, 1)
+	metrics.SetWorkers("snippet", workers)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("snippet").
+		For(&checklyv1alpha1.Snippet{}).
+		WithOptions(controller.Options{RateLimiter: r.RetryLimiter, MaxConcurrentReconciles: workers}).
+		Complete(r)
+}
+
+// snippetID returns the checklyhq.com ID of the Snippet referenced by a check, pending is set while the Snippet isn't
+// created in checklyhq.com yet. Snippets are managed in the operator account, checks of other accounts can't use them.
+func snippetID(ctx context.Context, c client.Client, accountID string, name string) (ID int64, pending bool, err error) {
+	if name == "" {
+		return
+	}
+	if accountID != "" {
+		err = fmt.Errorf("snippet %s is managed in the operator checklyhq.com account, the check in account %s", name, accountID)
+		return
+	}
+
+	snippet := &checklyv1alpha1.Snippet{}
+	err = c.Get(ctx, types.NamespacedName{Name: name}, snippet)
+	if err != nil {
+		return
+	}
+
+	return snippet.Status.ID, snippet.Status.ID == 0, nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/checkly/checkly-go-sdk"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+func TestSnippetID(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&checklyv1alpha1.Snippet{ObjectMeta: metav1.ObjectMeta{Name: "login"}, Status: checklyv1alpha1.SnippetStatus{ID: 4}},
+		&checklyv1alpha1.Snippet{ObjectMeta: metav1.ObjectMeta{Name: "logout"}},
+	).Build()
+
+	ID, pending, err := snippetID(context.TODO(), c, "", "")
+	if ID != 0 || pending || err != nil {
+		t.Errorf("Expected no snippet, got %d, %t, %v", ID, pending, err)
+	}
+
+	ID, pending, err = snippetID(context.TODO(), c, "", "login")
+	if ID != 4 || pending || err != nil {
+		t.Errorf("Expected the snippet ID, got %d, %t, %v", ID, pending, err)
+	}
+
+	_, pending, err = snippetID(context.TODO(), c, "", "logout")
+	if !pending || err != nil {
+		t.Errorf("Expected the snippet to be pending, got %t, %v", pending, err)
+	}
+
+	_, _, err = snippetID(context.TODO(), c, "", "missing")
+	if err == nil {
+		t.Error("Expected an error for the missing snippet")
+	}
+
+	// Snippets are managed in the operator account
+	_, _, err = snippetID(context.TODO(), c, "1234", "login")
+	if err == nil {
+		t.Error("Expected an error for a check of another account")
+	}
+}
+
+func TestReconcileSnippetReferences(t *testing.T) {
+	var requests int
+	var created checkly.Check
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &created)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "7"}`))
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	apiCheck := &checklyv1alpha1.ApiCheck{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments", Namespace: "default", Finalizers: []string{"k8s.checklyhq.com/finalizer"}},
+		Spec: checklyv1alpha1.ApiCheckSpec{
+			Endpoint:        "https://foo.bar/baz",
+			Success:         "200",
+			Group:           "team",
+			SetupSnippet:    "login",
+			TeardownSnippet: "logout",
+		},
+	}
+	snippet := &checklyv1alpha1.Snippet{ObjectMeta: metav1.ObjectMeta{Name: "logout"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		apiCheck,
+		snippet,
+		&checklyv1alpha1.Group{ObjectMeta: metav1.ObjectMeta{Name: "team"}, Status: checklyv1alpha1.GroupStatus{ID: 1}},
+		&checklyv1alpha1.Snippet{ObjectMeta: metav1.ObjectMeta{Name: "login"}, Status: checklyv1alpha1.SnippetStatus{ID: 4}},
+	).WithStatusSubresource(apiCheck, snippet).Build()
+	r := &ApiCheckReconciler{
+		Client:           c,
+		Scheme:           scheme,
+		ApiClient:        checkly.NewClient(server.URL, "key", server.Client(), nil),
+		ControllerDomain: "k8s.checklyhq.com",
+		Recorder:         record.NewFakeRecorder(10),
+	}
+
+	// The check waits for the teardown snippet to be created in checklyhq.com
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "payments"}}
+	res, err := r.Reconcile(context.TODO(), req)
+	if err != nil || !res.Requeue || requests != 0 {
+		t.Fatalf("Expected a requeue without requests, got %+v, %v and %d requests", res, err, requests)
+	}
+
+	snippet.Status.ID = 5
+	_ = c.Status().Update(context.TODO(), snippet)
+	_, err = r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if created.SetupSnippetID != 4 || created.TearDownSnippetID != 5 {
+		t.Errorf("Expected the check to reference the snippets, got %d and %d", created.SetupSnippetID, created.TearDownSnippetID)
+	}
+}
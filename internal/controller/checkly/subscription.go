@@ -0,0 +1,152 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/checkly/checkly-go-sdk"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+// groupSubscriptions returns the AlertChannel subscriptions of the group, the AlertChannels listed by name are
+// activated subscriptions, an explicit subscription to the same AlertChannel takes precedence
+func groupSubscriptions(group *checklyv1alpha1.Group) (subscriptions []checklyv1alpha1.AlertChannelSubscription) {
+	explicit := map[string]bool{}
+	for _, subscription := range group.Spec.AlertChannelSubscriptions {
+		explicit[subscription.Name] = true
+	}
+
+	for _, name := range group.Spec.AlertChannels {
+		if !explicit[name] {
+			subscriptions = append(subscriptions, checklyv1alpha1.AlertChannelSubscription{Name: name, Activated: true})
+		}
+	}
+	return append(subscriptions, group.Spec.AlertChannelSubscriptions...)
+}
+
+// resolveSubscriptions looks up the checklyhq.com IDs of the subscribed AlertChannels and returns the escalation tiers
+// of the activated ones. pending holds the name of an AlertChannel which isn't synced to checklyhq.com yet, the
+// caller requeues until it is. Subscribing to an AlertChannel of another account than accountID fails.
+func resolveSubscriptions(ctx context.Context, c client.Reader, accountID string, subscriptions []checklyv1alpha1.AlertChannelSubscription) (resolved []checkly.AlertChannelSubscription, tiers []string, pending string, err error) {
+	for _, subscription := range subscriptions {
+		ac := &checklyv1alpha1.AlertChannel{}
+		err = c.Get(ctx, types.NamespacedName{Name: subscription.Name}, ac)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if ac.Status.ID == 0 {
+			return nil, nil, ac.Name, nil
+		}
+		if ac.Status.AccountID != accountID {
+			return nil, nil, "", fmt.Errorf("AlertChannel %s is managed in checklyhq.com account %s, not in account %s", ac.Name, accountName(ac.Status.AccountID), accountName(accountID))
+		}
+
+		resolved = append(resolved, checkly.AlertChannelSubscription{
+			ChannelID: ac.Status.ID,
+			Activated: subscription.Activated,
+		})
+		if subscription.Activated {
+			tiers = append(tiers, ac.Spec.Tier)
+		}
+	}
+	return
+}
+
+// subscribedAlertChannels returns the names of the AlertChannels in the subscriptions
+func subscribedAlertChannels(subscriptions []checklyv1alpha1.AlertChannelSubscription) (names []string) {
+	for _, subscription := range subscriptions {
+		names = append(names, subscription.Name)
+	}
+	return
+}
+
+// withoutAlertChannel returns the subscriptions without the ones to the named AlertChannel
+func withoutAlertChannel(subscriptions []checklyv1alpha1.AlertChannelSubscription, name string) (remaining []checklyv1alpha1.AlertChannelSubscription) {
+	for _, subscription := range subscriptions {
+		if subscription.Name != name {
+			remaining = append(remaining, subscription)
+		}
+	}
+	return
+}
+
+// validateRoutes makes sure every route lists AlertChannels with positive weights adding up to 100
+func validateRoutes(routes []checklyv1alpha1.AlertRoute) error {
+	for i, route := range routes {
+		if len(route.AlertChannels) == 0 {
+			return fmt.Errorf("route %d doesn't list any AlertChannels", i)
+		}
+
+		var total int
+		for _, alertChannel := range route.AlertChannels {
+			if alertChannel.Weight <= 0 {
+				return fmt.Errorf("route %d: weight of AlertChannel %s has to be positive, got %d", i, alertChannel.Name, alertChannel.Weight)
+			}
+			total += alertChannel.Weight
+		}
+		if total != 100 {
+			return fmt.Errorf("route %d: weights add up to %d instead of 100", i, total)
+		}
+	}
+	return nil
+}
+
+// routedSubscription returns the subscription of the check to the AlertChannel picked by the first of the validated
+// routes matching its labels. The pick is derived from a hash of the check name, so a check stays with its
+// AlertChannel as long as the weights don't change. ok is false if none of the routes matches the check.
+func routedSubscription(routes []checklyv1alpha1.AlertRoute, check *checklyv1alpha1.ApiCheck) (subscription checklyv1alpha1.AlertChannelSubscription, ok bool) {
+	for _, route := range routes {
+		if !labels.SelectorFromSet(route.MatchLabels).Matches(labels.Set(check.Labels)) {
+			continue
+		}
+
+		hash := fnv.New32a()
+		hash.Write([]byte(check.Namespace + "/" + check.Name))
+		bucket := int(hash.Sum32() % 100)
+		for _, alertChannel := range route.AlertChannels {
+			bucket -= alertChannel.Weight
+			if bucket < 0 {
+				return checklyv1alpha1.AlertChannelSubscription{Name: alertChannel.Name, Activated: true}, true
+			}
+		}
+	}
+	return
+}
+
+// checkSubscriptions returns the AlertChannel subscriptions of the check including the one the routes of its group
+// assign it to, an explicit subscription to the same AlertChannel takes precedence
+func checkSubscriptions(check *checklyv1alpha1.ApiCheck, group *checklyv1alpha1.Group) []checklyv1alpha1.AlertChannelSubscription {
+	subscriptions := check.Spec.AlertChannelSubscriptions
+
+	routed, ok := routedSubscription(group.Spec.Routes, check)
+	if !ok {
+		return subscriptions
+	}
+	for _, subscription := range subscriptions {
+		if subscription.Name == routed.Name {
+			return subscriptions
+		}
+	}
+	return append(append([]checklyv1alpha1.AlertChannelSubscription{}, subscriptions...), routed)
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/checkly/checkly-go-sdk"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+func TestResolveSubscriptions(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&checklyv1alpha1.AlertChannel{
+			ObjectMeta: metav1.ObjectMeta{Name: "oncall"},
+			Spec:       checklyv1alpha1.AlertChannelSpec{Tier: "tier1"},
+			Status:     checklyv1alpha1.AlertChannelStatus{ID: 1},
+		},
+		&checklyv1alpha1.AlertChannel{
+			ObjectMeta: metav1.ObjectMeta{Name: "team"},
+			Spec:       checklyv1alpha1.AlertChannelSpec{Tier: "tier2"},
+			Status:     checklyv1alpha1.AlertChannelStatus{ID: 2},
+		},
+		&checklyv1alpha1.AlertChannel{ObjectMeta: metav1.ObjectMeta{Name: "new"}},
+	).Build()
+	ctx := context.Background()
+
+	group := &checklyv1alpha1.Group{Spec: checklyv1alpha1.GroupSpec{
+		AlertChannels:             []string{"oncall", "team"},
+		AlertChannelSubscriptions: []checklyv1alpha1.AlertChannelSubscription{{Name: "team", Activated: false}},
+	}}
+	resolved, tiers, pending, err := resolveSubscriptions(ctx, c, "", groupSubscriptions(group))
+	if err != nil || pending != "" {
+		t.Fatalf("Expected no error, got %v, pending %q", err, pending)
+	}
+	expected := []checkly.AlertChannelSubscription{{ChannelID: 1, Activated: true}, {ChannelID: 2, Activated: false}}
+	if len(resolved) != 2 || resolved[0] != expected[0] || resolved[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, resolved)
+	}
+	if len(tiers) != 1 || tiers[0] != "tier1" {
+		t.Errorf("Expected only the tier of the activated subscription, got %v", tiers)
+	}
+
+	// AlertChannels which aren't synced yet are reported, the caller requeues
+	_, _, pending, err = resolveSubscriptions(ctx, c, "", []checklyv1alpha1.AlertChannelSubscription{{Name: "oncall"}, {Name: "new"}})
+	if err != nil || pending != "new" {
+		t.Errorf("Expected the new AlertChannel to be pending, got %q, %v", pending, err)
+	}
+
+	_, _, _, err = resolveSubscriptions(ctx, c, "", []checklyv1alpha1.AlertChannelSubscription{{Name: "missing"}})
+	if err == nil {
+		t.Error("Expected error for a missing AlertChannel")
+	}
+}
+
+func TestAttachToGroups(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	ac := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "oncall"},
+		Spec:       checklyv1alpha1.AlertChannelSpec{Groups: []string{"synced", "unsynced", "missing"}},
+		Status:     checklyv1alpha1.AlertChannelStatus{ID: 1},
+	}
+	synced := &checklyv1alpha1.Group{
+		ObjectMeta: metav1.ObjectMeta{Name: "synced"},
+		Status:     checklyv1alpha1.GroupStatus{ID: 10},
+	}
+	unsynced := &checklyv1alpha1.Group{ObjectMeta: metav1.ObjectMeta{Name: "unsynced"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ac, synced, unsynced).WithStatusSubresource(ac).Build()
+	r := &AlertChannelReconciler{Client: c}
+	ctx := context.Background()
+
+	pending, err := r.attachToGroups(ctx, ac)
+	if err != nil || !pending {
+		t.Fatalf("Expected the AlertChannel to wait for groups, got %t, %v", pending, err)
+	}
+	condition := meta.FindStatusCondition(ac.Status.Conditions, ConditionGroupsPending)
+	if condition == nil || condition.Status != metav1.ConditionTrue || condition.Message != "Waiting for the groups unsynced (not synced), missing (not found)" {
+		t.Errorf("Expected the GroupsPending condition to list the missing groups, got %+v", condition)
+	}
+
+	stored := &checklyv1alpha1.Group{}
+	_ = c.Get(ctx, client.ObjectKeyFromObject(synced), stored)
+	if len(stored.Spec.AlertChannels) != 1 || stored.Spec.AlertChannels[0] != "oncall" {
+		t.Errorf("Expected the synced group to be attached, got %v", stored.Spec.AlertChannels)
+	}
+	_ = c.Get(ctx, client.ObjectKeyFromObject(unsynced), stored)
+	if len(stored.Spec.AlertChannels) != 0 {
+		t.Errorf("Expected the unsynced group not to be attached, got %v", stored.Spec.AlertChannels)
+	}
+
+	ac.Spec.Groups = []string{"synced"}
+	pending, err = r.attachToGroups(ctx, ac)
+	if err != nil || pending {
+		t.Fatalf("Expected nothing to wait for, got %t, %v", pending, err)
+	}
+	_ = c.Get(ctx, client.ObjectKeyFromObject(synced), stored)
+	if len(stored.Spec.AlertChannels) != 1 {
+		t.Errorf("Expected the group to be attached once, got %v", stored.Spec.AlertChannels)
+	}
+	if !meta.IsStatusConditionFalse(ac.Status.Conditions, ConditionGroupsPending) {
+		t.Errorf("Expected the GroupsPending condition to be False, got %+v", ac.Status.Conditions)
+	}
+}
+
+func TestValidateRoutes(t *testing.T) {
+	valid := []checklyv1alpha1.AlertRoute{{AlertChannels: []checklyv1alpha1.WeightedAlertChannel{{Name: "a", Weight: 70}, {Name: "b", Weight: 30}}}}
+	if err := validateRoutes(valid); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	invalid := map[string][]checklyv1alpha1.WeightedAlertChannel{
+		"no AlertChannels": nil,
+		"short of 100":     {{Name: "a", Weight: 50}, {Name: "b", Weight: 30}},
+		"zero weight":      {{Name: "a", Weight: 100}, {Name: "b", Weight: 0}},
+		"negative weight":  {{Name: "a", Weight: 110}, {Name: "b", Weight: -10}},
+	}
+	for name, alertChannels := range invalid {
+		if err := validateRoutes([]checklyv1alpha1.AlertRoute{{AlertChannels: alertChannels}}); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+func TestCheckSubscriptions(t *testing.T) {
+	group := &checklyv1alpha1.Group{Spec: checklyv1alpha1.GroupSpec{Routes: []checklyv1alpha1.AlertRoute{
+		{
+			MatchLabels:   map[string]string{"tier": "critical"},
+			AlertChannels: []checklyv1alpha1.WeightedAlertChannel{{Name: "pager", Weight: 100}},
+		},
+		{
+			AlertChannels: []checklyv1alpha1.WeightedAlertChannel{{Name: "team-a", Weight: 75}, {Name: "team-b", Weight: 25}},
+		},
+	}}}
+
+	// The checks are spread across the AlertChannels of the first matching route by weight
+	routed := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		check := &checklyv1alpha1.ApiCheck{}
+		check.Name = fmt.Sprintf("check-%d", i)
+		subscriptions := checkSubscriptions(check, group)
+		if len(subscriptions) != 1 || !subscriptions[0].Activated {
+			t.Fatalf("Expected a single activated subscription, got %v", subscriptions)
+		}
+		routed[subscriptions[0].Name]++
+	}
+	if routed["team-a"] < 700 || routed["team-a"] > 800 || routed["team-a"]+routed["team-b"] != 1000 {
+		t.Errorf("Expected the checks to be spread 75/25, got %v", routed)
+	}
+
+	critical := &checklyv1alpha1.ApiCheck{}
+	critical.Name = "checkout"
+	critical.Labels = map[string]string{"tier": "critical"}
+	subscriptions := checkSubscriptions(critical, group)
+	if len(subscriptions) != 1 || subscriptions[0].Name != "pager" {
+		t.Errorf("Expected the critical check to be routed to pager, got %v", subscriptions)
+	}
+
+	// The same check is always routed to the same AlertChannel, an explicit subscription takes precedence
+	if again := checkSubscriptions(critical, group); again[0] != subscriptions[0] {
+		t.Errorf("Expected a stable route, got %v and %v", subscriptions, again)
+	}
+	critical.Spec.AlertChannelSubscriptions = []checklyv1alpha1.AlertChannelSubscription{{Name: "pager"}}
+	subscriptions = checkSubscriptions(critical, group)
+	if len(subscriptions) != 1 || subscriptions[0].Activated {
+		t.Errorf("Expected the explicit subscription to take precedence, got %v", subscriptions)
+	}
+
+	if subscriptions = checkSubscriptions(critical, &checklyv1alpha1.Group{}); len(subscriptions) != 1 {
+		t.Errorf("Expected only the explicit subscription without routes, got %v", subscriptions)
+	}
+}
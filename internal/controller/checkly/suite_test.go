@@ -103,7 +103,7 @@ var _ = BeforeSuite(func() {
 			r.ParseForm()
 			method := r.Method
 			switch method {
-			case "PUT":
+			case "GET", "PUT":
 				w.WriteHeader(http.StatusOK)
 				w.Header().Set("Content-Type", "application/json")
 				resp := make(map[string]string)
@@ -128,7 +128,7 @@ var _ = BeforeSuite(func() {
 			r.ParseForm()
 			method := r.Method
 			switch method {
-			case "PUT":
+			case "GET", "PUT":
 				w.WriteHeader(http.StatusOK)
 				w.Header().Set("Content-Type", "application/json")
 				resp := make(map[string]interface{})
@@ -0,0 +1,62 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/checkly/checkly-operator/internal/metrics"
+)
+
+// Outcomes of a reconciliation reported in its summary log line
+const (
+	outcomeCreated = "created"
+	outcomeUpdated = "updated"
+	outcomeDeleted = "deleted"
+	outcomeNoop    = "noop"
+	outcomeFailed  = "failed"
+)
+
+// reconcileOutcome maps the operation a reconciliation made in checklyhq.com to its outcome, reconciliations returning
+// an error failed regardless of the operation
+func reconcileOutcome(operation string, err error) string {
+	if err != nil {
+		return outcomeFailed
+	}
+
+	switch operation {
+	case metrics.OperationCreate:
+		return outcomeCreated
+	case metrics.OperationUpdate:
+		return outcomeUpdated
+	case metrics.OperationDelete:
+		return outcomeDeleted
+	default:
+		return outcomeNoop
+	}
+}
+
+// logSummary logs a single line summarizing the reconciliation, so the outcomes can be scanned without the debug logs
+func logSummary(logger logr.Logger, operation string, checklyID interface{}, duration time.Duration, err error) {
+	keysAndValues := []interface{}{"outcome", reconcileOutcome(operation, err), "checkly ID", checklyID, "duration", duration.Round(time.Millisecond).String()}
+	if err != nil {
+		keysAndValues = append(keysAndValues, "error", err.Error())
+	}
+	logger.Info("Reconciled", keysAndValues...)
+}
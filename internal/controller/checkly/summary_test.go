@@ -0,0 +1,68 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+
+	"github.com/checkly/checkly-operator/internal/metrics"
+)
+
+func TestReconcileOutcome(t *testing.T) {
+	testData := []struct {
+		operation string
+		err       error
+		outcome   string
+	}{
+		{metrics.OperationCreate, nil, "created"},
+		{metrics.OperationUpdate, nil, "updated"},
+		{metrics.OperationDelete, nil, "deleted"},
+		{metrics.OperationNone, nil, "noop"},
+		{metrics.OperationUpdate, errors.New("502 Bad Gateway"), "failed"},
+	}
+
+	for _, tt := range testData {
+		if outcome := reconcileOutcome(tt.operation, tt.err); outcome != tt.outcome {
+			t.Errorf("Expected %s for %s, %v, got %s", tt.outcome, tt.operation, tt.err, outcome)
+		}
+	}
+}
+
+func TestLogSummary(t *testing.T) {
+	var lines []string
+	logger := funcr.New(func(prefix, args string) {
+		lines = append(lines, args)
+	}, funcr.Options{})
+
+	logSummary(logger, metrics.OperationUpdate, int64(42), 1234567*time.Microsecond, nil)
+	logSummary(logger, metrics.OperationCreate, "", time.Second, errors.New("502 Bad Gateway"))
+
+	if len(lines) != 2 {
+		t.Fatalf("Expected a single line per reconciliation, got %v", lines)
+	}
+	if !strings.Contains(lines[0], `"outcome"="updated" "checkly ID"=42 "duration"="1.235s"`) {
+		t.Errorf("Expected the updated summary, got %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"outcome"="failed"`) || !strings.Contains(lines[1], `"error"="502 Bad Gateway"`) {
+		t.Errorf("Expected the failed summary with the error, got %s", lines[1])
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// tagMappingEntry maps the value of a resource field onto a checklyhq.com tag
+type tagMappingEntry struct {
+	field []string
+	tag   string
+}
+
+// TagMapping maps resource fields onto checklyhq.com tags, ex. metadata.labels.team onto team:<value>
+type TagMapping []tagMappingEntry
+
+// ParseTagMapping parses a comma separated list of field=tag pairs, ex. "metadata.labels.team=team,spec.endpoint=url".
+// The keys of labels and annotations are taken as is, so they can contain dots.
+func ParseTagMapping(value string) (mapping TagMapping, err error) {
+	if value == "" {
+		return
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		path, tag, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || path == "" || tag == "" {
+			return nil, fmt.Errorf("tag mapping %q has to be in the field=tag format", pair)
+		}
+
+		var field []string
+		for _, prefix := range []string{"metadata.labels.", "metadata.annotations."} {
+			if key, ok := strings.CutPrefix(path, prefix); ok && key != "" {
+				field = append(strings.Split(strings.TrimSuffix(prefix, "."), "."), key)
+			}
+		}
+		if field == nil {
+			field = strings.Split(path, ".")
+		}
+		for _, name := range field {
+			if name == "" {
+				return nil, fmt.Errorf("tag mapping %q has an invalid field path", pair)
+			}
+		}
+
+		mapping = append(mapping, tagMappingEntry{field: field, tag: tag})
+	}
+
+	return
+}
+
+// Tags returns the tags of the fields set on the object, unset fields and fields holding objects or lists are skipped
+func (m TagMapping) Tags(obj client.Object) (tags []string, err error) {
+	if len(m) == 0 {
+		return
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range m {
+		value, found, err := unstructured.NestedFieldNoCopy(content, entry.field...)
+		if err != nil || !found {
+			continue
+		}
+
+		switch value := value.(type) {
+		case string:
+			if value != "" {
+				tags = append(tags, fmt.Sprintf("%s:%s", entry.tag, value))
+			}
+		case bool, int64, float64:
+			tags = append(tags, fmt.Sprintf("%s:%v", entry.tag, value))
+		}
+	}
+
+	return
+}
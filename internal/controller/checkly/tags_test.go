@@ -0,0 +1,63 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+func TestTagMapping(t *testing.T) {
+	mapping, err := ParseTagMapping("metadata.labels.team=team, metadata.labels.app.kubernetes.io/name=app,metadata.namespace=ns,spec.frequency=frequency,spec.muted=muted,metadata.labels.missing=missing")
+	if err != nil {
+		t.Fatalf("Expected no error, got %e", err)
+	}
+
+	apiCheck := &checklyv1alpha1.ApiCheck{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+			Labels: map[string]string{
+				"team":                   "sre",
+				"app.kubernetes.io/name": "shop",
+			},
+		},
+		Spec: checklyv1alpha1.ApiCheckSpec{
+			Frequency: 10,
+		},
+	}
+
+	tags, err := mapping.Tags(apiCheck)
+	if err != nil {
+		t.Fatalf("Expected no error, got %e", err)
+	}
+	expected := []string{"team:sre", "app:shop", "ns:bar", "frequency:10"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("Expected %v, got %v", expected, tags)
+	}
+
+	for _, value := range []string{"metadata.labels.team", "=team", "spec..frequency=frequency"} {
+		_, err = ParseTagMapping(value)
+		if err == nil {
+			t.Errorf("Expected error for %q, got none", value)
+		}
+	}
+}
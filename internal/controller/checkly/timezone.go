@@ -0,0 +1,48 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultTimezone is the timezone of the scheduled features of resources which don't set one, ex. the start and end
+// of maintenance windows, it's set operator-wide with the --default-timezone option
+var DefaultTimezone = time.UTC
+
+// ParseTimezone loads an IANA timezone, ex. Europe/London. The timezone of the operator's host is ambiguous across
+// clusters, so "Local" isn't accepted.
+func ParseTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("timezone %q has to be an IANA timezone, ex. Europe/London", name)
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown IANA timezone %q: %w", name, err)
+	}
+	return location, nil
+}
+
+// resolveTimezone returns the timezone set on a resource, or DefaultTimezone if it doesn't set one
+func resolveTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return DefaultTimezone, nil
+	}
+	return ParseTimezone(name)
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import "testing"
+
+func TestResolveTimezone(t *testing.T) {
+	london, err := ParseTimezone("Europe/London")
+	if err != nil {
+		t.Fatalf("Expected no error, got %e", err)
+	}
+
+	for _, name := range []string{"", "Local", "Mars/Olympus_Mons"} {
+		_, err = ParseTimezone(name)
+		if err == nil {
+			t.Errorf("Expected error for %q, got none", name)
+		}
+	}
+
+	defaultTimezone := DefaultTimezone
+	defer func() { DefaultTimezone = defaultTimezone }()
+	DefaultTimezone = london
+
+	location, err := resolveTimezone("")
+	if err != nil || location != london {
+		t.Errorf("Expected the default timezone, got %v, %v", location, err)
+	}
+
+	location, err = resolveTimezone("America/New_York")
+	if err != nil || location.String() != "America/New_York" {
+		t.Errorf("Expected the timezone of the resource, got %v, %v", location, err)
+	}
+
+	if _, err = resolveTimezone("EST5EDT-invalid"); err == nil {
+		t.Error("Expected error for an invalid timezone of the resource, got none")
+	}
+}
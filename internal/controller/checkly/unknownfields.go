@@ -0,0 +1,125 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Handling of spec fields set in the cluster which the operator doesn't know about, ex. because the CRDs were upgraded
+// ahead of the operator
+const (
+	// UnknownFieldsIgnore skips the check
+	UnknownFieldsIgnore = "ignore"
+	// UnknownFieldsWarn reports unknown fields in a condition and syncs the known ones
+	UnknownFieldsWarn = "warn"
+	// UnknownFieldsReject reports unknown fields in a condition and doesn't sync the resource
+	UnknownFieldsReject = "reject"
+)
+
+var jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// unknownFields returns the paths of the fields set in the object which the Go type doesn't define, these would be
+// dropped when the object is decoded into the type
+func unknownFields(object interface{}, t reflect.Type, path string) (fields []string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// Types decoding themselves can't be walked field by field
+	if reflect.PointerTo(t).Implements(jsonUnmarshaler) {
+		return
+	}
+
+	switch value := object.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Struct:
+			known := jsonFields(t)
+			for _, key := range sortedKeys(value) {
+				fieldType, ok := known[key]
+				if !ok {
+					fields = append(fields, path+"."+key)
+					continue
+				}
+				fields = append(fields, unknownFields(value[key], fieldType, path+"."+key)...)
+			}
+		case reflect.Map:
+			for _, key := range sortedKeys(value) {
+				fields = append(fields, unknownFields(value[key], t.Elem(), path+"."+key)...)
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, item := range value {
+				fields = append(fields, unknownFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+
+	return
+}
+
+// jsonFields maps the JSON names of the fields of a struct onto their types, including the fields of embedded structs
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && (name == "" || strings.Contains(options, "inline")) {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for embeddedName, embeddedType := range jsonFields(embedded) {
+					fields[embeddedName] = embeddedType
+				}
+				continue
+			}
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+
+	return fields
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
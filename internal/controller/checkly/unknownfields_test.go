@@ -0,0 +1,59 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"reflect"
+	"testing"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+func TestUnknownFields(t *testing.T) {
+	spec := map[string]interface{}{
+		"sendrecovery": false,
+		"futurefield":  "foo",
+		"email": map[string]interface{}{
+			"address": "foo@bar.baz",
+		},
+		"opsgenie": map[string]interface{}{
+			"apisecret": map[string]interface{}{
+				"name":      "foo",
+				"fieldPath": "bar",
+				"extra":     true,
+			},
+			"priority": "P1",
+		},
+		"webhook": map[string]interface{}{
+			"url": "https://foo.bar/alerts",
+			"headers": []interface{}{
+				map[string]interface{}{"key": "X-Foo", "locked": true},
+			},
+		},
+	}
+
+	fields := unknownFields(spec, reflect.TypeOf(checklyv1alpha1.AlertChannelSpec{}), "spec")
+	expected := []string{"spec.futurefield", "spec.opsgenie.apisecret.extra", "spec.webhook.headers[0].locked"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expected %v, got %v", expected, fields)
+	}
+
+	fields = unknownFields(map[string]interface{}{"sendfailure": true}, reflect.TypeOf(checklyv1alpha1.AlertChannelSpec{}), "spec")
+	if len(fields) != 0 {
+		t.Errorf("Expected no unknown fields, got %v", fields)
+	}
+}
@@ -0,0 +1,26 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+// Handling of AlertChannel specs failing the operator's validation
+const (
+	// ValidationBlock doesn't sync the AlertChannel until the spec is fixed
+	ValidationBlock = "block"
+	// ValidationBestEffort syncs a sanitized spec and reports the fixes in the Sanitized condition, specs checklyhq.com
+	// would reject are still blocked
+	ValidationBestEffort = "best-effort"
+)
@@ -0,0 +1,411 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkly
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/checkly/checkly-go-sdk"
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	external "github.com/checkly/checkly-operator/external/checkly"
+	"github.com/checkly/checkly-operator/internal/audit"
+	"github.com/checkly/checkly-operator/internal/metrics"
+	"github.com/checkly/checkly-operator/internal/notify"
+)
+
+// VariableGroupReconciler reconciles a VariableGroup object
+type VariableGroupReconciler struct {
+	client.Client
+	Scheme           *runtime.Scheme
+	ApiClient        checkly.Client
+	ControllerDomain string
+	CreateOnly       bool
+	SkipFinalizer    bool
+	ReconcileSummary bool
+	Audit            *audit.Logger
+	Notifier         *notify.Notifier
+	FinalizerTimeout time.Duration
+	DriftInterval    time.Duration
+	RetryLimiter     workqueue.RateLimiter
+	Workers          int
+	Breaker          *external.CircuitBreaker
+	Deprecations     *external.DeprecationTracker
+	HashKey          []byte
+	Recorder         record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=variablegroups,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=variablegroups/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=variablegroups/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.12.1/pkg/reconcile
+func (r *VariableGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.Result, err error) {
+	logger := log.FromContext(ctx)
+	defer metrics.WorkerStarted("variablegroup")()
+
+	variableGroupFinalizer := fmt.Sprintf("%s/finalizer", r.ControllerDomain)
+
+	group := &checklyv1alpha1.VariableGroup{}
+	start := time.Now()
+	operation := metrics.OperationNone
+	defer func() {
+		res, err = finishReconcile(ctx, reconcileRun{
+			kind:         "VariableGroup",
+			namespace:    req.Namespace,
+			name:         req.Name,
+			object:       group,
+			checklyID:    0,
+			operation:    operation,
+			start:        start,
+			client:       r.Client,
+			summary:      r.ReconcileSummary,
+			breaker:      r.Breaker,
+			deprecations: r.Deprecations,
+			recorder:     r.Recorder,
+		}, res, err)
+	}()
+
+	// ////////////////////////////////
+	// Delete Logic
+	// ///////////////////////////////
+	err = r.Get(ctx, req.NamespacedName, group)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// The resource has been deleted
+			logger.V(1).Info("VariableGroup removed")
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object
+		logger.Error(err, "can't read the VariableGroup object")
+		return ctrl.Result{}, err
+	}
+
+	// Paused resources are left as they are, in the cluster and in checklyhq.com, until the annotation is removed
+	if paused(ctx, r.Client, r.ControllerDomain, r.Recorder, "VariableGroup", group) {
+		logger.V(1).Info("VariableGroup reconciliation paused")
+		return ctrl.Result{}, nil
+	}
+
+	// Without the finalizer deletions don't wait for checklyhq.com, the finalizer added before it was disabled is removed
+	if r.SkipFinalizer {
+		var removed bool
+		removed, err = dropFinalizer(ctx, r.Client, group, variableGroupFinalizer)
+		if err != nil {
+			logger.Error(err, "Failed to remove disabled VariableGroup finalizer")
+			return ctrl.Result{}, err
+		}
+		if removed {
+			logger.V(1).Info("Removed disabled finalizer")
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if r.Breaker.Open() {
+		return unavailableResult(ctx, r.Client, r.Breaker, "VariableGroup", group), nil
+	}
+
+	if group.GetDeletionTimestamp() != nil {
+		if controllerutil.ContainsFinalizer(group, variableGroupFinalizer) {
+			logger.V(1).Info("Finalizer is present, trying to delete Checkly environment variables", "keys", group.Status.Keys)
+			if r.CreateOnly {
+				logger.Info("Create only mode, leaving checkly environment variables in place", "keys", group.Status.Keys)
+			} else if len(group.Status.Keys) != 0 {
+				operation = metrics.OperationDelete
+				err := r.deleteVariables(group.Status.Keys)
+				change := audit.Record{Operation: audit.Delete, Kind: "VariableGroup", Object: group, Spec: group.Spec, Before: &audit.State{Generation: group.Status.ObservedGeneration, AppliedHash: group.Status.AppliedHash}, Err: err}
+				r.Audit.Log(change)
+				r.Notifier.Notify(ctx, change)
+				if err != nil && !finalizerTimedOut(group, r.ControllerDomain, r.FinalizerTimeout) {
+					logger.Error(err, "Failed to delete checkly environment variables")
+					return ctrl.Result{}, err
+				}
+
+				if err != nil {
+					logger.Error(err, "Failed to delete checkly environment variables past the finalizer timeout, removing the finalizer anyway", "keys", group.Status.Keys)
+					r.Recorder.Eventf(group, corev1.EventTypeWarning, "FinalizerTimeout", "Failed to delete checkly environment variables %v past the finalizer timeout, they have to be deleted manually: %s", group.Status.Keys, err)
+				} else {
+					logger.Info("Successfully deleted checkly environment variables", "keys", group.Status.Keys)
+				}
+			}
+
+			controllerutil.RemoveFinalizer(group, variableGroupFinalizer)
+			err := r.Update(ctx, group)
+			if err != nil {
+				logger.Error(err, "Failed to delete finalizer")
+				return ctrl.Result{}, err
+			}
+			logger.V(1).Info("Successfully deleted finalizer")
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// /////////////////////////////
+	// Finalizer logic
+	// ////////////////////////////
+	if !r.SkipFinalizer && !controllerutil.ContainsFinalizer(group, variableGroupFinalizer) {
+		controllerutil.AddFinalizer(group, variableGroupFinalizer)
+		err = r.Update(ctx, group)
+		if err != nil {
+			logger.Error(err, "Failed to add VariableGroup finalizer")
+			return ctrl.Result{}, err
+		}
+		logger.V(1).Info("Added finalizer")
+		return ctrl.Result{}, nil
+	}
+
+	// /////////////////////////////
+	// Resolve values
+	// ////////////////////////////
+	variables := make([]external.Variable, 0, len(group.Spec.Variables))
+	for _, variable := range group.Spec.Variables {
+		value := variable.Value
+		if variable.ValueSecret.Name != "" {
+			value, err = GetSecretValue(ctx, r.Client, variable.ValueSecret)
+			if err != nil {
+				logger.Error(err, "Failed to read the secret value", "key", variable.Key)
+				return ctrl.Result{}, err
+			}
+		}
+		variables = append(variables, external.Variable{Key: variable.Key, Value: value, Locked: variable.Locked})
+	}
+
+	err = external.ValidateVariables(variables)
+	if err == nil {
+		err = r.claimedKeys(ctx, group)
+	}
+	if err != nil {
+		logger.Info("Invalid VariableGroup, not syncing it", "reason", err.Error())
+		return ctrl.Result{}, r.recordInvalid(ctx, group, err)
+	}
+
+	// The hash covers the resolved values, a rotated secret changes it and syncs the new value
+	hash, err := external.VariablesHash(r.HashKey, variables)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	synced := meta.IsStatusConditionTrue(group.Status.Conditions, ConditionSynced)
+	unchanged := group.Status.AppliedHash == hash && group.Status.ObservedGeneration == group.Generation && synced
+	if unchanged && r.CreateOnly {
+		logger.V(1).Info("Unchanged VariableGroup, skipping update")
+		return ctrl.Result{}, nil
+	}
+	// Unchanged variables are only written if they were changed in checklyhq.com, a failed read falls back to writing them
+	if unchanged {
+		drift, err := external.VariablesDrift(variables, r.ApiClient)
+		if err != nil {
+			logger.Error(err, "Failed to read the checkly environment variables")
+		} else if len(drift) == 0 {
+			logger.V(1).Info("Unchanged VariableGroup, skipping update")
+			return ctrl.Result{RequeueAfter: r.DriftInterval}, nil
+		} else {
+			logger.V(1).Info("Checkly environment variables differ from the desired state", "keys", drift)
+		}
+	}
+	if r.CreateOnly && len(group.Status.Keys) != 0 {
+		logger.V(1).Info("Create only mode, skipping update", "keys", group.Status.Keys)
+		return ctrl.Result{}, recordSync(ctx, r.Client, group, nil)
+	}
+
+	// /////////////////////////////
+	// Update logic
+	// ////////////////////////////
+	operation = metrics.OperationUpdate
+	if len(group.Status.Keys) == 0 {
+		operation = metrics.OperationCreate
+	}
+
+	keys := make([]string, 0, len(variables))
+	for _, variable := range variables {
+		keys = append(keys, variable.Key)
+	}
+	var removed []string
+	for _, key := range group.Status.Keys {
+		if !slices.Contains(keys, key) {
+			removed = append(removed, key)
+		}
+	}
+
+	applied, err := r.setVariables(group.Status.Keys, variables, removed)
+	change := audit.Record{Operation: audit.Update, Kind: "VariableGroup", Object: group, Spec: group.Spec, Before: &audit.State{Generation: group.Status.ObservedGeneration, AppliedHash: group.Status.AppliedHash}, Err: err}
+	if operation == metrics.OperationCreate {
+		change.Operation = audit.Create
+	}
+	r.Audit.Log(change)
+	r.Notifier.Notify(ctx, change)
+	if err != nil {
+		// Variables written before the failure are recorded, otherwise they'd be left behind when the group is
+		// deleted or the key is removed before the next successful sync
+		logger.Error(err, "Failed to sync the checkly environment variables", "keys", applied)
+		group.Status.Keys = applied
+		meta.SetStatusCondition(&group.Status.Conditions, syncedCondition(group.Generation, err))
+		if statusErr := r.Status().Update(ctx, group); statusErr != nil {
+			logger.Error(statusErr, "Failed to update VariableGroup status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	// The keys and hash change without the condition or generation changing when a secret is rotated, the status is
+	// always written
+	group.Status.Keys = keys
+	group.Status.AppliedHash = hash
+	group.Status.ObservedGeneration = group.Generation
+	meta.SetStatusCondition(&group.Status.Conditions, syncedCondition(group.Generation, nil))
+	err = r.Status().Update(ctx, group)
+	if err != nil {
+		logger.Error(err, "Failed to update VariableGroup status")
+		return ctrl.Result{}, err
+	}
+	logger.Info("Synced checkly environment variables", "keys", keys, "removed", removed)
+
+	return ctrl.Result{RequeueAfter: r.DriftInterval}, nil
+}
+
+// setVariables writes the variables to checklyhq.com and deletes the removed keys. It returns the keys which exist in
+// checklyhq.com afterwards, starting from the existing keys, which includes the variables written before a failure.
+func (r *VariableGroupReconciler) setVariables(existing []string, variables []external.Variable, removed []string) (applied []string, err error) {
+	applied = slices.Clone(existing)
+	for _, variable := range variables {
+		err = external.SetVariable(variable, r.ApiClient)
+		if err != nil {
+			return applied, fmt.Errorf("variable %s: %w", variable.Key, err)
+		}
+		if !slices.Contains(applied, variable.Key) {
+			applied = append(applied, variable.Key)
+		}
+	}
+	for _, key := range removed {
+		err = external.DeleteVariable(key, r.ApiClient)
+		if err != nil {
+			return applied, fmt.Errorf("variable %s: %w", key, err)
+		}
+		applied = slices.DeleteFunc(applied, func(k string) bool { return k == key })
+	}
+
+	return applied, nil
+}
+
+// deleteVariables deletes the checklyhq.com environment variables with the supplied keys
+func (r *VariableGroupReconciler) deleteVariables(keys []string) error {
+	for _, key := range keys {
+		err := external.DeleteVariable(key, r.ApiClient)
+		if err != nil {
+			return fmt.Errorf("variable %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// claimedKeys makes sure the keys of the VariableGroup aren't managed by another VariableGroup already, environment
+// variables are account wide and two groups would overwrite each other
+func (r *VariableGroupReconciler) claimedKeys(ctx context.Context, group *checklyv1alpha1.VariableGroup) error {
+	groups := &checklyv1alpha1.VariableGroupList{}
+	err := r.List(ctx, groups)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range groups.Items {
+		if other.Name == group.Name {
+			continue
+		}
+		for _, variable := range group.Spec.Variables {
+			if slices.Contains(other.Status.Keys, variable.Key) {
+				return fmt.Errorf("variable key %q is managed by VariableGroup %s", variable.Key, other.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// recordInvalid sets the Synced condition of the VariableGroup to the validation error of its spec
+func (r *VariableGroupReconciler) recordInvalid(ctx context.Context, group *checklyv1alpha1.VariableGroup, invalid error) error {
+	condition := metav1.Condition{
+		Type:               ConditionSynced,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonInvalid,
+		Message:            invalid.Error(),
+		ObservedGeneration: group.Generation,
+	}
+	if !meta.SetStatusCondition(&group.Status.Conditions, condition) {
+		return nil
+	}
+
+	r.Recorder.Eventf(group, corev1.EventTypeWarning, ReasonInvalid, "VariableGroup isn't synced to checklyhq.com: %s", invalid)
+	return r.Status().Update(ctx, group)
+}
+
+// secretChanged returns the VariableGroups reading values from the changed secret, so the rotated value is synced
+// right away
+func (r *VariableGroupReconciler) secretChanged(ctx context.Context, o client.Object) (requests []reconcile.Request) {
+	groups := &checklyv1alpha1.VariableGroupList{}
+	err := r.List(ctx, groups)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list VariableGroups using secret", "secret", client.ObjectKeyFromObject(o))
+		return
+	}
+
+	for _, group := range groups.Items {
+		for _, variable := range group.Spec.Variables {
+			if variable.ValueSecret.Namespace == o.GetNamespace() && variable.ValueSecret.Name == o.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: group.Name}})
+				break
+			}
+		}
+	}
+	return
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *VariableGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// The controller is named explicitly, it's the controller and name label of the controller-runtime metrics
+	workers := max(r.Workers, 1)
+	metrics.SetWorkers("variablegroup", workers)
+
+	// Only the metadata of secrets is watched, the values are read when the VariableGroup is reconciled
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("variablegroup").
+		For(&checklyv1alpha1.VariableGroup{}).
+		WithOptions(controller.Options{RateLimiter: r.RetryLimiter, MaxConcurrentReconciles: workers}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.secretChanged), builder.OnlyMetadata).
+		Complete(r)
+}
@@ -0,0 +1,279 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Alert channel types which can be configured through Service annotations
+const (
+	AlertChannelTypeEmail   = "email"
+	AlertChannelTypeSlack   = "slack"
+	AlertChannelTypeWebhook = "webhook"
+)
+
+// ServiceReconciler reconciles a Service object
+type ServiceReconciler struct {
+	client.Client
+	Scheme           *runtime.Scheme
+	ControllerDomain string
+	Recorder         record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=alertchannels,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=k8s.checklyhq.com,resources=groups,verbs=get
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.11.0/pkg/reconcile
+func (r *ServiceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.V(1).Info("Reconciler started")
+
+	service := &corev1.Service{}
+
+	// Check if service object is still present
+	err := r.Get(ctx, req.NamespacedName, service)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// AlertChannels are cluster scoped, they can't be garbage collected through an owner reference
+			logger.V(1).Info("Service got deleted")
+			return ctrl.Result{}, r.deleteAlertChannel(ctx, req.NamespacedName)
+		}
+		logger.Error(err, "Can't read the Service object")
+		return ctrl.Result{}, err
+	}
+
+	// The annotation may have been removed, a previously created AlertChannel is deleted
+	if service.Annotations[r.annotation("type")] == "" {
+		return ctrl.Result{}, r.deleteAlertChannel(ctx, req.NamespacedName)
+	}
+
+	alertChannelSpec, err := r.gatherAlertChannelData(service)
+	if err != nil {
+		// The annotations have to change for the Service to be reconciled successfully, there's no point in retrying
+		logger.Info("Invalid alert channel annotations", "err", err)
+		r.Recorder.Eventf(service, corev1.EventTypeWarning, "InvalidAnnotations", "Can't derive an AlertChannel from the annotations: %s", err)
+		return ctrl.Result{}, nil
+	}
+
+	denied, err := r.deniedGroup(ctx, service.Namespace, alertChannelSpec.Groups)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if denied != "" {
+		// Like invalid annotations the previously created AlertChannel is kept until the Group allows the namespace
+		logger.Info("Group doesn't allow alert channels of the namespace", "Group", denied)
+		r.Recorder.Eventf(service, corev1.EventTypeWarning, "GroupNotAllowed", "Group %s doesn't allow alert channels of Services in the %s namespace, it has to be listed in the %s annotation of the Group", denied, service.Namespace, r.annotation("service-namespaces"))
+		return ctrl.Result{}, nil
+	}
+
+	alertChannel := &checklyv1alpha1.AlertChannel{}
+	err = r.Get(ctx, types.NamespacedName{Name: AlertChannelName(req.NamespacedName)}, alertChannel)
+	if err == nil {
+		if !r.manages(alertChannel, req.NamespacedName) {
+			r.Recorder.Eventf(service, corev1.EventTypeWarning, "NameConflict", "AlertChannel %s exists and isn't managed by this Service", alertChannel.Name)
+			return ctrl.Result{}, nil
+		}
+		if equality.Semantic.DeepEqual(alertChannel.Spec, alertChannelSpec) {
+			return ctrl.Result{}, nil
+		}
+
+		logger.Info("AlertChannel exists, doing an update", "AlertChannel", alertChannel.Name)
+		alertChannel.Spec = alertChannelSpec
+		return ctrl.Result{}, r.Update(ctx, alertChannel)
+	}
+	if !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	newAlertChannel := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: AlertChannelName(req.NamespacedName),
+			Labels: map[string]string{
+				r.annotation("service-namespace"): service.Namespace,
+				r.annotation("service-name"):      service.Name,
+			},
+		},
+		Spec: alertChannelSpec,
+	}
+
+	err = r.Create(ctx, newAlertChannel)
+	if err != nil {
+		logger.Info("Failed to create AlertChannel", "err", err)
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Created AlertChannel", "AlertChannel", newAlertChannel.Name)
+	r.Recorder.Eventf(service, corev1.EventTypeNormal, "AlertChannelCreated", "Created AlertChannel %s", newAlertChannel.Name)
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ServiceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Service{}).
+		Complete(r)
+}
+
+// AlertChannelName returns the name of the AlertChannel derived from the annotations of a Service, AlertChannels are
+// cluster scoped so the namespace is part of the name
+func AlertChannelName(service types.NamespacedName) string {
+	return fmt.Sprintf("%s-%s", service.Namespace, service.Name)
+}
+
+func (r *ServiceReconciler) annotation(name string) string {
+	return fmt.Sprintf("%s/alertchannel-%s", r.ControllerDomain, name)
+}
+
+// manages determines if the AlertChannel was created from the annotations of the Service
+func (r *ServiceReconciler) manages(alertChannel *checklyv1alpha1.AlertChannel, service types.NamespacedName) bool {
+	labels := alertChannel.GetLabels()
+	return labels[r.annotation("service-namespace")] == service.Namespace && labels[r.annotation("service-name")] == service.Name
+}
+
+// deleteAlertChannel deletes the AlertChannel created from the annotations of the Service, if there's one
+func (r *ServiceReconciler) deleteAlertChannel(ctx context.Context, service types.NamespacedName) error {
+	alertChannel := &checklyv1alpha1.AlertChannel{}
+	err := r.Get(ctx, types.NamespacedName{Name: AlertChannelName(service)}, alertChannel)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !r.manages(alertChannel, service) {
+		return nil
+	}
+
+	log.FromContext(ctx).Info("Deleting AlertChannel of the Service", "AlertChannel", alertChannel.Name)
+	return client.IgnoreNotFound(r.Delete(ctx, alertChannel))
+}
+
+func (r *ServiceReconciler) gatherAlertChannelData(service *corev1.Service) (alertChannelSpec checklyv1alpha1.AlertChannelSpec, err error) {
+	channelType := service.Annotations[r.annotation("type")]
+	targetAnnotation := r.annotation("target")
+	if channelType == AlertChannelTypeSlack {
+		// The Slack webhook URL is a secret, it's read from a Secret in the namespace of the Service instead of being
+		// written to the annotations and the AlertChannel in plaintext
+		if service.Annotations[targetAnnotation] != "" {
+			err = fmt.Errorf("the Slack webhook URL can't be set with the %s annotation, reference a Secret holding it with %s", targetAnnotation, r.annotation("slack-url-secret"))
+			return
+		}
+		targetAnnotation = r.annotation("slack-url-secret")
+	}
+	target := service.Annotations[targetAnnotation]
+	if target == "" {
+		err = fmt.Errorf("could not find a value for the %s annotation, can't continue without one", targetAnnotation)
+		return
+	}
+
+	alertChannelSpec = checklyv1alpha1.AlertChannelSpec{
+		SendFailure:  true,
+		SendRecovery: true,
+	}
+
+	switch channelType {
+	case AlertChannelTypeEmail:
+		if _, parseErr := mail.ParseAddress(target); parseErr != nil {
+			err = fmt.Errorf("%s is not a valid email address: %w", target, parseErr)
+			return
+		}
+		alertChannelSpec.Email.Address = target
+	case AlertChannelTypeSlack:
+		name, key, found := strings.Cut(target, "/")
+		if !found || name == "" || key == "" {
+			err = fmt.Errorf("%s is not a valid secret reference, expected <secret name>/<key>", target)
+			return
+		}
+		alertChannelSpec.Slack.URLSecret = corev1.ObjectReference{Namespace: service.Namespace, Name: name, FieldPath: key}
+		alertChannelSpec.Slack.Channel = service.Annotations[r.annotation("slack-channel")]
+	case AlertChannelTypeWebhook:
+		parsed, parseErr := url.Parse(target)
+		if parseErr != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			err = fmt.Errorf("%s is not a valid http(s) URL", target)
+			return
+		}
+		alertChannelSpec.Webhook.URL = target
+	default:
+		err = fmt.Errorf("unknown alert channel type %q, valid options are %s, %s and %s", channelType, AlertChannelTypeEmail, AlertChannelTypeSlack, AlertChannelTypeWebhook)
+		return
+	}
+
+	if value, ok := service.Annotations[r.annotation("send-degraded")]; ok {
+		alertChannelSpec.SendDegraded = value == "true"
+	}
+	if value, ok := service.Annotations[r.annotation("send-recovery")]; ok {
+		alertChannelSpec.SendRecovery = value != "false"
+	}
+
+	for _, group := range strings.Split(service.Annotations[r.annotation("groups")], ",") {
+		group = strings.TrimSpace(group)
+		if group != "" {
+			alertChannelSpec.Groups = append(alertChannelSpec.Groups, group)
+		}
+	}
+
+	return
+}
+
+// deniedGroup returns the first Group the AlertChannel is attached to which doesn't allow alert channels of Services in
+// the namespace. Groups are cluster scoped and shared between teams, a Group opts in with a comma separated list of
+// namespaces in its alertchannel-service-namespaces annotation
+func (r *ServiceReconciler) deniedGroup(ctx context.Context, namespace string, groups []string) (string, error) {
+	for _, name := range groups {
+		group := &checklyv1alpha1.Group{}
+		err := r.Get(ctx, types.NamespacedName{Name: name}, group)
+		if errors.IsNotFound(err) {
+			return name, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		allowed := false
+		for _, allowedNamespace := range strings.Split(group.Annotations[r.annotation("service-namespaces")], ",") {
+			if strings.TrimSpace(allowedNamespace) == namespace {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return name, nil
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,254 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileService(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+			Annotations: map[string]string{
+				"testing.domain.tld/alertchannel-type":             "slack",
+				"testing.domain.tld/alertchannel-slack-url-secret": "slack/url",
+				"testing.domain.tld/alertchannel-slack-channel":    "#alerts",
+				"testing.domain.tld/alertchannel-groups":           "group-a, group-b",
+			},
+		},
+	}
+	groupA := &checklyv1alpha1.Group{ObjectMeta: metav1.ObjectMeta{
+		Name:        "group-a",
+		Annotations: map[string]string{"testing.domain.tld/alertchannel-service-namespaces": "bar"},
+	}}
+	groupB := &checklyv1alpha1.Group{ObjectMeta: metav1.ObjectMeta{
+		Name:        "group-b",
+		Annotations: map[string]string{"testing.domain.tld/alertchannel-service-namespaces": "foo, bar"},
+	}}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(service, groupA, groupB).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &ServiceReconciler{Client: c, Scheme: scheme, ControllerDomain: "testing.domain.tld", Recorder: recorder}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "foo", Namespace: "bar"}}
+	key := types.NamespacedName{Name: "bar-foo"}
+
+	_, err := r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	alertChannel := &checklyv1alpha1.AlertChannel{}
+	err = c.Get(ctx, key, alertChannel)
+	if err != nil {
+		t.Fatalf("Expected the AlertChannel to be created, got %v", err)
+	}
+	wantSecret := corev1.ObjectReference{Namespace: "bar", Name: "slack", FieldPath: "url"}
+	if alertChannel.Spec.Slack.URL != "" || alertChannel.Spec.Slack.URLSecret != wantSecret || alertChannel.Spec.Slack.Channel != "#alerts" {
+		t.Errorf("Expected the Slack configuration from the annotations, got %+v", alertChannel.Spec.Slack)
+	}
+	if !alertChannel.Spec.SendFailure || !alertChannel.Spec.SendRecovery || alertChannel.Spec.SendDegraded {
+		t.Errorf("Expected failure and recovery alerts to be sent, got %+v", alertChannel.Spec)
+	}
+	if len(alertChannel.Spec.Groups) != 2 || alertChannel.Spec.Groups[1] != "group-b" {
+		t.Errorf("Expected the groups from the annotation, got %v", alertChannel.Spec.Groups)
+	}
+
+	// Changed annotations update the AlertChannel
+	service.Annotations["testing.domain.tld/alertchannel-send-degraded"] = "true"
+	_ = c.Update(ctx, service)
+	_, err = r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_ = c.Get(ctx, key, alertChannel)
+	if !alertChannel.Spec.SendDegraded {
+		t.Error("Expected the AlertChannel to be updated")
+	}
+
+	// Invalid annotations leave the AlertChannel as it is
+	service.Annotations["testing.domain.tld/alertchannel-slack-url-secret"] = "slack"
+	_ = c.Update(ctx, service)
+	_, err = r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_ = c.Get(ctx, key, alertChannel)
+	if alertChannel.Spec.Slack.URLSecret != wantSecret {
+		t.Errorf("Expected the AlertChannel to be unchanged, got %+v", alertChannel.Spec.Slack.URLSecret)
+	}
+
+	// A Group which doesn't allow the namespace leaves the AlertChannel as it is
+	service.Annotations["testing.domain.tld/alertchannel-slack-url-secret"] = "slack/other"
+	service.Annotations["testing.domain.tld/alertchannel-groups"] = "group-a, group-c"
+	_ = c.Update(ctx, service)
+	_, err = r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_ = c.Get(ctx, key, alertChannel)
+	if alertChannel.Spec.Slack.URLSecret != wantSecret {
+		t.Errorf("Expected the AlertChannel to be unchanged, got %+v", alertChannel.Spec.Slack.URLSecret)
+	}
+
+	// Removing the annotation deletes the AlertChannel
+	delete(service.Annotations, "testing.domain.tld/alertchannel-type")
+	_ = c.Update(ctx, service)
+	_, err = r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	err = c.Get(ctx, key, alertChannel)
+	if !errors.IsNotFound(err) {
+		t.Errorf("Expected the AlertChannel to be deleted, got %v", err)
+	}
+}
+
+func TestReconcileServiceNameConflict(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+			Annotations: map[string]string{
+				"testing.domain.tld/alertchannel-type":   "email",
+				"testing.domain.tld/alertchannel-target": "foo@bar.baz",
+			},
+		},
+	}
+	existing := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar-foo"},
+	}
+	existing.Spec.Email.Address = "other@bar.baz"
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(service, existing).Build()
+	r := &ServiceReconciler{Client: c, Scheme: scheme, ControllerDomain: "testing.domain.tld", Recorder: record.NewFakeRecorder(10)}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "foo", Namespace: "bar"}}
+
+	_, err := r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	alertChannel := &checklyv1alpha1.AlertChannel{}
+	_ = c.Get(ctx, types.NamespacedName{Name: "bar-foo"}, alertChannel)
+	if alertChannel.Spec.Email.Address != "other@bar.baz" {
+		t.Errorf("Expected the AlertChannel not managed by the Service to be unchanged, got %s", alertChannel.Spec.Email.Address)
+	}
+
+	// Deleting the Service doesn't delete an AlertChannel it doesn't manage
+	_ = c.Delete(ctx, service)
+	_, err = r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	err = c.Get(ctx, types.NamespacedName{Name: "bar-foo"}, alertChannel)
+	if err != nil {
+		t.Errorf("Expected the AlertChannel to be kept, got %v", err)
+	}
+}
+
+func TestGatherAlertChannelData(t *testing.T) {
+	r := &ServiceReconciler{ControllerDomain: "testing.domain.tld"}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantErr     bool
+	}{
+		{"email", map[string]string{"testing.domain.tld/alertchannel-type": "email", "testing.domain.tld/alertchannel-target": "foo@bar.baz"}, false},
+		{"slack", map[string]string{"testing.domain.tld/alertchannel-type": "slack", "testing.domain.tld/alertchannel-slack-url-secret": "slack/url"}, false},
+		{"slack url in plaintext", map[string]string{"testing.domain.tld/alertchannel-type": "slack", "testing.domain.tld/alertchannel-target": "https://hooks.slack.com/services/foo"}, true},
+		{"invalid slack secret", map[string]string{"testing.domain.tld/alertchannel-type": "slack", "testing.domain.tld/alertchannel-slack-url-secret": "slack"}, true},
+		{"webhook", map[string]string{"testing.domain.tld/alertchannel-type": "webhook", "testing.domain.tld/alertchannel-target": "https://foo.bar/alerts"}, false},
+		{"missing target", map[string]string{"testing.domain.tld/alertchannel-type": "email"}, true},
+		{"invalid email", map[string]string{"testing.domain.tld/alertchannel-type": "email", "testing.domain.tld/alertchannel-target": "foo"}, true},
+		{"invalid url", map[string]string{"testing.domain.tld/alertchannel-type": "webhook", "testing.domain.tld/alertchannel-target": "ftp://foo.bar"}, true},
+		{"unknown type", map[string]string{"testing.domain.tld/alertchannel-type": "pigeon", "testing.domain.tld/alertchannel-target": "foo"}, true},
+	}
+
+	for _, tt := range tests {
+		service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+		_, err := r.gatherAlertChannelData(service)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestReconcileServiceGroupNotAllowed(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+			Annotations: map[string]string{
+				"testing.domain.tld/alertchannel-type":   "email",
+				"testing.domain.tld/alertchannel-target": "foo@bar.baz",
+				"testing.domain.tld/alertchannel-groups": "payments",
+			},
+		},
+	}
+	group := &checklyv1alpha1.Group{ObjectMeta: metav1.ObjectMeta{
+		Name:        "payments",
+		Annotations: map[string]string{"testing.domain.tld/alertchannel-service-namespaces": "payments"},
+	}}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(service, group).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &ServiceReconciler{Client: c, Scheme: scheme, ControllerDomain: "testing.domain.tld", Recorder: recorder}
+	ctx := context.Background()
+
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "foo", Namespace: "bar"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	err = c.Get(ctx, types.NamespacedName{Name: "bar-foo"}, &checklyv1alpha1.AlertChannel{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("Expected no AlertChannel attached to a Group of another team, got %v", err)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "GroupNotAllowed") {
+		t.Errorf("Expected a GroupNotAllowed event, got %s", event)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SamplingTick is the interval identical messages are counted in, the first one of every interval is always logged
+const SamplingTick = time.Second
+
+// Sampling returns a zap option logging the first of the messages with the same level and text in every tick and one
+// in rate of the rest, so bursts of identical messages during mass reconciles don't drown the others. Errors are
+// never sampled. A rate below 2 disables sampling.
+func Sampling(rate int, tick time.Duration) zap.Option {
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if rate < 2 {
+			return core
+		}
+		return &samplingCore{
+			Core:    core,
+			sampled: zapcore.NewSamplerWithOptions(core, tick, 1, rate),
+		}
+	})
+}
+
+// samplingCore passes errors to the wrapped core and everything else to the sampler
+type samplingCore struct {
+	zapcore.Core
+	sampled zapcore.Core
+}
+
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingCore{
+		Core:    c.Core.With(fields),
+		sampled: c.sampled.With(fields),
+	}
+}
+
+func (c *samplingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if entry.Level >= zapcore.ErrorLevel {
+		return c.Core.Check(entry, checked)
+	}
+	return c.sampled.Check(entry, checked)
+}
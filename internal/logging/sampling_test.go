@@ -0,0 +1,64 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSampling(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(core, Sampling(10, time.Hour))
+
+	for i := 0; i < 100; i++ {
+		logger.Info("Reconciler started", zap.Int("i", i))
+	}
+	if logs.FilterMessage("Reconciler started").Len() != 10 {
+		t.Errorf("Expected 10 of 100 identical messages to be logged, got %d", logs.FilterMessage("Reconciler started").Len())
+	}
+
+	// Other messages and fields added to the logger are sampled on their own
+	named := logger.With(zap.String("controller", "alertchannel"))
+	named.Info("Existing object, with ID")
+	if logs.FilterMessage("Existing object, with ID").Len() != 1 {
+		t.Error("Expected the first of a different message to be logged")
+	}
+
+	for i := 0; i < 100; i++ {
+		logger.Error("Failed to update checkly AlertChannel")
+	}
+	if logs.FilterMessage("Failed to update checkly AlertChannel").Len() != 100 {
+		t.Errorf("Expected every error to be logged, got %d", logs.FilterMessage("Failed to update checkly AlertChannel").Len())
+	}
+}
+
+func TestSamplingDisabled(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(core, Sampling(0, time.Hour))
+
+	for i := 0; i < 100; i++ {
+		logger.Info("Reconciler started")
+	}
+	if logs.Len() != 100 {
+		t.Errorf("Expected every message to be logged, got %d", logs.Len())
+	}
+}
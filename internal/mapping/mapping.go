@@ -0,0 +1,120 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mapping
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConfigMap maintains a ConfigMap mapping checklyhq.com IDs to the kubernetes resources managing them,
+// so in-cluster tooling can look them up without calling the checklyhq.com API, a nil ConfigMap does nothing
+type ConfigMap struct {
+	client client.Client
+	key    types.NamespacedName
+}
+
+// New returns a ConfigMap which maintains the mapping in the ConfigMap identified by key, it's created if missing
+func New(c client.Client, key types.NamespacedName) *ConfigMap {
+	return &ConfigMap{
+		client: c,
+		key:    key,
+	}
+}
+
+// Key returns the data key of a checklyhq.com resource, ex. alertchannel.123
+func Key(kind string, id interface{}) string {
+	return fmt.Sprintf("%s.%v", strings.ToLower(kind), id)
+}
+
+// Set maps the checklyhq.com ID to the name of the object, namespaced objects are stored as namespace/name
+func (m *ConfigMap) Set(ctx context.Context, kind string, id interface{}, obj client.Object) error {
+	if m == nil {
+		return nil
+	}
+
+	key := Key(kind, id)
+	value := obj.GetName()
+	if obj.GetNamespace() != "" {
+		value = client.ObjectKeyFromObject(obj).String()
+	}
+
+	return m.update(ctx, func(data map[string]string) bool {
+		if data[key] == value {
+			return false
+		}
+		data[key] = value
+		return true
+	})
+}
+
+// Remove drops the checklyhq.com ID from the mapping
+func (m *ConfigMap) Remove(ctx context.Context, kind string, id interface{}) error {
+	if m == nil {
+		return nil
+	}
+
+	key := Key(kind, id)
+
+	return m.update(ctx, func(data map[string]string) bool {
+		if _, ok := data[key]; !ok {
+			return false
+		}
+		delete(data, key)
+		return true
+	})
+}
+
+// update applies mutate to the data of the ConfigMap, retrying on conflicts as every controller writes to the same ConfigMap
+func (m *ConfigMap) update(ctx context.Context, mutate func(data map[string]string) bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := &corev1.ConfigMap{}
+		err := m.client.Get(ctx, m.key, cm)
+		if errors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      m.key.Name,
+					Namespace: m.key.Namespace,
+				},
+				Data: make(map[string]string),
+			}
+			if !mutate(cm.Data) {
+				return nil
+			}
+			return m.client.Create(ctx, cm)
+		}
+		if err != nil {
+			return err
+		}
+
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		if !mutate(cm.Data) {
+			return nil
+		}
+		return m.client.Update(ctx, cm)
+	})
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mapping
+
+import (
+	"context"
+	"testing"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestConfigMap(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "checkly-operator-system", Name: "checkly-ids"}
+	c := fake.NewClientBuilder().Build()
+	m := New(c, key)
+
+	ac := &checklyv1alpha1.AlertChannel{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	check := &checklyv1alpha1.ApiCheck{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "baz"}}
+
+	err := m.Set(ctx, "AlertChannel", int64(1), ac)
+	if err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	err = m.Set(ctx, "ApiCheck", "2", check)
+	if err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	cm := &corev1.ConfigMap{}
+	err = c.Get(ctx, key, cm)
+	if err != nil {
+		t.Fatalf("Expected ConfigMap to be created, got %s", err)
+	}
+	if cm.Data["alertchannel.1"] != "foo" {
+		t.Errorf("Expected foo, got %s", cm.Data["alertchannel.1"])
+	}
+	if cm.Data["apicheck.2"] != "baz/bar" {
+		t.Errorf("Expected baz/bar, got %s", cm.Data["apicheck.2"])
+	}
+
+	err = m.Remove(ctx, "AlertChannel", int64(1))
+	if err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	err = c.Get(ctx, key, cm)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if _, ok := cm.Data["alertchannel.1"]; ok {
+		t.Errorf("Expected alertchannel.1 to be removed")
+	}
+
+	// A nil ConfigMap should do nothing
+	var nilMapping *ConfigMap
+	err = nilMapping.Set(ctx, "AlertChannel", int64(1), ac)
+	if err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+)
+
+// Operations reported by the checkly_reconcile_duration_seconds metric, OperationNone covers reconciliations which
+// didn't write to checklyhq.com, ex. because the resource was unchanged or failed validation
+const (
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+	OperationNone   = "none"
+)
+
+// Verbs reported by the checklyhq.com API request metrics
+const (
+	VerbGet    = "get"
+	VerbCreate = "create"
+	VerbUpdate = "update"
+	VerbDelete = "delete"
+)
+
+// Reasons reported by the checkly_operator_paused metric
+const (
+	// PausedCreateOnly is reported in create only mode, where changes are not pushed to checklyhq.com
+	PausedCreateOnly = "create-only"
+	// PausedCircuitOpen is reported while the circuit breaker keeps the operator from calling the failing API
+	PausedCircuitOpen = "circuit-open"
+)
+
+var (
+	// TeamLabel is the kubernetes label key used to populate the team label of the metrics
+	TeamLabel = "team"
+
+	// NamespaceLabels determines if the namespace label of the metrics is populated, disabling it keeps the cardinality down
+	NamespaceLabels = true
+
+	reconcileTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "checkly_reconcile_total",
+			Help: "Total number of reconciliations per kind, namespace of namespaced kinds, team and result.",
+		},
+		[]string{"kind", "namespace", "team", "result"},
+	)
+
+	reconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "checkly_reconcile_duration_seconds",
+			Help:    "Duration of reconciliations per kind and the operation they made in checklyhq.com.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+		},
+		[]string{"kind", "operation"},
+	)
+
+	operatorPaused = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "checkly_operator_paused",
+			Help: "Set to 1 for every reason the operator is intentionally not syncing changes to checklyhq.com.",
+		},
+		[]string{"reason"},
+	)
+
+	pausedResources = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "checkly_operator_paused_resources",
+			Help: "Number of resources per kind whose reconciliation is paused by the paused annotation.",
+		},
+		[]string{"kind"},
+	)
+
+	workerUtilization = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "checkly_operator_worker_utilization",
+			Help: "Share of the reconcile workers of the controller busy reconciling, at 1 the workers are saturated and the queue grows.",
+		},
+		[]string{"controller"},
+	)
+
+	apiRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "checkly_api_requests_total",
+			Help: "Total number of checklyhq.com API requests per kind, verb and result.",
+		},
+		[]string{"kind", "verb", "result"},
+	)
+
+	apiRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "checkly_api_request_duration_seconds",
+			Help:    "Latency of checklyhq.com API requests per kind and verb.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+		},
+		[]string{"kind", "verb"},
+	)
+
+	workersMu     sync.Mutex
+	workers       = map[string]int{}
+	activeWorkers = map[string]int{}
+
+	pausedMu   sync.Mutex
+	pausedKeys = map[string]map[string]bool{}
+)
+
+func init() {
+	// Register custom metrics with the global prometheus registry, they're served on the manager metrics endpoint
+	metrics.Registry.MustRegister(reconcileTotal, reconcileDuration, operatorPaused, pausedResources, workerUtilization, apiRequestsTotal, apiRequestDuration)
+}
+
+// ObserveReconcile records the outcome of a reconciliation. Cluster scoped kinds have no namespace, their namespace label
+// is always empty and only the team label tells apart who they belong to.
+func ObserveReconcile(kind string, namespace string, labels map[string]string, err error) {
+	result := ResultSuccess
+	if err != nil {
+		result = ResultError
+	}
+
+	if !NamespaceLabels {
+		namespace = ""
+	}
+
+	reconcileTotal.WithLabelValues(kind, namespace, labels[TeamLabel], result).Inc()
+}
+
+// ObserveReconcileDuration records the duration of a reconciliation and the operation it made in checklyhq.com, the
+// namespace and team are left out to keep the number of histogram series down
+func ObserveReconcileDuration(kind string, operation string, duration time.Duration) {
+	reconcileDuration.WithLabelValues(kind, operation).Observe(duration.Seconds())
+}
+
+// ObserveAPIRequest records the outcome and latency of a request to the checklyhq.com API
+func ObserveAPIRequest(kind string, verb string, duration time.Duration, err error) {
+	result := ResultSuccess
+	if err != nil {
+		result = ResultError
+	}
+
+	apiRequestsTotal.WithLabelValues(kind, verb, result).Inc()
+	apiRequestDuration.WithLabelValues(kind, verb).Observe(duration.Seconds())
+}
+
+// SetPaused reports whether the operator is intentionally not syncing changes for the supplied reason, so monitoring
+// can tell an idle operator apart from a broken one
+func SetPaused(reason string, paused bool) {
+	value := 0.0
+	if paused {
+		value = 1
+	}
+
+	operatorPaused.WithLabelValues(reason).Set(value)
+}
+
+// SetResourcePaused records whether the reconciliation of a resource of the kind, identified by its namespace and name,
+// is paused by the paused annotation
+func SetResourcePaused(kind string, key string, isPaused bool) {
+	pausedMu.Lock()
+	defer pausedMu.Unlock()
+
+	if pausedKeys[kind] == nil {
+		pausedKeys[kind] = map[string]bool{}
+	}
+	if isPaused {
+		pausedKeys[kind][key] = true
+	} else {
+		delete(pausedKeys[kind], key)
+	}
+	pausedResources.WithLabelValues(kind).Set(float64(len(pausedKeys[kind])))
+}
+
+// SetWorkers records the number of reconcile workers of the controller, which the utilization is reported against
+func SetWorkers(controller string, count int) {
+	workersMu.Lock()
+	defer workersMu.Unlock()
+
+	workers[controller] = count
+	setUtilization(controller)
+}
+
+// WorkerStarted reports a worker of the controller started reconciling, the returned function reports it's done
+func WorkerStarted(controller string) (done func()) {
+	workersMu.Lock()
+	defer workersMu.Unlock()
+
+	activeWorkers[controller]++
+	setUtilization(controller)
+
+	return func() {
+		workersMu.Lock()
+		defer workersMu.Unlock()
+
+		activeWorkers[controller]--
+		setUtilization(controller)
+	}
+}
+
+func setUtilization(controller string) {
+	if workers[controller] == 0 {
+		return
+	}
+	workerUtilization.WithLabelValues(controller).Set(float64(activeWorkers[controller]) / float64(workers[controller]))
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveReconcile(t *testing.T) {
+	labels := map[string]string{
+		"team": "foo",
+	}
+
+	ObserveReconcile("ApiCheck", "bar", labels, nil)
+	ObserveReconcile("ApiCheck", "bar", labels, errors.New("baz"))
+
+	got := testutil.ToFloat64(reconcileTotal.WithLabelValues("ApiCheck", "bar", "foo", ResultSuccess))
+	if got != 1 {
+		t.Errorf("Expected %d, got %f", 1, got)
+	}
+
+	got = testutil.ToFloat64(reconcileTotal.WithLabelValues("ApiCheck", "bar", "foo", ResultError))
+	if got != 1 {
+		t.Errorf("Expected %d, got %f", 1, got)
+	}
+
+	NamespaceLabels = false
+	defer func() { NamespaceLabels = true }()
+
+	ObserveReconcile("ApiCheck", "bar", labels, nil)
+
+	got = testutil.ToFloat64(reconcileTotal.WithLabelValues("ApiCheck", "", "foo", ResultSuccess))
+	if got != 1 {
+		t.Errorf("Expected %d, got %f", 1, got)
+	}
+}
+
+func TestObserveAPIRequest(t *testing.T) {
+	ObserveAPIRequest("AlertChannel", VerbCreate, time.Second, nil)
+	ObserveAPIRequest("AlertChannel", VerbCreate, time.Second, errors.New("baz"))
+
+	got := testutil.ToFloat64(apiRequestsTotal.WithLabelValues("AlertChannel", VerbCreate, ResultSuccess))
+	if got != 1 {
+		t.Errorf("Expected %d, got %f", 1, got)
+	}
+
+	got = testutil.ToFloat64(apiRequestsTotal.WithLabelValues("AlertChannel", VerbCreate, ResultError))
+	if got != 1 {
+		t.Errorf("Expected %d, got %f", 1, got)
+	}
+
+	if count := testutil.CollectAndCount(apiRequestDuration, "checkly_api_request_duration_seconds"); count != 1 {
+		t.Errorf("Expected a single latency series, got %d", count)
+	}
+}
+
+func TestSetPaused(t *testing.T) {
+	SetPaused(PausedCreateOnly, true)
+
+	got := testutil.ToFloat64(operatorPaused.WithLabelValues(PausedCreateOnly))
+	if got != 1 {
+		t.Errorf("Expected %d, got %f", 1, got)
+	}
+
+	SetPaused(PausedCreateOnly, false)
+
+	got = testutil.ToFloat64(operatorPaused.WithLabelValues(PausedCreateOnly))
+	if got != 0 {
+		t.Errorf("Expected %d, got %f", 0, got)
+	}
+}
+
+func TestSetResourcePaused(t *testing.T) {
+	SetResourcePaused("Group", "/payments", true)
+	SetResourcePaused("Group", "/payments", true)
+	SetResourcePaused("Group", "/checkout", true)
+
+	got := testutil.ToFloat64(pausedResources.WithLabelValues("Group"))
+	if got != 2 {
+		t.Errorf("Expected %d, got %f", 2, got)
+	}
+
+	SetResourcePaused("Group", "/payments", false)
+	SetResourcePaused("Group", "/login", false)
+
+	got = testutil.ToFloat64(pausedResources.WithLabelValues("Group"))
+	if got != 1 {
+		t.Errorf("Expected %d, got %f", 1, got)
+	}
+}
+
+func TestWorkerUtilization(t *testing.T) {
+	SetWorkers("alertchannel", 4)
+
+	done := WorkerStarted("alertchannel")
+	WorkerStarted("alertchannel")()
+
+	got := testutil.ToFloat64(workerUtilization.WithLabelValues("alertchannel"))
+	if got != 0.25 {
+		t.Errorf("Expected %f, got %f", 0.25, got)
+	}
+
+	done()
+	got = testutil.ToFloat64(workerUtilization.WithLabelValues("alertchannel"))
+	if got != 0 {
+		t.Errorf("Expected %d, got %f", 0, got)
+	}
+}
+
+func TestObserveReconcileDuration(t *testing.T) {
+	ObserveReconcileDuration("AlertChannel", OperationUpdate, 30*time.Millisecond)
+	ObserveReconcileDuration("AlertChannel", OperationUpdate, 3*time.Second)
+	ObserveReconcileDuration("ApiCheck", OperationNone, time.Millisecond)
+
+	expected := `
+# HELP checkly_reconcile_duration_seconds Duration of reconciliations per kind and the operation they made in checklyhq.com.
+# TYPE checkly_reconcile_duration_seconds histogram
+checkly_reconcile_duration_seconds_bucket{kind="AlertChannel",operation="update",le="0.01"} 0
+checkly_reconcile_duration_seconds_bucket{kind="AlertChannel",operation="update",le="0.02"} 0
+checkly_reconcile_duration_seconds_bucket{kind="AlertChannel",operation="update",le="0.04"} 1
+checkly_reconcile_duration_seconds_bucket{kind="AlertChannel",operation="update",le="0.08"} 1
+checkly_reconcile_duration_seconds_bucket{kind="AlertChannel",operation="update",le="0.16"} 1
+checkly_reconcile_duration_seconds_bucket{kind="AlertChannel",operation="update",le="0.32"} 1
+checkly_reconcile_duration_seconds_bucket{kind="AlertChannel",operation="update",le="0.64"} 1
+checkly_reconcile_duration_seconds_bucket{kind="AlertChannel",operation="update",le="1.28"} 1
+checkly_reconcile_duration_seconds_bucket{kind="AlertChannel",operation="update",le="2.56"} 1
+checkly_reconcile_duration_seconds_bucket{kind="AlertChannel",operation="update",le="5.12"} 2
+checkly_reconcile_duration_seconds_bucket{kind="AlertChannel",operation="update",le="10.24"} 2
+checkly_reconcile_duration_seconds_bucket{kind="AlertChannel",operation="update",le="20.48"} 2
+checkly_reconcile_duration_seconds_bucket{kind="AlertChannel",operation="update",le="+Inf"} 2
+checkly_reconcile_duration_seconds_sum{kind="AlertChannel",operation="update"} 3.03
+checkly_reconcile_duration_seconds_count{kind="AlertChannel",operation="update"} 2
+checkly_reconcile_duration_seconds_bucket{kind="ApiCheck",operation="none",le="0.01"} 1
+checkly_reconcile_duration_seconds_bucket{kind="ApiCheck",operation="none",le="0.02"} 1
+checkly_reconcile_duration_seconds_bucket{kind="ApiCheck",operation="none",le="0.04"} 1
+checkly_reconcile_duration_seconds_bucket{kind="ApiCheck",operation="none",le="0.08"} 1
+checkly_reconcile_duration_seconds_bucket{kind="ApiCheck",operation="none",le="0.16"} 1
+checkly_reconcile_duration_seconds_bucket{kind="ApiCheck",operation="none",le="0.32"} 1
+checkly_reconcile_duration_seconds_bucket{kind="ApiCheck",operation="none",le="0.64"} 1
+checkly_reconcile_duration_seconds_bucket{kind="ApiCheck",operation="none",le="1.28"} 1
+checkly_reconcile_duration_seconds_bucket{kind="ApiCheck",operation="none",le="2.56"} 1
+checkly_reconcile_duration_seconds_bucket{kind="ApiCheck",operation="none",le="5.12"} 1
+checkly_reconcile_duration_seconds_bucket{kind="ApiCheck",operation="none",le="10.24"} 1
+checkly_reconcile_duration_seconds_bucket{kind="ApiCheck",operation="none",le="20.48"} 1
+checkly_reconcile_duration_seconds_bucket{kind="ApiCheck",operation="none",le="+Inf"} 1
+checkly_reconcile_duration_seconds_sum{kind="ApiCheck",operation="none"} 0.001
+checkly_reconcile_duration_seconds_count{kind="ApiCheck",operation="none"} 1
+`
+	err := testutil.CollectAndCompare(reconcileDuration, strings.NewReader(expected))
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/checkly/checkly-operator/internal/audit"
+)
+
+// Payload is the body posted to the webhook for every change made to a checklyhq.com resource
+type Payload struct {
+	Operation string       `json:"operation"`
+	Kind      string       `json:"kind"`
+	Name      string       `json:"name"`
+	Namespace string       `json:"namespace,omitempty"`
+	ChecklyID interface{}  `json:"checklyID"`
+	Spec      interface{}  `json:"spec"`
+	Before    *audit.State `json:"before,omitempty"`
+	Timestamp time.Time    `json:"timestamp"`
+}
+
+// Notifier posts a summary of successful changes to a webhook, a nil Notifier does nothing
+type Notifier struct {
+	url    string
+	client *http.Client
+}
+
+// New returns a Notifier posting to the supplied URL
+func New(url string) *Notifier {
+	return &Notifier{
+		url:    url,
+		client: &http.Client{Timeout: time.Second * 5},
+	}
+}
+
+// Notify posts the change to the webhook, failed changes are skipped. Failures are only logged, they must not fail the reconciliation.
+func (n *Notifier) Notify(ctx context.Context, change audit.Record) {
+	if n == nil || change.Err != nil {
+		return
+	}
+
+	err := n.post(ctx, Payload{
+		Operation: change.Operation,
+		Kind:      change.Kind,
+		Name:      change.Object.GetName(),
+		Namespace: change.Object.GetNamespace(),
+		ChecklyID: change.ChecklyID,
+		Spec:      change.Spec,
+		Before:    change.Before,
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to post change notification", "url", n.url)
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	"github.com/checkly/checkly-operator/internal/audit"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNotify(t *testing.T) {
+	var payloads []Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := Payload{}
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			t.Errorf("Expected no error, got %s", err)
+		}
+		payloads = append(payloads, payload)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notifier := New(server.URL)
+	check := &checklyv1alpha1.ApiCheck{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "bar"}}
+
+	notifier.Notify(context.Background(), audit.Record{Operation: audit.Create, Kind: "ApiCheck", Object: check, ChecklyID: "2"})
+	notifier.Notify(context.Background(), audit.Record{Operation: audit.Update, Kind: "ApiCheck", Object: check, ChecklyID: "2", Err: errors.New("baz")})
+
+	if len(payloads) != 1 {
+		t.Fatalf("Expected 1 notification, got %d", len(payloads))
+	}
+
+	payload := payloads[0]
+	if payload.Operation != audit.Create || payload.Kind != "ApiCheck" || payload.Name != "foo" || payload.Namespace != "bar" || payload.ChecklyID != "2" {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+
+	// Failures must not panic or block
+	New("http://127.0.0.1:0").Notify(context.Background(), audit.Record{Operation: audit.Delete, Kind: "ApiCheck", Object: check})
+
+	// A nil notifier should do nothing
+	var nilNotifier *Notifier
+	nilNotifier.Notify(context.Background(), audit.Record{Operation: audit.Delete, Kind: "ApiCheck", Object: check})
+}
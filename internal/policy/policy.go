@@ -0,0 +1,136 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+)
+
+// Policies holds compiled CEL expressions, keyed by the name of the policy
+type Policies struct {
+	programs map[string]cel.Program
+}
+
+// Compile parses and checks the supplied CEL expressions, each expression has access to the
+// `metadata` and `spec` variables of the evaluated resource and has to return a boolean
+func Compile(expressions map[string]string) (policies *Policies, err error) {
+	env, err := cel.NewEnv(
+		cel.Variable("metadata", cel.DynType),
+		cel.Variable("spec", cel.DynType),
+	)
+	if err != nil {
+		return
+	}
+
+	policies = &Policies{
+		programs: make(map[string]cel.Program),
+	}
+
+	for name, expression := range expressions {
+		ast, issues := env.Compile(expression)
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("policy %s does not compile: %w", name, issues.Err())
+		}
+
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			return nil, fmt.Errorf("policy %s has to return a boolean, got %s", name, ast.OutputType())
+		}
+
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s can not be evaluated: %w", name, err)
+		}
+
+		policies.programs[name] = program
+	}
+
+	return
+}
+
+// Evaluate runs every policy against the supplied metadata and spec, the returned error lists all the violated policies
+func (p *Policies) Evaluate(metadata interface{}, spec interface{}) error {
+	vars := make(map[string]interface{})
+	for name, value := range map[string]interface{}{"metadata": metadata, "spec": spec} {
+		converted, err := toMap(value)
+		if err != nil {
+			return err
+		}
+		vars[name] = converted
+	}
+
+	var violations []string
+	for name, program := range p.programs {
+		out, _, err := program.Eval(vars)
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("%s (%s)", name, err))
+			continue
+		}
+
+		allowed, ok := out.Value().(bool)
+		if !ok || !allowed {
+			violations = append(violations, name)
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	sort.Strings(violations)
+	return fmt.Errorf("violated policies: %s", strings.Join(violations, ", "))
+}
+
+// toMap converts a struct into the generic map representation CEL understands, keyed by the json field names.
+// Empty strings and objects are dropped, so `has()` can be used to determine if a field is set.
+func toMap(value interface{}) (converted map[string]interface{}, err error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(data, &converted)
+	if err != nil {
+		return
+	}
+
+	prune(converted)
+
+	return
+}
+
+func prune(values map[string]interface{}) {
+	for key, value := range values {
+		switch v := value.(type) {
+		case nil:
+			delete(values, key)
+		case string:
+			if v == "" {
+				delete(values, key)
+			}
+		case map[string]interface{}:
+			prune(v)
+			if len(v) == 0 {
+				delete(values, key)
+			}
+		}
+	}
+}
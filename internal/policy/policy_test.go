@@ -0,0 +1,100 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+type testSpec struct {
+	Region string        `json:"region"`
+	Email  testSpecEmail `json:"email"`
+}
+
+type testSpecEmail struct {
+	Address string `json:"address"`
+}
+
+type testMetadata struct {
+	Name string `json:"name"`
+}
+
+func TestCompile(t *testing.T) {
+	_, err := Compile(map[string]string{
+		"valid": `spec.region == "EU"`,
+	})
+	if err != nil {
+		t.Errorf("Expected no error, got %e", err)
+	}
+
+	_, err = Compile(map[string]string{
+		"invalid": `spec.region ==`,
+	})
+	if err == nil {
+		t.Error("Expected error, got none")
+	}
+
+	_, err = Compile(map[string]string{
+		"not-boolean": `"foo"`,
+	})
+	if err == nil {
+		t.Error("Expected error, got none")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	policies, err := Compile(map[string]string{
+		"eu-only":     `has(spec.region) && spec.region == "EU"`,
+		"name-prefix": `metadata.name.startsWith("team-")`,
+	})
+	if err != nil {
+		t.Errorf("Expected no error, got %e", err)
+	}
+
+	err = policies.Evaluate(testMetadata{Name: "team-foo"}, testSpec{Region: "EU"})
+	if err != nil {
+		t.Errorf("Expected no error, got %e", err)
+	}
+
+	err = policies.Evaluate(testMetadata{Name: "foo"}, testSpec{Region: "EU"})
+	if err == nil || !strings.Contains(err.Error(), "name-prefix") {
+		t.Errorf("Expected name-prefix violation, got %v", err)
+	}
+
+	err = policies.Evaluate(testMetadata{Name: "foo"}, testSpec{})
+	if err == nil || !strings.Contains(err.Error(), "eu-only, name-prefix") {
+		t.Errorf("Expected eu-only and name-prefix violations, got %v", err)
+	}
+
+	policies, err = Compile(map[string]string{
+		"no-email": `!has(spec.email)`,
+	})
+	if err != nil {
+		t.Errorf("Expected no error, got %e", err)
+	}
+
+	err = policies.Evaluate(testMetadata{Name: "foo"}, testSpec{})
+	if err != nil {
+		t.Errorf("Expected no error, got %e", err)
+	}
+
+	err = policies.Evaluate(testMetadata{Name: "foo"}, testSpec{Email: testSpecEmail{Address: "foo@bar.baz"}})
+	if err == nil {
+		t.Error("Expected error, got none")
+	}
+}
@@ -0,0 +1,287 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tfimport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/checkly/checkly-go-sdk"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+// alertChannelType is the Terraform resource type of checklyhq.com alert channels
+const alertChannelType = "checkly_alert_channel"
+
+// Options configures how the Terraform resources are converted
+type Options struct {
+	// ControllerDomain is the prefix of the pin-id annotation, it has to match the --controller-domain of the operator
+	ControllerDomain string
+
+	// SecretNamespace is the namespace of the secrets holding the credentials of the alert channels
+	SecretNamespace string
+}
+
+// Result holds the resources converted from the Terraform state
+type Result struct {
+	AlertChannels []checklyv1alpha1.AlertChannel
+	Secrets       []corev1.Secret
+}
+
+// state is the subset of the Terraform state file format version 4 the import reads
+type state struct {
+	Version   int `json:"version"`
+	Resources []struct {
+		Mode      string `json:"mode"`
+		Type      string `json:"type"`
+		Name      string `json:"name"`
+		Instances []struct {
+			IndexKey   interface{}     `json:"index_key"`
+			Attributes json.RawMessage `json:"attributes"`
+		} `json:"instances"`
+	} `json:"resources"`
+}
+
+// alertChannel holds the attributes of a checkly_alert_channel resource, nested blocks are stored as lists
+type alertChannel struct {
+	ID                 string `json:"id"`
+	SendRecovery       bool   `json:"send_recovery"`
+	SendFailure        bool   `json:"send_failure"`
+	SendDegraded       bool   `json:"send_degraded"`
+	SSLExpiry          bool   `json:"ssl_expiry"`
+	SSLExpiryThreshold int    `json:"ssl_expiry_threshold"`
+	Email              []struct {
+		Address string `json:"address"`
+	} `json:"email"`
+	Slack []struct {
+		URL     string `json:"url"`
+		Channel string `json:"channel"`
+	} `json:"slack"`
+	SMS []struct {
+		Name   string `json:"name"`
+		Number string `json:"number"`
+	} `json:"sms"`
+	Call []struct {
+		Name   string `json:"name"`
+		Number string `json:"number"`
+	} `json:"call"`
+	Opsgenie []struct {
+		APIKey   string `json:"api_key"`
+		Region   string `json:"region"`
+		Priority string `json:"priority"`
+	} `json:"opsgenie"`
+	Pagerduty []struct {
+		Account     string `json:"account"`
+		ServiceKey  string `json:"service_key"`
+		ServiceName string `json:"service_name"`
+	} `json:"pagerduty"`
+	Webhook []struct {
+		URL             string            `json:"url"`
+		Method          string            `json:"method"`
+		Template        string            `json:"template"`
+		Headers         map[string]string `json:"headers"`
+		QueryParameters map[string]string `json:"query_parameters"`
+	} `json:"webhook"`
+}
+
+// Import converts the checkly_alert_channel resources of the Terraform state into AlertChannels bound to the existing
+// alert channels with the pin-id annotation, credentials are moved into secrets referenced by the AlertChannels
+func Import(r io.Reader, opts Options) (result Result, err error) {
+	var s state
+	err = json.NewDecoder(r).Decode(&s)
+	if err != nil {
+		return result, fmt.Errorf("can't read the Terraform state: %w", err)
+	}
+	if s.Version != 4 {
+		return result, fmt.Errorf("unsupported Terraform state version %d, only version 4 is supported", s.Version)
+	}
+
+	names := map[string]bool{}
+	for _, resource := range s.Resources {
+		if resource.Mode != "managed" || resource.Type != alertChannelType {
+			continue
+		}
+
+		for _, instance := range resource.Instances {
+			address := resource.Name
+			if instance.IndexKey != nil {
+				address = fmt.Sprintf("%s-%v", resource.Name, instance.IndexKey)
+			}
+			name := resourceName(address)
+			if names[name] {
+				return result, fmt.Errorf("%s.%s: resource name %s is used more than once", alertChannelType, address, name)
+			}
+			names[name] = true
+
+			var attributes alertChannel
+			err = json.Unmarshal(instance.Attributes, &attributes)
+			if err != nil {
+				return result, fmt.Errorf("%s.%s: %w", alertChannelType, address, err)
+			}
+
+			ac, secret, err := convert(name, attributes, opts)
+			if err != nil {
+				return result, fmt.Errorf("%s.%s: %w", alertChannelType, address, err)
+			}
+			result.AlertChannels = append(result.AlertChannels, ac)
+			if secret != nil {
+				result.Secrets = append(result.Secrets, *secret)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// invalidNameChars matches the characters which aren't allowed in kubernetes resource names
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// resourceName converts the address of the Terraform resource into a valid kubernetes resource name
+func resourceName(address string) string {
+	name := invalidNameChars.ReplaceAllString(strings.ToLower(address), "-")
+	return strings.Trim(name, "-")
+}
+
+// convert returns the AlertChannel of the Terraform attributes and the secret holding its credentials, if it has any
+func convert(name string, attributes alertChannel, opts Options) (ac checklyv1alpha1.AlertChannel, secret *corev1.Secret, err error) {
+	if attributes.ID == "" {
+		return ac, nil, fmt.Errorf("no ID in the Terraform state")
+	}
+
+	ac = checklyv1alpha1.AlertChannel{
+		TypeMeta: metav1.TypeMeta{APIVersion: checklyv1alpha1.GroupVersion.String(), Kind: "AlertChannel"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: map[string]string{fmt.Sprintf("%s/pin-id", opts.ControllerDomain): attributes.ID},
+		},
+		Spec: checklyv1alpha1.AlertChannelSpec{
+			SendRecovery: attributes.SendRecovery,
+			SendFailure:  attributes.SendFailure,
+			SendDegraded: attributes.SendDegraded,
+			SSLExpiry:    attributes.SSLExpiry,
+		},
+	}
+	if attributes.SSLExpiry {
+		ac.Spec.SSLExpiryThreshold = attributes.SSLExpiryThreshold
+	}
+
+	credentials := map[string]string{}
+	secretRef := func(key string) corev1.ObjectReference {
+		return corev1.ObjectReference{Name: name, Namespace: opts.SecretNamespace, FieldPath: key}
+	}
+
+	switch {
+	case len(attributes.Email) != 0:
+		ac.Spec.Email = checkly.AlertChannelEmail{Address: attributes.Email[0].Address}
+	case len(attributes.Slack) != 0:
+		credentials["url"] = attributes.Slack[0].URL
+		ac.Spec.Slack = checklyv1alpha1.AlertChannelSlack{URLSecret: secretRef("url"), Channel: attributes.Slack[0].Channel}
+	case len(attributes.SMS) != 0:
+		ac.Spec.SMS = checklyv1alpha1.AlertChannelSMS{Number: attributes.SMS[0].Number, Name: attributes.SMS[0].Name}
+	case len(attributes.Call) != 0:
+		ac.Spec.Phone = checklyv1alpha1.AlertChannelPhone{Number: attributes.Call[0].Number, Name: attributes.Call[0].Name}
+	case len(attributes.Opsgenie) != 0:
+		credentials["apikey"] = attributes.Opsgenie[0].APIKey
+		ac.Spec.OpsGenie = checklyv1alpha1.AlertChannelOpsGenie{
+			APISecret: secretRef("apikey"),
+			Region:    attributes.Opsgenie[0].Region,
+			Priority:  attributes.Opsgenie[0].Priority,
+		}
+	case len(attributes.Pagerduty) != 0:
+		credentials["servicekey"] = attributes.Pagerduty[0].ServiceKey
+		ac.Spec.PagerDuty = checklyv1alpha1.AlertChannelPagerDuty{
+			ServiceKeySecret: secretRef("servicekey"),
+			ServiceName:      attributes.Pagerduty[0].ServiceName,
+			Account:          attributes.Pagerduty[0].Account,
+		}
+	case len(attributes.Webhook) != 0:
+		webhook := attributes.Webhook[0]
+		ac.Spec.Webhook = checklyv1alpha1.AlertChannelWebhook{
+			URL:             webhook.URL,
+			Method:          webhook.Method,
+			Template:        webhook.Template,
+			Headers:         keyValues(webhook.Headers),
+			QueryParameters: keyValues(webhook.QueryParameters),
+		}
+	default:
+		return ac, nil, fmt.Errorf("alert channel %s has none of the supported configurations", attributes.ID)
+	}
+
+	if len(credentials) == 0 {
+		return ac, nil, nil
+	}
+
+	secret = &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: opts.SecretNamespace},
+		StringData: credentials,
+	}
+	return ac, secret, nil
+}
+
+// keyValues converts the headers or query parameters of a webhook into the spec format, ordered by key
+func keyValues(values map[string]string) (converted []checklyv1alpha1.AlertChannelKeyValue) {
+	for key, value := range values {
+		converted = append(converted, checklyv1alpha1.AlertChannelKeyValue{Key: key, Value: value})
+	}
+	sort.Slice(converted, func(i, j int) bool {
+		return converted[i].Key < converted[j].Key
+	})
+	return
+}
+
+// Manifests renders the secrets and AlertChannels as a multi-document YAML stream, the secrets come first so they
+// exist once the AlertChannels are applied. The empty status and creation timestamp are left out.
+func (r Result) Manifests() ([]byte, error) {
+	var objects []runtime.Object
+	for i := range r.Secrets {
+		objects = append(objects, &r.Secrets[i])
+	}
+	for i := range r.AlertChannels {
+		objects = append(objects, &r.AlertChannels[i])
+	}
+
+	var buf bytes.Buffer
+	for _, object := range objects {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+		if err != nil {
+			return nil, err
+		}
+		delete(content, "status")
+		unstructured.RemoveNestedField(content, "metadata", "creationTimestamp")
+
+		manifest, err := yaml.Marshal(content)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString("---\n")
+		buf.Write(manifest)
+	}
+
+	return buf.Bytes(), nil
+}
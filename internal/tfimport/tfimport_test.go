@@ -0,0 +1,135 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tfimport
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const testState = `{
+  "version": 4,
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "checkly_alert_channel",
+      "name": "ops_email",
+      "instances": [
+        {"attributes": {"id": "11", "send_failure": true, "send_recovery": true, "email": [{"address": "ops@foo.bar"}]}}
+      ]
+    },
+    {
+      "mode": "managed",
+      "type": "checkly_alert_channel",
+      "name": "slack",
+      "instances": [
+        {"index_key": "Team_A", "attributes": {"id": "12", "ssl_expiry": true, "ssl_expiry_threshold": 14, "slack": [{"url": "https://hooks.slack.com/services/foo", "channel": "#alerts"}]}}
+      ]
+    },
+    {
+      "mode": "managed",
+      "type": "checkly_alert_channel",
+      "name": "hook",
+      "instances": [
+        {"attributes": {"id": "13", "webhook": [{"url": "https://foo.bar/alerts", "method": "POST", "headers": {"X-Foo": "foo", "Authorization": "bar"}}]}}
+      ]
+    },
+    {
+      "mode": "managed",
+      "type": "checkly_check",
+      "name": "api",
+      "instances": [{"attributes": {"id": "00000000-0000-0000-0000-000000000001"}}]
+    },
+    {
+      "mode": "data",
+      "type": "checkly_alert_channel",
+      "name": "existing",
+      "instances": [{"attributes": {"id": "14"}}]
+    }
+  ]
+}`
+
+func TestImport(t *testing.T) {
+	result, err := Import(strings.NewReader(testState), Options{ControllerDomain: "k8s.checklyhq.com", SecretNamespace: "checkly"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if len(result.AlertChannels) != 3 {
+		t.Fatalf("Expected the 3 managed alert channels, got %d", len(result.AlertChannels))
+	}
+
+	email := result.AlertChannels[0]
+	if email.Name != "ops-email" || email.Annotations["k8s.checklyhq.com/pin-id"] != "11" {
+		t.Errorf("Expected ops-email pinned to 11, got %s %v", email.Name, email.Annotations)
+	}
+	if email.Spec.Email.Address != "ops@foo.bar" || !email.Spec.SendFailure || !email.Spec.SendRecovery || email.Spec.SendDegraded {
+		t.Errorf("Expected the email configuration, got %+v", email.Spec)
+	}
+
+	slack := result.AlertChannels[1]
+	expectedRef := corev1.ObjectReference{Name: "slack-team-a", Namespace: "checkly", FieldPath: "url"}
+	if slack.Name != "slack-team-a" || slack.Spec.Slack.URL != "" || slack.Spec.Slack.URLSecret != expectedRef || slack.Spec.Slack.Channel != "#alerts" {
+		t.Errorf("Expected the Slack URL in a secret, got %s %+v", slack.Name, slack.Spec.Slack)
+	}
+	if !slack.Spec.SSLExpiry || slack.Spec.SSLExpiryThreshold != 14 {
+		t.Errorf("Expected SSL expiry alerts 14 days ahead, got %t %d", slack.Spec.SSLExpiry, slack.Spec.SSLExpiryThreshold)
+	}
+
+	if len(result.Secrets) != 1 || result.Secrets[0].StringData["url"] != "https://hooks.slack.com/services/foo" {
+		t.Errorf("Expected the secret of the Slack URL, got %+v", result.Secrets)
+	}
+
+	headers := result.AlertChannels[2].Spec.Webhook.Headers
+	if len(headers) != 2 || headers[0].Key != "Authorization" || headers[1].Key != "X-Foo" {
+		t.Errorf("Expected the headers ordered by key, got %v", headers)
+	}
+
+	manifests, err := result.Manifests()
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	documents := strings.Split(string(manifests), "---\n")
+	if len(documents) != 5 || !strings.Contains(documents[1], "kind: Secret") || !strings.Contains(documents[2], "kind: AlertChannel") {
+		t.Errorf("Expected the secret followed by the alert channels, got %s", manifests)
+	}
+	if strings.Contains(string(manifests), "status") || strings.Contains(string(manifests), "creationTimestamp") {
+		t.Errorf("Expected no status and creation timestamp, got %s", manifests)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	testData := []struct {
+		state string
+		err   string
+	}{
+		{`{"version": 3}`, "unsupported Terraform state version 3"},
+		{`{"version": 4, "resources": [{"mode": "managed", "type": "checkly_alert_channel", "name": "foo", "instances": [{"attributes": {"email": [{"address": "foo@bar"}]}}]}]}`, "no ID"},
+		{`{"version": 4, "resources": [{"mode": "managed", "type": "checkly_alert_channel", "name": "foo", "instances": [{"attributes": {"id": "1"}}]}]}`, "none of the supported configurations"},
+		{`{"version": 4, "resources": [{"mode": "managed", "type": "checkly_alert_channel", "name": "foo_bar", "instances": [{"attributes": {"id": "1", "email": [{"address": "foo@bar"}]}}]}, {"mode": "managed", "type": "checkly_alert_channel", "name": "foo-bar", "instances": [{"attributes": {"id": "2", "email": [{"address": "foo@bar"}]}}]}]}`, "used more than once"},
+		{`foo`, "can't read the Terraform state"},
+	}
+
+	for _, tt := range testData {
+		_, err := Import(strings.NewReader(tt.state), Options{})
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Expected error containing %q, got %v", tt.err, err)
+		}
+	}
+}
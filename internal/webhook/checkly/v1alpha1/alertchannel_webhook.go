@@ -0,0 +1,161 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	errs "errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+	external "github.com/checkly/checkly-operator/external/checkly"
+)
+
+var alertchannellog = logf.Log.WithName("alertchannel-resource")
+
+// parentLookupTimeout bounds reading the parents of an AlertChannel, it's well below the timeoutSeconds of the webhook
+// so a slow API server lets the AlertChannel through with a warning instead of failing the request
+var parentLookupTimeout = 3 * time.Second
+
+// SetupAlertChannelWebhookWithManager registers the validating webhook of AlertChannels with the manager.
+func SetupAlertChannelWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&checklyv1alpha1.AlertChannel{}).
+		WithValidator(&AlertChannelCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-k8s-checklyhq-com-v1alpha1-alertchannel,mutating=false,failurePolicy=fail,sideEffects=None,groups=k8s.checklyhq.com,resources=alertchannels,verbs=create;update,versions=v1alpha1,name=valertchannel.k8s.checklyhq.com,admissionReviewVersions=v1,timeoutSeconds=5
+
+// AlertChannelCustomValidator rejects AlertChannels which don't configure exactly one alert channel type with its
+// required fields, or whose parent references form a cycle, when they're applied instead of failing to sync them
+// later on
+type AlertChannelCustomValidator struct {
+	// Client reads the parents of the AlertChannels, parent references aren't checked for cycles without it
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &AlertChannelCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type AlertChannel.
+func (v *AlertChannelCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	alertChannel, ok := obj.(*checklyv1alpha1.AlertChannel)
+	if !ok {
+		return nil, fmt.Errorf("expected an AlertChannel object but got %T", obj)
+	}
+	alertchannellog.V(1).Info("Validation for AlertChannel upon creation", "name", alertChannel.GetName())
+
+	return v.validate(ctx, alertChannel)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type AlertChannel.
+func (v *AlertChannelCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	alertChannel, ok := newObj.(*checklyv1alpha1.AlertChannel)
+	if !ok {
+		return nil, fmt.Errorf("expected an AlertChannel object for the newObj but got %T", newObj)
+	}
+	oldAlertChannel, ok := oldObj.(*checklyv1alpha1.AlertChannel)
+	if !ok {
+		return nil, fmt.Errorf("expected an AlertChannel object for the oldObj but got %T", oldObj)
+	}
+	alertchannellog.V(1).Info("Validation for AlertChannel upon update", "name", alertChannel.GetName())
+
+	// AlertChannels applied before the webhook was enabled may be invalid, updates leaving the spec as it is, ex. the
+	// operator removing its finalizer, are let through
+	if alertChannel.GetDeletionTimestamp() != nil || equality.Semantic.DeepEqual(oldAlertChannel.Spec, alertChannel.Spec) {
+		return nil, nil
+	}
+
+	return v.validate(ctx, alertChannel)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type AlertChannel.
+func (v *AlertChannelCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *AlertChannelCustomValidator) validate(ctx context.Context, alertChannel *checklyv1alpha1.AlertChannel) (admission.Warnings, error) {
+	warnings := rawConfigWarnings(alertChannel)
+	err := validateAlertChannel(alertChannel)
+	if err != nil {
+		return warnings, err
+	}
+
+	err = v.validateParentRef(ctx, alertChannel)
+	if ctx.Err() == nil && errs.Is(err, context.DeadlineExceeded) {
+		alertchannellog.Info("Timed out reading the parents of the AlertChannel, admitting it unchecked", "name", alertChannel.GetName())
+		return append(warnings, "spec.parentref was not checked for cycles, reading the parent AlertChannels timed out"), nil
+	}
+	return warnings, err
+}
+
+// validateParentRef rejects AlertChannels whose chain of parents leads back to an AlertChannel of the chain, parents
+// which don't exist yet end the chain. Reading the parents is bounded by the parentLookupTimeout.
+func (v *AlertChannelCustomValidator) validateParentRef(ctx context.Context, alertChannel *checklyv1alpha1.AlertChannel) error {
+	if v.Client == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, parentLookupTimeout)
+	defer cancel()
+
+	chain := []string{alertChannel.Name}
+	for parentName := alertChannel.Spec.ParentRef; parentName != ""; {
+		if index := slices.Index(chain, parentName); index != -1 {
+			return fmt.Errorf("parent references form a cycle: %s", strings.Join(append(chain[index:], parentName), " -> "))
+		}
+		chain = append(chain, parentName)
+
+		parent := &checklyv1alpha1.AlertChannel{}
+		err := v.Client.Get(ctx, types.NamespacedName{Name: parentName}, parent)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		parentName = parent.Spec.ParentRef
+	}
+	return nil
+}
+
+// rawConfigWarnings warns about the rawconfig of the AlertChannel, its contents are passed on to checklyhq.com as they are
+func rawConfigWarnings(alertChannel *checklyv1alpha1.AlertChannel) admission.Warnings {
+	if strings.TrimSpace(alertChannel.Spec.RawConfig) == "" {
+		return nil
+	}
+	return admission.Warnings{"spec.rawconfig is not validated by the operator, mistakes only surface as checklyhq.com API errors"}
+}
+
+func validateAlertChannel(alertChannel *checklyv1alpha1.AlertChannel) error {
+	// The alert channel type and its fields may be inherited, only conflicting types are known to be invalid up front
+	if alertChannel.Spec.ParentRef != "" || alertChannel.Spec.PolicyRef != "" {
+		return external.ValidateAlertChannelType(alertChannel.Spec)
+	}
+	return external.ValidateAlertChannelFields(alertChannel.Spec)
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/checkly/checkly-go-sdk"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	checklyv1alpha1 "github.com/checkly/checkly-operator/api/checkly/v1alpha1"
+)
+
+func TestAlertChannelCustomValidator(t *testing.T) {
+	v := &AlertChannelCustomValidator{}
+	ctx := context.Background()
+
+	valid := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		Spec: checklyv1alpha1.AlertChannelSpec{
+			Email: checkly.AlertChannelEmail{Address: "foo@bar.baz"},
+		},
+	}
+	_, err := v.ValidateCreate(ctx, valid)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	conflicting := valid.DeepCopy()
+	conflicting.Spec.Webhook.URL = "https://foo.bar/alerts"
+	_, err = v.ValidateCreate(ctx, conflicting)
+	if err == nil {
+		t.Error("Expected an error for an AlertChannel configuring email and webhook, got none")
+	}
+
+	empty := &checklyv1alpha1.AlertChannel{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	_, err = v.ValidateCreate(ctx, empty)
+	if err == nil {
+		t.Error("Expected an error for an AlertChannel without a type, got none")
+	}
+
+	// The type may be set in rawconfig, for types the spec doesn't model
+	raw := empty.DeepCopy()
+	raw.Spec.RawConfig = `{"type": "SLACK", "config": {"url": "https://hooks.slack.com/services/foo", "channel": "#alerts"}}`
+	warnings, err := v.ValidateCreate(ctx, raw)
+	if err != nil {
+		t.Errorf("Expected no error for an AlertChannel only setting rawconfig, got %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "not validated") {
+		t.Errorf("Expected a warning about the unvalidated rawconfig, got %v", warnings)
+	}
+
+	// The type may be inherited from the parent
+	child := empty.DeepCopy()
+	child.Spec.ParentRef = "bar"
+	_, err = v.ValidateCreate(ctx, child)
+	if err != nil {
+		t.Errorf("Expected no error for an AlertChannel with a parent, got %v", err)
+	}
+
+	// Updates leaving an invalid spec unchanged are let through
+	updated := empty.DeepCopy()
+	updated.Finalizers = []string{"k8s.checklyhq.com/finalizer"}
+	_, err = v.ValidateUpdate(ctx, empty, updated)
+	if err != nil {
+		t.Errorf("Expected no error for an update leaving the spec unchanged, got %v", err)
+	}
+
+	_, err = v.ValidateUpdate(ctx, valid, conflicting)
+	if err == nil {
+		t.Error("Expected an error for an update configuring email and webhook, got none")
+	}
+}
+
+func TestAlertChannelCustomValidatorParentRef(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	parent := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "parent"},
+		Spec: checklyv1alpha1.AlertChannelSpec{
+			Email:     checkly.AlertChannelEmail{Address: "foo@bar.baz"},
+			ParentRef: "child",
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(parent).Build()
+	v := &AlertChannelCustomValidator{Client: c}
+	ctx := context.Background()
+
+	child := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "child"},
+		Spec:       checklyv1alpha1.AlertChannelSpec{ParentRef: "parent"},
+	}
+	_, err := v.ValidateCreate(ctx, child)
+	if err == nil || !strings.Contains(err.Error(), "child -> parent -> child") {
+		t.Errorf("Expected the cycle to be rejected, got %v", err)
+	}
+
+	child.Spec.ParentRef = "child"
+	_, err = v.ValidateCreate(ctx, child)
+	if err == nil || !strings.Contains(err.Error(), "child -> child") {
+		t.Errorf("Expected the self reference to be rejected, got %v", err)
+	}
+
+	// Parents which don't exist yet may be applied later on
+	child.Spec.ParentRef = "missing"
+	_, err = v.ValidateCreate(ctx, child)
+	if err != nil {
+		t.Errorf("Expected no error for a missing parent, got %v", err)
+	}
+}
+
+func TestAlertChannelCustomValidatorParentRefTimeout(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = checklyv1alpha1.AddToScheme(scheme)
+
+	// The API server never answers, reading the parent only ends with the lookup timeout
+	c := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, _ client.WithWatch, _ client.ObjectKey, _ client.Object, _ ...client.GetOption) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}).Build()
+	v := &AlertChannelCustomValidator{Client: c}
+
+	defer func(timeout time.Duration) { parentLookupTimeout = timeout }(parentLookupTimeout)
+	parentLookupTimeout = 10 * time.Millisecond
+
+	child := &checklyv1alpha1.AlertChannel{
+		ObjectMeta: metav1.ObjectMeta{Name: "child"},
+		Spec:       checklyv1alpha1.AlertChannelSpec{ParentRef: "parent"},
+	}
+	warnings, err := v.ValidateCreate(context.Background(), child)
+	if err != nil || len(warnings) != 1 || !strings.Contains(warnings[0], "spec.parentref") {
+		t.Errorf("Expected the AlertChannel to be admitted with a warning, got %v, %v", warnings, err)
+	}
+
+	// A request the API server gave up on isn't admitted
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = v.ValidateCreate(ctx, child)
+	if err == nil {
+		t.Error("Expected the cancelled request to fail")
+	}
+}